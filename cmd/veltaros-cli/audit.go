@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/VeltarosLabs/Veltaros/internal/audit"
+)
+
+func runAudit(args []string) {
+	if len(args) < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "verify":
+		runAuditVerify(args[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func runAuditVerify(args []string) {
+	fs := flag.NewFlagSet("audit verify", flag.ExitOnError)
+	file := fs.String("file", "data/node/audit.log", "Audit log path")
+	_ = fs.Parse(args)
+
+	path := strings.TrimSpace(*file)
+	if path == "" {
+		fatal(errors.New("--file is required"))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		fatal(err)
+	}
+	defer f.Close()
+
+	res, err := audit.Verify(f)
+	if err != nil {
+		var be *audit.BreakError
+		if errors.As(err, &be) {
+			fmt.Printf("FAIL: line %d (seq %d): %s\n", be.Line, be.Seq, be.Reason)
+			os.Exit(1)
+		}
+		fatal(err)
+	}
+	fmt.Printf("OK: %d entries, head %s\n", res.Entries, res.LastHash)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+)
+
+// runTxInspect decodes a SignedTx JSON document and checks it locally: the
+// txId against the draft, the from address against the public key, the
+// signature, and finally every ValidateSignedTx rule. It exits 1 if any
+// check fails, so it can gate a broadcast in scripts.
+func runTxInspect(args []string) {
+	fs := flag.NewFlagSet("tx inspect", flag.ExitOnError)
+	file := fs.String("file", "", "SignedTx JSON file (default: stdin)")
+	_ = fs.Parse(args)
+
+	var (
+		raw []byte
+		err error
+	)
+	if path := strings.TrimSpace(*file); path != "" && path != "-" {
+		raw, err = os.ReadFile(path)
+	} else {
+		raw, err = io.ReadAll(io.LimitReader(os.Stdin, 4*blockchain.MaxTxBytes))
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	var st blockchain.SignedTx
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&st); err != nil {
+		fatal(fmt.Errorf("decode SignedTx: %w", err))
+	}
+
+	d := st.Draft
+	fmt.Printf("version:      %d\n", d.Version)
+	fmt.Printf("networkId:    %s\n", d.NetworkID)
+	fmt.Printf("from:         %s\n", d.From)
+	if d.IsMulti() {
+		for i, o := range d.Outputs {
+			fmt.Printf("output[%d]:    %s %d\n", i, o.To, o.Amount)
+		}
+	} else {
+		fmt.Printf("to:           %s\n", d.To)
+		fmt.Printf("amount:       %d\n", d.Amount)
+	}
+	fmt.Printf("fee:          %d\n", d.Fee)
+	fmt.Printf("nonce:        %d\n", d.Nonce)
+	fmt.Printf("timestamp:    %d (%s)\n", d.Timestamp, time.Unix(d.Timestamp, 0).UTC().Format(time.RFC3339))
+	if d.Memo != "" {
+		fmt.Printf("memo:         %q\n", d.Memo)
+	}
+	if d.ClientRef != "" {
+		fmt.Printf("clientRef:    %q\n", d.ClientRef)
+	}
+	if d.Class != 0 {
+		fmt.Printf("class:        %d\n", d.Class)
+	}
+	if d.IsCancel() {
+		fmt.Println("kind:         cancel")
+	}
+	fmt.Printf("publicKeyHex: %s\n", st.PublicKeyHex)
+	fmt.Printf("signatureHex: %s\n", st.SignatureHex)
+	fmt.Printf("txId:         %s\n", st.TxID)
+	fmt.Println()
+
+	failed := false
+	check := func(name string, err error) {
+		if err != nil {
+			failed = true
+			fmt.Printf("FAIL  %-11s %s\n", name, err)
+			return
+		}
+		fmt.Printf("ok    %s\n", name)
+	}
+
+	h, herr := blockchain.TxHash(d)
+	check("txid", func() error {
+		if herr != nil {
+			return herr
+		}
+		if got := hex.EncodeToString(h[:]); !strings.EqualFold(got, st.TxID) {
+			return fmt.Errorf("computed %s", got)
+		}
+		return nil
+	}())
+	check("from/key", func() error {
+		derived, err := blockchain.AddressFromEd25519PublicKeyHex(st.PublicKeyHex)
+		if err != nil {
+			return err
+		}
+		if derived != d.From {
+			return fmt.Errorf("public key derives %s", derived)
+		}
+		return nil
+	}())
+	check("signature", func() error {
+		if herr != nil {
+			return errors.New("no txid to verify against")
+		}
+		pub, err := hex.DecodeString(st.PublicKeyHex)
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			return errors.New("invalid publicKeyHex")
+		}
+		sig, err := hex.DecodeString(st.SignatureHex)
+		if err != nil || len(sig) != ed25519.SignatureSize {
+			return errors.New("invalid signatureHex")
+		}
+		msg := blockchain.SignatureMessage(d.NetworkID, h)
+		if !ed25519.Verify(pub, msg[:], sig) {
+			return errors.New("does not verify")
+		}
+		return nil
+	}())
+	check("validate", blockchain.ValidateSignedTx(st))
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
 	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
 	"github.com/VeltarosLabs/Veltaros/internal/wallet"
+	"github.com/VeltarosLabs/Veltaros/pkg/api"
 	"github.com/VeltarosLabs/Veltaros/pkg/version"
 )
 
@@ -28,6 +34,12 @@ func main() {
 		runSign(os.Args[2:])
 	case "verify":
 		runVerify(os.Args[2:])
+	case "faucet":
+		runFaucet(os.Args[2:])
+	case "tx":
+		runTx(os.Args[2:])
+	case "audit":
+		runAudit(os.Args[2:])
 	default:
 		usage()
 		os.Exit(2)
@@ -39,14 +51,30 @@ func usage() {
 
 Usage:
   veltaros-cli version
-  veltaros-cli wallet new --out <path>
-  veltaros-cli wallet address --key <path>
+  veltaros-cli wallet new --out <path> [--mnemonic [--words 12|24]] [--encrypt]
+  veltaros-cli wallet restore --mnemonic "<words...>" --out <path> [--encrypt]
+  veltaros-cli wallet address --key <path> [--network <id>]   (bech32 form)
   veltaros-cli sign --key <path> --msg <text>
   veltaros-cli verify --pub <hex> --msg <text> --sig <hex>
+  veltaros-cli faucet --url <node> --addr <address> --amount <n> [--api-key <key>]
+  veltaros-cli tx send --to <addr> --amount <n> --key <path> --node <url> [--fee <n>] [--nonce <n>] [--network <id>] [--memo <text>]
+  veltaros-cli tx send --out <addr>=<n> [--out <addr>=<n> ...] --key <path> --node <url> [--fee <n>] [--nonce <n>] [--network <id>] [--memo <text>]
+  veltaros-cli tx pending --key <path> --node <url>
+  veltaros-cli tx cancel --nonce <n> --key <path> --node <url> [--fee <n>]
+  veltaros-cli tx inspect [--file <tx.json>]   (default: stdin)
+  veltaros-cli audit verify --file <path>
 
 Notes:
-  - wallet keys are stored as hex-encoded ed25519 private keys (64 bytes).
-  - addresses are deterministic: hex(pubHash20||checksum4).
+  - wallet keys are stored as hex-encoded ed25519 private keys (64 bytes),
+    or with --encrypt as a PBKDF2-SHA256 + AES-256-GCM JSON envelope.
+    Commands that load a key prompt for its passphrase on stdin, or read
+    VELTAROS_WALLET_PASSPHRASE when set.
+  - recovery phrases are BIP39 English mnemonics; the ed25519 key is the
+    SLIP-0010 master key of the BIP39 seed (no passphrase).
+  - addresses are deterministic: hex(pubHash20||checksum4). Address
+    arguments also accept the bech32m form, prefixed "vel1" on
+    veltaros-mainnet and "tvel1" on every other network; a prefix that does
+    not match the node's network is rejected.
 `)
 }
 
@@ -66,46 +94,65 @@ func runWallet(args []string) {
 	case "new":
 		fs := flag.NewFlagSet("wallet new", flag.ExitOnError)
 		out := fs.String("out", filepath.Join("data", "wallets", "default.key"), "Output path for private key file")
+		useMnemonic := fs.Bool("mnemonic", false, "Derive the key from a new recovery phrase and print it")
+		words := fs.Int("words", wallet.DefaultMnemonicWords, "Recovery phrase length with --mnemonic (12 or 24)")
+		encrypt := fs.Bool("encrypt", false, "Encrypt the key file with a passphrase")
 		_ = fs.Parse(args[1:])
 
-		kp, err := wallet.Generate()
+		var (
+			kp     wallet.Keypair
+			phrase string
+			err    error
+		)
+		if *useMnemonic {
+			if *words != 12 && *words != 24 {
+				fatal(fmt.Errorf("--words must be 12 or 24"))
+			}
+			phrase, err = wallet.NewMnemonic(*words)
+			if err == nil {
+				kp, err = wallet.FromMnemonic(phrase)
+			}
+		} else {
+			kp, err = wallet.Generate()
+		}
 		if err != nil {
 			fatal(err)
 		}
-		if err := wallet.SavePrivateKeyHex(*out, kp.PrivateKey); err != nil {
-			fatal(err)
+		saveWallet(*out, kp, *encrypt)
+		if phrase != "" {
+			fmt.Println("Recovery phrase (write it down; it restores this wallet):")
+			fmt.Println(phrase)
+		}
+
+	case "restore":
+		fs := flag.NewFlagSet("wallet restore", flag.ExitOnError)
+		out := fs.String("out", filepath.Join("data", "wallets", "default.key"), "Output path for private key file")
+		phrase := fs.String("mnemonic", "", "Recovery phrase (12 or 24 words)")
+		encrypt := fs.Bool("encrypt", false, "Encrypt the key file with a passphrase")
+		_ = fs.Parse(args[1:])
+
+		if strings.TrimSpace(*phrase) == "" {
+			fatal(fmt.Errorf("--mnemonic is required"))
 		}
-		addr, err := wallet.AddressFromPublicKey(kp.PublicKey)
+		kp, err := wallet.FromMnemonic(*phrase)
 		if err != nil {
 			fatal(err)
 		}
-		fmt.Println("Saved private key:", *out)
-		fmt.Println("Address:", addr)
+		saveWallet(*out, kp, *encrypt)
 
 	case "address":
 		fs := flag.NewFlagSet("wallet address", flag.ExitOnError)
 		keyPath := fs.String("key", filepath.Join("data", "wallets", "default.key"), "Path to private key file")
+		network := fs.String("network", "", "Print the bech32 form for this network ID instead of hex")
 		_ = fs.Parse(args[1:])
 
-		priv, err := wallet.LoadPrivateKeyHex(*keyPath)
-		if err != nil {
-			fatal(err)
-		}
-		pubAny := priv.Public()
-		pub, ok := pubAny.([]byte)
-		if ok {
-			addr, err := wallet.AddressFromPublicKey(pub)
+		_, addr := loadWalletKey(*keyPath)
+		if id := strings.TrimSpace(*network); id != "" {
+			b32, err := wallet.EncodeAddressBech32(addr, blockchain.AddressHRP(id))
 			if err != nil {
 				fatal(err)
 			}
-			fmt.Println(addr)
-			return
-		}
-
-		// ed25519.PrivateKey.Public() returns ed25519.PublicKey (alias []byte)
-		addr, err := wallet.AddressFromPublicKey(pubAny.([]byte))
-		if err != nil {
-			fatal(err)
+			addr = b32
 		}
 		fmt.Println(addr)
 
@@ -115,6 +162,24 @@ func runWallet(args []string) {
 	}
 }
 
+func saveWallet(path string, kp wallet.Keypair, encrypt bool) {
+	var err error
+	if encrypt {
+		err = wallet.SaveEncrypted(path, kp.PrivateKey, readPassphrase("New passphrase: ", true))
+	} else {
+		err = wallet.SavePrivateKeyHex(path, kp.PrivateKey)
+	}
+	if err != nil {
+		fatal(err)
+	}
+	addr, err := wallet.AddressFromPublicKey(kp.PublicKey)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println("Saved private key:", path)
+	fmt.Println("Address:", addr)
+}
+
 func runSign(args []string) {
 	fs := flag.NewFlagSet("sign", flag.ExitOnError)
 	keyPath := fs.String("key", filepath.Join("data", "wallets", "default.key"), "Path to private key file")
@@ -125,10 +190,7 @@ func runSign(args []string) {
 		fatal(fmt.Errorf("--msg is required"))
 	}
 
-	priv, err := wallet.LoadPrivateKeyHex(*keyPath)
-	if err != nil {
-		fatal(err)
-	}
+	priv, _ := loadWalletKey(*keyPath)
 
 	sig, err := vcrypto.SignEd25519(priv, []byte(*msg))
 	if err != nil {
@@ -167,6 +229,51 @@ func runVerify(args []string) {
 	os.Exit(1)
 }
 
+func runFaucet(args []string) {
+	fs := flag.NewFlagSet("faucet", flag.ExitOnError)
+	nodeURL := fs.String("url", "http://127.0.0.1:8080", "Node HTTP API base URL")
+	addr := fs.String("addr", "", "Address to credit")
+	amount := fs.Uint64("amount", 0, "Amount to request")
+	apiKey := fs.String("api-key", os.Getenv("VELTAROS_API_KEY"), "Optional API key (X-API-Key)")
+	_ = fs.Parse(args)
+
+	a := strings.TrimSpace(*addr)
+	if err := checkAddressInput(a); err != nil {
+		fatal(fmt.Errorf("--addr: %w", err))
+	}
+	if *amount == 0 {
+		fatal(fmt.Errorf("--amount must be > 0"))
+	}
+
+	cl, err := api.New(*nodeURL, api.WithAPIKey(*apiKey))
+	if err != nil {
+		fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	res, err := cl.Faucet(ctx, a, *amount)
+	if err != nil {
+		var se *api.StatusError
+		if errors.As(err, &se) {
+			switch se.StatusCode {
+			case http.StatusNotFound:
+				fatal(fmt.Errorf("faucet is disabled on this node"))
+			case http.StatusTooManyRequests:
+				fatal(fmt.Errorf("faucet rate limited; try again later"))
+			case http.StatusUnauthorized:
+				fatal(fmt.Errorf("faucet requires a valid --api-key"))
+			}
+		}
+		fatal(err)
+	}
+
+	fmt.Println("Credited:", res.Amount)
+	fmt.Println("Address:", res.Address)
+	fmt.Println("Balance:", res.Balance)
+}
+
 func fatal(err error) {
 	_, _ = os.Stderr.WriteString("veltaros-cli error: " + err.Error() + "\n")
 	os.Exit(1)
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// readPassphrase returns VELTAROS_WALLET_PASSPHRASE when set, otherwise
+// prompts on stderr and reads a line from stdin. With confirm, an interactive
+// prompt is repeated and both entries must match. Input is not hidden: the
+// standard library has no portable way to disable terminal echo, so scripted
+// use should prefer the environment variable.
+func readPassphrase(prompt string, confirm bool) []byte {
+	if p := os.Getenv("VELTAROS_WALLET_PASSPHRASE"); p != "" {
+		return []byte(p)
+	}
+
+	p := promptLine(prompt)
+	if p == "" {
+		fatal(fmt.Errorf("passphrase must not be empty"))
+	}
+	if confirm && stdinIsTerminal() {
+		if promptLine("Repeat passphrase: ") != p {
+			fatal(fmt.Errorf("passphrases do not match"))
+		}
+	}
+	return []byte(p)
+}
+
+func promptLine(prompt string) string {
+	_, _ = os.Stderr.WriteString(prompt)
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && line == "" {
+		fatal(fmt.Errorf("read passphrase: %w", err))
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+	"github.com/VeltarosLabs/Veltaros/internal/wallet"
+	"github.com/VeltarosLabs/Veltaros/pkg/api"
+)
+
+func runTx(args []string) {
+	if len(args) < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "send":
+		runTxSend(args[1:])
+	case "pending":
+		runTxPending(args[1:])
+	case "cancel":
+		runTxCancel(args[1:])
+	case "inspect":
+		runTxInspect(args[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func runTxSend(args []string) {
+	fs := flag.NewFlagSet("tx send", flag.ExitOnError)
+	keyPath := fs.String("key", filepath.Join("data", "wallets", "default.key"), "Path to private key file")
+	nodeURL := fs.String("node", "http://127.0.0.1:8080", "Node HTTP API base URL")
+	to := fs.String("to", "", "Recipient address")
+	amount := fs.Uint64("amount", 0, "Amount to send (includes the fee)")
+	var outs outputFlags
+	fs.Var(&outs, "out", "Pay <addr>=<amount> in full, fee extra; repeat for several recipients in one tx (instead of --to/--amount)")
+	fee := fs.Uint64("fee", blockchain.MinFee, "Transaction fee")
+	nonce := fs.Uint64("nonce", 0, "Sender nonce (default: the node's expected nonce)")
+	network := fs.String("network", "", "Network ID (default: the node's network)")
+	memo := fs.String("memo", "", "Optional memo")
+	_ = fs.Parse(args)
+
+	recipient := strings.TrimSpace(*to)
+	if len(outs) > 0 {
+		if recipient != "" || *amount != 0 {
+			fatal(fmt.Errorf("use either --out or --to/--amount"))
+		}
+	} else {
+		if err := checkAddressInput(recipient); err != nil {
+			fatal(fmt.Errorf("--to: %w", err))
+		}
+		if *amount == 0 {
+			fatal(fmt.Errorf("--amount must be > 0"))
+		}
+	}
+
+	priv, addr := loadWalletKey(*keyPath)
+	cl := newNodeClient(*nodeURL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	networkID := strings.TrimSpace(*network)
+	if networkID == "" {
+		st, err := cl.Status(ctx)
+		if err != nil {
+			fatal(err)
+		}
+		networkID = st.NetworkID
+	}
+	// Bech32 recipients are signed in hex form, once their prefix has been
+	// checked against the network.
+	var err error
+	if recipient != "" {
+		if recipient, err = blockchain.NormalizeAddress(recipient, networkID); err != nil {
+			fatal(fmt.Errorf("--to: %w", err))
+		}
+	}
+	for i := range outs {
+		if outs[i].To, err = blockchain.NormalizeAddress(outs[i].To, networkID); err != nil {
+			fatal(fmt.Errorf("--out: %w", err))
+		}
+	}
+	n := *nonce
+	if n == 0 {
+		acct, err := cl.Account(ctx, addr)
+		if err != nil {
+			fatal(err)
+		}
+		n = acct.ExpectedNonce
+	}
+
+	draft := blockchain.TxDraft{
+		Version:   blockchain.TxVersion,
+		NetworkID: networkID,
+		From:      addr,
+		To:        recipient,
+		Amount:    *amount,
+		Fee:       *fee,
+		Nonce:     n,
+		Timestamp: time.Now().UTC().Unix(),
+		Memo:      *memo,
+	}
+	if len(outs) > 0 {
+		draft.Version = blockchain.TxVersionMulti
+		draft.Outputs = outs
+	}
+	signed, err := blockchain.SignDraft(priv, draft)
+	if err != nil {
+		fatal(err)
+	}
+	if err := blockchain.ValidateSignedTx(signed); err != nil {
+		fatal(err)
+	}
+
+	res, err := cl.Broadcast(ctx, toWireTx(signed))
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println(res.TxID)
+	if res.Held {
+		fmt.Fprintf(os.Stderr, "held: nonce %d is ahead of expected nonce %d\n", n, res.ExpectedNonce)
+	}
+	if res.Warning != "" {
+		fmt.Fprintln(os.Stderr, "warning:", res.Warning)
+	}
+}
+
+func runTxPending(args []string) {
+	fs := flag.NewFlagSet("tx pending", flag.ExitOnError)
+	keyPath := fs.String("key", filepath.Join("data", "wallets", "default.key"), "Path to private key file")
+	nodeURL := fs.String("node", "http://127.0.0.1:8080", "Node HTTP API base URL")
+	_ = fs.Parse(args)
+
+	_, addr := loadWalletKey(*keyPath)
+	cl := newNodeClient(*nodeURL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	list, err := cl.Mempool(ctx, addr)
+	if err != nil {
+		fatal(err)
+	}
+	if list.Count == 0 {
+		fmt.Println("No pending transactions for", addr)
+		return
+	}
+	for _, tx := range list.Txs {
+		if len(tx.Draft.Outputs) > 0 {
+			var total uint64
+			for _, o := range tx.Draft.Outputs {
+				total += o.Amount
+			}
+			fmt.Printf("nonce=%d txId=%s outputs=%d amount=%d fee=%d\n",
+				tx.Draft.Nonce, tx.TxID, len(tx.Draft.Outputs), total, tx.Draft.Fee)
+			continue
+		}
+		fmt.Printf("nonce=%d txId=%s to=%s amount=%d fee=%d\n",
+			tx.Draft.Nonce, tx.TxID, tx.Draft.To, tx.Draft.Amount, tx.Draft.Fee)
+	}
+}
+
+// runTxCancel replaces a pending tx with a zero-value self-transfer at the
+// same nonce and a higher fee.
+func runTxCancel(args []string) {
+	fs := flag.NewFlagSet("tx cancel", flag.ExitOnError)
+	keyPath := fs.String("key", filepath.Join("data", "wallets", "default.key"), "Path to private key file")
+	nodeURL := fs.String("node", "http://127.0.0.1:8080", "Node HTTP API base URL")
+	nonce := fs.Uint64("nonce", 0, "Nonce of the pending transaction to cancel")
+	fee := fs.Uint64("fee", 0, "Cancel fee (default: pending fee + 1)")
+	_ = fs.Parse(args)
+
+	if *nonce == 0 {
+		fatal(fmt.Errorf("--nonce is required"))
+	}
+
+	priv, addr := loadWalletKey(*keyPath)
+	cl := newNodeClient(*nodeURL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	st, err := cl.Status(ctx)
+	if err != nil {
+		fatal(err)
+	}
+	list, err := cl.Mempool(ctx, addr)
+	if err != nil {
+		fatal(err)
+	}
+
+	var pending *api.SignedTx
+	for i := range list.Txs {
+		if list.Txs[i].Draft.Nonce == *nonce {
+			pending = &list.Txs[i]
+			break
+		}
+	}
+	if pending == nil {
+		fatal(fmt.Errorf("no pending transaction from %s with nonce %d", addr, *nonce))
+	}
+
+	cancelFee := pending.Draft.Fee + 1
+	if *fee > 0 {
+		if *fee <= pending.Draft.Fee {
+			fatal(fmt.Errorf("--fee must exceed the pending fee (%d)", pending.Draft.Fee))
+		}
+		cancelFee = *fee
+	}
+
+	signed, err := blockchain.SignDraft(priv, blockchain.TxDraft{
+		Version:   blockchain.TxVersion,
+		NetworkID: st.NetworkID,
+		From:      addr,
+		To:        addr,
+		Amount:    0,
+		Fee:       cancelFee,
+		Nonce:     *nonce,
+		Timestamp: time.Now().UTC().Unix(),
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	res, err := cl.Broadcast(ctx, toWireTx(signed))
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println("Cancel broadcast:", res.TxID)
+	if res.Replaced != "" {
+		fmt.Println("Replaced:", res.Replaced)
+	}
+}
+
+func loadWalletKey(path string) (ed25519.PrivateKey, string) {
+	encrypted, err := wallet.IsEncryptedKeyFile(path)
+	if err != nil {
+		fatal(err)
+	}
+	var priv ed25519.PrivateKey
+	if encrypted {
+		priv, err = wallet.LoadEncrypted(path, readPassphrase("Passphrase for "+path+": ", false))
+	} else {
+		priv, err = wallet.LoadPrivateKeyHex(path)
+	}
+	if err != nil {
+		fatal(err)
+	}
+	addr, err := wallet.AddressFromPublicKey(priv.Public().(ed25519.PublicKey))
+	if err != nil {
+		fatal(err)
+	}
+	return priv, addr
+}
+
+func newNodeClient(nodeURL string) *api.Client {
+	cl, err := api.New(nodeURL, api.WithAPIKey(os.Getenv("VELTAROS_API_KEY")))
+	if err != nil {
+		fatal(err)
+	}
+	return cl
+}
+
+func toWireTx(st blockchain.SignedTx) api.SignedTx {
+	d := st.Draft
+	return api.SignedTx{
+		Draft: api.TxDraft{
+			Version:   d.Version,
+			NetworkID: d.NetworkID,
+			From:      d.From,
+			To:        d.To,
+			Amount:    d.Amount,
+			Fee:       d.Fee,
+			Outputs:   wireOutputs(d.Outputs),
+			Nonce:     d.Nonce,
+			Timestamp: d.Timestamp,
+			Memo:      d.Memo,
+			ClientRef: d.ClientRef,
+			Class:     d.Class,
+		},
+		PublicKeyHex: st.PublicKeyHex,
+		SignatureHex: st.SignatureHex,
+		TxID:         st.TxID,
+	}
+}
+
+func wireOutputs(outs []blockchain.TxOutput) []api.TxOutput {
+	if len(outs) == 0 {
+		return nil
+	}
+	w := make([]api.TxOutput, len(outs))
+	for i, o := range outs {
+		w[i] = api.TxOutput{To: o.To, Amount: o.Amount}
+	}
+	return w
+}
+
+// outputFlags collects repeated --out <addr>=<amount> flags.
+type outputFlags []blockchain.TxOutput
+
+func (o *outputFlags) String() string {
+	parts := make([]string, len(*o))
+	for i, out := range *o {
+		parts[i] = fmt.Sprintf("%s=%d", out.To, out.Amount)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (o *outputFlags) Set(v string) error {
+	addr, amt, ok := strings.Cut(v, "=")
+	if !ok {
+		return fmt.Errorf("want <addr>=<amount>, got %q", v)
+	}
+	addr = strings.TrimSpace(addr)
+	if err := checkAddressInput(addr); err != nil {
+		return err
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(amt), 10, 64)
+	if err != nil || n == 0 {
+		return fmt.Errorf("amount must be a positive integer, got %q", amt)
+	}
+	*o = append(*o, blockchain.TxOutput{To: addr, Amount: n})
+	return nil
+}
+
+// checkAddressInput accepts a hex or bech32 address. The bech32 prefix is not
+// checked here, since the network may not be known yet; NormalizeAddress
+// does that before signing.
+func checkAddressInput(addr string) error {
+	if len(addr) == 2*blockchain.AddressLenBytes {
+		return blockchain.ValidateAddress(addr)
+	}
+	if _, _, err := wallet.DecodeAddressBech32(addr); err != nil {
+		return fmt.Errorf("not a hex or bech32 address: %w", err)
+	}
+	return nil
+}
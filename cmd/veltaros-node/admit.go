@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+	"github.com/VeltarosLabs/Veltaros/internal/ledger"
+)
+
+var errUnknownRecipient = errors.New("recipient address is unknown")
+
+// txErrorCode maps a tx validation or admission error to the stable code
+// reported in API responses next to the message.
+func txErrorCode(err error) string {
+	if code := blockchain.TxErrorCode(err); code != "" {
+		return code
+	}
+	switch {
+	case errors.Is(err, errUnknownRecipient):
+		return "UNKNOWN_RECIPIENT"
+	case errors.Is(err, blockchain.ErrMempoolFull):
+		return "MEMPOOL_FULL"
+	case errors.Is(err, ledger.ErrInsufficientBalance):
+		return "INSUFFICIENT_BALANCE"
+	case errors.Is(err, errHoldbackFull):
+		return "HOLDBACK_FULL"
+	case errors.Is(err, errNonceHeld):
+		return "NONCE_HELD"
+	}
+	return "REJECTED"
+}
+
+type admitResult struct {
+	Duplicate bool
+	Replaced  string
+	Held      bool // parked in the nonce holdback; not yet in the mempool
+}
+
+// admitTx is the mempool admission path shared by HTTP broadcast and p2p
+// relay; src records which of the two it came from. The tx must already have
+// passed blockchain.ValidateSignedTx.
+// With a nonce holdback configured, a tx ahead of the sender's expected nonce
+// is held instead; admitting a tx releases any held successors.
+func admitTx(rt *nodeRuntime, tx blockchain.SignedTx, src blockchain.TxSource) (admitResult, error) {
+	// Checked up front so an underpaying or out-of-window tx is not held
+	// either.
+	if err := rt.chain.CheckRelayFee(tx); err != nil {
+		return admitResult{}, err
+	}
+	if err := rt.chain.CheckTxTime(tx); err != nil {
+		return admitResult{}, err
+	}
+	if rt.holdback != nil && !rt.chain.MempoolHas(tx.TxID) &&
+		tx.Draft.Nonce > rt.chain.ExpectedNonce(tx.Draft.From) {
+		if !recipientsKnown(rt, tx.Draft) {
+			return admitResult{}, errUnknownRecipient
+		}
+		if err := rt.holdback.hold(tx, src); err != nil {
+			return admitResult{}, err
+		}
+		return admitResult{Held: true}, nil
+	}
+
+	res, err := admitNow(rt, tx, src)
+	if err == nil && rt.holdback != nil && !res.Duplicate {
+		releaseHeld(rt, tx.Draft.From)
+	}
+	return res, err
+}
+
+// recipientsKnown reports whether every recipient of d already has a ledger
+// account, or chain.requireKnownRecipient is off.
+func recipientsKnown(rt *nodeRuntime, d blockchain.TxDraft) bool {
+	if !rt.chain.Params().RequireKnownRecipient {
+		return true
+	}
+	for _, to := range d.Recipients() {
+		if !rt.ledger.HasAccount(to) {
+			return false
+		}
+	}
+	return true
+}
+
+// admitNow stages tx's spend, reserves its nonce and adds it to the mempool.
+// Nothing is saved here: admission touches no confirmed balance, and the
+// staged spends are rebuilt from the mempool store on restart, so the
+// periodic persist (and the one at shutdown) is enough. Saving per tx made
+// every gossiped tx cost a pair of fsynced writes.
+func admitNow(rt *nodeRuntime, tx blockchain.SignedTx, src blockchain.TxSource) (admitResult, error) {
+	if !recipientsKnown(rt, tx.Draft) {
+		return admitResult{}, errUnknownRecipient
+	}
+	if rt.chain.MempoolHas(tx.TxID) {
+		return admitResult{Duplicate: true}, nil
+	}
+	if old, ok := rt.chain.MempoolFindByNonce(tx.Draft.From, tx.Draft.Nonce); ok {
+		// Replace-by-fee (including cancels): swap the pending tx and its staged spend.
+		if err := replacePending(rt, old, tx, src); err != nil {
+			return admitResult{}, err
+		}
+		if err := trimMempool(rt, tx.TxID); err != nil {
+			return admitResult{}, err
+		}
+		return admitResult{Replaced: old.TxID}, nil
+	}
+	if err := rt.chain.MempoolCheckRoom(tx); err != nil {
+		return admitResult{}, err
+	}
+	if err := rt.ledger.StageMempoolSpend(tx.Draft.From, tx.Draft.SpendAmount()); err != nil {
+		return admitResult{}, err
+	}
+	if !rt.chain.ReserveNonce(tx.Draft.From, tx.Draft.Nonce) {
+		rt.ledger.UnstageMempoolSpend(tx.Draft.From, tx.Draft.SpendAmount())
+		return admitResult{}, blockchain.ErrNonceTooLow
+	}
+	if err := rt.chain.MempoolAddFrom(tx, src); err != nil {
+		rt.ledger.UnstageMempoolSpend(tx.Draft.From, tx.Draft.SpendAmount())
+		rt.chain.MempoolEvictFrom(tx.Draft.From, tx.Draft.Nonce)
+		return admitResult{}, err
+	}
+	if err := trimMempool(rt, tx.TxID); err != nil {
+		return admitResult{}, err
+	}
+	return admitResult{}, nil
+}
+
+// trimMempool enforces the mempool count/byte limits after an admission,
+// releasing the staged spends of evicted txs. It reports ErrMempoolFull if
+// the just-admitted txID was itself evicted.
+func trimMempool(rt *nodeRuntime, txID string) error {
+	var err error
+	for _, ev := range rt.chain.MempoolTrim() {
+		rt.ledger.UnstageMempoolSpend(ev.Draft.From, ev.Draft.SpendAmount())
+		if ev.TxID == txID {
+			err = blockchain.ErrMempoolFull
+		}
+	}
+	return err
+}
+
+// replacePending moves the staged spend from old to tx and swaps them in the
+// mempool, restoring the original staging if the replacement is rejected.
+func replacePending(rt *nodeRuntime, old, tx blockchain.SignedTx, src blockchain.TxSource) error {
+	from := tx.Draft.From
+	rt.ledger.UnstageMempoolSpend(from, old.Draft.SpendAmount())
+	if err := rt.ledger.StageMempoolSpend(from, tx.Draft.SpendAmount()); err != nil {
+		_ = rt.ledger.StageMempoolSpend(from, old.Draft.SpendAmount())
+		return err
+	}
+	if err := rt.chain.MempoolReplace(old.TxID, tx, src); err != nil {
+		rt.ledger.UnstageMempoolSpend(from, tx.Draft.SpendAmount())
+		_ = rt.ledger.StageMempoolSpend(from, old.Draft.SpendAmount())
+		return err
+	}
+	return nil
+}
+
+// handleRelayedTx admits a tx gossiped by a peer. Malformed or invalid txs
+// are reported as errors (penalizing the peer); policy rejections such as a
+// stale nonce or a timestamp outside the window (clock skew, or aged in
+// transit) are expected under normal gossip and are dropped quietly.
+func handleRelayedTx(log *slog.Logger, rt *nodeRuntime, from string, payload []byte) (bool, error) {
+	var tx blockchain.SignedTx
+	if err := json.Unmarshal(payload, &tx); err != nil {
+		return false, errors.New("invalid json")
+	}
+	if tx.Draft.NetworkID != rt.networkID {
+		return false, errors.New("networkId mismatch")
+	}
+	if err := blockchain.ValidateSignedTx(tx); err != nil {
+		return false, err
+	}
+	res, err := admitTx(rt, tx, blockchain.TxSourceGossip)
+	if err != nil {
+		log.Debug("relayed tx rejected", "peer", from, "txId", tx.TxID, "err", err)
+		return false, nil
+	}
+	return !res.Duplicate && !res.Held, nil
+}
+
+// relayTx gossips a newly admitted tx to peers.
+func relayTx(rt *nodeRuntime, tx blockchain.SignedTx, exceptAddr string) {
+	payload, err := json.Marshal(tx)
+	if err != nil {
+		return
+	}
+	rt.p2p.BroadcastTx(payload, exceptAddr)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+	"github.com/VeltarosLabs/Veltaros/internal/clock"
+	"github.com/VeltarosLabs/Veltaros/internal/ledger"
+)
+
+// TestRelayedTxOutsideWindowDroppedQuietly checks that a relayed tx dated
+// outside the mempool window is a policy drop (no error, so the peer is not
+// penalized), and that the future limit comes from the chain params.
+func TestRelayedTxOutsideWindowDroppedQuietly(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	chain := blockchain.New(
+		filepath.Join(dir, "nonces.json"),
+		filepath.Join(dir, "blocks.json"),
+		filepath.Join(dir, "mempool.json"),
+		blockchain.WithClock(clock.NewFake(now)),
+	)
+	led := ledger.New(filepath.Join(dir, "ledger.json"))
+	rt := &nodeRuntime{chain: chain, ledger: led, networkID: "veltaros-testnet"}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := blockchain.AddressFromEd25519PublicKeyHex(hex.EncodeToString(pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	toPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := blockchain.AddressFromEd25519PublicKeyHex(hex.EncodeToString(toPub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := led.FaucetCredit(from, 10_000); err != nil {
+		t.Fatal(err)
+	}
+	relayed := func(nonce uint64, at time.Time) []byte {
+		tx, err := blockchain.SignDraft(priv, blockchain.TxDraft{
+			NetworkID: rt.networkID,
+			From:      from,
+			To:        to,
+			Amount:    100,
+			Fee:       1,
+			Nonce:     nonce,
+			Timestamp: at.Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := json.Marshal(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+
+	for name, at := range map[string]time.Time{
+		"future": now.Add(10 * time.Minute),
+		"past":   now.Add(-25 * time.Hour),
+	} {
+		accepted, err := handleRelayedTx(log, rt, "peer", relayed(1, at))
+		if err != nil || accepted {
+			t.Errorf("%s: accepted=%v err=%v, want a quiet drop", name, accepted, err)
+		}
+	}
+	if n := chain.MempoolCount(); n != 0 {
+		t.Fatalf("mempool holds %d txs, want 0", n)
+	}
+
+	p := chain.Params()
+	p.MaxFutureSkewSec = 3600
+	chain.SetParams(p)
+	accepted, err := handleRelayedTx(log, rt, "peer", relayed(1, now.Add(10*time.Minute)))
+	if err != nil || !accepted {
+		t.Errorf("within a 1h limit: accepted=%v err=%v", accepted, err)
+	}
+}
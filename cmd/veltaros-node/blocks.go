@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"log/slog"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+)
+
+// handlePeerBlock links a block announced by a peer when it extends our tip.
+func handlePeerBlock(log *slog.Logger, rt *nodeRuntime, from string, height uint64, b blockchain.Block) error {
+	if ours := rt.chain.Height(); height != 0 && height <= ours {
+		// Same height or behind: either known or a competing branch.
+		h := b.Header.Hash()
+		if _, ok := rt.chain.GetBlock(hex.EncodeToString(h[:])); ok {
+			return blockchain.ErrKnownBlock
+		}
+		return blockchain.ErrUnknownParent
+	}
+
+	res, err := rt.chain.AcceptBlock(b, rt.ledger)
+	if err != nil {
+		if !errors.Is(err, blockchain.ErrUnknownParent) && !errors.Is(err, blockchain.ErrKnownBlock) {
+			log.Warn("rejected peer block", "peer", from, "height", height, "err", err)
+		}
+		return err
+	}
+	log.Info("accepted peer block",
+		"peer", from,
+		"height", res.Block.Height,
+		"hash", res.Block.HashHex,
+		"txs", res.Block.TxCount,
+		"failed", res.Failed,
+	)
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/events"
+)
+
+const (
+	// maxEventStreams caps concurrent /events clients; each holds a
+	// connection and a subscriber queue.
+	maxEventStreams = 64
+	// eventKeepalive is how often an idle stream gets a comment line, so
+	// proxies do not time it out and dead clients are noticed.
+	eventKeepalive = 15 * time.Second
+)
+
+var eventTypes = map[string]bool{
+	events.TxAdded:       true,
+	events.BlockAdded:    true,
+	events.PeerConnected: true,
+	events.PeerBanned:    true,
+}
+
+// serveEvents streams bus events to the client as Server-Sent Events, one
+// "event: <type>" / "data: <json>" pair each, until the client disconnects
+// or the node shuts down. ?types=a,b limits the stream to those types. A
+// client that reads too slowly misses events rather than holding up the
+// node; the bus logs the drops.
+func serveEvents(log *slog.Logger, rt *nodeRuntime) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+
+		var only map[string]bool
+		if raw := strings.TrimSpace(r.URL.Query().Get("types")); raw != "" {
+			only = make(map[string]bool)
+			for _, t := range strings.Split(raw, ",") {
+				t = strings.TrimSpace(t)
+				if !eventTypes[t] {
+					writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": fmt.Sprintf("unknown event type %q", t)})
+					return
+				}
+				only[t] = true
+			}
+		}
+
+		if rt.events.Subscribers() >= maxEventStreams {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"ok": false, "error": "too many event streams"})
+			return
+		}
+
+		// The server's WriteTimeout would cut the stream off; instead each
+		// write gets its own deadline.
+		rc := http.NewResponseController(w)
+		writeTimeout := rt.apiCfg.WriteTimeout
+		send := func(chunk string) error {
+			if writeTimeout > 0 {
+				if err := rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+					return err
+				}
+			}
+			if _, err := io.WriteString(w, chunk); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+
+		sub := rt.events.Subscribe()
+		defer sub.Close()
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		if err := send(": connected\n\n"); err != nil {
+			return
+		}
+
+		keepalive := time.NewTicker(eventKeepalive)
+		defer keepalive.Stop()
+		for {
+			var chunk string
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepalive.C:
+				chunk = ": keepalive\n\n"
+			case ev, ok := <-sub.C:
+				if !ok {
+					return // shutting down
+				}
+				if only != nil && !only[ev.Type] {
+					continue
+				}
+				data, err := json.Marshal(ev)
+				if err != nil {
+					log.Warn("event encode failed", "type", ev.Type, "err", err)
+					continue
+				}
+				chunk = "event: " + ev.Type + "\ndata: " + string(data) + "\n\n"
+			}
+			if err := send(chunk); err != nil {
+				return
+			}
+		}
+	}
+}
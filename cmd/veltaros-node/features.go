@@ -0,0 +1,45 @@
+package main
+
+import "github.com/VeltarosLabs/Veltaros/internal/config"
+
+// nodeFeatures is the /features map: which optional behaviors this node runs
+// with, derived from its configuration. Values are booleans only, so nothing
+// secret (keys, paths, addresses) is exposed. Entries for capabilities this
+// build does not implement are present and false, so clients can tell
+// "disabled" from "unknown to this version".
+func nodeFeatures(cfg config.Config, devMode bool) map[string]bool {
+	return map[string]bool{
+		// P2P
+		"txGossip":      true,
+		"blockGossip":   true,
+		"blockSync":     true,
+		"p2pEncryption": false,
+
+		// Chain
+		"blockProduction":       cfg.Producer.Enabled,
+		"requireKnownRecipient": cfg.Chain.RequireKnownRecipient,
+		"honorTxClass":          cfg.Chain.HonorTxClass,
+		"nonceHoldback":         cfg.Chain.NonceGrace > 0 || cfg.Chain.StrictNonces,
+		"strictNonces":          cfg.Chain.StrictNonces,
+		"mempoolExpiry":         cfg.Chain.MempoolMaxAge > 0,
+		"integrityChecks":       cfg.Chain.IntegrityInterval > 0,
+		"quarantineCorrupt":     cfg.Chain.IntegrityInterval > 0 && cfg.Chain.QuarantineCorrupt,
+		"proofOfWork":           false,
+		"proofOfStake":          false,
+
+		// API
+		"devMode":        devMode,
+		"faucet":         cfg.API.FaucetEnabled,
+		"binaryTx":       cfg.API.BinaryTx,
+		"adminApi":       cfg.API.APIKey.IsSet(),
+		"keyOnValidate":  cfg.API.APIKey.IsSet() && cfg.API.KeyOnValidate,
+		"keyOnBroadcast": cfg.API.APIKey.IsSet() && cfg.API.KeyOnBroadcast,
+		"keyOnEvents":    cfg.API.APIKey.IsSet() && cfg.API.KeyOnEvents,
+		"minPeersStrict": cfg.API.MinPeers > 0 && cfg.API.MinPeersStrict,
+		"auditLog":       cfg.API.AuditLogPath != "",
+		"metrics":        cfg.API.Metrics,
+
+		// Storage
+		"storeBackups": cfg.Storage.KeepBackups,
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+	"github.com/VeltarosLabs/Veltaros/internal/ledger"
+)
+
+// loadGenesis reads the configured genesis file, if any, and checks that it
+// is for the network the node joins. nil means the built-in genesis.
+func loadGenesis(path, networkID string) (*blockchain.GenesisConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+	g, err := blockchain.LoadGenesisConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if g.NetworkID != networkID {
+		return nil, fmt.Errorf("genesis %s is for network %q, node is configured for %q", path, g.NetworkID, networkID)
+	}
+	return &g, nil
+}
+
+// applyGenesis credits g's allocations to a fresh ledger on an empty chain
+// and saves it, so they are applied exactly once. A ledger built on another
+// genesis is refused: its balances belong to a different network.
+func applyGenesis(log *slog.Logger, chain *blockchain.Chain, led *ledger.Ledger, g *blockchain.GenesisConfig) error {
+	hash := chain.GenesisHashHex()
+	log.Info("genesis", "hash", hash, "configured", g != nil)
+
+	if lg := led.Genesis(); lg != "" {
+		if lg != hash {
+			return fmt.Errorf("ledger was built on genesis %s, not the configured %s; use a separate data directory per network", lg, hash)
+		}
+		return nil
+	}
+	if g == nil || len(g.Allocations) == 0 {
+		return nil
+	}
+	if chain.Height() > 0 {
+		log.Warn("ledger has no genesis allocations but the chain is not empty; not applying them", "height", chain.Height())
+		return nil
+	}
+
+	if err := led.ApplyGenesis(hash, g.Allocations); err != nil {
+		if errors.Is(err, ledger.ErrLedgerNotEmpty) {
+			return errors.New("ledger already has balances not from the configured genesis; start from an empty ledger store")
+		}
+		return err
+	}
+	if err := led.Save(); err != nil {
+		return fmt.Errorf("save ledger after genesis: %w", err)
+	}
+	var supply uint64
+	for _, bal := range g.Allocations {
+		supply += bal
+	}
+	log.Info("applied genesis allocations", "accounts", len(g.Allocations), "supply", supply)
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+	"github.com/VeltarosLabs/Veltaros/internal/clock"
+)
+
+// maxHeldTotal bounds the holdback buffer across all senders.
+const maxHeldTotal = 1024
+
+// strictHoldWindow is how long chain.strictNonces keeps a future tx waiting
+// for its gap to fill when chain.nonceGrace is not set.
+const strictHoldWindow = 2 * time.Minute
+
+var (
+	errHoldbackFull = errors.New("too many out-of-order txs held; retry after earlier nonces confirm")
+	errNonceHeld    = errors.New("a different tx with this nonce is already held")
+)
+
+type heldTx struct {
+	tx     blockchain.SignedTx
+	src    blockchain.TxSource
+	heldAt time.Time
+}
+
+// nonceHoldback briefly parks txs whose nonce is ahead of the sender's
+// expected nonce, so a burst that arrives out of order can be admitted in
+// sequence. Held txs have no effect (no staging, no nonce reservation) until
+// they are admitted. In strict mode a tx whose gap never fills is dropped
+// when its window passes; otherwise it is then admitted with the gap.
+type nonceHoldback struct {
+	window    time.Duration
+	perSender int
+	strict    bool
+	clock     clock.Clock
+
+	mu    sync.Mutex
+	held  map[string]map[uint64]heldTx // from -> nonce -> tx
+	total int
+}
+
+// newNonceHoldback returns an empty holdback timing its window by clk (nil
+// means the system clock).
+func newNonceHoldback(window time.Duration, perSender int, strict bool, clk clock.Clock) *nonceHoldback {
+	return &nonceHoldback{
+		window:    window,
+		perSender: perSender,
+		strict:    strict,
+		clock:     clock.OrReal(clk),
+		held:      make(map[string]map[uint64]heldTx),
+	}
+}
+
+func (h *nonceHoldback) hold(tx blockchain.SignedTx, src blockchain.TxSource) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	from := tx.Draft.From
+	bySender := h.held[from]
+	if cur, ok := bySender[tx.Draft.Nonce]; ok {
+		if cur.tx.TxID == tx.TxID {
+			return nil
+		}
+		return errNonceHeld
+	}
+	if len(bySender) >= h.perSender || h.total >= maxHeldTotal {
+		return errHoldbackFull
+	}
+	if bySender == nil {
+		bySender = make(map[uint64]heldTx)
+		h.held[from] = bySender
+	}
+	bySender[tx.Draft.Nonce] = heldTx{tx: tx, src: src, heldAt: h.clock.Now()}
+	h.total++
+	return nil
+}
+
+// take removes and returns the held tx from sender at nonce, if any.
+func (h *nonceHoldback) take(from string, nonce uint64) (heldTx, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	bySender := h.held[from]
+	e, ok := bySender[nonce]
+	if !ok {
+		return heldTx{}, false
+	}
+	h.removeLocked(from, nonce)
+	return e, true
+}
+
+// count returns how many txs from sender are held.
+func (h *nonceHoldback) count(from string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.held[from])
+}
+
+// nonces returns the nonces held for sender, ascending.
+func (h *nonceHoldback) nonces(from string) []uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]uint64, 0, len(h.held[from]))
+	for n := range h.held[from] {
+		out = append(out, n)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func (h *nonceHoldback) get(txID string) (blockchain.SignedTx, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, bySender := range h.held {
+		for _, e := range bySender {
+			if e.tx.TxID == txID {
+				return e.tx, true
+			}
+		}
+	}
+	return blockchain.SignedTx{}, false
+}
+
+// expired removes and returns txs held longer than the window, ordered by
+// sender then nonce so each sender's txs are admitted in sequence.
+func (h *nonceHoldback) expired(now time.Time) []heldTx {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var out []heldTx
+	for from, bySender := range h.held {
+		for nonce, e := range bySender {
+			if now.Sub(e.heldAt) >= h.window {
+				out = append(out, e)
+				h.removeLocked(from, nonce)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].tx.Draft, out[j].tx.Draft
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.Nonce < b.Nonce
+	})
+	return out
+}
+
+func (h *nonceHoldback) removeLocked(from string, nonce uint64) {
+	delete(h.held[from], nonce)
+	if len(h.held[from]) == 0 {
+		delete(h.held, from)
+	}
+	h.total--
+}
+
+// releaseHeld admits held txs from sender for as long as the next expected
+// nonce is waiting in the buffer, relaying each one.
+func releaseHeld(rt *nodeRuntime, from string) {
+	for {
+		e, ok := rt.holdback.take(from, rt.chain.ExpectedNonce(from))
+		if !ok {
+			return
+		}
+		res, err := admitNow(rt, e.tx, e.src)
+		if err != nil || res.Duplicate {
+			continue
+		}
+		relayTx(rt, e.tx, "")
+	}
+}
+
+// runHoldbackExpiry handles txs whose gap never filled once their window has
+// passed: in strict mode they are dropped, otherwise they are admitted under
+// the normal (gap-tolerant) nonce policy.
+func runHoldbackExpiry(ctx context.Context, log *slog.Logger, rt *nodeRuntime) {
+	t := time.NewTicker(max(rt.holdback.window/2, 10*time.Millisecond))
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			for _, e := range rt.holdback.expired(rt.holdback.clock.Now()) {
+				if rt.holdback.strict {
+					log.Debug("held tx dropped, nonce gap never filled", "txId", e.tx.TxID, "from", e.tx.Draft.From, "nonce", e.tx.Draft.Nonce)
+					continue
+				}
+				res, err := admitNow(rt, e.tx, e.src)
+				if err != nil {
+					log.Debug("held tx rejected after window", "txId", e.tx.TxID, "err", err)
+					continue
+				}
+				if !res.Duplicate {
+					relayTx(rt, e.tx, "")
+					releaseHeld(rt, e.tx.Draft.From)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+	"github.com/VeltarosLabs/Veltaros/internal/clock"
+)
+
+func TestHoldbackWindowFollowsClock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	h := newNonceHoldback(time.Minute, 4, false, fake)
+
+	tx := blockchain.SignedTx{TxID: "aa", Draft: blockchain.TxDraft{From: "alice", Nonce: 3}}
+	if err := h.hold(tx, blockchain.TxSourceGossip); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.expired(fake.Advance(59 * time.Second)); len(got) != 0 {
+		t.Fatalf("expired %d txs before the window passed", len(got))
+	}
+	got := h.expired(fake.Advance(time.Second))
+	if len(got) != 1 || got[0].tx.TxID != tx.TxID {
+		t.Fatalf("expired = %v, want the held tx", got)
+	}
+	if h.count("alice") != 0 {
+		t.Error("expired tx still held")
+	}
+}
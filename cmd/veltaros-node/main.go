@@ -9,17 +9,26 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"mime"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/VeltarosLabs/Veltaros/internal/api"
+	"github.com/VeltarosLabs/Veltaros/internal/audit"
 	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
 	"github.com/VeltarosLabs/Veltaros/internal/config"
+	"github.com/VeltarosLabs/Veltaros/internal/consensus"
+	"github.com/VeltarosLabs/Veltaros/internal/events"
 	"github.com/VeltarosLabs/Veltaros/internal/ledger"
 	"github.com/VeltarosLabs/Veltaros/internal/logging"
 	"github.com/VeltarosLabs/Veltaros/internal/p2p"
@@ -27,15 +36,41 @@ import (
 	"github.com/VeltarosLabs/Veltaros/pkg/version"
 )
 
+// maxBlocksLimit caps /blocks?limit=.
+const maxBlocksLimit = 100
+
+// maxAccountTxsLimit caps /account/<address>/txs?limit=.
+const maxAccountTxsLimit = 100
+
 type nodeRuntime struct {
 	startedAt time.Time
 	chain     *blockchain.Chain
 	ledger    *ledger.Ledger
 	store     *storage.Store
 	p2p       *p2p.Node
+	producer  *blockchain.BlockProducer
+	holdback  *nonceHoldback // nil unless chain.nonceGrace > 0 or chain.strictNonces
+	audit     *audit.Log     // nil when api.auditLog is empty
+	runtime   *storage.RuntimeTracker
+	events    *events.Bus
+	logLevel  *slog.LevelVar
+	features  map[string]bool
 	networkID string
 	apiCfg    config.APIConfig
 	devMode   bool
+
+	// effective is the resolved config, served on /config (secrets print
+	// as "***").
+	effective config.Config
+
+	// bg tracks the background loops; see shutdown.
+	bg sync.WaitGroup
+	// stopping is set when shutdown begins, so the watchdog does not take
+	// the API going away for a wedge.
+	stopping atomic.Bool
+
+	// integrity is the outcome of the last block store check, for /metrics.
+	integrity integrityStatus
 }
 
 func main() {
@@ -44,16 +79,54 @@ func main() {
 		os.Exit(exitWithError(err))
 	}
 	cfg := parsed.Config
+	if parsed.PrintConfig {
+		out, err := config.Marshal(cfg)
+		if err != nil {
+			os.Exit(exitWithError(err))
+		}
+		_, _ = os.Stdout.Write(out)
+		return
+	}
 
+	logLevel := new(slog.LevelVar)
 	log := logging.New(logging.Config{
-		Level:  cfg.Log.Level,
-		Format: cfg.Log.Format,
+		Level:    cfg.Log.Level,
+		Format:   cfg.Log.Format,
+		LevelVar: logLevel,
 	})
 
 	store, err := storage.New(cfg.Storage.DataDir)
 	if err != nil {
 		os.Exit(exitWithError(err))
 	}
+	storage.SetLogger(log)
+
+	// Undo a crash part-way through saving the ledger and nonce stores
+	// together (see saveLedgerState) before either is loaded.
+	if undone, err := storage.RecoverBatch(cfg.Ledger.StorePath); err != nil {
+		log.Error("interrupted ledger/nonce save not rolled back", "path", cfg.Ledger.StorePath, "err", err)
+	} else if undone {
+		log.Warn("rolled back an interrupted ledger/nonce save", "path", cfg.Ledger.StorePath)
+	}
+	// Likewise for the nonce and mempool stores (see saveMempoolState).
+	if undone, err := storage.RecoverBatch(cfg.Network.NonceStorePath); err != nil {
+		log.Error("interrupted nonce/mempool save not rolled back", "path", cfg.Network.NonceStorePath, "err", err)
+	} else if undone {
+		log.Warn("rolled back an interrupted nonce/mempool save", "path", cfg.Network.NonceStorePath)
+	}
+
+	genesis, err := loadGenesis(cfg.Chain.GenesisPath, cfg.Network.NetworkID)
+	if err != nil {
+		os.Exit(exitWithError(err))
+	}
+
+	runtimeMeta := storage.NewRuntimeTracker(cfg.Storage.RuntimeMetaPath, cfg.Storage.KeepBackups)
+	if err := runtimeMeta.Start(time.Now().UTC()); err != nil {
+		log.Warn("runtime metadata unavailable", "path", cfg.Storage.RuntimeMetaPath, "err", err)
+	} else {
+		m := runtimeMeta.Snapshot(time.Now().UTC())
+		log.Info("node start recorded", "restarts", m.Restarts, "uncleanStops", m.UncleanStops, "cumulativeUptimeSec", m.CumulativeUptimeSec)
+	}
 
 	identityKeyPath := filepath.Clean(cfg.Network.IdentityKeyPath)
 	identityPriv, err := loadOrCreateIdentityKey(identityKeyPath)
@@ -65,12 +138,50 @@ func main() {
 		os.Exit(exitWithError(err))
 	}
 
-	chain := blockchain.New(cfg.Network.NonceStorePath, cfg.Network.BlockStorePath)
+	bus := events.NewBus(log, 0)
+
+	var chainOpts []blockchain.Option
+	if cfg.Chain.PowDifficulty > 0 {
+		chainOpts = append(chainOpts, blockchain.WithConsensus(consensus.NewPoW(uint(cfg.Chain.PowDifficulty))))
+	}
+	chain := blockchain.New(cfg.Network.NonceStorePath, cfg.Network.BlockStorePath, cfg.Network.MempoolStorePath, chainOpts...)
+	chain.SetParams(chainParams(cfg.Chain))
+	blockStore, err := blockchain.OpenBlockStore(cfg.Storage.BlockBackend, cfg.Network.BlockStorePath, cfg.Storage.KeepBackups)
+	if err != nil {
+		os.Exit(exitWithError(err))
+	}
+	chain.SetBlockStore(blockStore)
+	chain.SetKeepBackups(cfg.Storage.KeepBackups)
+	if genesis != nil {
+		chain.SetGenesis(genesis.Block())
+	}
 	_ = chain.LoadNonceState()
-	_ = chain.LoadBlocks()
+	if err := chain.LoadBlocks(); errors.Is(err, blockchain.ErrGenesisMismatch) {
+		os.Exit(exitWithError(err))
+	}
+	chain.SetEvents(bus)
 
 	led := ledger.New(cfg.Ledger.StorePath)
-	_ = led.Load()
+	led.SetKeepBackup(cfg.Storage.KeepBackups)
+	// Replay the ledger WAL only as far as the blocks that were saved.
+	if err := led.LoadUpTo(chain.Height()); err != nil {
+		log.Warn("ledger store not fully restored", "path", cfg.Ledger.StorePath, "err", err)
+	}
+	if err := applyGenesis(log, chain, led, genesis); err != nil {
+		os.Exit(exitWithError(err))
+	}
+	checkLedgerHeight(log, led, chain.Height())
+	led.ResetJournal(chain.Height())
+
+	restoreMempool(log, chain, led)
+
+	var rewardAddr string
+	if cfg.Producer.RewardAddr != "" {
+		rewardAddr, err = blockchain.NormalizeAddress(cfg.Producer.RewardAddr, cfg.Network.NetworkID)
+		if err != nil {
+			os.Exit(exitWithError(errors.New("producer.rewardAddr: " + err.Error())))
+		}
+	}
 
 	p2pNode, err := p2p.New(p2p.Config{
 		ListenAddr:       cfg.Network.ListenAddr,
@@ -80,12 +191,34 @@ func main() {
 		DialTimeout:      cfg.Network.DialTimeout,
 		HandshakeTimeout: cfg.Network.HandshakeTimeout,
 
+		MaxPendingHandshakes: cfg.Network.MaxPendingHandshakes,
+		MaxUnverifiedPeers:   cfg.Network.MaxUnverifiedPeers,
+		MaxInbound:           cfg.Network.MaxInbound,
+		MaxOutbound:          cfg.Network.MaxOutbound,
+		ProtectedPeers:       cfg.Network.ProtectedPeers,
+		MaxDialAttempts:      cfg.Network.MaxDialAttempts,
+		MaxKnownPeers:        cfg.Network.MaxKnownPeers,
+		PuzzleBits:           cfg.Network.PuzzleBits,
+		TxGossipBudget:       cfg.Network.TxGossipBudget,
+		TxGossipInterval:     cfg.Network.TxGossipInterval,
+		PingInterval:         cfg.Network.PingInterval,
+		PongTimeout:          cfg.Network.PongTimeout,
+		PeerIdleTimeout:      cfg.Network.PeerIdleTimeout,
+		AllowPrivateAddrs:    cfg.Network.AllowPrivateAddrs,
+
 		NetworkID:       cfg.Network.NetworkID,
 		IdentityPrivKey: identityPriv,
 
 		BanlistPath:    cfg.Network.BanlistPath,
 		PeerStorePath:  cfg.Network.PeerStorePath,
 		ScoreStorePath: cfg.Network.ScoreStorePath,
+
+		KeepStoreBackups: cfg.Storage.KeepBackups,
+
+		Encrypt:       cfg.Network.Encrypt,
+		MinEncryption: cfg.Network.MinEncryption,
+
+		Events: bus,
 	}, log)
 	if err != nil {
 		os.Exit(exitWithError(err))
@@ -94,7 +227,6 @@ func main() {
 	if err := p2pNode.Start(); err != nil {
 		os.Exit(exitWithError(err))
 	}
-	defer func() { _ = p2pNode.Close() }()
 
 	devMode := strings.EqualFold(strings.TrimSpace(os.Getenv("VELTAROS_DEV_MODE")), "true")
 
@@ -104,15 +236,49 @@ func main() {
 		ledger:    led,
 		store:     store,
 		p2p:       p2pNode,
+		producer:  blockchain.NewBlockProducer(chain, led, cfg.Producer.MaxTxs, log),
+		runtime:   runtimeMeta,
+		events:    bus,
+		logLevel:  logLevel,
+		features:  nodeFeatures(cfg, devMode),
 		networkID: cfg.Network.NetworkID,
 		apiCfg:    cfg.API,
 		devMode:   devMode,
+		effective: cfg,
+	}
+
+	if cfg.API.AuditLogPath != "" {
+		al, err := audit.Open(cfg.API.AuditLogPath)
+		if err != nil {
+			os.Exit(exitWithError(err))
+		}
+		rt.audit = al
 	}
 
+	if cfg.Chain.NonceGrace > 0 || cfg.Chain.StrictNonces {
+		window := cfg.Chain.NonceGrace
+		if cfg.Chain.StrictNonces {
+			window = strictHoldWindow
+		}
+		rt.holdback = newNonceHoldback(window, cfg.Chain.NonceGraceMax, cfg.Chain.StrictNonces, nil)
+	}
+
+	p2pNode.SetTxHandler(func(from string, payload []byte) (bool, error) {
+		return handleRelayedTx(log, rt, from, payload)
+	})
+	p2pNode.SetBlockHandler(func(from string, height uint64, b blockchain.Block) error {
+		return handlePeerBlock(log, rt, from, height, b)
+	})
+	p2pNode.SetBlockSource(rt.chain)
+	rt.producer.SetOnBlock(func(sb blockchain.StoredBlock) {
+		rt.p2p.BroadcastBlock(sb.Height, sb.Block, "")
+	})
+	rt.producer.SetRewardAddress(rewardAddr)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	go func() {
+	rt.bg.Go(func() {
 		t := time.NewTicker(30 * time.Second)
 		defer t.Stop()
 		for {
@@ -120,114 +286,415 @@ func main() {
 			case <-ctx.Done():
 				return
 			case <-t.C:
-				_ = rt.chain.SaveNonceState()
-				_ = rt.chain.SaveBlocks()
-				_ = rt.ledger.Save()
+				rt.persist()
+				_ = rt.runtime.Flush(time.Now().UTC())
 			}
 		}
-	}()
+	})
+
+	if cfg.Producer.Enabled {
+		rt.producer.Start(ctx, cfg.Producer.Interval)
+		log.Info("block producer started", "interval", cfg.Producer.Interval.String(), "maxTxs", cfg.Producer.MaxTxs, "rewardAddr", cfg.Producer.RewardAddr)
+	}
+
+	if rt.holdback != nil {
+		rt.bg.Go(func() { runHoldbackExpiry(ctx, log, rt) })
+	}
+
+	if cfg.Chain.MempoolMaxAge > 0 {
+		rt.bg.Go(func() { runMempoolExpiry(ctx, log, rt, cfg.Chain.MempoolMaxAge) })
+	}
+
+	if cfg.Chain.IntegrityInterval > 0 {
+		rt.bg.Go(func() {
+			runIntegrityChecks(ctx, log, rt, cfg.Chain.IntegrityInterval, cfg.Chain.QuarantineCorrupt)
+		})
+	}
 
 	var apiSrv *http.Server
 	if cfg.API.Enabled {
 		apiSrv = startAPI(log, cfg.API.ListenAddr, rt)
-		defer func() {
-			_ = rt.chain.SaveNonceState()
-			_ = rt.chain.SaveBlocks()
-			_ = rt.ledger.Save()
-			cctx, ccancel := context.WithTimeout(context.Background(), 8*time.Second)
-			defer ccancel()
-			_ = apiSrv.Shutdown(cctx)
-		}()
+		if cfg.API.WatchdogInterval > 0 {
+			rt.bg.Go(func() {
+				runWatchdog(ctx, log, rt, cfg.API.ListenAddr, cfg.API.WatchdogInterval, cfg.API.WatchdogFailures)
+			})
+			log.Info("api watchdog started", "interval", cfg.API.WatchdogInterval.String(), "failures", cfg.API.WatchdogFailures)
+		}
 	}
 
 	waitForShutdown(log)
-	_ = rt.chain.SaveNonceState()
-	_ = rt.chain.SaveBlocks()
-	_ = rt.ledger.Save()
+	rt.shutdown(log, apiSrv, cancel)
 	log.Info("shutdown complete")
 }
 
+// checkLedgerHeight warns when the ledger store was saved at a different
+// block height than the block store, e.g. after a crash between the two
+// writes. Balances then do not match the chain; nothing is repaired
+// automatically.
+func checkLedgerHeight(log *slog.Logger, led *ledger.Ledger, chainHeight uint64) {
+	h, ok := led.AppliedHeight()
+	switch {
+	case !ok:
+		log.Info("ledger store predates recorded heights; consistency not checked", "chainHeight", chainHeight)
+	case h < chainHeight:
+		log.Warn("ledger lags the chain; balances miss recent blocks", "ledgerHeight", h, "chainHeight", chainHeight)
+	case h > chainHeight:
+		log.Warn("ledger is ahead of the chain; block store lost recent blocks", "ledgerHeight", h, "chainHeight", chainHeight)
+	}
+}
+
+// restoreMempool reloads persisted pending txs and re-stages their ledger
+// spends. Problems are logged; they never block startup.
+func restoreMempool(log *slog.Logger, chain *blockchain.Chain, led *ledger.Ledger) {
+	drops, err := chain.LoadMempool(
+		func(tx blockchain.SignedTx) error {
+			return led.StageMempoolSpend(tx.Draft.From, tx.Draft.SpendAmount())
+		},
+		func(tx blockchain.SignedTx) {
+			led.UnstageMempoolSpend(tx.Draft.From, tx.Draft.SpendAmount())
+		},
+	)
+	if err != nil {
+		log.Warn("mempool store unreadable; starting with empty mempool", "err", err)
+		return
+	}
+	for _, d := range drops {
+		log.Warn("dropped persisted mempool tx", "txId", d.TxID, "reason", d.Reason)
+	}
+	if n := chain.MempoolCount(); n > 0 {
+		log.Info("mempool restored", "txs", n, "dropped", len(drops))
+	}
+}
+
+// runMempoolExpiry evicts stale pending txs (and the later nonces that depend
+// on them) and releases their staged spends so the funds become spendable
+// again.
+func runMempoolExpiry(ctx context.Context, log *slog.Logger, rt *nodeRuntime, maxAge time.Duration) {
+	every := max(min(maxAge/4, time.Minute), time.Second)
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		evicted := rt.chain.EvictExpired(maxAge)
+		if len(evicted) == 0 {
+			continue
+		}
+		for _, tx := range evicted {
+			rt.ledger.UnstageMempoolSpend(tx.Draft.From, tx.Draft.SpendAmount())
+			log.Debug("evicted mempool tx", "txId", tx.TxID, "from", tx.Draft.From, "nonce", tx.Draft.Nonce)
+		}
+		// Eviction rolls back nonce reservations for the removed chains.
+		if err := rt.saveMempoolState(); err != nil {
+			log.Error("save after mempool expiry failed", "err", err)
+		}
+		log.Info("evicted expired mempool txs", "count", len(evicted), "maxAge", maxAge.String())
+	}
+}
+
+// runIntegrityChecks periodically verifies the block store on disk off the
+// hot path and records each outcome for /metrics.
+func runIntegrityChecks(ctx context.Context, log *slog.Logger, rt *nodeRuntime, every time.Duration, quarantine bool) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		rep, err := rt.chain.CheckIntegrity(ctx, 256)
+		if err != nil {
+			return
+		}
+		rt.integrity.record(rep, time.Now())
+		if rep.OK {
+			log.Debug("block store integrity ok", "checked", rep.Checked)
+			continue
+		}
+		log.Error("block store corruption detected", "height", rep.BadHeight, "reason", rep.Reason, "checked", rep.Checked)
+		if !quarantine {
+			continue
+		}
+		removed, err := rt.chain.QuarantineFrom(rep.BadHeight, rt.ledger)
+		if err != nil {
+			// Most likely the ledger journal does not reach back that far;
+			// the chain is left as it was rather than out of step with it.
+			log.Error("block store quarantine refused", "fromHeight", rep.BadHeight, "err", err)
+			continue
+		}
+		readmitted := readmitUnwound(rt, removed)
+		rt.persist()
+		log.Warn("quarantined corrupt block store tail",
+			"fromHeight", rep.BadHeight,
+			"blocks", len(removed),
+			"readmittedTxs", readmitted,
+			"height", rt.chain.Height(),
+		)
+	}
+}
+
+// readmitUnwound returns the txs of unwound blocks to the mempool, oldest
+// first, and reports how many were admitted. Txs that no longer validate are
+// dropped.
+func readmitUnwound(rt *nodeRuntime, blocks []blockchain.StoredBlock) int {
+	n := 0
+	for _, b := range blocks {
+		for _, tx := range b.Block.Transactions {
+			if blockchain.ValidateSignedTx(tx) != nil {
+				continue
+			}
+			if res, err := admitNow(rt, tx, blockchain.TxSourceRestored); err == nil && !res.Duplicate {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func chainParams(cfg config.ChainConfig) blockchain.ChainParams {
+	p := blockchain.DefaultParams()
+	p.RequireKnownRecipient = cfg.RequireKnownRecipient
+	p.HonorTxClass = cfg.HonorTxClass
+	p.MaxMempoolTxs = cfg.MempoolMaxTxs
+	p.MaxMempoolBytes = cfg.MempoolMaxBytes
+	p.MinRelayFee = cfg.MinRelayFee
+	p.MinRelayFeePerByte = cfg.MinRelayFeePerByte
+	p.BlockReward = cfg.BlockReward
+	p.MaxFutureSkewSec = int64(cfg.MaxFutureSkew / time.Second)
+	p.PowDifficulty = uint(cfg.PowDifficulty)
+	return p
+}
+
 func startAPI(log *slog.Logger, listen string, rt *nodeRuntime) *http.Server {
 	mux := http.NewServeMux()
-	txLimiter := api.NewLimiter(2.0, 10.0, 1.0)
+	txLimiter := api.NewLimiter(rt.apiCfg.TxRateLimit, rt.apiCfg.TxBurst, rt.apiCfg.TxCost)
+	// Already validated by config.
+	_ = txLimiter.SetTrustedProxies(rt.apiCfg.TrustedProxies)
+	if rt.apiCfg.RateLimitByKey {
+		txLimiter.SetKeyFunc(api.APIKeyBucket([]string{rt.apiCfg.APIKey.Reveal()}))
+	}
 
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
 		writeJSON(w, http.StatusOK, map[string]any{
 			"ok":   true,
 			"time": time.Now().UTC().Format(time.RFC3339Nano),
 		})
 	})
 
-	mux.HandleFunc("/version", func(w http.ResponseWriter, _ *http.Request) {
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
 		writeJSON(w, http.StatusOK, version.Get())
 	})
 
+	mux.HandleFunc("/features", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, rt.features)
+	})
+
 	// Explorer basics
 	mux.HandleFunc("/tip", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		if !allowMethod(w, r, http.MethodGet) {
 			return
 		}
+		height, tip := rt.chain.Tip()
 		writeJSON(w, http.StatusOK, map[string]any{
-			"height":  rt.chain.Height(),
-			"tipHash": rt.chain.TipHashHex(),
+			"height":  height,
+			"tipHash": tip,
 		})
 	})
 
 	mux.HandleFunc("/blocks", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		if !allowMethod(w, r, http.MethodGet) {
 			return
 		}
 		limit := 25
+		if v := strings.TrimSpace(r.URL.Query().Get("limit")); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "limit must be a positive integer"})
+				return
+			}
+			limit = min(n, maxBlocksLimit)
+		}
+		height, tip := rt.chain.Tip()
+		blocks := rt.chain.RecentBlocks(limit)
 		writeJSON(w, http.StatusOK, map[string]any{
-			"count":  len(rt.chain.RecentBlocks(limit)),
-			"blocks": rt.chain.RecentBlocks(limit),
+			"height":  height,
+			"tipHash": tip,
+			"count":   len(blocks),
+			"blocks":  blocks,
 		})
 	})
 
 	mux.HandleFunc("/block/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		raw := strings.TrimPrefix(r.URL.Path, "/block/")
+		raw, rawTxID, proofView := strings.Cut(raw, "/proof/")
+		if len(raw) > maxHashInput || len(rawTxID) > maxHashInput {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "hash must be 64 hex characters"})
 			return
 		}
-		h := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/block/"))
+		h := strings.ToLower(strings.TrimSpace(raw))
 		if h == "" {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "hash required"})
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "hash required"})
+			return
+		}
+		if !isHex32(h) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "hash must be 64 hex characters"})
+			return
+		}
+		txID := strings.ToLower(strings.TrimSpace(rawTxID))
+		if proofView && !isHex32(txID) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "txId must be 64 hex characters"})
 			return
 		}
 		b, ok := rt.chain.GetBlock(h)
 		if !ok {
-			writeJSON(w, http.StatusNotFound, map[string]any{"error": "not found"})
+			notFound(w)
 			return
 		}
-		writeJSON(w, http.StatusOK, b)
+		if !proofView {
+			writeJSON(w, http.StatusOK, b)
+			return
+		}
+
+		path, dirs, err := b.Block.MerkleProof(txID)
+		if errors.Is(err, blockchain.ErrNotInMerkleTree) {
+			notFound(w)
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "proof failed: " + err.Error()})
+			return
+		}
+		hexPath := make([]string, len(path))
+		for i, p := range path {
+			hexPath[i] = hex.EncodeToString(p[:])
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"blockHash":  b.HashHex,
+			"height":     b.Height,
+			"merkleRoot": b.MerkleRoot,
+			"txId":       txID,
+			"path":       hexPath,
+			"dirs":       dirs,
+		})
 	})
 
-	mux.HandleFunc("/status", func(w http.ResponseWriter, _ *http.Request) {
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		height, tip := rt.chain.Tip()
+		params := rt.chain.Params()
 		writeJSON(w, http.StatusOK, map[string]any{
-			"networkID": rt.networkID,
-			"startedAt": rt.startedAt.Format(time.RFC3339Nano),
-			"uptimeSec": int64(time.Since(rt.startedAt).Seconds()),
-			"peers":     rt.p2p.PeerCount(),
-			"height":    rt.chain.Height(),
-			"mempool":   rt.chain.MempoolCount(),
-			"tipHash":   rt.chain.TipHashHex(),
-			"dataDir":   rt.store.DataDir,
-			"devMode":   rt.devMode,
+			"networkID":     rt.networkID,
+			"startedAt":     rt.startedAt.Format(time.RFC3339Nano),
+			"uptimeSec":     int64(time.Since(rt.startedAt).Seconds()),
+			"peers":         rt.p2p.PeerCount(),
+			"handshaking":   rt.p2p.PendingHandshakes(),
+			"unverified":    rt.p2p.UnverifiedPeers(),
+			"height":        height,
+			"mempool":       rt.chain.MempoolCount(),
+			"mempoolBytes":  rt.chain.MempoolBytes(),
+			"tipHash":       tip,
+			"dataDir":       rt.store.DataDir,
+			"devMode":       rt.devMode,
+			"minFee":        max(uint64(blockchain.MinFee), params.MinRelayFee),
+			"minFeePerByte": params.MinRelayFeePerByte,
+			"runtime":       rt.runtime.Snapshot(time.Now().UTC()),
 		})
 	})
 
-	mux.HandleFunc("/peers", func(w http.ResponseWriter, _ *http.Request) {
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if !rt.apiCfg.Metrics {
+			notFound(w)
+			return
+		}
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		w.Header().Set("Content-Type", api.MetricsContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		_ = api.WriteMetrics(w, nodeMetrics(rt, txLimiter))
+	})
+
+	mux.HandleFunc("/params", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, rt.chain.Params())
+	})
+
+	mux.HandleFunc("/peers", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
 		writeJSON(w, http.StatusOK, map[string]any{
 			"count": rt.p2p.PeerCount(),
 			"peers": rt.p2p.Peers(),
 		})
 	})
 
+	mux.HandleFunc("/bans", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		now := time.Now().UTC()
+		entries := rt.p2p.BanEntries()
+		bans := make([]banView, 0, len(entries))
+		for _, e := range entries {
+			bans = append(bans, newBanView(e, now))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"count": len(bans),
+			"bans":  bans,
+		})
+	})
+
+	mux.HandleFunc("/scores", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		scores := rt.p2p.ScoreSnapshots()
+		writeJSON(w, http.StatusOK, map[string]any{
+			"count":  len(scores),
+			"scores": scores,
+		})
+	})
+
+	mux.HandleFunc("/events", serveEvents(log, rt))
+
 	mux.HandleFunc("/mempool", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		if raw := r.URL.Query().Get("from"); raw != "" {
+			from, err := parseAddressInput(raw, rt.networkID)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+				return
+			}
+			txs := rt.chain.MempoolBySender(from)
+			writeJSON(w, http.StatusOK, map[string]any{
+				"count": len(txs),
+				"txs":   txs,
+			})
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]any{
@@ -236,42 +703,126 @@ func startAPI(log *slog.Logger, listen string, rt *nodeRuntime) *http.Server {
 		})
 	})
 
+	mux.HandleFunc("/mempool/stats", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, rt.chain.MempoolStats())
+	})
+
+	mux.HandleFunc("/supply", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		sup, err := rt.ledger.Supply()
+		if err != nil {
+			log.Error("ledger supply", "err", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"total":       sup.Total,
+			"circulating": sup.Circulating,
+			"staked":      sup.Staked,
+			"accounts":    sup.Accounts,
+			"height":      sup.Height,
+		})
+	})
+
 	mux.HandleFunc("/account/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		if !allowMethod(w, r, http.MethodGet) {
 			return
 		}
-		addr := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/account/"))
-		if addr == "" {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "address required"})
+		rest := strings.TrimPrefix(r.URL.Path, "/account/")
+		rest, nonceView := strings.CutSuffix(rest, "/nonce")
+		rest, txsView := strings.CutSuffix(rest, "/txs")
+		addr, err := parseAddressInput(rest, rt.networkID)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
 			return
 		}
-		if err := blockchain.ValidateAddress(addr); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid address"})
+		if txsView {
+			q := r.URL.Query()
+			limit := 25
+			if v := strings.TrimSpace(q.Get("limit")); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n <= 0 {
+					writeJSON(w, http.StatusBadRequest, map[string]any{"error": "limit must be a positive integer"})
+					return
+				}
+				limit = min(n, maxAccountTxsLimit)
+			}
+			offset := 0
+			if v := strings.TrimSpace(q.Get("offset")); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					writeJSON(w, http.StatusBadRequest, map[string]any{"error": "offset must be a non-negative integer"})
+					return
+				}
+				offset = n
+			}
+			txs, total := rt.chain.AccountTxs(addr, offset, limit)
+			writeJSON(w, http.StatusOK, map[string]any{
+				"address": addr,
+				"total":   total,
+				"offset":  offset,
+				"count":   len(txs),
+				"txs":     txs,
+			})
 			return
 		}
+		if nonceView {
+			st := rt.chain.NonceState(addr)
+			held := []uint64{}
+			if rt.holdback != nil {
+				held = rt.holdback.nonces(addr)
+			}
+			resp := map[string]any{
+				"address":           addr,
+				"lastConfirmed":     st.LastConfirmed,
+				"expectedNext":      st.ExpectedNext,
+				"reservedInMempool": st.Reserved,
+				"held":              held,
+			}
+			if v := r.URL.Query().Get("check"); v != "" {
+				n, err := strconv.ParseUint(v, 10, 64)
+				if err != nil || n == 0 {
+					writeJSON(w, http.StatusBadRequest, map[string]any{"error": "check must be a nonce > 0"})
+					return
+				}
+				resp["check"] = map[string]any{"nonce": n, "status": nonceStatus(st, held, n)}
+			}
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+		future := 0
+		if rt.holdback != nil {
+			future = rt.holdback.count(addr)
+		}
 		writeJSON(w, http.StatusOK, map[string]any{
 			"address":          addr,
 			"lastNonce":        rt.chain.LastNonce(addr),
 			"expectedNonce":    rt.chain.ExpectedNonce(addr),
+			"queuedTxs":        len(rt.chain.MempoolBySender(addr)),
+			"futureTxs":        future,
 			"confirmedBalance": rt.ledger.ConfirmedBalance(addr),
 			"pendingOut":       rt.ledger.PendingOut(addr),
+			"stakedBalance":    rt.ledger.StakedBalance(addr),
 			"spendableBalance": rt.ledger.SpendableBalance(addr),
 		})
 	})
 
 	mux.HandleFunc("/faucet", func(w http.ResponseWriter, r *http.Request) {
 		if !rt.apiCfg.FaucetEnabled {
-			writeJSON(w, http.StatusNotFound, map[string]any{"error": "not found"})
+			notFound(w)
 			return
 		}
-		if r.Method != http.MethodPost {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		if !allowMethod(w, r, http.MethodPost) {
 			return
 		}
-		if rt.apiCfg.APIKey != "" {
+		if rt.apiCfg.APIKey.IsSet() {
 			got := strings.TrimSpace(r.Header.Get("X-API-Key"))
-			if got != strings.TrimSpace(rt.apiCfg.APIKey) {
+			if !api.ConstantTimeEqualString(got, rt.apiCfg.APIKey.Reveal()) {
 				writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
 				return
 			}
@@ -289,9 +840,9 @@ func startAPI(log *slog.Logger, listen string, rt *nodeRuntime) *http.Server {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid json"})
 			return
 		}
-		req.Address = strings.TrimSpace(req.Address)
-		if err := blockchain.ValidateAddress(req.Address); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid address"})
+		req.Address, err = parseAddressInput(req.Address, rt.networkID)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
 			return
 		}
 		if req.Amount == 0 {
@@ -302,7 +853,7 @@ func startAPI(log *slog.Logger, listen string, rt *nodeRuntime) *http.Server {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
 			return
 		}
-		_ = rt.ledger.Save()
+		// FaucetCredit is in the WAL; the periodic persist snapshots it.
 		writeJSON(w, http.StatusOK, map[string]any{
 			"ok":      true,
 			"address": req.Address,
@@ -311,32 +862,89 @@ func startAPI(log *slog.Logger, listen string, rt *nodeRuntime) *http.Server {
 		})
 	})
 
+	mux.HandleFunc("/tx/", func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		raw := strings.TrimPrefix(r.URL.Path, "/tx/")
+		if len(raw) > maxHashInput {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "txid must be 64 hex characters"})
+			return
+		}
+		id := strings.ToLower(strings.TrimSpace(raw))
+		if !isHex32(id) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "txid must be 64 hex characters"})
+			return
+		}
+		if tx, ok := rt.chain.MempoolGet(id); ok {
+			writeJSON(w, http.StatusOK, map[string]any{"txId": id, "status": "pending", "clientRef": tx.Draft.ClientRef})
+			return
+		}
+		if rt.holdback != nil {
+			if tx, ok := rt.holdback.get(id); ok {
+				writeJSON(w, http.StatusOK, map[string]any{"txId": id, "status": "held", "clientRef": tx.Draft.ClientRef})
+				return
+			}
+		}
+		if b, ok := rt.chain.FindTx(id); ok {
+			var ref string
+			for _, tx := range b.Block.Transactions {
+				if tx.TxID == id {
+					ref = tx.Draft.ClientRef
+					break
+				}
+			}
+			writeJSON(w, http.StatusOK, map[string]any{
+				"txId":      id,
+				"status":    "confirmed",
+				"height":    b.Height,
+				"blockHash": b.HashHex,
+				"clientRef": ref,
+			})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"txId": id, "status": "unknown"})
+	})
+
 	mux.HandleFunc("/tx/validate", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		if !allowMethod(w, r, http.MethodPost) {
 			return
 		}
 		if !txLimiter.Allow(r) {
 			writeJSON(w, http.StatusTooManyRequests, map[string]any{"ok": false, "error": "rate limited"})
 			return
 		}
-		tx, err := decodeSignedTx(r, rt.networkID)
+		tx, err := decodeSignedTx(r, rt.networkID, rt.apiCfg.BinaryTx)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+			writeJSON(w, decodeErrorStatus(err), map[string]any{"ok": false, "error": err.Error()})
 			return
 		}
 		if err := blockchain.ValidateSignedTx(tx); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "code": txErrorCode(err), "error": err.Error()})
+			return
+		}
+		if err := rt.chain.CheckTxTime(tx); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "code": txErrorCode(err), "error": err.Error()})
 			return
 		}
-		required := tx.Draft.Amount
+		if err := rt.chain.CheckRelayFee(tx); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "code": txErrorCode(err), "error": err.Error()})
+			return
+		}
+		if !recipientsKnown(rt, tx.Draft) {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "code": txErrorCode(errUnknownRecipient), "error": errUnknownRecipient.Error()})
+			return
+		}
+		required := tx.Draft.SpendAmount()
 		if rt.ledger.SpendableBalance(tx.Draft.From) < required {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "insufficient balance"})
+			err := ledger.ErrInsufficientBalance
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "code": txErrorCode(err), "error": err.Error()})
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]any{
 			"ok":            true,
 			"txId":          tx.TxID,
+			"clientRef":     tx.Draft.ClientRef,
 			"from":          tx.Draft.From,
 			"lastNonce":     rt.chain.LastNonce(tx.Draft.From),
 			"expectedNonce": rt.chain.ExpectedNonce(tx.Draft.From),
@@ -346,115 +954,405 @@ func startAPI(log *slog.Logger, listen string, rt *nodeRuntime) *http.Server {
 	})
 
 	mux.HandleFunc("/tx/broadcast", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		if !allowMethod(w, r, http.MethodPost) {
 			return
 		}
 		if !txLimiter.Allow(r) {
 			writeJSON(w, http.StatusTooManyRequests, map[string]any{"ok": false, "error": "rate limited"})
 			return
 		}
-		tx, err := decodeSignedTx(r, rt.networkID)
+		peers := rt.p2p.PeerCount()
+		lowPeers := rt.apiCfg.MinPeers > 0 && peers < rt.apiCfg.MinPeers
+		if lowPeers && rt.apiCfg.MinPeersStrict {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+				"ok":       false,
+				"error":    "insufficient peers",
+				"peers":    peers,
+				"minPeers": rt.apiCfg.MinPeers,
+			})
+			return
+		}
+		tx, err := decodeSignedTx(r, rt.networkID, rt.apiCfg.BinaryTx)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+			writeJSON(w, decodeErrorStatus(err), map[string]any{"ok": false, "error": err.Error()})
 			return
 		}
 		if err := blockchain.ValidateSignedTx(tx); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "code": txErrorCode(err), "error": err.Error()})
 			return
 		}
-		if rt.chain.MempoolHas(tx.TxID) {
-			writeJSON(w, http.StatusOK, map[string]any{"ok": true, "txId": tx.TxID, "note": "already in mempool"})
-			return
+		resp := map[string]any{"ok": true, "txId": tx.TxID, "clientRef": tx.Draft.ClientRef, "peers": peers}
+		if lowPeers {
+			resp["warning"] = "node has fewer peers than configured minimum; tx may not propagate"
+			resp["minPeers"] = rt.apiCfg.MinPeers
 		}
-		if err := rt.ledger.StageMempoolSpend(tx.Draft.From, tx.Draft.Amount); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+		res, err := admitTx(rt, tx, blockchain.TxSourceLocal)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, blockchain.ErrMempoolFull) {
+				status = http.StatusServiceUnavailable
+			}
+			writeJSON(w, status, map[string]any{"ok": false, "code": txErrorCode(err), "error": err.Error()})
 			return
 		}
-		if !rt.chain.ReserveNonce(tx.Draft.From, tx.Draft.Nonce) {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "nonce too low"})
+		switch {
+		case res.Held:
+			resp["held"] = true
+			resp["expectedNonce"] = rt.chain.ExpectedNonce(tx.Draft.From)
+			resp["note"] = "nonce ahead of expected; held until earlier nonces arrive"
+			writeJSON(w, http.StatusAccepted, resp)
 			return
+		case res.Duplicate:
+			resp["note"] = "already in mempool"
+		default:
+			relayTx(rt, tx, "")
 		}
-		if err := rt.chain.MempoolAdd(tx); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
-			return
+		if res.Replaced != "" {
+			resp["replaced"] = res.Replaced
 		}
-		_ = rt.chain.SaveNonceState()
-		_ = rt.ledger.Save()
-		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "txId": tx.TxID})
+		writeJSON(w, http.StatusOK, resp)
 	})
 
 	mux.HandleFunc("/dev/produce-block", func(w http.ResponseWriter, r *http.Request) {
 		if !rt.devMode {
-			writeJSON(w, http.StatusNotFound, map[string]any{"error": "not found"})
+			notFound(w)
 			return
 		}
-		if r.Method != http.MethodPost {
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"error": "method not allowed"})
+		if !allowMethod(w, r, http.MethodPost) {
 			return
 		}
-		if rt.apiCfg.APIKey != "" {
+		if rt.apiCfg.APIKey.IsSet() {
 			got := strings.TrimSpace(r.Header.Get("X-API-Key"))
-			if got != strings.TrimSpace(rt.apiCfg.APIKey) {
+			if !api.ConstantTimeEqualString(got, rt.apiCfg.APIKey.Reveal()) {
 				writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
 				return
 			}
 		}
 
-		txs := rt.chain.MempoolDrain()
-		prev := rt.chain.TipHash()
-		blk, err := blockchain.BuildBlock(prev, txs)
+		res, err := rt.producer.ProduceOnce(true)
 		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
 			return
 		}
+		sb := res.Block
+		recordAudit(log, rt, r, "dev.produceBlock", map[string]string{
+			"height": strconv.FormatUint(sb.Height, 10),
+			"hash":   sb.HashHex,
+		})
 
-		applied := 0
-		failed := 0
-		rt.ledger.ResetPending()
+		rt.persist()
 
-		for _, tx := range txs {
-			if err := rt.ledger.ApplyConfirmedTx(tx.Draft.From, tx.Draft.To, tx.Draft.Amount, tx.Draft.Fee); err != nil {
-				failed++
-				continue
+		writeJSON(w, http.StatusOK, map[string]any{
+			"ok":         true,
+			"applied":    res.Applied,
+			"failed":     res.Failed,
+			"dropped":    res.Dropped,
+			"height":     sb.Height,
+			"blockHash":  sb.HashHex,
+			"merkleRoot": sb.MerkleRoot,
+			"txCount":    sb.TxCount,
+			"reward":     res.Reward,
+		})
+	})
+
+	mux.HandleFunc("/block/assemble", func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r, rt.apiCfg) {
+			return
+		}
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		res, err := rt.producer.Assemble()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "assemble failed: " + err.Error()})
+			return
+		}
+		h := res.Block.Header
+		hash := h.Hash()
+		writeJSON(w, http.StatusOK, map[string]any{
+			"ok":        true,
+			"height":    res.Height,
+			"blockHash": hex.EncodeToString(hash[:]),
+			"header": map[string]any{
+				"version":    h.Version,
+				"prevHash":   hex.EncodeToString(h.PrevHash[:]),
+				"merkleRoot": hex.EncodeToString(h.MerkleRoot[:]),
+				"timestamp":  h.Timestamp,
+				"nonce":      h.Nonce,
+			},
+			"merkleRoot": hex.EncodeToString(h.MerkleRoot[:]),
+			"txCount":    len(res.TxIDs),
+			"txIds":      res.TxIDs,
+			"totalFees":  res.TotalFees,
+			"coinbase":   res.Block.Coinbase,
+			"skipped":    res.Skipped,
+		})
+	})
+
+	mux.HandleFunc("/admin/banlist/reload", func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r, rt.apiCfg) {
+			return
+		}
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		res, err := rt.p2p.ReloadBanlist()
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "banlist reload failed: " + err.Error()})
+			return
+		}
+		recordAudit(log, rt, r, "banlist.reload", map[string]string{
+			"added":    strconv.Itoa(len(res.Added)),
+			"extended": strconv.Itoa(len(res.Extended)),
+		})
+		writeJSON(w, http.StatusOK, map[string]any{
+			"ok":     true,
+			"result": res,
+			"active": rt.p2p.BanCount(),
+		})
+	})
+
+	mux.HandleFunc("/admin/ban", func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r, rt.apiCfg) {
+			return
+		}
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			Addr     string `json:"addr"`
+			Duration string `json:"duration"`
+			Reason   string `json:"reason"`
+		}
+		if !readAdminJSON(w, r, &req) {
+			return
+		}
+		addr, err := parsePeerAddrInput(req.Addr)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+			return
+		}
+		var d time.Duration // 0 = permanent
+		if v := strings.TrimSpace(req.Duration); v != "" && v != "permanent" {
+			d, err = time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": `duration must be a positive Go duration (e.g. "24h") or "permanent"`})
+				return
 			}
-			applied++
 		}
+		reason := strings.TrimSpace(req.Reason)
+		if len(reason) > maxBanReason {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "reason too long"})
+			return
+		}
+		if reason == "" {
+			reason = "admin"
+		}
+		if err := rt.p2p.BanPeer(addr, d, reason); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "ban failed: " + err.Error()})
+			return
+		}
+		duration := "permanent"
+		if d > 0 {
+			duration = d.String()
+		}
+		recordAudit(log, rt, r, "peer.ban", map[string]string{"addr": addr, "duration": duration, "reason": reason})
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "addr": addr, "duration": duration, "active": rt.p2p.BanCount()})
+	})
 
-		sb, err := rt.chain.AddBlock(blk)
+	mux.HandleFunc("/admin/unban", func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r, rt.apiCfg) {
+			return
+		}
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			Addr string `json:"addr"`
+		}
+		if !readAdminJSON(w, r, &req) {
+			return
+		}
+		addr, err := parsePeerAddrInput(req.Addr)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]any{"error": err.Error()})
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
 			return
 		}
+		was, err := rt.p2p.UnbanPeer(addr)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "unban failed: " + err.Error()})
+			return
+		}
+		recordAudit(log, rt, r, "peer.unban", map[string]string{"addr": addr, "wasBanned": strconv.FormatBool(was)})
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "addr": addr, "wasBanned": was, "active": rt.p2p.BanCount()})
+	})
 
-		_ = rt.chain.SaveBlocks()
-		_ = rt.chain.SaveNonceState()
-		_ = rt.ledger.Save()
+	mux.HandleFunc("/admin/peers/connect", func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r, rt.apiCfg) {
+			return
+		}
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			Addr string `json:"addr"`
+		}
+		if !readAdminJSON(w, r, &req) {
+			return
+		}
+		addr, err := parsePeerAddrInput(req.Addr)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+			return
+		}
+		if err := rt.p2p.ConnectPeer(addr); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "connect failed: " + err.Error()})
+			return
+		}
+		recordAudit(log, rt, r, "peer.connect", map[string]string{"addr": addr})
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "addr": addr})
+	})
 
-		writeJSON(w, http.StatusOK, map[string]any{
-			"ok":         true,
-			"applied":    applied,
-			"failed":     failed,
-			"height":     sb.Height,
-			"blockHash":  sb.HashHex,
-			"merkleRoot": sb.MerkleRoot,
-			"txCount":    sb.TxCount,
+	mux.HandleFunc("/admin/peers/disconnect", func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r, rt.apiCfg) {
+			return
+		}
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		var req struct {
+			Addr string `json:"addr"`
+		}
+		if !readAdminJSON(w, r, &req) {
+			return
+		}
+		addr, err := parsePeerAddrInput(req.Addr)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+			return
+		}
+		was := rt.p2p.DisconnectPeer(addr)
+		recordAudit(log, rt, r, "peer.disconnect", map[string]string{"addr": addr, "wasConnected": strconv.FormatBool(was)})
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "addr": addr, "wasConnected": was})
+	})
+
+	mux.HandleFunc("/admin/peers/backoff", func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r, rt.apiCfg) {
+			return
+		}
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		addr, err := parsePeerAddrInput(r.URL.Query().Get("addr"))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "backoff": rt.p2p.DialBackoff(addr)})
+	})
+
+	mux.HandleFunc("/admin/runtime/reset", func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r, rt.apiCfg) {
+			return
+		}
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		prev := rt.runtime.Snapshot(time.Now().UTC())
+		if err := rt.runtime.Reset(time.Now().UTC()); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": "runtime reset failed: " + err.Error()})
+			return
+		}
+		log.Warn("runtime counters reset", "restarts", prev.Restarts, "uncleanStops", prev.UncleanStops, "cumulativeUptimeSec", prev.CumulativeUptimeSec)
+		recordAudit(log, rt, r, "runtime.reset", map[string]string{
+			"restarts":            strconv.FormatUint(prev.Restarts, 10),
+			"uncleanStops":        strconv.FormatUint(prev.UncleanStops, 10),
+			"cumulativeUptimeSec": strconv.FormatInt(prev.CumulativeUptimeSec, 10),
 		})
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "runtime": rt.runtime.Snapshot(time.Now().UTC())})
+	})
+
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r, rt.apiCfg) {
+			return
+		}
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		out, err := config.Marshal(rt.effective)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"ok": false, "error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, json.RawMessage(out))
+	})
+
+	mux.HandleFunc("/admin/loglevel", func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r, rt.apiCfg) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req struct {
+				Level string `json:"level"`
+			}
+			if !readAdminJSON(w, r, &req) {
+				return
+			}
+			lvl, err := logging.ParseLevel(req.Level)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+				return
+			}
+			prev := rt.logLevel.Level()
+			rt.logLevel.Set(lvl)
+			log.Warn("log level changed", "from", logging.LevelName(prev), "to", logging.LevelName(lvl))
+			recordAudit(log, rt, r, "loglevel.set", map[string]string{
+				"from": logging.LevelName(prev),
+				"to":   logging.LevelName(lvl),
+			})
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"ok": false, "code": "METHOD_NOT_ALLOWED", "error": "method not allowed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "level": logging.LevelName(rt.logLevel.Level())})
+	})
+
+	// Catch-all: unknown routes get a JSON 404 instead of the default plaintext.
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]any{"ok": false, "code": "NOT_FOUND", "error": "unknown route"})
 	})
 
 	secured := api.SecurityMiddleware(api.SecurityConfig{
 		AllowedOrigins: rt.apiCfg.AllowedOrigins,
-		APIKey:         rt.apiCfg.APIKey,
+		APIKey:         rt.apiCfg.APIKey.Reveal(),
 		RequireKeyFor: map[string]bool{
 			"/tx/validate":       rt.apiCfg.KeyOnValidate,
 			"/tx/broadcast":      rt.apiCfg.KeyOnBroadcast,
-			"/dev/produce-block": rt.devMode && rt.apiCfg.APIKey != "",
+			"/bans":              rt.apiCfg.KeyOnPeerState,
+			"/scores":            rt.apiCfg.KeyOnPeerState,
+			"/events":            rt.apiCfg.KeyOnEvents,
+			"/dev/produce-block": rt.devMode && rt.apiCfg.APIKey.IsSet(),
+			"/metrics":           true,
+			"/block/assemble":    true,
+			"/config":            true,
+
+			"/admin/ban":            true,
+			"/admin/banlist/reload": true,
+			"/admin/unban":          true,
+			"/admin/loglevel":       true,
+			"/admin/runtime/reset":  true,
 		},
 	}, mux)
 
+	// TrustedProxies was validated with the rest of the config.
+	trusted, _ := api.ParsePrefixes(rt.apiCfg.TrustedProxies)
+
 	srv := &http.Server{
 		Addr:              listen,
-		Handler:           secured,
+		Handler:           api.LoggingMiddleware(log, api.VersionedRoutes(secured), trusted...),
 		ReadHeaderTimeout: 5 * time.Second,
 		ReadTimeout:       rt.apiCfg.ReadTimeout,
 		WriteTimeout:      rt.apiCfg.WriteTimeout,
@@ -471,13 +1369,62 @@ func startAPI(log *slog.Logger, listen string, rt *nodeRuntime) *http.Server {
 	return srv
 }
 
-func decodeSignedTx(r *http.Request, networkID string) (blockchain.SignedTx, error) {
+// recordAudit appends an admin action to the audit log, if enabled. A write
+// failure is logged but does not undo the action.
+func recordAudit(log *slog.Logger, rt *nodeRuntime, r *http.Request, action string, details map[string]string) {
+	if rt.audit == nil {
+		return
+	}
+	actor := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(actor); err == nil {
+		actor = host
+	}
+	if err := rt.audit.Append(action, actor, details); err != nil {
+		log.Error("audit log append failed", "action", action, "err", err)
+	}
+}
+
+// requireAdmin gates operator endpoints: they are hidden unless an API key is
+// configured, and the request must present it.
+func requireAdmin(w http.ResponseWriter, r *http.Request, cfg config.APIConfig) bool {
+	if !cfg.APIKey.IsSet() {
+		notFound(w)
+		return false
+	}
+	got := strings.TrimSpace(r.Header.Get("X-API-Key"))
+	if !api.ConstantTimeEqualString(got, cfg.APIKey.Reveal()) {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "unauthorized"})
+		return false
+	}
+	return true
+}
+
+// errBinaryTxDisabled is returned for octet-stream bodies when api.binaryTx is off.
+var errBinaryTxDisabled = errors.New("binary tx submission is disabled")
+
+// decodeSignedTx reads a SignedTx as JSON or, with Content-Type
+// application/octet-stream and allowBinary, in the binary block encoding.
+// Both produce the same txId for the same signed draft and go through the
+// same validation afterwards.
+func decodeSignedTx(r *http.Request, networkID string, allowBinary bool) (blockchain.SignedTx, error) {
+	binaryBody := false
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mt == "application/octet-stream" {
+		if !allowBinary {
+			return blockchain.SignedTx{}, errBinaryTxDisabled
+		}
+		binaryBody = true
+	}
+
 	body, err := readBodyLimited(r.Body, 256*1024)
 	if err != nil {
 		return blockchain.SignedTx{}, err
 	}
 	var tx blockchain.SignedTx
-	if err := json.Unmarshal(body, &tx); err != nil {
+	if binaryBody {
+		if tx, err = blockchain.ParseSignedTxBytes(body); err != nil {
+			return blockchain.SignedTx{}, err
+		}
+	} else if err := json.Unmarshal(body, &tx); err != nil {
 		return blockchain.SignedTx{}, errors.New("invalid json")
 	}
 	if tx.Draft.NetworkID != networkID {
@@ -486,6 +1433,147 @@ func decodeSignedTx(r *http.Request, networkID string) (blockchain.SignedTx, err
 	return tx, nil
 }
 
+func decodeErrorStatus(err error) int {
+	if errors.Is(err, errBinaryTxDisabled) {
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusBadRequest
+}
+
+// allowMethod writes a JSON 405 (with Allow header) unless r uses method.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeJSON(w, http.StatusMethodNotAllowed, map[string]any{"ok": false, "code": "METHOD_NOT_ALLOWED", "error": "method not allowed"})
+	return false
+}
+
+// Request input is length-checked against these bounds before it is trimmed,
+// lowercased or decoded, so oversized paths and fields are rejected cheaply.
+// Valid values are at most 2*AddressLenBytes characters (the bech32 address
+// form is shorter than hex) and exactly 64 hex characters; the slack allows
+// surrounding whitespace.
+const (
+	maxAddressInput = 2*blockchain.AddressLenBytes + 16
+	maxHashInput    = 64 + 16
+)
+
+var (
+	errAddressRequired = errors.New("address required")
+	errAddressTooLong  = errors.New("address too long")
+	errInvalidAddress  = errors.New("invalid address")
+)
+
+// parseAddressInput trims and validates an address taken from a path, query
+// or body field. Either form is accepted; a bech32 address must carry this
+// network's prefix and is returned in hex form.
+func parseAddressInput(raw, networkID string) (string, error) {
+	if len(raw) > maxAddressInput {
+		return "", errAddressTooLong
+	}
+	addr := strings.TrimSpace(raw)
+	if addr == "" {
+		return "", errAddressRequired
+	}
+	addr, err := blockchain.NormalizeAddress(addr, networkID)
+	if errors.Is(err, blockchain.ErrAddressNetwork) {
+		return "", err
+	}
+	if err != nil {
+		return "", errInvalidAddress
+	}
+	return addr, nil
+}
+
+// banView is a ban as /bans reports it: RemainingSec (rounded up) is how
+// long a timed ban has left; it is omitted for permanent bans.
+type banView struct {
+	p2p.BanEntry
+	RemainingSec int64 `json:"remainingSec,omitempty"`
+}
+
+func newBanView(e p2p.BanEntry, now time.Time) banView {
+	v := banView{BanEntry: e}
+	if !e.Permanent {
+		v.RemainingSec = int64((e.Until.Sub(now) + time.Second - 1) / time.Second)
+	}
+	return v
+}
+
+// maxBanReason bounds the reason stored with an admin ban.
+const maxBanReason = 200
+
+// parsePeerAddrInput trims and checks a peer address (host:port, as peers
+// are keyed in the banlist) from an admin request.
+func parsePeerAddrInput(raw string) (string, error) {
+	if len(raw) > 300 {
+		return "", errors.New("addr too long")
+	}
+	addr := strings.TrimSpace(raw)
+	if addr == "" {
+		return "", errors.New("addr required")
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return "", errors.New("addr must be host:port")
+	}
+	return addr, nil
+}
+
+// readAdminJSON decodes a small admin request body into v, answering 400
+// itself on failure.
+func readAdminJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	body, err := readBodyLimited(r.Body, 4*1024)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+		return false
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"ok": false, "error": "invalid json"})
+		return false
+	}
+	return true
+}
+
+// nonceStatus classifies nonce n for a sender:
+//
+//	confirmed  at or below the highest nonce in a block
+//	reserved   taken by a pending mempool tx
+//	held       parked in the holdback waiting for a gap to fill
+//	skipped    below the next nonce but never used; it can no longer be
+//	           (a tx was admitted past it)
+//	next       the nonce the node will accept next
+//	future     ahead of next; would be held or rejected
+func nonceStatus(st blockchain.NonceState, held []uint64, n uint64) string {
+	switch {
+	case n <= st.LastConfirmed:
+		return "confirmed"
+	case slices.Contains(st.Reserved, n):
+		return "reserved"
+	case slices.Contains(held, n):
+		return "held"
+	case n < st.ExpectedNext:
+		return "skipped"
+	case n == st.ExpectedNext:
+		return "next"
+	}
+	return "future"
+}
+
+// isHex32 reports whether s is a 64-character hex string (a 32-byte hash).
+func isHex32(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+func notFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, map[string]any{"ok": false, "code": "NOT_FOUND", "error": "not found"})
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
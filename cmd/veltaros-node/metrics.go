@@ -0,0 +1,49 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/api"
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+)
+
+// integrityStatus holds the outcome of the last block store integrity check.
+type integrityStatus struct {
+	lastCheck   atomic.Int64  // unix seconds; 0 before the first check
+	corruptAt   atomic.Uint64 // first bad height found by the last check; 0 if clean
+	corruptions atomic.Uint64 // checks that found corruption
+}
+
+func (s *integrityStatus) record(rep blockchain.IntegrityReport, at time.Time) {
+	s.lastCheck.Store(at.Unix())
+	if rep.OK {
+		s.corruptAt.Store(0)
+		return
+	}
+	s.corruptAt.Store(rep.BadHeight)
+	s.corruptions.Add(1)
+}
+
+// nodeMetrics samples the values served on /metrics.
+func nodeMetrics(rt *nodeRuntime, txLimiter *api.Limiter) []api.Metric {
+	height, _ := rt.chain.Tip()
+	ps := rt.p2p.Stats()
+	return []api.Metric{
+		api.Gauge("veltaros_uptime_seconds", "Seconds since the node started.", time.Since(rt.startedAt).Seconds()),
+		api.Gauge("veltaros_chain_height", "Height of the chain tip.", float64(height)),
+		api.Gauge("veltaros_mempool_txs", "Pending transactions in the mempool.", float64(rt.chain.MempoolCount())),
+		api.Gauge("veltaros_mempool_bytes", "Total serialized size of pending transactions.", float64(rt.chain.MempoolBytes())),
+		api.Gauge("veltaros_p2p_peers", "Connected peers.", float64(rt.p2p.PeerCount())),
+		api.Gauge("veltaros_p2p_unverified_peers", "Connected peers that have not completed the challenge handshake.", float64(rt.p2p.UnverifiedPeers())),
+		api.Gauge("veltaros_p2p_known_peers", "Addresses in the peer store.", float64(rt.p2p.KnownPeerCount())),
+		api.Gauge("veltaros_p2p_banned_peers", "Active bans.", float64(rt.p2p.BanCount())),
+		api.Counter("veltaros_p2p_dial_failures_total", "Outbound dials or handshakes that failed.", ps.DialFailures),
+		api.Counter("veltaros_p2p_frames_read_total", "P2P frames received.", ps.FramesRead),
+		api.Counter("veltaros_p2p_frames_written_total", "P2P frames sent.", ps.FramesWritten),
+		api.Counter("veltaros_api_rate_limited_total", "Transaction API requests rejected by the rate limiter.", txLimiter.Rejected()),
+		api.Gauge("veltaros_integrity_last_check_timestamp_seconds", "Unix time of the last block store integrity check; 0 if none has run.", float64(rt.integrity.lastCheck.Load())),
+		api.Gauge("veltaros_integrity_corrupt_height", "First corrupt height found on disk by the last integrity check; 0 if it was clean.", float64(rt.integrity.corruptAt.Load())),
+		api.Counter("veltaros_integrity_corruptions_total", "Integrity checks that found a corrupt block store.", rt.integrity.corruptions.Load()),
+	}
+}
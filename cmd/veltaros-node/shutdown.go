@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/storage"
+)
+
+// apiShutdownTimeout bounds how long in-flight API requests may take to finish.
+const apiShutdownTimeout = 8 * time.Second
+
+// shutdown stops the node in a fixed order so that every store is written
+// exactly once, after everything that could still modify it has stopped:
+//
+//  1. event streams are closed, then the API server stops accepting requests
+//     and waits for in-flight ones, so no new tx is admitted;
+//  2. background loops (block producer, periodic persist, expiry, integrity
+//     checks) are cancelled and waited for;
+//  3. p2p closes its peers, waits for their handlers, and saves its own
+//     peer, score and ban stores;
+//  4. the chain and ledger stores are saved and the block store is closed,
+//     then the runtime metadata records a clean stop.
+//
+// apiSrv may be nil when the API is disabled.
+func (rt *nodeRuntime) shutdown(log *slog.Logger, apiSrv *http.Server, cancel context.CancelFunc) {
+	rt.stopping.Store(true)
+	// Release /events streams, which would otherwise hold Shutdown open.
+	rt.events.Close()
+	if apiSrv != nil {
+		ctx, ccancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+		if err := apiSrv.Shutdown(ctx); err != nil {
+			log.Warn("api shutdown incomplete", "err", err)
+		}
+		ccancel()
+	}
+
+	cancel()
+	if done := rt.producer.Done(); done != nil {
+		<-done
+	}
+	rt.bg.Wait()
+
+	_ = rt.p2p.Close()
+
+	rt.persist()
+	if err := rt.chain.CloseBlockStore(); err != nil {
+		log.Warn("block store close failed", "err", err)
+	}
+	_ = rt.runtime.Close(time.Now().UTC())
+}
+
+// persist saves the chain (nonces, blocks, mempool) and ledger stores. The
+// ledger and nonces go first, together, so a crash part-way leaves the block
+// store behind them rather than ahead; startup warns about either (see
+// checkLedgerHeight).
+func (rt *nodeRuntime) persist() {
+	_ = rt.saveLedgerState()
+	_ = rt.chain.SaveBlocks()
+	_ = rt.chain.SaveMempool()
+}
+
+// saveLedgerState writes the ledger and nonce stores as one storage.Batch:
+// both or neither. The ledger goes first, so its path names the batch journal
+// that startup recovers from (see storage.RecoverBatch).
+func (rt *nodeRuntime) saveLedgerState() error {
+	var b storage.Batch
+	if err := rt.ledger.Stage(&b); err != nil {
+		return err
+	}
+	if err := rt.chain.StageNonceState(&b); err != nil {
+		return err
+	}
+	return b.Commit()
+}
+
+// saveMempoolState writes the nonce and mempool stores as one storage.Batch,
+// so an eviction is never persisted in one without the other. The nonce
+// store goes first and names the journal (see storage.RecoverBatch).
+func (rt *nodeRuntime) saveMempoolState() error {
+	var b storage.Batch
+	if err := rt.chain.StageNonceState(&b); err != nil {
+		return err
+	}
+	if err := rt.chain.StageMempool(&b); err != nil {
+		return err
+	}
+	return b.Commit()
+}
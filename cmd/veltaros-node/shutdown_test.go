@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+	"github.com/VeltarosLabs/Veltaros/internal/events"
+	"github.com/VeltarosLabs/Veltaros/internal/ledger"
+	"github.com/VeltarosLabs/Veltaros/internal/p2p"
+	"github.com/VeltarosLabs/Veltaros/internal/storage"
+)
+
+// TestShutdownSavesEachStoreOnce starts from an empty data dir with backups
+// on: a store written once during shutdown exists without a backup, one
+// written twice has the first write as its backup.
+func TestShutdownSavesEachStoreOnce(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	chain := blockchain.New(path("nonces.json"), path("blocks.json"), path("mempool.json"))
+	bs, err := blockchain.OpenBlockStore(blockchain.BlockBackendJSON, path("blocks.json"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.SetBlockStore(bs)
+	chain.SetKeepBackups(true)
+	led := ledger.New(path("ledger.json"))
+	led.SetKeepBackup(true)
+
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, err := p2p.New(p2p.Config{
+		ListenAddr:       "127.0.0.1:0",
+		MaxPeers:         8,
+		NetworkID:        "veltaros-testnet",
+		IdentityPrivKey:  priv,
+		BanlistPath:      path("banlist.json"),
+		PeerStorePath:    path("peers.json"),
+		ScoreStorePath:   path("scores.json"),
+		KeepStoreBackups: true,
+	}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	rt := &nodeRuntime{
+		chain:    chain,
+		ledger:   led,
+		p2p:      node,
+		producer: blockchain.NewBlockProducer(chain, led, 10, log),
+		runtime:  storage.NewRuntimeTracker(path("runtime.json"), true),
+		events:   events.NewBus(log, 0),
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiSrv := &http.Server{Handler: http.NotFoundHandler()}
+	go func() { _ = apiSrv.Serve(ln) }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rt.producer.Start(ctx, time.Hour)
+	rt.bg.Go(func() { <-ctx.Done() })
+
+	rt.shutdown(log, apiSrv, cancel)
+
+	// The mempool store keeps no backup, so only its presence is checked.
+	if _, err := os.Stat(path("mempool.json")); err != nil {
+		t.Errorf("mempool.json not saved: %v", err)
+	}
+	for _, name := range []string{
+		"ledger.json", "nonces.json", "blocks.json",
+		"banlist.json", "peers.json", "scores.json", "runtime.json",
+	} {
+		if _, err := os.Stat(path(name)); err != nil {
+			t.Errorf("%s not saved: %v", name, err)
+		}
+		if _, err := os.Stat(path(name) + storage.BackupSuffix); err == nil {
+			t.Errorf("%s saved more than once", name)
+		}
+	}
+
+	raw, err := os.ReadFile(path("runtime.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta storage.RuntimeMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Running {
+		t.Error("runtime metadata does not record a clean stop")
+	}
+}
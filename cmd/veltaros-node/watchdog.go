@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// runWatchdog probes the node every interval: a GET of its own /healthz over
+// loopback, then a read of the chain height, which waits on the chain lock
+// that most handlers take. Each probe may take up to a full interval, so a
+// node that is only slow under load keeps passing; one that stays unresponsive
+// for failures consecutive probes is wedged, and the process exits (without
+// the usual graceful shutdown, which would likely hang too) so its supervisor
+// restarts it.
+func runWatchdog(ctx context.Context, log *slog.Logger, rt *nodeRuntime, listen string, interval time.Duration, failures int) {
+	url := "http://" + loopbackAddr(listen) + "/healthz"
+	client := &http.Client{Timeout: interval}
+
+	// A timer rather than a ticker: the next probe starts a full interval
+	// after the last one ended, however long that one took.
+	wait := time.NewTimer(interval)
+	defer wait.Stop()
+
+	var pending chan error // the previous probe, if it has not returned yet
+	failed := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-wait.C:
+		}
+
+		if pending != nil {
+			select {
+			case <-pending: // finished late; start afresh
+				pending = nil
+			default:
+			}
+		}
+
+		var err error
+		if pending != nil {
+			// Still stuck from last time; do not stack another probe on it.
+			err = errors.New("previous probe still running")
+		} else {
+			done := make(chan error, 1)
+			go func() { done <- probeNode(ctx, client, url, rt) }()
+			select {
+			case err = <-done:
+			case <-time.After(interval):
+				pending = done
+				err = fmt.Errorf("probe timed out after %s", interval)
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		switch {
+		case err == nil:
+			if failed > 0 {
+				log.Info("watchdog probe recovered", "after", failed)
+			}
+			failed = 0
+		case ctx.Err() != nil || rt.stopping.Load():
+			return
+		default:
+			failed++
+			log.Warn("watchdog probe failed", "err", err, "failures", failed, "limit", failures)
+			if failed >= failures {
+				log.Error("watchdog: node unresponsive; exiting for restart", "failures", failed, "interval", interval.String())
+				os.Exit(1)
+			}
+		}
+		wait.Reset(interval)
+	}
+}
+
+func probeNode(ctx context.Context, client *http.Client, url string, rt *nodeRuntime) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4*1024))
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("healthz returned %d", resp.StatusCode)
+	}
+
+	_ = rt.chain.Height()
+	return nil
+}
+
+// loopbackAddr turns a listen address into one the node can dial itself on:
+// an unspecified host (":8080", "0.0.0.0:8080", "[::]:8080") becomes
+// loopback.
+func loopbackAddr(listen string) string {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return listen
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case host == "" || (ip != nil && ip.To4() != nil && ip.IsUnspecified()):
+		host = "127.0.0.1"
+	case ip != nil && ip.IsUnspecified():
+		host = "::1"
+	}
+	return net.JoinHostPort(host, port)
+}
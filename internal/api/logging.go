@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"time"
+)
+
+// LoggingMiddleware logs one line per request: method, path, status, response
+// size, duration and client IP. The client IP follows the same rules as the
+// rate limiter: X-Forwarded-For is only believed from the trusted proxies.
+//
+// Health checks are polled constantly, so /healthz is logged at debug level;
+// everything else at info. Put it outermost (around VersionedRoutes and
+// SecurityMiddleware) so requests they reject are logged too.
+func LoggingMiddleware(log *slog.Logger, next http.Handler, trusted ...netip.Prefix) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		level := slog.LevelInfo
+		if r.URL.Path == "/healthz" || r.URL.Path == VersionPrefix+"/healthz" {
+			level = slog.LevelDebug
+		}
+		if !log.Enabled(r.Context(), level) {
+			return
+		}
+		log.LogAttrs(context.Background(), level, "api request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", sw.statusCode()),
+			slog.Int64("bytes", sw.bytes),
+			slog.Duration("dur", time.Since(start)),
+			slog.String("ip", clientIP(r, trusted)),
+		)
+	})
+}
+
+// statusWriter records the status code and body size written through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK // handler wrote nothing
+	}
+	return w.status
+}
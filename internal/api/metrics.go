@@ -0,0 +1,53 @@
+package api
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// Metric is one unlabelled sample in the Prometheus text exposition format.
+type Metric struct {
+	Name  string
+	Help  string
+	Type  string // "counter" or "gauge"
+	Value float64
+}
+
+func Counter(name, help string, v uint64) Metric {
+	return Metric{Name: name, Help: help, Type: "counter", Value: float64(v)}
+}
+
+func Gauge(name, help string, v float64) Metric {
+	return Metric{Name: name, Help: help, Type: "gauge", Value: v}
+}
+
+// MetricsContentType is the Content-Type of WriteMetrics output.
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WriteMetrics renders ms in the Prometheus text format (version 0.0.4).
+func WriteMetrics(w io.Writer, ms []Metric) error {
+	bw := bufio.NewWriter(w)
+	for _, m := range ms {
+		bw.WriteString("# HELP " + m.Name + " " + escapeHelp(m.Help) + "\n")
+		bw.WriteString("# TYPE " + m.Name + " " + m.Type + "\n")
+		bw.WriteString(m.Name + " " + strconv.FormatFloat(m.Value, 'g', -1, 64) + "\n")
+	}
+	return bw.Flush()
+}
+
+// escapeHelp escapes backslashes and newlines as the format requires.
+func escapeHelp(s string) string {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			out = append(out, '\\', '\\')
+		case '\n':
+			out = append(out, '\\', 'n')
+		default:
+			out = append(out, s[i])
+		}
+	}
+	return string(out)
+}
@@ -1,9 +1,15 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,11 +23,26 @@ type Limiter struct {
 	rate      float64 // tokens/sec
 	burst     float64
 	cost      float64
-	clients   map[string]*bucket
+	clients   map[string]*bucket // "ip:<addr>" or "<KeyFunc name>@<addr>"
 	ttl       time.Duration
 	lastPrune time.Time
+
+	// trusted lists reverse proxies whose X-Forwarded-For is honored; empty
+	// means the header is ignored.
+	trusted []netip.Prefix
+
+	// keyFn, if set, names the bucket for a request within its client IP;
+	// "" falls back to the IP's shared bucket.
+	keyFn KeyFunc
+
+	rejected atomic.Uint64
 }
 
+// KeyFunc picks the rate-limit bucket a request is charged to, or returns ""
+// to charge the client IP. Buckets are still per IP: a name only separates
+// the request from the IP's other traffic.
+type KeyFunc func(r *http.Request) string
+
 func NewLimiter(rate float64, burst float64, cost float64) *Limiter {
 	return &Limiter{
 		rate:      rate,
@@ -33,8 +54,96 @@ func NewLimiter(rate float64, burst float64, cost float64) *Limiter {
 	}
 }
 
+// SetTrustedProxies enables X-Forwarded-For for requests whose RemoteAddr
+// falls in one of cidrs (bare IPs are treated as /32 or /128). The client is
+// then the rightmost untrusted hop in the header, so entries a client
+// prepends itself are never used. Call before serving.
+func (l *Limiter) SetTrustedProxies(cidrs []string) error {
+	prefixes, err := ParsePrefixes(cidrs)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	l.trusted = prefixes
+	l.mu.Unlock()
+	return nil
+}
+
+// SetKeyFunc charges requests to the bucket fn names, scoped to the client
+// IP, instead of the IP's shared bucket whenever it returns non-empty. Call
+// before serving.
+func (l *Limiter) SetKeyFunc(fn KeyFunc) {
+	l.mu.Lock()
+	l.keyFn = fn
+	l.mu.Unlock()
+}
+
+// APIKeyBucket returns a KeyFunc naming a bucket per key in keys, so
+// authenticated requests are not limited together with unauthenticated
+// traffic from the same address (NAT). The Limiter scopes the name to the
+// client IP: one node key is shared by every client, so a bucket per key
+// alone would limit them all together. Only a matching X-API-Key counts:
+// anything else falls back to the IP, so made-up keys cannot mint fresh
+// buckets. Buckets are named by a short fingerprint, never the key itself.
+func APIKeyBucket(keys []string) KeyFunc {
+	type known struct{ key, bucket string }
+	var ks []known
+	for _, k := range keys {
+		if k = strings.TrimSpace(k); k != "" {
+			sum := sha256.Sum256([]byte(k))
+			ks = append(ks, known{key: k, bucket: "key:" + hex.EncodeToString(sum[:8])})
+		}
+	}
+	return func(r *http.Request) string {
+		got := strings.TrimSpace(r.Header.Get("X-API-Key"))
+		if got == "" {
+			return ""
+		}
+		for _, k := range ks {
+			if ConstantTimeEqualString(got, k.key) {
+				return k.bucket
+			}
+		}
+		return ""
+	}
+}
+
+// ParsePrefixes parses CIDRs or bare IPs.
+func ParsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(cidrs))
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !strings.Contains(c, "/") {
+			a, err := netip.ParseAddr(c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", c, err)
+			}
+			out = append(out, netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", c, err)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
+
 func (l *Limiter) Allow(r *http.Request) bool {
-	ip := clientIP(r)
+	l.mu.Lock()
+	trusted, keyFn := l.trusted, l.keyFn
+	l.mu.Unlock()
+	ip := clientIP(r, trusted)
+	id := "ip:" + ip
+	if keyFn != nil {
+		if name := keyFn(r); name != "" {
+			id = name + "@" + ip
+		}
+	}
 	now := time.Now().UTC()
 
 	l.mu.Lock()
@@ -42,10 +151,10 @@ func (l *Limiter) Allow(r *http.Request) bool {
 
 	l.pruneLocked(now)
 
-	b, ok := l.clients[ip]
+	b, ok := l.clients[id]
 	if !ok {
 		b = &bucket{tokens: l.burst, last: now}
-		l.clients[ip] = b
+		l.clients[id] = b
 	}
 
 	elapsed := now.Sub(b.last).Seconds()
@@ -58,30 +167,73 @@ func (l *Limiter) Allow(r *http.Request) bool {
 	}
 
 	if b.tokens < l.cost {
+		l.rejected.Add(1)
 		return false
 	}
 	b.tokens -= l.cost
 	return true
 }
 
+// Rejected is the number of requests Allow has refused.
+func (l *Limiter) Rejected() uint64 {
+	return l.rejected.Load()
+}
+
 func (l *Limiter) pruneLocked(now time.Time) {
 	if now.Sub(l.lastPrune) < 2*time.Minute {
 		return
 	}
 	l.lastPrune = now
 
-	for ip, b := range l.clients {
+	for id, b := range l.clients {
 		if now.Sub(b.last) > l.ttl {
-			delete(l.clients, ip)
+			delete(l.clients, id)
 		}
 	}
 }
 
-func clientIP(r *http.Request) string {
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
 	// We intentionally do NOT trust X-Forwarded-For by default (can be spoofed).
 	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
 		return r.RemoteAddr
 	}
-	return host
+	if len(trusted) == 0 {
+		return host
+	}
+	remote, err := netip.ParseAddr(host)
+	if err != nil || !isTrusted(remote, trusted) {
+		return host
+	}
+
+	// Walk X-Forwarded-For right to left (nearest hop first), skipping our
+	// own proxies. The first untrusted hop is the client; anything to its
+	// left was supplied by that client and is not believed. A malformed hop
+	// stops the walk and the last proxy is used.
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	client := host
+	for i := len(hops) - 1; i >= 0; i-- {
+		a, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = a.Unmap().String()
+		if !isTrusted(a, trusted) {
+			break
+		}
+	}
+	return client
+}
+
+func isTrusted(a netip.Addr, trusted []netip.Prefix) bool {
+	a = a.Unmap()
+	for _, p := range trusted {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPForwardedFor(t *testing.T) {
+	trusted, err := ParsePrefixes([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name, remote string
+		xff          []string
+		want         string
+	}{
+		{"untrusted source, spoofed header", "203.0.113.9:5000", []string{"198.51.100.1"}, "203.0.113.9"},
+		{"untrusted source, header naming a proxy", "203.0.113.9:5000", []string{"10.0.0.1"}, "203.0.113.9"},
+		{"trusted proxy", "10.1.2.3:443", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"bare trusted IP", "192.0.2.1:443", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"client-prepended hops ignored", "10.1.2.3:443", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"proxy chain", "10.1.2.3:443", []string{"198.51.100.1, 10.9.9.9"}, "198.51.100.1"},
+		{"split headers", "10.1.2.3:443", []string{"1.1.1.1", "198.51.100.1, 10.9.9.9"}, "198.51.100.1"},
+		{"malformed nearest hop", "10.1.2.3:443", []string{"198.51.100.1, garbage"}, "10.1.2.3"},
+		{"no header", "10.1.2.3:443", nil, "10.1.2.3"},
+		{"mapped IPv6", "10.1.2.3:443", []string{"::ffff:198.51.100.1"}, "198.51.100.1"},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tc.remote
+		for _, v := range tc.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if got := clientIP(r, trusted); got != tc.want {
+			t.Errorf("%s: clientIP = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+
+	// Without trusted proxies the header is never read.
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.1.2.3:443"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := clientIP(r, nil); got != "10.1.2.3" {
+		t.Errorf("no trusted proxies: clientIP = %s", got)
+	}
+}
+
+func TestLimiterIgnoresSpoofedForwardedFor(t *testing.T) {
+	l := NewLimiter(0.001, 2, 1)
+	if err := l.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An untrusted client rotating made-up X-Forwarded-For values still
+	// drains one bucket: its own.
+	allowed := 0
+	for i := range 10 {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "203.0.113.9:5000"
+		r.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		if l.Allow(r) {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("spoofing client allowed %d requests, want the burst of 2", allowed)
+	}
+
+	// Behind the trusted proxy, distinct clients get distinct buckets.
+	for i := range 5 {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.1:443"
+		r.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		if !l.Allow(r) {
+			t.Errorf("proxied client %d limited with a fresh bucket", i)
+		}
+	}
+}
+
+func TestLimiterAPIKeyBuckets(t *testing.T) {
+	l := NewLimiter(0.001, 1, 1)
+	l.SetKeyFunc(APIKeyBucket([]string{"s3cret"}))
+	req := func(remote, key string) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remote
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		return r
+	}
+
+	// Drain the IP's shared bucket.
+	if !l.Allow(req("203.0.113.9:5000", "")) {
+		t.Fatal("first unkeyed request limited")
+	}
+
+	for _, tc := range []struct {
+		name, remote, key string
+		want              bool
+	}{
+		{"absent key falls back to the IP", "203.0.113.9:5001", "", false},
+		{"wrong key falls back to the IP", "203.0.113.9:5002", "guess", false},
+		{"valid key has its own bucket", "203.0.113.9:5003", "s3cret", true},
+		{"valid key bucket drained", "203.0.113.9:5004", "s3cret", false},
+		// One node key is shared by every client: another address must
+		// not be charged to the first one's bucket.
+		{"valid key from another IP", "198.51.100.7:5000", "s3cret", true},
+	} {
+		if got := l.Allow(req(tc.remote, tc.key)); got != tc.want {
+			t.Errorf("%s: allowed = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
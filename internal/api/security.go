@@ -48,7 +48,7 @@ func SecurityMiddleware(cfg SecurityConfig, next http.Handler) http.Handler {
 		// Optional API key enforcement
 		if cfg.APIKey != "" && cfg.RequireKeyFor != nil && cfg.RequireKeyFor[r.URL.Path] {
 			got := r.Header.Get("X-API-Key")
-			if !constantTimeEqualString(got, cfg.APIKey) {
+			if !ConstantTimeEqualString(got, cfg.APIKey) {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusUnauthorized)
 				_, _ = w.Write([]byte(`{"ok":false,"error":"unauthorized"}`))
@@ -60,7 +60,8 @@ func SecurityMiddleware(cfg SecurityConfig, next http.Handler) http.Handler {
 	})
 }
 
-func constantTimeEqualString(a, b string) bool {
+// ConstantTimeEqualString compares secrets without leaking timing on content.
+func ConstantTimeEqualString(a, b string) bool {
 	ab := []byte(a)
 	bb := []byte(b)
 	if len(ab) != len(bb) {
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// VersionPrefix is the mount point for the current API version. Unprefixed
+// paths are kept as aliases of v1 for existing clients; they are deprecated
+// and will be removed once a /v2 with different response shapes ships.
+const VersionPrefix = "/v1"
+
+// VersionedRoutes serves next both at its own paths and under VersionPrefix.
+// It sits in front of SecurityMiddleware so per-path rules (API key, rate
+// limits) see the same path either way.
+func VersionedRoutes(next http.Handler) http.Handler {
+	strip := http.StripPrefix(VersionPrefix, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, VersionPrefix+"/") {
+			strip.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,200 @@
+// Package audit records operator actions in an append-only, hash-chained
+// JSON Lines file.
+//
+// Each line is one Entry. Entry.Hash is
+//
+//	hex(sha256(prevHash || "\n" || canonical JSON of the entry with Hash = ""))
+//
+// and Entry.PrevHash is the previous entry's Hash (GenesisHash for the first
+// entry). Editing, inserting, reordering or deleting any entry changes the
+// hashes that follow it, so Verify reports the first line that no longer
+// links.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// GenesisHash seeds the chain: sha256("veltaros-audit-genesis-v1").
+var GenesisHash = func() string {
+	h := sha256.Sum256([]byte("veltaros-audit-genesis-v1"))
+	return hex.EncodeToString(h[:])
+}()
+
+// maxLineBytes bounds a single entry when reading a log back.
+const maxLineBytes = 64 * 1024
+
+type Entry struct {
+	Seq      uint64            `json:"seq"` // 1-based, contiguous
+	Time     time.Time         `json:"time"`
+	Action   string            `json:"action"`
+	Actor    string            `json:"actor,omitempty"`
+	Details  map[string]string `json:"details,omitempty"`
+	PrevHash string            `json:"prevHash"`
+	Hash     string            `json:"hash"`
+}
+
+// ComputeHash returns the chain hash for e (ignoring e.Hash).
+func ComputeHash(e Entry) (string, error) {
+	e.Hash = ""
+	body, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(e.PrevHash))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Log appends entries to a file, continuing an existing chain.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	seq  uint64
+	last string
+}
+
+// Open prepares path for appending. An existing file must verify; a broken
+// chain is returned as an error rather than silently extended.
+func Open(path string) (*Log, error) {
+	l := &Log{path: filepath.Clean(path), last: GenesisHash}
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return l, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	res, err := Verify(f)
+	if err != nil {
+		return nil, fmt.Errorf("audit log %s: %w", l.path, err)
+	}
+	l.seq = res.Entries
+	l.last = res.LastHash
+	return l, nil
+}
+
+// Append writes one entry and syncs it to disk.
+func (l *Log) Append(action, actor string, details map[string]string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := Entry{
+		Seq:      l.seq + 1,
+		Time:     time.Now().UTC(),
+		Action:   action,
+		Actor:    actor,
+		Details:  details,
+		PrevHash: l.last,
+	}
+	h, err := ComputeHash(e)
+	if err != nil {
+		return err
+	}
+	e.Hash = h
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	l.seq = e.Seq
+	l.last = e.Hash
+	return nil
+}
+
+// VerifyResult describes an intact chain.
+type VerifyResult struct {
+	Entries  uint64
+	LastHash string
+}
+
+// BreakError pinpoints the first entry that fails verification.
+type BreakError struct {
+	Line   int // 1-based line number in the file
+	Seq    uint64
+	Reason string
+}
+
+func (e *BreakError) Error() string {
+	return fmt.Sprintf("audit chain broken at line %d (seq %d): %s", e.Line, e.Seq, e.Reason)
+}
+
+// Verify walks the chain in r and returns a *BreakError at the first entry
+// that is malformed, out of sequence, or does not hash-link to its
+// predecessor.
+func Verify(r io.Reader) (VerifyResult, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 4096), maxLineBytes)
+
+	prev := GenesisHash
+	var seq uint64
+	line := 0
+	for sc.Scan() {
+		line++
+		raw := sc.Bytes()
+		if len(raw) == 0 {
+			return VerifyResult{}, &BreakError{Line: line, Seq: seq + 1, Reason: "empty line"}
+		}
+
+		var e Entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return VerifyResult{}, &BreakError{Line: line, Seq: seq + 1, Reason: "malformed entry"}
+		}
+		if e.Seq != seq+1 {
+			return VerifyResult{}, &BreakError{Line: line, Seq: e.Seq, Reason: fmt.Sprintf("expected seq %d", seq+1)}
+		}
+		if e.PrevHash != prev {
+			return VerifyResult{}, &BreakError{Line: line, Seq: e.Seq, Reason: "prevHash does not match previous entry"}
+		}
+		want, err := ComputeHash(e)
+		if err != nil {
+			return VerifyResult{}, err
+		}
+		if e.Hash != want {
+			return VerifyResult{}, &BreakError{Line: line, Seq: e.Seq, Reason: "entry hash mismatch (contents altered)"}
+		}
+
+		prev = e.Hash
+		seq = e.Seq
+	}
+	if err := sc.Err(); err != nil {
+		return VerifyResult{}, err
+	}
+	return VerifyResult{Entries: seq, LastHash: prev}, nil
+}
@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeLog appends n entries to a new log under dir and returns its path.
+func writeLog(t *testing.T, dir string, n int) string {
+	t.Helper()
+	path := filepath.Join(dir, "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range n {
+		if err := l.Append("ban", "admin", map[string]string{"i": string(rune('a' + i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func readLines(t *testing.T, path string) [][]byte {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.SplitAfter(bytes.TrimSuffix(raw, []byte("\n")), []byte("\n"))
+}
+
+func TestAppendReopenVerify(t *testing.T) {
+	path := writeLog(t, t.TempDir(), 2)
+
+	// Reopening continues the chain rather than restarting it.
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Append("unban", "admin", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	res, err := Verify(f)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if res.Entries != 3 {
+		t.Errorf("entries = %d, want 3", res.Entries)
+	}
+	if res.LastHash != l.last {
+		t.Errorf("last hash = %s, want %s", res.LastHash, l.last)
+	}
+}
+
+func TestVerifyReportsBreak(t *testing.T) {
+	cases := map[string]struct {
+		tamper   func(lines [][]byte) [][]byte
+		wantLine int
+		wantSeq  uint64
+	}{
+		"edited": {
+			tamper: func(lines [][]byte) [][]byte {
+				lines[1] = bytes.Replace(lines[1], []byte(`"action":"ban"`), []byte(`"action":"nop"`), 1)
+				return lines
+			},
+			wantLine: 2, wantSeq: 2,
+		},
+		"reordered": {
+			tamper: func(lines [][]byte) [][]byte {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			wantLine: 2, wantSeq: 3,
+		},
+		"deleted": {
+			tamper: func(lines [][]byte) [][]byte {
+				return append(lines[:1:1], lines[2:]...)
+			},
+			wantLine: 2, wantSeq: 3,
+		},
+	}
+	for name, tc := range cases {
+		path := writeLog(t, t.TempDir(), 4)
+		tampered := bytes.Join(tc.tamper(readLines(t, path)), nil)
+
+		_, err := Verify(bytes.NewReader(tampered))
+		var be *BreakError
+		if !errors.As(err, &be) {
+			t.Errorf("%s: err = %v, want *BreakError", name, err)
+			continue
+		}
+		if be.Line != tc.wantLine || be.Seq != tc.wantSeq {
+			t.Errorf("%s: break at line %d seq %d, want line %d seq %d", name, be.Line, be.Seq, tc.wantLine, tc.wantSeq)
+		}
+	}
+}
+
+func TestOpenRefusesBrokenChain(t *testing.T) {
+	path := writeLog(t, t.TempDir(), 3)
+	lines := readLines(t, path)
+	if err := os.WriteFile(path, bytes.Join(append(lines[:1:1], lines[2:]...), nil), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Open(path)
+	var be *BreakError
+	if !errors.As(err, &be) {
+		t.Fatalf("err = %v, want *BreakError", err)
+	}
+	if be.Line != 2 {
+		t.Errorf("break at line %d, want 2", be.Line)
+	}
+}
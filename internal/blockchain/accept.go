@@ -0,0 +1,75 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+var (
+	// ErrKnownBlock is returned for a block we already store.
+	ErrKnownBlock = errors.New("block already known")
+	// ErrUnknownParent is returned when a block does not extend our tip.
+	ErrUnknownParent = errors.New("block does not extend current tip")
+	// ErrTxConfirmed is returned for a block that includes a tx already
+	// confirmed in an earlier block.
+	ErrTxConfirmed = errors.New("tx already confirmed")
+	// ErrNonceUsed is returned for a block that includes a tx whose nonce
+	// its sender has already used, in an earlier block or earlier in the
+	// same one.
+	ErrNonceUsed = errors.New("nonce already used")
+)
+
+// AcceptBlock links a block received from elsewhere (e.g. a peer) on top of
+// the tip and settles it: included txs leave the mempool (releasing their
+// staged spends), conflicting same-nonce txs are evicted, nonces are
+// advanced, each tx is applied to the ledger, and the coinbase, if any, is
+// credited. A block that would settle a tx, or a sender's nonce, a second
+// time, or that holds a tx its sender cannot pay for, is rejected before it
+// is linked (ErrTxConfirmed, ErrNonceUsed, ErrUnfundedTx).
+func (c *Chain) AcceptBlock(b Block, led LedgerApplier) (ProducedBlock, error) {
+	h := b.Header.Hash()
+	if _, ok := c.GetBlock(hex.EncodeToString(h[:])); ok {
+		return ProducedBlock{}, ErrKnownBlock
+	}
+
+	c.settleMu.Lock()
+	defer c.settleMu.Unlock()
+
+	sb, err := c.addBlock(b, func(txs []SignedTx) error { return checkFunds(led, txs) })
+	if err != nil {
+		return ProducedBlock{}, err
+	}
+
+	out := ProducedBlock{Block: sb}
+	var fees uint64
+	for _, tx := range b.Transactions {
+		from := tx.Draft.From
+		if pending, ok := c.MempoolRemove(tx.TxID); ok {
+			led.UnstageMempoolSpend(from, pending.Draft.SpendAmount())
+		} else if other, ok := c.MempoolFindByNonce(from, tx.Draft.Nonce); ok {
+			c.MempoolRemove(other.TxID)
+			led.UnstageMempoolSpend(from, other.Draft.SpendAmount())
+			out.Dropped++
+		}
+		// AddBlock has rejected used nonces, so this is never a replay.
+		// ReserveNonce would report false whenever the tx (or a later one
+		// from the sender) was pending here, so the reservation is only
+		// raised.
+		c.nonces.Advance(from, tx.Draft.Nonce)
+
+		// Funds were checked before linking; a failure here is the ledger
+		// refusing for another reason (e.g. a journal gap).
+		if err := applyConfirmed(led, sb.Height, tx); err != nil {
+			out.Failed++
+			continue
+		}
+		out.Applied++
+		fees += tx.Draft.Fee
+	}
+	// A failed credit is left like a failed tx: the block stays linked.
+	if amount, err := settleCoinbase(led, sb.Height, b.Coinbase, fees); err == nil {
+		out.Reward = amount
+	}
+	led.SetAppliedHeight(sb.Height)
+	return out, nil
+}
@@ -0,0 +1,94 @@
+package blockchain
+
+// txLoc locates a confirmed tx: its block height and position in the block.
+type txLoc struct {
+	height uint64
+	index  int
+}
+
+// AccountTx is a confirmed tx that an address sent or received.
+type AccountTx struct {
+	Height    uint64   `json:"height"`
+	BlockHash string   `json:"blockHash"`
+	Tx        SignedTx `json:"tx"`
+}
+
+// indexAccountTxsLocked records sb's txs, once each, under their sender
+// and every recipient. Like txIndex it is derived from the block store (LoadBlocks and
+// resetWindowLocked rebuild it), so it needs no file of its own, and covers
+// only the blocks in memory.
+func (c *Chain) indexAccountTxsLocked(sb StoredBlock) {
+	for i, tx := range sb.Block.Transactions {
+		loc := txLoc{height: sb.Height, index: i}
+		c.accountTxs[tx.Draft.From] = append(c.accountTxs[tx.Draft.From], loc)
+		for _, to := range tx.Draft.Recipients() {
+			// An address paid by several outputs lists the tx once.
+			if locs := c.accountTxs[to]; len(locs) == 0 || locs[len(locs)-1] != loc {
+				c.accountTxs[to] = append(locs, loc)
+			}
+		}
+	}
+}
+
+// dropAccountTxsLocked forgets addr's txs at or below height, as the window
+// moves past them.
+func (c *Chain) dropAccountTxsLocked(addr string, height uint64) {
+	locs := c.accountTxs[addr]
+	i := 0
+	for i < len(locs) && locs[i].height <= height {
+		i++
+	}
+	if i == len(locs) {
+		delete(c.accountTxs, addr)
+		return
+	}
+	c.accountTxs[addr] = locs[i:]
+}
+
+// AccountTxs returns up to limit of addr's confirmed txs, newest first,
+// skipping the newest offset, and how many there are in total. Txs in
+// blocks under the window come from the store's index; if that cannot be
+// read, only those in memory are listed and counted.
+func (c *Chain) AccountTxs(addr string, offset, limit int) ([]AccountTx, int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if offset < 0 {
+		offset, limit = 0, 0
+	}
+	out, total := c.windowAccountTxsLocked(addr, offset, limit)
+	if c.indexed == nil {
+		return out, total
+	}
+	stored, n, err := c.indexed.accountTxs(addr, c.baseLocked(), max(offset-total, 0), limit-len(out))
+	if err != nil {
+		return out, total
+	}
+	return append(out, stored...), total + n
+}
+
+// windowAccountTxsLocked is AccountTxs over the blocks in memory.
+func (c *Chain) windowAccountTxsLocked(addr string, offset, limit int) ([]AccountTx, int) {
+	locs := c.accountTxs[addr]
+	total := len(locs)
+	if offset < 0 || offset >= total || limit <= 0 || len(c.blocks) == 0 {
+		return []AccountTx{}, total
+	}
+
+	base := c.blocks[0].Height
+	out := make([]AccountTx, 0, min(limit, total-offset))
+	for i := total - 1 - offset; i >= 0 && len(out) < limit; i-- {
+		loc := locs[i]
+		j := int(loc.height - base)
+		if j < 0 || j >= len(c.blocks) || c.blocks[j].Height != loc.height {
+			continue
+		}
+		sb := c.blocks[j]
+		out = append(out, AccountTx{
+			Height:    sb.Height,
+			BlockHash: sb.HashHex,
+			Tx:        sb.Block.Transactions[loc.index],
+		})
+	}
+	return out, total
+}
@@ -1,7 +1,7 @@
 package blockchain
 
 import (
-	"encoding/hex"
+	"crypto/sha256"
 	"errors"
 
 	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
@@ -11,22 +11,63 @@ const (
 	AddressLenBytes = 24 // 20 hash + 4 checksum
 )
 
+var (
+	errAddressHex      = errors.New("invalid address hex")
+	errAddressLen      = errors.New("invalid address length")
+	errAddressChecksum = errors.New("invalid address checksum")
+)
+
 // ValidateAddress checks hex(pubHash20||checksum4) where checksum4 = doubleSha256(pubHash20)[:4]
+//
+// It runs on every account lookup and tx validation, so the common
+// (correct-length) case decodes into a stack buffer and does not allocate.
 func ValidateAddress(addr string) error {
-	b, err := hex.DecodeString(addr)
-	if err != nil {
-		return errors.New("invalid address hex")
-	}
-	if len(b) != AddressLenBytes {
-		return errors.New("invalid address length")
+	if len(addr) != 2*AddressLenBytes {
+		return validateAddressSlow(addr)
 	}
 
-	pubHash20 := b[:20]
-	got := b[20:24]
-	want := vcrypto.DoubleSha256(pubHash20)
+	var b [AddressLenBytes]byte
+	for i := range b {
+		hi, ok1 := fromHexChar(addr[2*i])
+		lo, ok2 := fromHexChar(addr[2*i+1])
+		if !ok1 || !ok2 {
+			return errAddressHex
+		}
+		b[i] = hi<<4 | lo
+	}
 
-	if !vcrypto.ConstantTimeEqual(got, want[:4]) {
-		return errors.New("invalid address checksum")
+	first := sha256.Sum256(b[:20])
+	want := sha256.Sum256(first[:])
+	if !vcrypto.ConstantTimeEqual(b[20:24], want[:4]) {
+		return errAddressChecksum
 	}
 	return nil
 }
+
+// validateAddressSlow reports wrong-length input with the original error
+// precedence: bad hex before bad length. It scans in place rather than
+// decoding, so oversized input costs no allocation.
+func validateAddressSlow(addr string) error {
+	if len(addr)%2 != 0 {
+		return errAddressHex // hex.DecodeString rejects odd lengths too
+	}
+	for i := 0; i < len(addr); i++ {
+		if _, ok := fromHexChar(addr[i]); !ok {
+			return errAddressHex
+		}
+	}
+	return errAddressLen
+}
+
+// fromHexChar mirrors encoding/hex: both cases are accepted.
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
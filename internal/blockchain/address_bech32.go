@@ -0,0 +1,79 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
+)
+
+// Bech32 address form: bech32m(hrp, pubHash20). It names the same account as
+// hex(pubHash20||checksum4); the hex form stays canonical inside signed txs,
+// the ledger and API responses, and either form is accepted as input.
+//
+// The HRP depends on the network, so a testnet-shaped address is rejected by
+// a mainnet node (and by the CLI signing for mainnet) instead of silently
+// naming the same key hash there.
+const (
+	MainnetNetworkID = "veltaros-mainnet"
+
+	AddressHRPMainnet = "vel"
+	AddressHRPTestnet = "tvel" // every network other than mainnet
+)
+
+// ErrAddressNetwork is returned for a bech32 address whose prefix belongs to
+// another network.
+var ErrAddressNetwork = errors.New("address is for another network")
+
+var (
+	errAddressBech32    = errors.New("invalid bech32 address")
+	errAddressBech32Len = errors.New("invalid bech32 address length")
+)
+
+// AddressHRP is the bech32 prefix for addresses on networkID.
+func AddressHRP(networkID string) string {
+	if networkID == MainnetNetworkID {
+		return AddressHRPMainnet
+	}
+	return AddressHRPTestnet
+}
+
+// ValidateAddressBech32 checks a bech32 address and that its prefix belongs to
+// networkID.
+func ValidateAddressBech32(addr, networkID string) error {
+	_, err := addressFromBech32(addr, networkID)
+	return err
+}
+
+// NormalizeAddress accepts either address form and returns the lowercase hex
+// form, so every spelling of an account maps to one ledger key. A bech32
+// address must carry networkID's prefix.
+func NormalizeAddress(addr, networkID string) (string, error) {
+	if len(addr) == 2*AddressLenBytes {
+		if err := ValidateAddress(addr); err != nil {
+			return "", err
+		}
+		return strings.ToLower(addr), nil
+	}
+	return addressFromBech32(addr, networkID)
+}
+
+func addressFromBech32(addr, networkID string) (string, error) {
+	hrp, hash, err := vcrypto.Bech32Decode(addr)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errAddressBech32, err)
+	}
+	if hrp != AddressHRPMainnet && hrp != AddressHRPTestnet {
+		return "", fmt.Errorf("%w: unknown prefix %q", errAddressBech32, hrp)
+	}
+	if len(hash) != 20 {
+		return "", errAddressBech32Len
+	}
+	if want := AddressHRP(networkID); hrp != want {
+		return "", fmt.Errorf("%w: prefix %q, want %q", ErrAddressNetwork, hrp, want)
+	}
+	check := vcrypto.DoubleSha256(hash)
+	return hex.EncodeToString(hash) + hex.EncodeToString(check[:4]), nil
+}
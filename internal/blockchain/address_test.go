@@ -0,0 +1,128 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
+)
+
+// validateAddressRef is ValidateAddress as it was before the allocation-free
+// fast path; the two must agree on every input.
+func validateAddressRef(addr string) error {
+	b, err := hex.DecodeString(addr)
+	if err != nil {
+		return errors.New("invalid address hex")
+	}
+	if len(b) != AddressLenBytes {
+		return errors.New("invalid address length")
+	}
+	want := vcrypto.DoubleSha256(b[:20])
+	if !vcrypto.ConstantTimeEqual(b[20:24], want[:4]) {
+		return errors.New("invalid address checksum")
+	}
+	return nil
+}
+
+func sameError(a, b error) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Error() == b.Error()
+}
+
+func TestValidateAddressMatchesReference(t *testing.T) {
+	valid := newTestKey(t).addr
+	inputs := []string{
+		"",
+		"0",
+		valid,
+		strings.ToUpper(valid),
+		valid[:len(valid)-1] + "0",
+		valid[:len(valid)-2],
+		valid + "00",
+		valid + "0",
+		"zz" + valid[2:],
+		valid[:47] + "g",
+		strings.Repeat("0", 48),
+		strings.Repeat("g", 50),
+	}
+	r := rand.New(rand.NewPCG(1, 2))
+	const hexChars = "0123456789abcdefABCDEFxyz"
+	for range 20_000 {
+		n := 2*AddressLenBytes + r.IntN(5) - 2
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = hexChars[r.IntN(len(hexChars))]
+		}
+		inputs = append(inputs, string(b))
+	}
+	for range 1_000 {
+		inputs = append(inputs, newTestKey(t).addr)
+	}
+
+	for _, in := range inputs {
+		if got, want := ValidateAddress(in), validateAddressRef(in); !sameError(got, want) {
+			t.Fatalf("ValidateAddress(%q) = %v, reference %v", in, got, want)
+		}
+	}
+}
+
+func TestValidateAddressDoesNotAllocate(t *testing.T) {
+	valid := newTestKey(t).addr
+	bad := valid[:len(valid)-1] + "0"
+	if bad == valid {
+		bad = valid[:len(valid)-1] + "1"
+	}
+	for _, in := range []string{valid, bad, strings.Repeat("g", 200)} {
+		if n := testing.AllocsPerRun(100, func() { _ = ValidateAddress(in) }); n != 0 {
+			t.Errorf("ValidateAddress(%.10s...) allocates %v times", in, n)
+		}
+	}
+}
+
+func BenchmarkValidateAddress(b *testing.B) {
+	valid := newTestKey(b).addr
+	b.Run("fast", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = ValidateAddress(valid)
+		}
+	})
+	b.Run("reference", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = validateAddressRef(valid)
+		}
+	})
+}
+
+func TestNormalizeAddressFormsShareKey(t *testing.T) {
+	const network = "veltaros-testnet"
+	addr := newTestKey(t).addr
+	b, err := hex.DecodeString(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bech, err := vcrypto.Bech32Encode(AddressHRP(network), b[:20])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, in := range []string{addr, strings.ToUpper(addr), bech, strings.ToUpper(bech)} {
+		got, err := NormalizeAddress(in, network)
+		if err != nil {
+			t.Fatalf("NormalizeAddress(%q): %v", in, err)
+		}
+		if got != addr {
+			t.Fatalf("NormalizeAddress(%q) = %q, want %q", in, got, addr)
+		}
+	}
+
+	if _, err := NormalizeAddress(bech, MainnetNetworkID); !errors.Is(err, ErrAddressNetwork) {
+		t.Fatalf("testnet address on mainnet: err = %v, want ErrAddressNetwork", err)
+	}
+}
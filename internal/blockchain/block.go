@@ -3,6 +3,7 @@ package blockchain
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"time"
 
 	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
@@ -19,11 +20,26 @@ type BlockHeader struct {
 type Block struct {
 	Header       BlockHeader
 	Transactions []SignedTx
+	Coinbase     *Coinbase `json:",omitempty"`
 }
 
-func (h BlockHeader) Hash() [32]byte {
-	// Canonical header serialization (fixed-size fields, little-endian for integers).
-	buf := make([]byte, 0, 4+32+32+8+8)
+const blockHeaderSize = 4 + 32 + 32 + 8 + 8
+
+const (
+	// MaxBlockBytes bounds Block.CanonicalBytes (header plus all txs).
+	MaxBlockBytes = 1 << 20
+
+	// TargetBlockSpacingSec is the intended interval between blocks. It is a
+	// producer target, not a validation rule.
+	TargetBlockSpacingSec = 10
+)
+
+// blockTxOverhead is the per-tx length prefix in CanonicalBytes.
+const blockTxOverhead = 4
+
+// Bytes returns the canonical header serialization (fixed-size fields, little-endian for integers).
+func (h BlockHeader) Bytes() []byte {
+	buf := make([]byte, 0, blockHeaderSize)
 
 	tmp4 := make([]byte, 4)
 	binary.LittleEndian.PutUint32(tmp4, h.Version)
@@ -39,17 +55,76 @@ func (h BlockHeader) Hash() [32]byte {
 	binary.LittleEndian.PutUint64(tmp8, h.Nonce)
 	buf = append(buf, tmp8...)
 
-	return vcrypto.DoubleSha256(buf)
+	return buf
+}
+
+// ParseBlockHeader decodes the output of BlockHeader.Bytes.
+func ParseBlockHeader(b []byte) (BlockHeader, error) {
+	if len(b) != blockHeaderSize {
+		return BlockHeader{}, fmt.Errorf("invalid header length: %d (want %d)", len(b), blockHeaderSize)
+	}
+	var h BlockHeader
+	h.Version = binary.LittleEndian.Uint32(b[0:4])
+	copy(h.PrevHash[:], b[4:36])
+	copy(h.MerkleRoot[:], b[36:68])
+	h.Timestamp = int64(binary.LittleEndian.Uint64(b[68:76]))
+	h.Nonce = binary.LittleEndian.Uint64(b[76:84])
+	return h, nil
+}
+
+// Hash is the block identifier used by the store, the tip, and PrevHash links.
+func (h BlockHeader) Hash() [32]byte {
+	return vcrypto.DoubleSha256(h.Bytes())
+}
+
+// CanonicalBytes serializes the full block deterministically:
+// [80] header bytes
+// [4] txCount (uint32)
+// repeated txCount times: [4] txLen (uint32) + [txLen] canonical signed tx bytes
+func (b Block) CanonicalBytes() ([]byte, error) {
+	buf := make([]byte, 0, blockHeaderSize+4+len(b.Transactions)*512)
+	buf = append(buf, b.Header.Bytes()...)
+
+	tmp4 := make([]byte, 4)
+	binary.LittleEndian.PutUint32(tmp4, uint32(len(b.Transactions)))
+	buf = append(buf, tmp4...)
+
+	for i := range b.Transactions {
+		tb, err := CanonicalSignedTxBytes(b.Transactions[i])
+		if err != nil {
+			return nil, err
+		}
+		binary.LittleEndian.PutUint32(tmp4, uint32(len(tb)))
+		buf = append(buf, tmp4...)
+		buf = append(buf, tb...)
+	}
+	return buf, nil
+}
+
+// Hash returns doubleSha256(CanonicalBytes), a content hash covering every
+// transaction byte. It is used for integrity checks when comparing or relaying
+// blocks; BlockHeader.Hash remains the block's identity.
+func (b Block) Hash() ([32]byte, error) {
+	raw, err := b.CanonicalBytes()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return vcrypto.DoubleSha256(raw), nil
 }
 
+// GenesisTimestamp is the genesis header's timestamp. It is a sentinel, not a
+// real time: the genesis hash (and so every chain built on it) commits to it,
+// so changing it would fork existing networks. ValidateBasic exempts the
+// genesis block from the "timestamp must be set" rule via IsGenesis.
+const GenesisTimestamp int64 = 0
+
 func NewGenesisBlock() Block {
 	// Minimal deterministic genesis.
-	now := time.Unix(0, 0).UTC()
 	return Block{
 		Header: BlockHeader{
 			Version:   1,
 			PrevHash:  [32]byte{},
-			Timestamp: now.Unix(),
+			Timestamp: GenesisTimestamp,
 			Nonce:     0,
 			// MerkleRoot = zero for empty tx list
 		},
@@ -57,51 +132,93 @@ func NewGenesisBlock() Block {
 	}
 }
 
-func BuildBlock(prevHash [32]byte, txs []SignedTx) (Block, error) {
-	txIDs := make([]string, 0, len(txs))
+// BuildBlock assembles a block of txs on prevHash, timestamped at. cb, if not
+// nil, pays the producer; see Coinbase.
+func BuildBlock(prevHash [32]byte, txs []SignedTx, cb *Coinbase, at time.Time) (Block, error) {
+	if cb != nil {
+		if err := cb.validate(); err != nil {
+			return Block{}, err
+		}
+	}
+	size := blockHeaderSize + 4
 	for _, tx := range txs {
 		if err := ValidateSignedTx(tx); err != nil {
 			return Block{}, err
 		}
-		txIDs = append(txIDs, tx.TxID)
+		n, err := signedTxSize(tx)
+		if err != nil {
+			return Block{}, err
+		}
+		size += blockTxOverhead + n
+	}
+	if size > MaxBlockBytes {
+		return Block{}, fmt.Errorf("block too large: %d bytes (max %d)", size, MaxBlockBytes)
+	}
+	if err := checkUniqueTxIDs(txs); err != nil {
+		return Block{}, err
 	}
 
-	root, err := MerkleRootFromTxIDs(txIDs)
+	b := Block{Transactions: txs, Coinbase: cb}
+	root, err := MerkleRootFromTxIDs(b.merkleLeaves())
 	if err != nil {
 		return Block{}, err
 	}
 
-	now := time.Now().UTC().Unix()
-	return Block{
-		Header: BlockHeader{
-			Version:    1,
-			PrevHash:   prevHash,
-			MerkleRoot: root,
-			Timestamp:  now,
-			Nonce:      0,
-		},
-		Transactions: txs,
-	}, nil
+	b.Header = BlockHeader{
+		Version:    1,
+		PrevHash:   prevHash,
+		MerkleRoot: root,
+		Timestamp:  at.Unix(),
+		Nonce:      0,
+	}
+	return b, nil
+}
+
+// IsGenesis reports whether b is exactly the block NewGenesisBlock returns.
+// A block of that shape can never be appended after genesis, since its zero
+// PrevHash cannot match a tip.
+func (b *Block) IsGenesis() bool {
+	g := NewGenesisBlock()
+	return b.Header == g.Header && len(b.Transactions) == 0 && b.Coinbase == nil
 }
 
 func (b *Block) ValidateBasic() error {
-	if b.Header.Timestamp <= 0 {
+	if b.Header.Timestamp <= 0 && !b.IsGenesis() {
 		return errors.New("block timestamp must be set")
 	}
 
-	// Basic per-tx validation
+	// Duplicates would be applied to the ledger twice; reject them before
+	// any per-tx work.
+	if err := checkUniqueTxIDs(b.Transactions); err != nil {
+		return err
+	}
+
+	if b.Coinbase != nil {
+		if err := b.Coinbase.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Basic per-tx validation; signatures are verified last, together.
+	checks := make([]sigCheck, len(b.Transactions))
 	for i := range b.Transactions {
-		if err := ValidateSignedTx(b.Transactions[i]); err != nil {
+		sc, err := checkSignedTx(b.Transactions[i])
+		if err != nil {
 			return err
 		}
+		checks[i] = sc
 	}
 
-	// MerkleRoot consistency check
-	txIDs := make([]string, 0, len(b.Transactions))
-	for _, tx := range b.Transactions {
-		txIDs = append(txIDs, tx.TxID)
+	raw, err := b.CanonicalBytes()
+	if err != nil {
+		return err
 	}
-	root, err := MerkleRootFromTxIDs(txIDs)
+	if len(raw) > MaxBlockBytes {
+		return fmt.Errorf("block too large: %d bytes (max %d)", len(raw), MaxBlockBytes)
+	}
+
+	// MerkleRoot consistency check
+	root, err := MerkleRootFromTxIDs(b.merkleLeaves())
 	if err != nil {
 		return err
 	}
@@ -109,5 +226,27 @@ func (b *Block) ValidateBasic() error {
 		return errors.New("merkle root mismatch")
 	}
 
+	return b.verifySigChecks(checks)
+}
+
+// ErrDuplicateTx is returned when a block lists the same txId more than once.
+var ErrDuplicateTx = errors.New("duplicate tx in block")
+
+func checkUniqueTxIDs(txs []SignedTx) error {
+	seen := make(map[string]struct{}, len(txs))
+	for _, tx := range txs {
+		if _, ok := seen[tx.TxID]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicateTx, tx.TxID)
+		}
+		seen[tx.TxID] = struct{}{}
+	}
 	return nil
 }
+
+func signedTxSize(tx SignedTx) (int, error) {
+	raw, err := CanonicalSignedTxBytes(tx)
+	if err != nil {
+		return 0, err
+	}
+	return len(raw), nil
+}
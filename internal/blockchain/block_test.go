@@ -0,0 +1,270 @@
+package blockchain
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/clock"
+	"github.com/VeltarosLabs/Veltaros/internal/ledger"
+)
+
+// untouchedLedger fails the test if anything is settled on it. Its balances
+// read as zero.
+type untouchedLedger struct{ t *testing.T }
+
+func (untouchedLedger) ConfirmedBalance(string) uint64 { return 0 }
+func (untouchedLedger) StakedBalance(string) uint64    { return 0 }
+
+func (l untouchedLedger) ApplyConfirmedTxAt(uint64, string, string, uint64, uint64) error {
+	l.t.Error("ledger touched: ApplyConfirmedTxAt")
+	return nil
+}
+
+func (l untouchedLedger) ApplyConfirmedMultiTxAt(uint64, string, []ledger.Output, uint64) error {
+	l.t.Error("ledger touched: ApplyConfirmedMultiTxAt")
+	return nil
+}
+
+func (l untouchedLedger) ApplyCoinbaseAt(uint64, string, uint64) error {
+	l.t.Error("ledger touched: ApplyCoinbaseAt")
+	return nil
+}
+
+func (l untouchedLedger) UnstageMempoolSpend(string, uint64) {
+	l.t.Error("ledger touched: UnstageMempoolSpend")
+}
+
+func (l untouchedLedger) SetAppliedHeight(uint64) {
+	l.t.Error("ledger touched: SetAppliedHeight")
+}
+
+// blockWithDuplicate returns a block on prev listing tx twice, with a merkle
+// root that matches, so only the duplicate check can reject it.
+func blockWithDuplicate(t *testing.T, prev [32]byte, tx SignedTx) Block {
+	t.Helper()
+	b := Block{Transactions: []SignedTx{tx, tx}}
+	root, err := MerkleRootFromTxIDs(b.merkleLeaves())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Header = BlockHeader{Version: 1, PrevHash: prev, MerkleRoot: root, Timestamp: time.Now().Unix()}
+	return b
+}
+
+func TestBuildBlockRejectsDuplicateTx(t *testing.T) {
+	alice, bob := newTestKey(t), newTestKey(t)
+	tx := alice.transfer(t, bob.addr, 1_000, 10, 1)
+	if _, err := BuildBlock([32]byte{}, []SignedTx{tx, tx}, nil, time.Now()); !errors.Is(err, ErrDuplicateTx) {
+		t.Fatalf("err = %v, want ErrDuplicateTx", err)
+	}
+}
+
+func TestValidateBasicRejectsDuplicateTx(t *testing.T) {
+	alice, bob := newTestKey(t), newTestKey(t)
+	b := blockWithDuplicate(t, [32]byte{1}, alice.transfer(t, bob.addr, 1_000, 10, 1))
+	if err := b.ValidateBasic(); !errors.Is(err, ErrDuplicateTx) {
+		t.Fatalf("err = %v, want ErrDuplicateTx", err)
+	}
+}
+
+func TestAcceptBlockRejectsDuplicateBeforeLedger(t *testing.T) {
+	c := newTestChain(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	tx := alice.transfer(t, bob.addr, 1_000, 10, 1)
+	if err := c.MempoolAdd(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	b := blockWithDuplicate(t, c.TipHash(), tx)
+	if _, err := c.AcceptBlock(b, untouchedLedger{t}); !errors.Is(err, ErrDuplicateTx) {
+		t.Fatalf("err = %v, want ErrDuplicateTx", err)
+	}
+	if c.Height() != 0 {
+		t.Errorf("height = %d, want the block not linked", c.Height())
+	}
+	if !c.MempoolHas(tx.TxID) {
+		t.Error("pending tx removed by a rejected block")
+	}
+	if c.LastNonce(alice.addr) != 0 {
+		t.Error("nonce reserved by a rejected block")
+	}
+}
+
+func TestAcceptBlockRejectsReplay(t *testing.T) {
+	c := newTestChain(t)
+	led := newTestLedger(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	if err := led.FaucetCredit(alice.addr, 1_000); err != nil {
+		t.Fatal(err)
+	}
+	tx := alice.transfer(t, bob.addr, 100, 1, 1)
+	mine(t, NewBlockProducer(c, led, 0, nil), tx)
+
+	for name, txs := range map[string][]SignedTx{
+		"same tx":    {tx},
+		"same nonce": {alice.transfer(t, bob.addr, 200, 1, 1)},
+		"lower nonce in block": {
+			alice.transfer(t, bob.addr, 100, 1, 3),
+			alice.transfer(t, bob.addr, 100, 1, 2),
+		},
+	} {
+		want := ErrNonceUsed
+		if name == "same tx" {
+			want = ErrTxConfirmed
+		}
+		blk, err := BuildBlock(c.TipHash(), txs, nil, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.AcceptBlock(blk, led); !errors.Is(err, want) {
+			t.Errorf("%s: err = %v, want %v", name, err, want)
+		}
+		if c.Height() != 1 {
+			t.Fatalf("%s: height = %d, want the block not linked", name, c.Height())
+		}
+		if a, b := led.ConfirmedBalance(alice.addr), led.ConfirmedBalance(bob.addr); a != 900 || b != 99 {
+			t.Errorf("%s: balances a=%d b=%d, want 900 and 99", name, a, b)
+		}
+	}
+}
+
+func TestAcceptBlockRejectsUnfundedTx(t *testing.T) {
+	c := newTestChain(t)
+	led := newTestLedger(t)
+	alice, bob, carol := newTestKey(t), newTestKey(t), newTestKey(t)
+	if err := led.FaucetCredit(alice.addr, 1_000); err != nil {
+		t.Fatal(err)
+	}
+
+	// bob can spend what alice sends him earlier in the same block, but
+	// alice cannot spend it twice.
+	funded := []SignedTx{
+		alice.transfer(t, bob.addr, 600, 10, 1),
+		bob.transfer(t, carol.addr, 500, 10, 1),
+	}
+	for name, txs := range map[string][]SignedTx{
+		"overspend":           append(funded[:2:2], alice.transfer(t, carol.addr, 600, 10, 2)),
+		"spend before credit": {funded[1], funded[0]},
+	} {
+		blk, err := BuildBlock(c.TipHash(), txs, nil, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.AcceptBlock(blk, led); !errors.Is(err, ErrUnfundedTx) {
+			t.Errorf("%s: err = %v, want ErrUnfundedTx", name, err)
+		}
+		if c.Height() != 0 || led.ConfirmedBalance(alice.addr) != 1_000 {
+			t.Fatalf("%s: block linked or ledger touched", name)
+		}
+	}
+
+	blk, err := BuildBlock(c.TipHash(), funded, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := c.AcceptBlock(blk, led)
+	if err != nil || out.Applied != 2 || out.Failed != 0 {
+		t.Fatalf("funded block: %+v, %v", out, err)
+	}
+	if got := led.ConfirmedBalance(carol.addr); got != 490 {
+		t.Errorf("carol = %d, want 490", got)
+	}
+}
+
+func TestProduceOnceLeavesOutUnfundedTxs(t *testing.T) {
+	c := newTestChain(t)
+	led := newTestLedger(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	if err := led.FaucetCredit(alice.addr, 1_000); err != nil {
+		t.Fatal(err)
+	}
+	// Added without staging, so nothing stops the second tx overspending.
+	ok := alice.transfer(t, bob.addr, 800, 10, 1)
+	over := alice.transfer(t, bob.addr, 800, 10, 2)
+	later := alice.transfer(t, bob.addr, 10, 1, 3)
+	admit(t, c, ok, over, later)
+
+	out, err := NewBlockProducer(c, led, 0, nil).ProduceOnce(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Applied != 1 || out.Failed != 0 || out.Dropped != 2 {
+		t.Errorf("produced %+v, want 1 applied, 0 failed, 2 dropped", out)
+	}
+	if _, found := c.FindTx(over.TxID); found {
+		t.Error("unfunded tx confirmed")
+	}
+	if c.MempoolHas(over.TxID) || c.MempoolHas(later.TxID) {
+		t.Error("unfunded tx or its successor left pending")
+	}
+	if got := led.ConfirmedBalance(bob.addr); got != 790 {
+		t.Errorf("bob = %d, want 790", got)
+	}
+}
+
+func TestOldTxsValidInBlocks(t *testing.T) {
+	c := newTestChain(t)
+	led := newTestLedger(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	if err := led.FaucetCredit(alice.addr, 1_000); err != nil {
+		t.Fatal(err)
+	}
+	old := alice.transferAt(t, time.Now().Add(-48*time.Hour), bob.addr, 100, 1, 1)
+
+	// Too old for the mempool...
+	if err := c.MempoolAdd(old); !errors.Is(err, ErrTimestampPast) {
+		t.Fatalf("mempool add: err = %v, want ErrTimestampPast", err)
+	}
+	// ...but still valid in a block, as when syncing history.
+	if err := ValidateSignedTx(old); err != nil {
+		t.Fatalf("ValidateSignedTx: %v", err)
+	}
+	blk, err := BuildBlock(c.TipHash(), []SignedTx{old}, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AcceptBlock(blk, led); err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if got := led.ConfirmedBalance(bob.addr); got != 99 {
+		t.Errorf("bob = %d, want 99", got)
+	}
+}
+
+func TestChainClockDrivesTimekeeping(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+	c := New(
+		filepath.Join(dir, "nonces.json"),
+		filepath.Join(dir, "blocks.json"),
+		filepath.Join(dir, "mempool.json"),
+		WithClock(fake),
+	)
+	led := newTestLedger(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	if err := led.FaucetCredit(alice.addr, 1_000); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dated by the chain clock, years from the wall clock.
+	tx := alice.transferAt(t, now, bob.addr, 100, 1, 1)
+	if err := c.MempoolAdd(tx); err != nil {
+		t.Fatalf("mempool add: %v", err)
+	}
+	fake.Advance(time.Minute)
+	out, err := NewBlockProducer(c, led, 0, nil).ProduceOnce(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.Block.Block.Header.Timestamp, now.Add(time.Minute).Unix(); got != want {
+		t.Errorf("block timestamp = %d, want %d", got, want)
+	}
+
+	fake.Advance(25 * time.Hour)
+	if err := c.CheckTxTime(alice.transferAt(t, now, bob.addr, 100, 1, 2)); !errors.Is(err, ErrTimestampPast) {
+		t.Errorf("after 25h: err = %v, want ErrTimestampPast", err)
+	}
+}
@@ -0,0 +1,478 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltBlockStore keeps the chain in a bbolt database with these buckets:
+//
+//	blocks    height -> StoredBlock (JSON)
+//	heights   height -> header hash (hex)
+//	hashes    header hash (hex) -> height
+//	txs       txId -> height || index in block (uint32)
+//	accounts  acct(addr) || height || index -> (empty), per sender and recipient
+//	senders   acct(from) || nonce -> height
+//	meta      "index" -> version of the three indexes above
+//
+// Heights and nonces are 8-byte big-endian and acct(addr) is addr prefixed
+// with its length, so a cursor walks each address's entries in order and a
+// range read seeks straight to its first key. The indexes let a Chain keep
+// only its newest blocks in memory and look older ones up here (see
+// indexedBlockStore).
+//
+// Save is handed the chain from some height up to the tip; blocks stored
+// below that height are kept. A Save that extends the chain only puts the
+// new blocks; one that does not (a reorg or quarantine) also deletes every
+// stored height from the first that differs. Each Save is a single
+// transaction, so a crash leaves the previous or the new chain, never a mix.
+type BoltBlockStore struct {
+	db *bolt.DB
+
+	// importPath is a JSON or JSON-lines block file to take over if the
+	// database is empty on Load.
+	importPath string
+}
+
+var (
+	boltBlocks   = []byte("blocks")
+	boltHeights  = []byte("heights")
+	boltHashes   = []byte("hashes")
+	boltTxs      = []byte("txs")
+	boltAccounts = []byte("accounts")
+	boltSenders  = []byte("senders")
+	boltMeta     = []byte("meta")
+
+	boltIndexKey = []byte("index")
+)
+
+// boltIndexVersion is bumped when the index buckets change; a database
+// with another version (or none, from before they existed) is reindexed
+// when opened.
+const boltIndexVersion = 1
+
+// boltOpenTimeout bounds the wait for the database's file lock, held by any
+// other process that has it open.
+const boltOpenTimeout = 2 * time.Second
+
+// BoltBlockStorePath is where the bolt backend keeps the database for a block
+// store configured at path: the same name with a .db extension.
+func BoltBlockStorePath(path string) string {
+	path = filepath.Clean(path)
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".db"
+}
+
+// OpenBoltBlockStore opens (creating if needed) the database at path.
+// importPath may name a block file from the json or log backend; an empty
+// database takes its blocks over on Load and the file is renamed to
+// <importPath>.imported, so it is not mistaken for current data later.
+func OpenBoltBlockStore(path, importPath string) (*BoltBlockStore, error) {
+	path = filepath.Clean(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("open block db %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltBlocks, boltHeights, boltHashes, boltMeta} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if v := tx.Bucket(boltMeta).Get(boltIndexKey); len(v) == 8 && binary.BigEndian.Uint64(v) == boltIndexVersion {
+			return nil
+		}
+		return reindexBolt(tx)
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	s := &BoltBlockStore{db: db}
+	if importPath != "" && filepath.Clean(importPath) != path {
+		s.importPath = filepath.Clean(importPath)
+	}
+	return s, nil
+}
+
+func heightKey(h uint64) []byte {
+	var k [8]byte
+	binary.BigEndian.PutUint64(k[:], h)
+	return k[:]
+}
+
+// acctKey is acct(addr) followed by parts.
+func acctKey(addr string, parts ...[]byte) []byte {
+	k := append([]byte{byte(len(addr))}, addr...)
+	for _, p := range parts {
+		k = append(k, p...)
+	}
+	return k
+}
+
+func indexKey(i int) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(i))
+}
+
+// reindexBolt rebuilds the index buckets from the blocks bucket.
+func reindexBolt(tx *bolt.Tx) error {
+	for _, name := range [][]byte{boltTxs, boltAccounts, boltSenders} {
+		if tx.Bucket(name) != nil {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.CreateBucket(name); err != nil {
+			return err
+		}
+	}
+	err := tx.Bucket(boltBlocks).ForEach(func(_, v []byte) error {
+		var b StoredBlock
+		if err := json.Unmarshal(v, &b); err != nil {
+			return err
+		}
+		return indexBolt(tx, b, true)
+	})
+	if err != nil {
+		return fmt.Errorf("reindex block db: %w", err)
+	}
+	return tx.Bucket(boltMeta).Put(boltIndexKey, heightKey(boltIndexVersion))
+}
+
+// indexBolt adds b's txs to the index buckets, or removes them.
+func indexBolt(tx *bolt.Tx, b StoredBlock, add bool) error {
+	tb, ab, sb := tx.Bucket(boltTxs), tx.Bucket(boltAccounts), tx.Bucket(boltSenders)
+	put := func(bucket *bolt.Bucket, k, v []byte) error {
+		if !add {
+			return bucket.Delete(k)
+		}
+		return bucket.Put(k, v)
+	}
+	h := heightKey(b.Height)
+	for i, t := range b.Block.Transactions {
+		d, at := t.Draft, append(heightKey(b.Height), indexKey(i)...)
+		if err := put(tb, []byte(t.TxID), at); err != nil {
+			return err
+		}
+		if err := put(sb, acctKey(d.From, heightKey(d.Nonce)), h); err != nil {
+			return err
+		}
+		if err := put(ab, acctKey(d.From, at), []byte{}); err != nil {
+			return err
+		}
+		for _, to := range d.Recipients() {
+			if to == d.From {
+				continue
+			}
+			if err := put(ab, acctKey(to, at), []byte{}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *BoltBlockStore) Load() ([]StoredBlock, error) {
+	blocks := []StoredBlock{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBlocks).ForEach(func(_, v []byte) error {
+			var b StoredBlock
+			if err := json.Unmarshal(v, &b); err != nil {
+				return err
+			}
+			blocks = append(blocks, b)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 && s.importPath != "" {
+		return s.importFile()
+	}
+	return blocks, nil
+}
+
+// importFile moves the blocks of the file at importPath into the empty
+// database.
+func (s *BoltBlockStore) importFile() ([]StoredBlock, error) {
+	if _, err := os.Stat(s.importPath); errors.Is(err, os.ErrNotExist) {
+		return []StoredBlock{}, nil
+	}
+	blocks, err := NewJSONBlockStore(s.importPath).Load()
+	if err != nil {
+		return nil, fmt.Errorf("import %s: %w", s.importPath, err)
+	}
+	if err := s.Save(blocks); err != nil {
+		return nil, fmt.Errorf("import %s: %w", s.importPath, err)
+	}
+	if err := os.Rename(s.importPath, s.importPath+".imported"); err != nil {
+		return nil, fmt.Errorf("import %s: %w", s.importPath, err)
+	}
+	return blocks, nil
+}
+
+func (s *BoltBlockStore) Save(blocks []StoredBlock) error {
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Height < blocks[j].Height })
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bb, hb, xb := tx.Bucket(boltBlocks), tx.Bucket(boltHeights), tx.Bucket(boltHashes)
+
+		// Skip the prefix already stored; in the common case that is every
+		// block but the newest.
+		keep := 0
+		if k, v := hb.Cursor().Last(); k != nil {
+			last := binary.BigEndian.Uint64(k)
+			if i := sort.Search(len(blocks), func(i int) bool { return blocks[i].Height >= last }); i < len(blocks) && blocks[i].Height == last && blocks[i].HashHex == string(v) {
+				keep = i + 1
+			} else {
+				for keep < len(blocks) && string(hb.Get(heightKey(blocks[keep].Height))) == blocks[keep].HashHex {
+					keep++
+				}
+			}
+		}
+
+		// Drop whatever is stored from the first block that differs, or
+		// from the first block handed in: those below it are not ours to
+		// judge.
+		from := uint64(0)
+		if keep > 0 {
+			from = blocks[keep-1].Height + 1
+		} else if len(blocks) > 0 {
+			from = blocks[0].Height
+		}
+		var stale [][2][]byte
+		c := hb.Cursor()
+		for k, v := c.Seek(heightKey(from)); k != nil; k, v = c.Next() {
+			stale = append(stale, [2][]byte{append([]byte(nil), k...), append([]byte(nil), v...)})
+		}
+		// A stale block that no longer decodes (quarantine removes exactly
+		// those) cannot be unindexed by its txs; rebuild the indexes instead.
+		reindex := false
+		for _, kv := range stale {
+			var old StoredBlock
+			if err := json.Unmarshal(bb.Get(kv[0]), &old); err != nil {
+				reindex = true
+			} else if err := indexBolt(tx, old, false); err != nil {
+				return err
+			}
+			if err := xb.Delete(kv[1]); err != nil {
+				return err
+			}
+			if err := bb.Delete(kv[0]); err != nil {
+				return err
+			}
+			if err := hb.Delete(kv[0]); err != nil {
+				return err
+			}
+		}
+
+		for _, b := range blocks[keep:] {
+			raw, err := json.Marshal(b)
+			if err != nil {
+				return err
+			}
+			k := heightKey(b.Height)
+			if err := bb.Put(k, raw); err != nil {
+				return err
+			}
+			if err := hb.Put(k, []byte(b.HashHex)); err != nil {
+				return err
+			}
+			if err := xb.Put([]byte(b.HashHex), k); err != nil {
+				return err
+			}
+			if !reindex {
+				if err := indexBolt(tx, b, true); err != nil {
+					return err
+				}
+			}
+		}
+		if reindex {
+			return reindexBolt(tx)
+		}
+		return nil
+	})
+}
+
+func (s *BoltBlockStore) BlocksFrom(height uint64, limit int) ([]StoredBlock, error) {
+	out := []StoredBlock{}
+	if limit <= 0 {
+		return out, nil
+	}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBlocks).Cursor()
+		for k, v := c.Seek(heightKey(height)); k != nil && len(out) < limit; k, v = c.Next() {
+			var b StoredBlock
+			if err := json.Unmarshal(v, &b); err != nil {
+				return err
+			}
+			out = append(out, b)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *BoltBlockStore) BlockByHash(hashHex string) (StoredBlock, bool, error) {
+	var (
+		b     StoredBlock
+		found bool
+	)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		k := tx.Bucket(boltHashes).Get([]byte(hashHex))
+		if k == nil {
+			return nil
+		}
+		v := tx.Bucket(boltBlocks).Get(k)
+		if v == nil {
+			return fmt.Errorf("block db: hash %s indexed at a missing height", hashHex)
+		}
+		found = true
+		return json.Unmarshal(v, &b)
+	})
+	if err != nil {
+		return StoredBlock{}, false, err
+	}
+	return b, found, nil
+}
+
+// tail is Load for the newest n blocks only.
+func (s *BoltBlockStore) tail(n int) ([]StoredBlock, error) {
+	var out []StoredBlock
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBlocks).Cursor()
+		for k, v := c.Last(); k != nil && len(out) < n; k, v = c.Prev() {
+			var b StoredBlock
+			if err := json.Unmarshal(v, &b); err != nil {
+				return err
+			}
+			out = append(out, b)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 && s.importPath != "" {
+		blocks, err := s.importFile()
+		if err != nil {
+			return nil, err
+		}
+		return blocks[max(len(blocks)-n, 0):], nil
+	}
+	slices.Reverse(out)
+	return out, nil
+}
+
+func (s *BoltBlockStore) txLoc(txID string) (txLoc, bool, error) {
+	var (
+		loc   txLoc
+		found bool
+	)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(boltTxs).Get([]byte(txID))
+		if v == nil {
+			return nil
+		}
+		if len(v) != 12 {
+			return fmt.Errorf("block db: bad tx index entry for %s", txID)
+		}
+		loc = txLoc{height: binary.BigEndian.Uint64(v), index: int(binary.BigEndian.Uint32(v[8:]))}
+		found = true
+		return nil
+	})
+	return loc, found, err
+}
+
+func (s *BoltBlockStore) accountTxs(addr string, below uint64, offset, limit int) ([]AccountTx, int, error) {
+	out := []AccountTx{}
+	total := 0
+	prefix := acctKey(addr)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bb := tx.Bucket(boltBlocks)
+		var (
+			at uint64
+			sb StoredBlock
+		)
+		c := tx.Bucket(boltAccounts).Cursor()
+		k, _ := c.Seek(acctKey(addr, heightKey(below)))
+		if k == nil {
+			k, _ = c.Last()
+		} else {
+			k, _ = c.Prev()
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix) && len(k) == len(prefix)+12; k, _ = c.Prev() {
+			total++
+			if total <= offset || len(out) >= limit {
+				continue
+			}
+			h, i := binary.BigEndian.Uint64(k[len(prefix):]), int(binary.BigEndian.Uint32(k[len(prefix)+8:]))
+			if h != at {
+				v := bb.Get(heightKey(h))
+				if v == nil {
+					return fmt.Errorf("block db: %s indexed at missing height %d", addr, h)
+				}
+				sb = StoredBlock{}
+				if err := json.Unmarshal(v, &sb); err != nil {
+					return err
+				}
+				at = h
+			}
+			if i >= len(sb.Block.Transactions) {
+				return fmt.Errorf("block db: %s indexed at missing tx %d of height %d", addr, i, h)
+			}
+			out = append(out, AccountTx{Height: h, BlockHash: sb.HashHex, Tx: sb.Block.Transactions[i]})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (s *BoltBlockStore) lastNonce(addr string, below uint64) (uint64, error) {
+	var nonce uint64
+	prefix := acctKey(addr)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltSenders).Cursor()
+		end := acctKey(addr, heightKey(math.MaxUint64))
+		k, v := c.Seek(end)
+		if k == nil {
+			k, v = c.Last()
+		} else if !bytes.Equal(k, end) {
+			k, v = c.Prev()
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix) && len(k) == len(prefix)+8; k, v = c.Prev() {
+			if binary.BigEndian.Uint64(v) < below {
+				nonce = binary.BigEndian.Uint64(k[len(prefix):])
+				return nil
+			}
+		}
+		return nil
+	})
+	return nonce, err
+}
+
+// Close releases the database and its file lock. Saves after Close fail.
+func (s *BoltBlockStore) Close() error {
+	return s.db.Close()
+}
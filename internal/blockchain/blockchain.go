@@ -2,42 +2,97 @@ package blockchain
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/clock"
+	"github.com/VeltarosLabs/Veltaros/internal/events"
+	"github.com/VeltarosLabs/Veltaros/internal/storage"
 )
 
 type Chain struct {
 	mu sync.RWMutex
 
+	// settleMu is held while a block is linked and settled on the ledger
+	// (ProduceOnce, AcceptBlock) and while UnwindTo reverts, so the ledger
+	// and the tip always move together. Taken before mu.
+	settleMu sync.Mutex
+
+	// saveMu is held by SaveBlocks and while UnwindTo reverts, so the
+	// window only moves past blocks once they are saved. Taken after
+	// settleMu, before mu.
+	saveMu sync.Mutex
+
 	genesis Block
+	params  ChainParams
+	clock   clock.Clock
 	height  uint64
 	tipHash [32]byte
 
-	mempool map[string]SignedTx
+	// consensus checks block headers in AddBlock; nil accepts any header.
+	// Set only at construction (WithConsensus).
+	consensus HeaderValidator
+
+	mempool      map[string]MempoolEntry      // txId -> entry
+	mempoolBytes int                          // sum of SizeBytes over mempool
+	bySender     map[string]map[uint64]string // from -> nonce -> txId, over mempool
+	mempoolStore *MempoolStore
 
 	nonces     *NonceTracker
 	nonceStore *NonceStore
 
 	blockStorePath string
+	blockStore     BlockStore
+
+	// blocks is the window of the chain kept in memory: all of it, or with
+	// an indexed store the newest window blocks plus any not yet saved.
+	// Older blocks are read from indexed; see blockwindow.go. The maps
+	// below cover the window only.
+	indexed        indexedBlockStore // blockStore, if indexed
+	window         int               // 0 keeps every block
 	blocks         []StoredBlock
 	blocksByHash   map[string]StoredBlock
+	txIndex        map[string]uint64 // txId -> block height
+	confirmedNonce map[string]uint64 // sender -> highest nonce in a block
+
+	// address -> txs it sent or received, oldest first; see AccountTxs
+	accountTxs map[string][]txLoc
+
+	events *events.Bus // nil unless SetEvents
 }
 
-func New(nonceStorePath string, blockStorePath string) *Chain {
+func New(nonceStorePath string, blockStorePath string, mempoolStorePath string, opts ...Option) *Chain {
 	g := NewGenesisBlock()
 	genHash := g.Header.Hash()
 
-	return &Chain{
+	c := &Chain{
 		genesis:        g,
+		params:         DefaultParams(),
+		clock:          clock.Real{},
 		height:         0,
 		tipHash:        genHash,
-		mempool:        make(map[string]SignedTx),
+		mempool:        make(map[string]MempoolEntry),
+		bySender:       make(map[string]map[uint64]string),
 		nonces:         NewNonceTracker(),
 		nonceStore:     NewNonceStore(nonceStorePath),
+		mempoolStore:   NewMempoolStore(mempoolStorePath),
 		blockStorePath: blockStorePath,
+		blockStore:     NewJSONBlockStore(blockStorePath),
 		blocks:         []StoredBlock{},
 		blocksByHash:   make(map[string]StoredBlock),
+		txIndex:        make(map[string]uint64),
+		confirmedNonce: make(map[string]uint64),
+		accountTxs:     make(map[string][]txLoc),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	c.nonces.clock = c.clock
+	return c
 }
 
 func (c *Chain) Height() uint64 {
@@ -57,63 +112,261 @@ func (c *Chain) TipHashHex() string {
 	return hex.EncodeToString(h[:])
 }
 
+// Tip returns height and tip hash from a single snapshot so callers never
+// report a height from one block and a hash from another.
+func (c *Chain) Tip() (uint64, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.height, hex.EncodeToString(c.tipHash[:])
+}
+
+// tip is Tip with the raw hash, for building on the current tip.
+func (c *Chain) tip() (uint64, [32]byte) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.height, c.tipHash
+}
+
 func (c *Chain) Genesis() Block { return c.genesis }
 
+func (c *Chain) Params() ChainParams {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.params
+}
+
+// SetParams installs node policy. Consensus limits are fixed by package
+// constants and are not taken from p.
+func (c *Chain) SetParams(p ChainParams) {
+	d := DefaultParams()
+	d.RequireKnownRecipient = p.RequireKnownRecipient
+	d.HonorTxClass = p.HonorTxClass
+	d.MaxMempoolTxs = max(p.MaxMempoolTxs, 0)
+	d.MaxMempoolBytes = max(p.MaxMempoolBytes, 0)
+	d.MinRelayFee = p.MinRelayFee
+	d.MinRelayFeePerByte = p.MinRelayFeePerByte
+	d.BlockReward = p.BlockReward
+	d.PowDifficulty = p.PowDifficulty
+	if p.MaxFutureSkewSec > 0 {
+		d.MaxFutureSkewSec = p.MaxFutureSkewSec
+	}
+
+	c.mu.Lock()
+	c.params = d
+	c.mu.Unlock()
+}
+
 func (c *Chain) AddBlock(b Block) (StoredBlock, error) {
+	return c.addBlock(b, nil)
+}
+
+// addBlock is AddBlock with checkTxs, if non-nil, run on the block's txs
+// last, under the chain lock, so the tip cannot move between the check and
+// the link.
+func (c *Chain) addBlock(b Block, checkTxs func([]SignedTx) error) (StoredBlock, error) {
 	if err := b.ValidateBasic(); err != nil {
 		return StoredBlock{}, err
 	}
+	if c.consensus != nil {
+		if err := c.consensus.ValidateBlockHeader(b.Header.Bytes()); err != nil {
+			return StoredBlock{}, err
+		}
+	}
 
 	c.mu.Lock()
+	if b.Header.PrevHash != c.tipHash {
+		c.mu.Unlock()
+		return StoredBlock{}, ErrUnknownParent
+	}
+	if b.Coinbase != nil && b.Coinbase.Reward > c.params.BlockReward {
+		c.mu.Unlock()
+		return StoredBlock{}, fmt.Errorf("%w: %d > %d", ErrCoinbaseReward, b.Coinbase.Reward, c.params.BlockReward)
+	}
+	if err := c.checkUnconfirmedLocked(b.Transactions); err != nil {
+		c.mu.Unlock()
+		return StoredBlock{}, err
+	}
+	if checkTxs != nil {
+		if err := checkTxs(b.Transactions); err != nil {
+			c.mu.Unlock()
+			return StoredBlock{}, err
+		}
+	}
 	c.height++
 	c.tipHash = b.Header.Hash()
 
 	sb := MakeStoredBlock(c.height, b)
 	c.blocks = append(c.blocks, sb)
 	c.blocksByHash[sb.HashHex] = sb
+	c.indexTxsLocked(sb)
+	bus := c.events
 	c.mu.Unlock()
 
+	bus.Publish(events.BlockAdded, blockAddedEvent(sb))
 	return sb, nil
 }
 
+// checkUnconfirmedLocked rejects txs that would be settled a second time:
+// one already in a block, or one whose nonce does not exceed its sender's
+// last confirmed nonce (or its previous nonce in txs).
+func (c *Chain) checkUnconfirmedLocked(txs []SignedTx) error {
+	last := make(map[string]uint64)
+	for _, tx := range txs {
+		confirmed, err := c.txConfirmedLocked(tx.TxID)
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			return fmt.Errorf("%w: %s", ErrTxConfirmed, tx.TxID)
+		}
+		d := tx.Draft
+		floor, ok := last[d.From]
+		if !ok {
+			if floor, err = c.confirmedNonceLocked(d.From); err != nil {
+				return err
+			}
+		}
+		if d.Nonce <= floor {
+			return fmt.Errorf("%w: %s nonce %d (last %d)", ErrNonceUsed, d.From, d.Nonce, floor)
+		}
+		last[d.From] = d.Nonce
+	}
+	return nil
+}
+
+func (c *Chain) indexTxsLocked(sb StoredBlock) {
+	for _, tx := range sb.Block.Transactions {
+		c.txIndex[tx.TxID] = sb.Height
+		if tx.Draft.Nonce > c.confirmedNonce[tx.Draft.From] {
+			c.confirmedNonce[tx.Draft.From] = tx.Draft.Nonce
+		}
+	}
+	c.indexAccountTxsLocked(sb)
+}
+
+// FindTx returns the block containing txID, if it has been confirmed.
+func (c *Chain) FindTx(txID string) (StoredBlock, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	height, ok := c.txIndex[txID]
+	if !ok && c.indexed != nil {
+		if loc, found, err := c.indexed.txLoc(txID); err == nil && found && loc.height < c.baseLocked() {
+			height, ok = loc.height, true
+		}
+	}
+	if !ok {
+		return StoredBlock{}, false
+	}
+	blocks, err := c.blocksFromLocked(height, 1)
+	if err != nil || len(blocks) == 0 || blocks[0].Height != height {
+		return StoredBlock{}, false
+	}
+	return blocks[0], true
+}
+
+// SetKeepBackups makes the block and nonce stores keep a .bak of the
+// previous file on every save. Call it at startup.
+func (c *Chain) SetKeepBackups(keep bool) {
+	c.mu.Lock()
+	switch s := c.blockStore.(type) {
+	case *JSONBlockStore:
+		s.KeepBackup = keep
+	case *LogBlockStore:
+		s.mu.Lock()
+		s.KeepBackup = keep
+		s.mu.Unlock()
+	}
+	c.mu.Unlock()
+	if c.nonceStore != nil {
+		c.nonceStore.KeepBackup = keep
+	}
+}
+
+// SetBlockStore replaces the default JSON block store (see OpenBlockStore).
+// Call it at startup, before LoadBlocks; store should be at the chain's
+// block store path, which quarantine files are named after. If store is
+// indexed (the bolt backend) only the newest blocks are kept in memory.
+func (c *Chain) SetBlockStore(store BlockStore) {
+	c.mu.Lock()
+	c.blockStore = store
+	c.indexed, _ = store.(indexedBlockStore)
+	c.window = 0
+	if c.indexed != nil {
+		c.window = blockWindow
+	}
+	c.mu.Unlock()
+}
+
 // Block store persistence
 func (c *Chain) LoadBlocks() error {
-	store := NewBlockStore(c.blockStorePath)
-	blocks, err := store.Load()
+	c.mu.RLock()
+	store, indexed, window := c.blockStore, c.indexed, c.window
+	c.mu.RUnlock()
+
+	var (
+		blocks []StoredBlock
+		err    error
+	)
+	if indexed != nil && window > 0 {
+		blocks, err = indexed.tail(window)
+	} else {
+		blocks, err = store.Load()
+	}
 	if err != nil {
 		return err
 	}
+	first := blocks
+	if len(blocks) > 0 && blocks[0].Height > 1 {
+		if first, err = store.BlocksFrom(1, 1); err != nil {
+			return err
+		}
+	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.blocks = blocks
-	c.blocksByHash = make(map[string]StoredBlock, len(blocks))
-	for _, b := range blocks {
-		c.blocksByHash[b.HashHex] = b
+	// A damaged first block is left to CheckIntegrity to quarantine.
+	if len(first) > 0 && first[0].Height == 1 && first[0].Block.Header.PrevHash != c.genesis.Header.Hash() {
+		return fmt.Errorf("%w (block 1 has prevHash %s)", ErrGenesisMismatch, first[0].PrevHashHex)
 	}
 
-	// If blocks exist, set height/tip based on last
 	if len(blocks) > 0 {
-		last := blocks[len(blocks)-1]
-		c.height = last.Height
-		if h, err := hex.DecodeString(last.HashHex); err == nil && len(h) == 32 {
-			copy(c.tipHash[:], h)
-		}
+		c.resetWindowLocked(blocks)
 	}
-
 	return nil
 }
 
+// SaveBlocks saves the blocks in memory; with an indexed store it then lets
+// go of the saved ones beyond the window.
 func (c *Chain) SaveBlocks() error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
 	c.mu.RLock()
 	blocks := make([]StoredBlock, len(c.blocks))
 	copy(blocks, c.blocks)
-	path := c.blockStorePath
+	store, window := c.blockStore, c.window
 	c.mu.RUnlock()
 
-	store := NewBlockStore(path)
-	return store.Save(blocks)
+	if err := store.Save(blocks); err != nil {
+		return err
+	}
+	if window > 0 {
+		c.mu.Lock()
+		c.trimWindowLocked(blocks)
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+// CloseBlockStore releases the block store (the bolt backend's database).
+// Call it once, after the final SaveBlocks.
+func (c *Chain) CloseBlockStore() error {
+	c.mu.RLock()
+	store := c.blockStore
+	c.mu.RUnlock()
+	return store.Close()
 }
 
 func (c *Chain) RecentBlocks(limit int) []StoredBlock {
@@ -123,39 +376,156 @@ func (c *Chain) RecentBlocks(limit int) []StoredBlock {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	if len(c.blocks) == 0 {
+	limit = int(min(uint64(limit), c.height))
+	out, err := c.blocksFromLocked(c.height+1-uint64(limit), limit)
+	if err != nil {
 		return []StoredBlock{}
 	}
+	return out
+}
 
-	if limit > len(c.blocks) {
-		limit = len(c.blocks)
-	}
-	out := make([]StoredBlock, 0, limit)
-	start := len(c.blocks) - limit
-	for i := start; i < len(c.blocks); i++ {
-		out = append(out, c.blocks[i])
+// BlocksFrom returns up to limit consecutive blocks starting at height
+// (ascending). Genesis (height 0) is never included.
+func (c *Chain) BlocksFrom(height uint64, limit int) []StoredBlock {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out, err := c.blocksFromLocked(height, limit)
+	if err != nil {
+		return []StoredBlock{}
 	}
 	return out
 }
 
+// GetBlock looks up a block by lowercase hex header hash, including genesis
+// (height 0).
 func (c *Chain) GetBlock(hashHex string) (StoredBlock, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	b, ok := c.blocksByHash[hashHex]
-	return b, ok
+	if b, ok := c.blocksByHash[hashHex]; ok {
+		return b, true
+	}
+	if g := MakeStoredBlock(0, c.genesis); g.HashHex == hashHex {
+		return g, true
+	}
+	if c.indexed != nil {
+		if b, ok, err := c.indexed.BlockByHash(hashHex); err == nil && ok && b.Height < c.baseLocked() {
+			return b, true
+		}
+	}
+	return StoredBlock{}, false
 }
 
 // Mempool
 func (c *Chain) MempoolAdd(tx SignedTx) error {
+	return c.MempoolAddFrom(tx, TxSourceLocal)
+}
+
+// MempoolAddFrom is MempoolAdd recording how the tx arrived.
+func (c *Chain) MempoolAddFrom(tx SignedTx, src TxSource) error {
+	if err := ValidateSignedTx(tx); err != nil {
+		return err
+	}
+	if err := c.CheckTxTime(tx); err != nil {
+		return err
+	}
+	e := newMempoolEntry(tx, src, c.clock.Now())
+	c.mu.Lock()
+	if err := c.checkRelayFeeLocked(tx); err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	c.mempoolPutLocked(e)
+	bus := c.events
+	c.mu.Unlock()
+
+	bus.Publish(events.TxAdded, txAddedEvent(e, ""))
+	return nil
+}
+
+// MempoolFindByNonce returns the pending tx from addr at nonce, if any.
+func (c *Chain) MempoolFindByNonce(addr string, nonce uint64) (SignedTx, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	id, ok := c.bySender[addr][nonce]
+	if !ok {
+		return SignedTx{}, false
+	}
+	return c.mempool[id].Tx, true
+}
+
+// MempoolBySender lists pending txs from addr ordered by nonce.
+func (c *Chain) MempoolBySender(addr string) []SignedTx {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make([]SignedTx, 0, len(c.bySender[addr]))
+	for _, id := range c.bySender[addr] {
+		out = append(out, c.mempool[id].Tx)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Draft.Nonce < out[j].Draft.Nonce })
+	return out
+}
+
+// MempoolReplace swaps a pending tx for one at the same (from, nonce) paying at
+// least ChainParams.MinReplacementFee (replace-by-fee). src records how tx
+// arrived.
+func (c *Chain) MempoolReplace(oldTxID string, tx SignedTx, src TxSource) error {
 	if err := ValidateSignedTx(tx); err != nil {
 		return err
 	}
+	if err := c.CheckTxTime(tx); err != nil {
+		return err
+	}
+
 	c.mu.Lock()
-	c.mempool[tx.TxID] = tx
+	e, err := c.mempoolReplaceLocked(oldTxID, tx, src)
+	bus := c.events
 	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	bus.Publish(events.TxAdded, txAddedEvent(e, oldTxID))
 	return nil
 }
 
+func (c *Chain) mempoolReplaceLocked(oldTxID string, tx SignedTx, src TxSource) (MempoolEntry, error) {
+	if err := c.checkRelayFeeLocked(tx); err != nil {
+		return MempoolEntry{}, err
+	}
+	e, ok := c.mempool[oldTxID]
+	if !ok {
+		return MempoolEntry{}, errors.New("replaced tx not in mempool")
+	}
+	old := e.Tx
+	if old.Draft.From != tx.Draft.From || old.Draft.Nonce != tx.Draft.Nonce {
+		return MempoolEntry{}, errors.New("replacement must match from and nonce")
+	}
+	need, err := c.params.MinReplacementFee(old.Draft.Fee, tx.Draft)
+	if err != nil {
+		return MempoolEntry{}, err
+	}
+	if tx.Draft.Fee < need {
+		return MempoolEntry{}, fmt.Errorf("%w: fee %d, pending tx pays %d, node requires %d", ErrReplaceFeeTooLow, tx.Draft.Fee, old.Draft.Fee, need)
+	}
+	c.mempoolDeleteLocked(oldTxID)
+	ne := newMempoolEntry(tx, src, c.clock.Now())
+	c.mempoolPutLocked(ne)
+	return ne, nil
+}
+
+// MempoolRemove deletes a pending tx and returns it, if present.
+func (c *Chain) MempoolRemove(txID string) (SignedTx, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.mempool[txID]
+	if ok {
+		c.mempoolDeleteLocked(txID)
+	}
+	return e.Tx, ok
+}
+
 func (c *Chain) MempoolHas(txID string) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -163,14 +533,25 @@ func (c *Chain) MempoolHas(txID string) bool {
 	return ok
 }
 
+// MempoolGet returns a pending tx by txId.
+func (c *Chain) MempoolGet(txID string) (SignedTx, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.mempool[txID]
+	return e.Tx, ok
+}
+
+// MempoolList returns the pending txs in inclusion priority order; see
+// MempoolEntries for their arrival metadata.
 func (c *Chain) MempoolList() []SignedTx {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	out := make([]SignedTx, 0, len(c.mempool))
-	for _, tx := range c.mempool {
-		out = append(out, tx)
+	for _, e := range c.mempool {
+		out = append(out, e.Tx)
 	}
+	sortMempool(out, c.params.HonorTxClass)
 	return out
 }
 
@@ -180,18 +561,93 @@ func (c *Chain) MempoolCount() int {
 	return len(c.mempool)
 }
 
-func (c *Chain) MempoolDrain() []SignedTx {
+// MempoolTopN returns, without removing them, the ready txs (see
+// MempoolReady) that fit in one block (MaxBlockBytes, and at most n if > 0),
+// highest priority first. A sender's txs keep their nonce order, so a
+// higher-fee later nonce never precedes an earlier one.
+func (c *Chain) MempoolTopN(n int) []SignedTx {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	size := blockHeaderSize + 4
+	return c.readyLocked(n, func(tx SignedTx) bool {
+		sz, err := signedTxSize(tx)
+		if err != nil || size+blockTxOverhead+sz > MaxBlockBytes {
+			return false
+		}
+		size += blockTxOverhead + sz
+		return true
+	})
+}
+
+// MempoolRemoveAll deletes the given txs and returns those that were pending,
+// in argument order. IDs that are not pending are skipped.
+func (c *Chain) MempoolRemoveAll(txIDs []string) []SignedTx {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	out := make([]SignedTx, 0, len(c.mempool))
-	for _, tx := range c.mempool {
-		out = append(out, tx)
+	out := make([]SignedTx, 0, len(txIDs))
+	for _, id := range txIDs {
+		if e, ok := c.mempool[id]; ok {
+			c.mempoolDeleteLocked(id)
+			out = append(out, e.Tx)
+		}
 	}
-	c.mempool = make(map[string]SignedTx)
 	return out
 }
 
+// EvictExpired removes pending txs whose Draft.Timestamp is older than
+// maxAge, together with their same-sender dependents (see MempoolEvictFrom),
+// and returns them so the caller can release their ledger staging.
+func (c *Chain) EvictExpired(maxAge time.Duration) []SignedTx {
+	if maxAge <= 0 {
+		return nil
+	}
+	cutoff := c.clock.Now().Add(-maxAge).Unix()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Lowest expired nonce per sender; everything above it goes too.
+	lowest := make(map[string]uint64)
+	for _, e := range c.mempool {
+		d := e.Tx.Draft
+		if d.Timestamp >= cutoff {
+			continue
+		}
+		if n, ok := lowest[d.From]; !ok || d.Nonce < n {
+			lowest[d.From] = d.Nonce
+		}
+	}
+
+	var out []SignedTx
+	for from, nonce := range lowest {
+		out = append(out, c.evictFromLocked(from, nonce)...)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TxID < out[j].TxID })
+	return out
+}
+
+// sortMempool orders txs for block inclusion: class (if honored) then fee,
+// both descending, then lower nonce, with txId as a deterministic tie-break.
+func sortMempool(txs []SignedTx, honorClass bool) {
+	sort.Slice(txs, func(i, j int) bool { return mempoolLess(txs[i], txs[j], honorClass) })
+}
+
+func mempoolLess(x, y SignedTx, honorClass bool) bool {
+	a, b := x.Draft, y.Draft
+	if honorClass && a.Class != b.Class {
+		return a.Class > b.Class
+	}
+	if a.Fee != b.Fee {
+		return a.Fee > b.Fee
+	}
+	if a.Nonce != b.Nonce {
+		return a.Nonce < b.Nonce
+	}
+	return x.TxID < y.TxID
+}
+
 // Nonces
 func (c *Chain) LastNonce(addr string) uint64 {
 	return c.nonces.Get(addr)
@@ -224,4 +680,105 @@ func (c *Chain) SaveNonceState() error {
 	return c.nonceStore.Save(c.nonces.Snapshot())
 }
 
+// StageNonceState queues the nonce store on b, to be written together with
+// the ledger (see storage.Batch).
+func (c *Chain) StageNonceState(b *storage.Batch) error {
+	if c.nonceStore == nil {
+		return nil
+	}
+	return c.nonceStore.Stage(b, c.nonces.Snapshot())
+}
+
+// MempoolDrop records a persisted tx that was not restored by LoadMempool.
+type MempoolDrop struct {
+	TxID   string
+	Reason string
+}
+
+// LoadMempool restores persisted txs. Each tx is re-validated and passed to
+// stage (typically re-reserving its ledger spend) before it is re-added;
+// entries that are corrupt, no longer valid, already confirmed (by txId or
+// by a block using their nonce), or rejected by stage are dropped and
+// reported rather than failing startup. The restored mempool is then trimmed
+// to the current limits, which may have been lowered since it was saved;
+// each tx trimmed is passed to unstage and reported too.
+func (c *Chain) LoadMempool(stage func(SignedTx) error, unstage func(SignedTx)) ([]MempoolDrop, error) {
+	if c.mempoolStore == nil {
+		return nil, nil
+	}
+	entries, err := c.mempoolStore.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var drops []MempoolDrop
+	for i, raw := range entries {
+		var tx SignedTx
+		if err := json.Unmarshal(raw, &tx); err != nil {
+			drops = append(drops, MempoolDrop{TxID: fmt.Sprintf("#%d", i), Reason: "corrupt entry"})
+			continue
+		}
+		if err := ValidateSignedTx(tx); err != nil {
+			drops = append(drops, MempoolDrop{TxID: tx.TxID, Reason: err.Error()})
+			continue
+		}
+		if err := c.CheckTxTime(tx); err != nil {
+			drops = append(drops, MempoolDrop{TxID: tx.TxID, Reason: err.Error()})
+			continue
+		}
+		// The fee policy may have been raised since the mempool was saved.
+		if err := c.CheckRelayFee(tx); err != nil {
+			drops = append(drops, MempoolDrop{TxID: tx.TxID, Reason: err.Error()})
+			continue
+		}
+		// The mempool store may lag the block store after a crash; never
+		// restage a tx that is already confirmed.
+		if _, ok := c.FindTx(tx.TxID); ok {
+			drops = append(drops, MempoolDrop{TxID: tx.TxID, Reason: "already in a block"})
+			continue
+		}
+		// Likewise for a different tx confirmed at the same nonce: this one
+		// could never become ready and would hold its staged spend.
+		if last := c.ConfirmedNonce(tx.Draft.From); tx.Draft.Nonce <= last {
+			drops = append(drops, MempoolDrop{TxID: tx.TxID, Reason: fmt.Sprintf("nonce %d already used in a block (last %d)", tx.Draft.Nonce, last)})
+			continue
+		}
+		if c.MempoolHas(tx.TxID) {
+			continue
+		}
+		if stage != nil {
+			if err := stage(tx); err != nil {
+				drops = append(drops, MempoolDrop{TxID: tx.TxID, Reason: err.Error()})
+				continue
+			}
+		}
+		c.mu.Lock()
+		c.mempoolPutLocked(newMempoolEntry(tx, TxSourceRestored, c.clock.Now()))
+		c.mu.Unlock()
+	}
+
+	for _, tx := range c.MempoolTrim() {
+		if unstage != nil {
+			unstage(tx)
+		}
+		drops = append(drops, MempoolDrop{TxID: tx.TxID, Reason: "mempool limits"})
+	}
+	return drops, nil
+}
+
+func (c *Chain) SaveMempool() error {
+	if c.mempoolStore == nil {
+		return nil
+	}
+	return c.mempoolStore.Save(c.MempoolList())
+}
+
+// StageMempool queues the mempool store on b (see storage.Batch).
+func (c *Chain) StageMempool(b *storage.Batch) error {
+	if c.mempoolStore == nil {
+		return nil
+	}
+	return c.mempoolStore.Stage(b, c.MempoolList())
+}
+
 var ErrInvalidBlock = errors.New("invalid block")
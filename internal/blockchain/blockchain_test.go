@@ -0,0 +1,100 @@
+package blockchain
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/ledger"
+)
+
+const testNetworkID = "veltaros-testnet"
+
+// testKey is a signing key and the address it controls.
+type testKey struct {
+	priv ed25519.PrivateKey
+	addr string
+}
+
+func newTestKey(t testing.TB) testKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := AddressFromEd25519PublicKeyHex(hex.EncodeToString(pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{priv: priv, addr: addr}
+}
+
+// transfer signs a version 1 transfer from k to to, dated now.
+func (k testKey) transfer(t testing.TB, to string, amount, fee, nonce uint64) SignedTx {
+	t.Helper()
+	return k.transferAt(t, time.Now(), to, amount, fee, nonce)
+}
+
+// transferAt is transfer dated at.
+func (k testKey) transferAt(t testing.TB, at time.Time, to string, amount, fee, nonce uint64) SignedTx {
+	t.Helper()
+	return k.sign(t, TxDraft{To: to, Amount: amount, Fee: fee, Nonce: nonce, Timestamp: at.Unix()})
+}
+
+// transferClass is transfer declaring class.
+func (k testKey) transferClass(t testing.TB, class uint8, to string, amount, fee, nonce uint64) SignedTx {
+	t.Helper()
+	return k.sign(t, TxDraft{To: to, Amount: amount, Fee: fee, Nonce: nonce, Timestamp: time.Now().Unix(), Class: class})
+}
+
+// sign fills in d's network and sender and signs it with k.
+func (k testKey) sign(t testing.TB, d TxDraft) SignedTx {
+	t.Helper()
+	d.NetworkID, d.From = testNetworkID, k.addr
+	tx, err := SignDraft(k.priv, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// newTestChain returns an empty chain whose stores live in a temp dir.
+func newTestChain(t testing.TB) *Chain {
+	t.Helper()
+	dir := t.TempDir()
+	return New(
+		filepath.Join(dir, "nonces.json"),
+		filepath.Join(dir, "blocks.json"),
+		filepath.Join(dir, "mempool.json"),
+	)
+}
+
+// newTestLedger returns an empty ledger stored in a temp dir, journaling
+// from genesis.
+func newTestLedger(t testing.TB) *ledger.Ledger {
+	t.Helper()
+	led := ledger.New(filepath.Join(t.TempDir(), "ledger.json"))
+	led.ResetJournal(0)
+	return led
+}
+
+// mine admits txs and produces one block holding them.
+func mine(t testing.TB, p *BlockProducer, txs ...SignedTx) ProducedBlock {
+	t.Helper()
+	for _, tx := range txs {
+		if err := p.chain.MempoolAdd(tx); err != nil {
+			t.Fatalf("mempool add: %v", err)
+		}
+	}
+	out, err := p.ProduceOnce(true)
+	if err != nil {
+		t.Fatalf("produce: %v", err)
+	}
+	if out.Applied != len(txs) {
+		t.Fatalf("applied %d of %d txs", out.Applied, len(txs))
+	}
+	return out
+}
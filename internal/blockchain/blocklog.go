@@ -0,0 +1,188 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/VeltarosLabs/Veltaros/internal/storage"
+)
+
+// LogBlockStore keeps the chain as one JSON block per line. A Save that only
+// extends the chain appends the new blocks and fsyncs, so saving after each
+// block costs one block's worth of I/O rather than the whole chain.
+//
+// The file is rewritten in full (atomically, like JSONBlockStore) when the
+// chain no longer extends what is on disk (a reorg or quarantine), when Load
+// found a torn final line or fell back to the .bak, and on the first Save
+// if Load was never called.
+type LogBlockStore struct {
+	path string
+
+	// KeepBackup keeps the previous file as <path>.bak on each full rewrite;
+	// Load falls back to it if the primary is corrupt. Appends do not touch it.
+	KeepBackup bool
+
+	mu       sync.Mutex
+	onDisk   int    // blocks in the file
+	lastHash string // hash of the last of them
+	rewrite  bool   // next Save must rewrite the file
+}
+
+func NewLogBlockStore(path string) *LogBlockStore {
+	return &LogBlockStore{path: filepath.Clean(path), rewrite: true}
+}
+
+func (s *LogBlockStore) Load() ([]StoredBlock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		blocks []StoredBlock
+		torn   bool
+		tries  int
+	)
+	err := storage.ReadFileWithFallback(s.path, func(raw []byte) error {
+		tries++
+		var err error
+		blocks, torn, err = decodeBlocks(raw)
+		if trimmed := bytes.TrimSpace(raw); err == nil && len(trimmed) > 0 && trimmed[0] == '[' {
+			torn = true // a JSON array from the other backend; convert it
+		}
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			s.onDisk, s.lastHash, s.rewrite = 0, "", true
+			return []StoredBlock{}, nil
+		}
+		return nil, err
+	}
+
+	s.onDisk = len(blocks)
+	s.lastHash = ""
+	if len(blocks) > 0 {
+		s.lastHash = blocks[len(blocks)-1].HashHex
+	}
+	// Rewrite if the file is not exactly what was returned: a torn tail,
+	// another format, or the primary was missing or unreadable and the .bak
+	// was used.
+	_, statErr := os.Stat(s.path)
+	s.rewrite = torn || tries > 1 || statErr != nil
+	return blocks, nil
+}
+
+func (s *LogBlockStore) Save(blocks []StoredBlock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Height < blocks[j].Height })
+
+	extends := !s.rewrite && s.onDisk <= len(blocks) &&
+		(s.onDisk == 0 || blocks[s.onDisk-1].HashHex == s.lastHash)
+	if extends {
+		if s.onDisk == len(blocks) {
+			return nil
+		}
+		if err := s.appendLocked(blocks[s.onDisk:]); err == nil {
+			s.markLocked(blocks)
+			return nil
+		}
+		// A failed append may have left a partial line; rewrite instead.
+	}
+
+	var buf bytes.Buffer
+	if err := encodeBlockLines(&buf, blocks); err != nil {
+		return err
+	}
+	if err := storage.WriteFileAtomic(s.path, buf.Bytes(), s.KeepBackup); err != nil {
+		s.rewrite = true
+		return err
+	}
+	s.markLocked(blocks)
+	return nil
+}
+
+// BlocksFrom and BlockByHash read the file as it is, without changing what
+// the next Save does.
+func (s *LogBlockStore) BlocksFrom(height uint64, limit int) ([]StoredBlock, error) {
+	blocks, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return blocksFrom(blocks, height, limit), nil
+}
+
+func (s *LogBlockStore) BlockByHash(hashHex string) (StoredBlock, bool, error) {
+	blocks, err := s.read()
+	if err != nil {
+		return StoredBlock{}, false, err
+	}
+	b, ok := blockByHash(blocks, hashHex)
+	return b, ok, nil
+}
+
+func (s *LogBlockStore) Close() error { return nil }
+
+func (s *LogBlockStore) read() ([]StoredBlock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var blocks []StoredBlock
+	err := storage.ReadFileWithFallback(s.path, func(raw []byte) error {
+		var err error
+		blocks, _, err = decodeBlocks(raw)
+		return err
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return []StoredBlock{}, nil
+	}
+	return blocks, err
+}
+
+func (s *LogBlockStore) appendLocked(blocks []StoredBlock) error {
+	var buf bytes.Buffer
+	if err := encodeBlockLines(&buf, blocks); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *LogBlockStore) markLocked(blocks []StoredBlock) {
+	s.onDisk = len(blocks)
+	s.lastHash = ""
+	if len(blocks) > 0 {
+		s.lastHash = blocks[len(blocks)-1].HashHex
+	}
+	s.rewrite = false
+}
+
+func encodeBlockLines(buf *bytes.Buffer, blocks []StoredBlock) error {
+	enc := json.NewEncoder(buf)
+	for _, b := range blocks {
+		if err := enc.Encode(b); err != nil { // Encode adds the newline
+			return err
+		}
+	}
+	return nil
+}
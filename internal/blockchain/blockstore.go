@@ -1,13 +1,17 @@
 package blockchain
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/storage"
 )
 
 type StoredBlock struct {
@@ -20,34 +24,102 @@ type StoredBlock struct {
 	Block       Block  `json:"block"`
 }
 
-type BlockStore struct {
+// BlockStore persists the chain's blocks. Save is handed the whole chain
+// each time, or for an indexedBlockStore the chain from its window in
+// memory up; an implementation may write only what changed since.
+//
+// BlocksFrom and BlockByHash read single blocks or height ranges (as the sync
+// protocol asks for them) without going through Load. The file backends read
+// their file to answer; the bolt backend reads just the keys asked for.
+type BlockStore interface {
+	Load() ([]StoredBlock, error)
+	Save(blocks []StoredBlock) error
+	// BlocksFrom returns up to limit consecutive blocks starting at height,
+	// ascending; none if height is past the stored tip.
+	BlocksFrom(height uint64, limit int) ([]StoredBlock, error)
+	// BlockByHash looks up a stored block by lowercase hex header hash.
+	BlockByHash(hashHex string) (StoredBlock, bool, error)
+	Close() error
+}
+
+// Block store backends, as named by OpenBlockStore.
+const (
+	BlockBackendJSON = "json" // one JSON array, rewritten on every save
+	BlockBackendLog  = "log"  // one JSON block per line, appended to
+	BlockBackendBolt = "bolt" // bbolt database keyed by height and hash
+)
+
+// OpenBlockStore returns the named backend's store at path. The two file
+// backends read either file format, so a node can switch between them in
+// place; the next save converts the file. The bolt backend keeps its
+// database next to path (see BoltBlockStorePath) and imports the file there
+// the first time it opens empty.
+func OpenBlockStore(backend, path string, keepBackup bool) (BlockStore, error) {
+	switch backend {
+	case BlockBackendJSON, "":
+		s := NewJSONBlockStore(path)
+		s.KeepBackup = keepBackup
+		return s, nil
+	case BlockBackendLog:
+		s := NewLogBlockStore(path)
+		s.KeepBackup = keepBackup
+		return s, nil
+	case BlockBackendBolt:
+		return OpenBoltBlockStore(BoltBlockStorePath(path), path)
+	}
+	return nil, fmt.Errorf("unknown block store backend %q", backend)
+}
+
+// JSONBlockStore keeps the chain as a single JSON array, rewritten in full by
+// each Save.
+type JSONBlockStore struct {
 	path string
+
+	// KeepBackup keeps the previous file as <path>.bak on each Save; Load
+	// falls back to it if the primary is corrupt.
+	KeepBackup bool
 }
 
-func NewBlockStore(path string) *BlockStore {
-	return &BlockStore{path: filepath.Clean(path)}
+func NewJSONBlockStore(path string) *JSONBlockStore {
+	return &JSONBlockStore{path: filepath.Clean(path)}
 }
 
-func (s *BlockStore) Load() ([]StoredBlock, error) {
-	raw, err := os.ReadFile(s.path)
+func (s *JSONBlockStore) Load() ([]StoredBlock, error) {
+	var blocks []StoredBlock
+	err := storage.ReadFileWithFallback(s.path, func(raw []byte) error {
+		var err error
+		blocks, _, err = decodeBlocks(raw)
+		return err
+	})
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return []StoredBlock{}, nil
 		}
 		return nil, err
 	}
+	return blocks, nil
+}
 
-	var blocks []StoredBlock
-	if err := json.Unmarshal(raw, &blocks); err != nil {
+func (s *JSONBlockStore) BlocksFrom(height uint64, limit int) ([]StoredBlock, error) {
+	blocks, err := s.Load()
+	if err != nil {
 		return nil, err
 	}
+	return blocksFrom(blocks, height, limit), nil
+}
 
-	// Sort by height ascending for consistency
-	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Height < blocks[j].Height })
-	return blocks, nil
+func (s *JSONBlockStore) BlockByHash(hashHex string) (StoredBlock, bool, error) {
+	blocks, err := s.Load()
+	if err != nil {
+		return StoredBlock{}, false, err
+	}
+	b, ok := blockByHash(blocks, hashHex)
+	return b, ok, nil
 }
 
-func (s *BlockStore) Save(blocks []StoredBlock) error {
+func (s *JSONBlockStore) Close() error { return nil }
+
+func (s *JSONBlockStore) Save(blocks []StoredBlock) error {
 	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
 		return err
 	}
@@ -59,16 +131,70 @@ func (s *BlockStore) Save(blocks []StoredBlock) error {
 		return err
 	}
 
-	tmp := s.path + ".tmp"
-	if err := os.WriteFile(tmp, data, 0o600); err != nil {
-		return err
+	return storage.WriteFileAtomic(s.path, data, s.KeepBackup)
+}
+
+// decodeBlocks reads a block store file in either format: a JSON array, or
+// one JSON block per line. A final line cut short (a crash mid-append) is
+// dropped and reported as torn; any other bad line is an error. Blocks are
+// returned by ascending height.
+func decodeBlocks(raw []byte) (blocks []StoredBlock, torn bool, err error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &blocks); err != nil {
+			return nil, false, err
+		}
+	} else {
+		blocks = []StoredBlock{}
+		for rest := raw; len(rest) > 0; {
+			line := rest
+			complete := false
+			if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+				line, rest, complete = rest[:i], rest[i+1:], true
+			} else {
+				rest = nil
+			}
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			var b StoredBlock
+			if err := json.Unmarshal(line, &b); err != nil {
+				if !complete {
+					torn = true
+					break
+				}
+				return nil, false, fmt.Errorf("block store line %d: %w", len(blocks)+1, err)
+			}
+			blocks = append(blocks, b)
+		}
 	}
-	if err := os.Rename(tmp, s.path); err != nil {
-		_ = os.Remove(tmp)
-		return err
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Height < blocks[j].Height })
+	return blocks, torn, nil
+}
+
+// blocksFrom slices up to limit blocks from height out of blocks, which are
+// ascending and consecutive.
+func blocksFrom(blocks []StoredBlock, height uint64, limit int) []StoredBlock {
+	if limit <= 0 || len(blocks) == 0 {
+		return []StoredBlock{}
+	}
+	base := blocks[0].Height
+	height = max(height, base)
+	i := height - base
+	if i >= uint64(len(blocks)) {
+		return []StoredBlock{}
+	}
+	return blocks[i:min(int(i)+limit, len(blocks))]
+}
+
+func blockByHash(blocks []StoredBlock, hashHex string) (StoredBlock, bool) {
+	for _, b := range blocks {
+		if b.HashHex == hashHex {
+			return b, true
+		}
 	}
-	_ = os.Chmod(s.path, 0o600)
-	return nil
+	return StoredBlock{}, false
 }
 
 func MakeStoredBlock(height uint64, b Block) StoredBlock {
@@ -0,0 +1,371 @@
+package blockchain
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var blockBackends = []string{BlockBackendJSON, BlockBackendLog, BlockBackendBolt}
+
+// openStore opens backend's block store in dir and closes it when the test
+// ends (closing twice is harmless).
+func openStore(t *testing.T, dir, backend string) BlockStore {
+	t.Helper()
+	store, err := OpenBlockStore(backend, filepath.Join(dir, "blocks.json"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+// openChain returns a chain on dir whose blocks are kept by backend. The bolt
+// backend locks its database, so close the chain's store before opening
+// another on the same dir.
+func openChain(t *testing.T, dir, backend string) *Chain {
+	t.Helper()
+	path := filepath.Join(dir, "blocks.json")
+	c := New(filepath.Join(dir, "nonces.json"), path, filepath.Join(dir, "mempool.json"))
+	c.SetBlockStore(openStore(t, dir, backend))
+	if err := c.LoadBlocks(); err != nil {
+		t.Fatalf("%s: load: %v", backend, err)
+	}
+	return c
+}
+
+// reopen closes c's block store and opens the chain again from dir.
+func reopen(t *testing.T, c *Chain, dir, backend string) *Chain {
+	t.Helper()
+	if err := c.CloseBlockStore(); err != nil {
+		t.Fatal(err)
+	}
+	return openChain(t, dir, backend)
+}
+
+// storeView is what the store's own range reads return for the same queries
+// viewOf makes through the chain.
+func storeView(t *testing.T, store BlockStore, c *Chain) (from []StoredBlock, byHash []StoredBlock) {
+	t.Helper()
+	from, err := store.BlocksFrom(2, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range c.BlocksFrom(1, 100) {
+		got, ok, err := store.BlockByHash(b.HashHex)
+		if err != nil || !ok {
+			t.Fatalf("store BlockByHash(%s) = %v, %v", b.HashHex, ok, err)
+		}
+		byHash = append(byHash, got)
+	}
+	return from, byHash
+}
+
+// chainView is what readers of a chain see through its query methods.
+type chainView struct {
+	Height  uint64
+	Tip     string
+	Recent  []StoredBlock
+	From    []StoredBlock
+	ByHash  []StoredBlock
+	TxBlock []uint64
+}
+
+func viewOf(t *testing.T, c *Chain, txIDs []string) chainView {
+	t.Helper()
+	v := chainView{Height: c.Height(), Tip: c.TipHashHex()}
+	v.Recent = c.RecentBlocks(3)
+	v.From = c.BlocksFrom(2, 100)
+	for _, b := range c.BlocksFrom(1, 100) {
+		got, ok := c.GetBlock(b.HashHex)
+		if !ok {
+			t.Fatalf("GetBlock(%s) not found", b.HashHex)
+		}
+		v.ByHash = append(v.ByHash, got)
+	}
+	for _, id := range txIDs {
+		b, ok := c.FindTx(id)
+		if !ok {
+			v.TxBlock = append(v.TxBlock, 0)
+			continue
+		}
+		v.TxBlock = append(v.TxBlock, b.Height)
+	}
+	return v
+}
+
+// produceOn mines n blocks on c, each with one transfer, and returns the
+// txIds.
+func produceOn(t *testing.T, c *Chain, n int) []string {
+	t.Helper()
+	led := newTestLedger(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	if err := led.FaucetCredit(alice.addr, 1_000_000); err != nil {
+		t.Fatal(err)
+	}
+	p := NewBlockProducer(c, led, 0, nil)
+	var ids []string
+	for i := range n {
+		tx := alice.transfer(t, bob.addr, 1_000, 10, uint64(i+1))
+		mine(t, p, tx)
+		ids = append(ids, tx.TxID)
+	}
+	return ids
+}
+
+func TestBlockStoreBackends(t *testing.T) {
+	src := newTestChain(t)
+	ids := produceOn(t, src, 6)
+	want := viewOf(t, src, ids)
+
+	// The same blocks, saved through each backend, read back identically.
+	for _, backend := range blockBackends {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			empty := openChain(t, dir, backend)
+			if empty.Height() != 0 || len(empty.RecentBlocks(10)) != 0 {
+				t.Fatal("empty store loaded blocks")
+			}
+			if err := empty.CloseBlockStore(); err != nil {
+				t.Fatal(err)
+			}
+
+			store := openStore(t, dir, backend)
+			if err := store.Save(src.BlocksFrom(1, 100)); err != nil {
+				t.Fatal(err)
+			}
+
+			// Range reads come straight from the store, without Load.
+			from, byHash := storeView(t, store, src)
+			if !reflect.DeepEqual(from, want.From) || !reflect.DeepEqual(byHash, want.ByHash) {
+				t.Fatalf("store range reads differ:\n from %+v\nwant %+v", from, want.From)
+			}
+			if got, err := store.BlocksFrom(5, 1); err != nil || len(got) != 1 || got[0].Height != 5 {
+				t.Fatalf("BlocksFrom(5, 1) = %+v, %v", got, err)
+			}
+			if got, err := store.BlocksFrom(7, 10); err != nil || len(got) != 0 {
+				t.Fatalf("BlocksFrom past the tip = %+v, %v", got, err)
+			}
+			if _, ok, err := store.BlockByHash(src.GenesisHashHex()); err != nil || ok {
+				t.Fatalf("BlockByHash(genesis) = %v, %v; genesis is not stored", ok, err)
+			}
+			if err := store.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := viewOf(t, openChain(t, dir, backend), ids); !reflect.DeepEqual(got, want) {
+				t.Fatalf("reloaded chain differs:\n got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestBlockStoreBackendsAfterUnwind(t *testing.T) {
+	for _, backend := range blockBackends {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			c := openChain(t, dir, backend)
+			led := newTestLedger(t)
+			alice, bob := newTestKey(t), newTestKey(t)
+			if err := led.FaucetCredit(alice.addr, 1_000_000); err != nil {
+				t.Fatal(err)
+			}
+			p := NewBlockProducer(c, led, 0, nil)
+			var ids []string
+			for i := range 5 {
+				tx := alice.transfer(t, bob.addr, 1_000, 10, uint64(i+1))
+				mine(t, p, tx)
+				ids = append(ids, tx.TxID)
+			}
+			if err := c.SaveBlocks(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The store must follow the chain back, then forward again.
+			unwound := c.BlocksFrom(4, 1)[0].HashHex
+			if _, err := c.UnwindTo(3, led); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.SaveBlocks(); err != nil {
+				t.Fatal(err)
+			}
+			got := reopen(t, c, dir, backend)
+			if got.Height() != 3 || got.TipHashHex() != c.TipHashHex() {
+				t.Fatalf("after unwind: height %d tip %s, want 3 %s", got.Height(), got.TipHashHex(), c.TipHashHex())
+			}
+			if err := got.CloseBlockStore(); err != nil {
+				t.Fatal(err)
+			}
+			store := openStore(t, dir, backend)
+			if _, ok, err := store.BlockByHash(unwound); err != nil || ok {
+				t.Fatalf("unwound block still stored: %v, %v", ok, err)
+			}
+			c.SetBlockStore(store)
+
+			tx := alice.transfer(t, bob.addr, 5_000, 10, 4)
+			mine(t, p, tx)
+			ids = append(ids, tx.TxID)
+			if err := c.SaveBlocks(); err != nil {
+				t.Fatal(err)
+			}
+			want := viewOf(t, c, ids)
+			if got := viewOf(t, reopen(t, c, dir, backend), ids); !reflect.DeepEqual(got, want) {
+				t.Fatalf("reloaded chain differs:\n got %+v\nwant %+v", got, want)
+			}
+			if want.TxBlock[3] != 0 || want.TxBlock[4] != 0 || want.TxBlock[5] != 4 {
+				t.Errorf("tx heights = %v, want the unwound txs gone", want.TxBlock)
+			}
+		})
+	}
+}
+
+func TestBlockStoreSwitchBackend(t *testing.T) {
+	for _, pair := range [][2]string{
+		{BlockBackendJSON, BlockBackendLog},
+		{BlockBackendLog, BlockBackendJSON},
+		{BlockBackendJSON, BlockBackendBolt},
+		{BlockBackendLog, BlockBackendBolt},
+	} {
+		from, to := pair[0], pair[1]
+		t.Run(from+"->"+to, func(t *testing.T) {
+			dir := t.TempDir()
+			c := openChain(t, dir, from)
+			ids := produceOn(t, c, 4)
+			if err := c.SaveBlocks(); err != nil {
+				t.Fatal(err)
+			}
+			want := viewOf(t, c, ids)
+
+			// Each file backend reads the other's file, and its next save
+			// converts it; bolt imports either on its first load.
+			switched := reopen(t, c, dir, to)
+			if got := viewOf(t, switched, ids); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%s read of %s store differs", to, from)
+			}
+			if err := switched.SaveBlocks(); err != nil {
+				t.Fatal(err)
+			}
+			if got := viewOf(t, reopen(t, switched, dir, to), ids); !reflect.DeepEqual(got, want) {
+				t.Fatalf("converted store differs")
+			}
+			if to == BlockBackendBolt {
+				if _, err := os.Stat(filepath.Join(dir, "blocks.json.imported")); err != nil {
+					t.Errorf("imported file not renamed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestBlockStoreBoltWindow keeps two blocks of a bolt chain in memory and
+// expects the rest to be served from the store as if they were.
+func TestBlockStoreBoltWindow(t *testing.T) {
+	dir := t.TempDir()
+	open := func() *Chain {
+		t.Helper()
+		c := New(filepath.Join(dir, "nonces.json"), filepath.Join(dir, "blocks.json"), filepath.Join(dir, "mempool.json"))
+		c.SetBlockStore(openStore(t, dir, BlockBackendBolt))
+		c.window = 2
+		if err := c.LoadBlocks(); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	c, ref := open(), newTestChain(t)
+	led := newTestLedger(t)
+	alice, bob, carol := newTestKey(t), newTestKey(t), newTestKey(t)
+	if err := led.FaucetCredit(alice.addr, 1_000_000); err != nil {
+		t.Fatal(err)
+	}
+	p := NewBlockProducer(c, led, 0, nil)
+	var ids []string
+	for i := range 6 {
+		to := bob.addr
+		if i%2 == 1 {
+			to = carol.addr
+		}
+		txs := []SignedTx{alice.transfer(t, to, 1_000, 10, uint64(i+1))}
+		if i == 1 {
+			// bob's only tx, so his nonce has to come from under the window
+			txs = append(txs, bob.transfer(t, carol.addr, 100, 10, 1))
+		}
+		out := mine(t, p, txs...)
+		ids = append(ids, txIDs(txs)...)
+		if _, err := ref.AddBlock(out.Block.Block); err != nil {
+			t.Fatal(err)
+		}
+		if i%2 == 1 {
+			if err := c.SaveBlocks(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// same compares what c and ref show readers, account history included.
+	same := func(c, ref *Chain) {
+		t.Helper()
+		if got, want := viewOf(t, c, ids), viewOf(t, ref, ids); !reflect.DeepEqual(got, want) {
+			t.Fatalf("windowed chain differs:\n got %+v\nwant %+v", got, want)
+		}
+		if got, want := c.RecentBlocks(25), ref.RecentBlocks(25); !reflect.DeepEqual(got, want) {
+			t.Fatalf("RecentBlocks(25) = %d blocks, want %d", len(got), len(want))
+		}
+		for _, addr := range []string{alice.addr, bob.addr, carol.addr} {
+			for _, page := range [][2]int{{0, 10}, {0, 1}, {1, 2}, {3, 10}, {10, 10}} {
+				got, gotN := c.AccountTxs(addr, page[0], page[1])
+				want, wantN := ref.AccountTxs(addr, page[0], page[1])
+				if gotN != wantN || !reflect.DeepEqual(got, want) {
+					t.Fatalf("AccountTxs(%s, %d, %d) = %d of %d, want %d of %d", addr, page[0], page[1], len(got), gotN, len(want), wantN)
+				}
+			}
+			if got, want := c.ConfirmedNonce(addr), ref.ConfirmedNonce(addr); got != want {
+				t.Fatalf("ConfirmedNonce(%s) = %d, want %d", addr, got, want)
+			}
+		}
+	}
+	if len(c.blocks) != 2 {
+		t.Fatalf("%d blocks in memory, want 2", len(c.blocks))
+	}
+	same(c, ref)
+
+	// A tx settled in a block under the window is still not settled twice.
+	first, _ := c.FindTx(ids[0])
+	replay, err := BuildBlock(c.TipHash(), first.Block.Transactions, nil, c.clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddBlock(replay); !errors.Is(err, ErrTxConfirmed) {
+		t.Fatalf("replaying block 1's tx: %v, want ErrTxConfirmed", err)
+	}
+
+	// Reloading reads only the window back.
+	if err := c.CloseBlockStore(); err != nil {
+		t.Fatal(err)
+	}
+	c = open()
+	if len(c.blocks) != 2 {
+		t.Fatalf("reloaded %d blocks, want 2", len(c.blocks))
+	}
+	same(c, ref)
+
+	// Unwinding under the window refills it from the store.
+	if _, err := c.UnwindTo(2, led); err != nil {
+		t.Fatal(err)
+	}
+	unwound := newTestChain(t)
+	for _, b := range ref.BlocksFrom(1, 2) {
+		if _, err := unwound.AddBlock(b.Block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	same(c, unwound)
+	if err := c.SaveBlocks(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CloseBlockStore(); err != nil {
+		t.Fatal(err)
+	}
+	same(open(), unwound)
+}
@@ -0,0 +1,164 @@
+package blockchain
+
+import "fmt"
+
+// blockWindow is how many of its newest blocks a Chain keeps in memory when
+// its block store can look the older ones up itself (an indexedBlockStore,
+// i.e. the bolt backend). With the file backends the whole chain stays in
+// memory.
+const blockWindow = 1024
+
+// indexedBlockStore is a BlockStore that indexes the txs it stores, so a
+// Chain need not. Lookups take below, the height of the Chain's first block
+// in memory, and see only stored blocks under it: those are saved and still
+// on the chain, while stored blocks at or above it may be stale.
+type indexedBlockStore interface {
+	BlockStore
+	// tail is Load for the newest n blocks only.
+	tail(n int) ([]StoredBlock, error)
+	// txLoc locates a stored tx by txId.
+	txLoc(txID string) (txLoc, bool, error)
+	// accountTxs is Chain.AccountTxs over the stored blocks under below.
+	accountTxs(addr string, below uint64, offset, limit int) ([]AccountTx, int, error)
+	// lastNonce is addr's highest nonce in the stored blocks under below.
+	lastNonce(addr string, below uint64) (uint64, error)
+}
+
+// baseLocked is the height of c.blocks[0], or of the next block if there
+// are none. Every block under it is saved, and on the chain.
+func (c *Chain) baseLocked() uint64 {
+	return c.height + 1 - uint64(len(c.blocks))
+}
+
+// blocksFromLocked is BlocksFrom, reading blocks under the window from the
+// store.
+func (c *Chain) blocksFromLocked(height uint64, limit int) ([]StoredBlock, error) {
+	out := []StoredBlock{}
+	if limit <= 0 {
+		return out, nil
+	}
+	height = max(height, 1)
+	base := c.baseLocked()
+	if height < base && c.indexed != nil {
+		n := int(min(uint64(limit), base-height))
+		got, err := c.indexed.BlocksFrom(height, n)
+		if err != nil {
+			return nil, err
+		}
+		if len(got) != n || got[0].Height != height || got[n-1].Height != height+uint64(n)-1 {
+			return nil, fmt.Errorf("block store is missing blocks between %d and %d", height, height+uint64(n)-1)
+		}
+		out = append(out, got...)
+		limit -= n
+	}
+	if height < base {
+		height = base
+	}
+	if i := height - base; i < uint64(len(c.blocks)) && limit > 0 {
+		end := min(int(i)+limit, len(c.blocks))
+		out = append(out, c.blocks[i:end]...)
+	}
+	return out, nil
+}
+
+// tailLocked returns the blocks from height to the tip. Blocks under the
+// window that the store can no longer read (the damage QuarantineFrom
+// removes) are left out.
+func (c *Chain) tailLocked(height uint64) []StoredBlock {
+	height = max(height, 1)
+	if height > c.height {
+		return nil
+	}
+	if blocks, err := c.blocksFromLocked(height, int(c.height-height+1)); err == nil {
+		return blocks
+	}
+	return append([]StoredBlock(nil), c.blocks...)
+}
+
+// windowAtLocked returns the blocks c.blocks should hold once the chain is
+// unwound to height: the part of the window at or below it, or, if the
+// unwind goes under the window, a new one read from the store.
+func (c *Chain) windowAtLocked(height uint64) ([]StoredBlock, error) {
+	from := c.baseLocked()
+	if height < from {
+		from = 1
+		if c.window > 0 && height > uint64(c.window) {
+			from = height - uint64(c.window) + 1
+		}
+	}
+	if height < from {
+		return []StoredBlock{}, nil
+	}
+	return c.blocksFromLocked(from, int(height-from+1))
+}
+
+// txConfirmedLocked reports whether txID is in a block.
+func (c *Chain) txConfirmedLocked(txID string) (bool, error) {
+	if _, ok := c.txIndex[txID]; ok || c.indexed == nil {
+		return ok, nil
+	}
+	loc, ok, err := c.indexed.txLoc(txID)
+	return ok && loc.height < c.baseLocked(), err
+}
+
+// confirmedNonceLocked is addr's highest nonce in a block. Nonces rise with
+// height, so one in the window is the highest; c.confirmedNonce has only
+// senders with a tx there.
+func (c *Chain) confirmedNonceLocked(addr string) (uint64, error) {
+	if n, ok := c.confirmedNonce[addr]; ok || c.indexed == nil {
+		return n, nil
+	}
+	return c.indexed.lastNonce(addr, c.baseLocked())
+}
+
+// resetWindowLocked makes blocks the chain's window and its tip the last of
+// them, or genesis if there are none, rebuilding the in-memory indexes.
+func (c *Chain) resetWindowLocked(blocks []StoredBlock) {
+	c.blocks = blocks
+	c.blocksByHash = make(map[string]StoredBlock, len(blocks))
+	c.txIndex = make(map[string]uint64)
+	c.confirmedNonce = make(map[string]uint64)
+	c.accountTxs = make(map[string][]txLoc)
+	for _, b := range blocks {
+		c.blocksByHash[b.HashHex] = b
+		c.indexTxsLocked(b)
+	}
+
+	if len(blocks) == 0 {
+		c.height = 0
+		c.tipHash = c.genesis.Header.Hash()
+	} else {
+		last := blocks[len(blocks)-1]
+		c.height = last.Height
+		c.tipHash = last.Block.Header.Hash()
+	}
+}
+
+// trimWindowLocked drops blocks beyond the window from the front of
+// c.blocks, with their index entries, once saved (the prefix of c.blocks
+// that is also in saved).
+func (c *Chain) trimWindowLocked(saved []StoredBlock) {
+	cut := 0
+	for cut < len(c.blocks)-c.window && cut < len(saved) &&
+		c.blocks[cut].Height == saved[cut].Height && c.blocks[cut].HashHex == saved[cut].HashHex {
+		cut++
+	}
+	if cut == 0 {
+		return
+	}
+	for _, b := range c.blocks[:cut] {
+		delete(c.blocksByHash, b.HashHex)
+		for _, tx := range b.Block.Transactions {
+			d := tx.Draft
+			delete(c.txIndex, tx.TxID)
+			if c.confirmedNonce[d.From] == d.Nonce {
+				delete(c.confirmedNonce, d.From)
+			}
+			c.dropAccountTxsLocked(d.From, b.Height)
+			for _, to := range d.Recipients() {
+				c.dropAccountTxsLocked(to, b.Height)
+			}
+		}
+	}
+	c.blocks = append([]StoredBlock(nil), c.blocks[cut:]...)
+}
@@ -0,0 +1,83 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
+)
+
+// Coinbase pays a block's producer. It is not a tx and carries no
+// signature: when the block is applied, To is credited Reward plus the fees
+// of the block's txs that applied, so fees move to the producer instead of
+// leaving the supply. A block without one burns its fees, as before.
+//
+// The coinbase is committed to by the header: its ID is the block's first
+// merkle leaf, ahead of the txIds.
+type Coinbase struct {
+	To     string `json:"to"`
+	Reward uint64 `json:"reward"`
+}
+
+// ErrCoinbaseReward is returned for a block claiming more than the node's
+// ChainParams.BlockReward.
+var ErrCoinbaseReward = errors.New("coinbase reward exceeds block reward")
+
+// coinbaseDomain prefixes the bytes hashed into a coinbase ID so it can
+// never equal a txId (the hash of a JSON draft).
+const coinbaseDomain = "veltaros-coinbase"
+
+// ID is the coinbase's merkle leaf as hex:
+// doubleSha256(coinbaseDomain || [1] len(To) || To || [8] Reward LE).
+func (cb Coinbase) ID() string {
+	buf := make([]byte, 0, len(coinbaseDomain)+1+len(cb.To)+8)
+	buf = append(buf, coinbaseDomain...)
+	buf = append(buf, byte(len(cb.To)))
+	buf = append(buf, cb.To...)
+	buf = binary.LittleEndian.AppendUint64(buf, cb.Reward)
+	h := vcrypto.DoubleSha256(buf)
+	return hex.EncodeToString(h[:])
+}
+
+func (cb Coinbase) validate() error {
+	if err := ValidateAddress(cb.To); err != nil {
+		return fmt.Errorf("coinbase: %w", err)
+	}
+	return nil
+}
+
+// merkleLeaves lists what b's MerkleRoot covers: the coinbase ID, if any,
+// then every txId in order.
+func (b *Block) merkleLeaves() []string {
+	leaves := make([]string, 0, len(b.Transactions)+1)
+	if b.Coinbase != nil {
+		leaves = append(leaves, b.Coinbase.ID())
+	}
+	for _, tx := range b.Transactions {
+		leaves = append(leaves, tx.TxID)
+	}
+	return leaves
+}
+
+// settleCoinbase credits cb.To with cb.Reward plus fees, the fees of the
+// block's txs that applied, and returns the amount credited. A nil cb or a
+// zero amount credits nothing.
+func settleCoinbase(led LedgerApplier, height uint64, cb *Coinbase, fees uint64) (uint64, error) {
+	if cb == nil {
+		return 0, nil
+	}
+	amount, carry := bits.Add64(cb.Reward, fees, 0)
+	if carry != 0 {
+		return 0, errors.New("coinbase amount overflows uint64")
+	}
+	if amount == 0 {
+		return 0, nil
+	}
+	if err := led.ApplyCoinbaseAt(height, cb.To, amount); err != nil {
+		return 0, err
+	}
+	return amount, nil
+}
@@ -0,0 +1,92 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/ledger"
+)
+
+func totalSupply(t *testing.T, led *ledger.Ledger) uint64 {
+	t.Helper()
+	s, err := led.TotalSupply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCoinbaseConservesSupply(t *testing.T) {
+	for _, reward := range []uint64{0, 50} {
+		c := newTestChain(t)
+		params := DefaultParams()
+		params.BlockReward = reward
+		c.SetParams(params)
+		led := newTestLedger(t)
+		alice, bob, miner := newTestKey(t), newTestKey(t), newTestKey(t)
+		if err := led.FaucetCredit(alice.addr, 10_000); err != nil {
+			t.Fatal(err)
+		}
+		p := NewBlockProducer(c, led, 0, nil)
+		p.SetRewardAddress(miner.addr)
+
+		before := totalSupply(t, led)
+		out := mine(t, p,
+			alice.transfer(t, bob.addr, 1_000, 10, 1),
+			alice.transfer(t, bob.addr, 2_000, 25, 2),
+		)
+		if out.Reward != reward+35 {
+			t.Errorf("reward %d: credited %d, want %d", reward, out.Reward, reward+35)
+		}
+		if got := led.ConfirmedBalance(miner.addr); got != reward+35 {
+			t.Errorf("reward %d: miner = %d, want %d", reward, got, reward+35)
+		}
+		// Fees move to the producer; only the block reward is new money.
+		if got := totalSupply(t, led); got != before+reward {
+			t.Errorf("reward %d: supply %d -> %d, want +%d", reward, before, got, reward)
+		}
+
+		// An empty block mints just the reward.
+		mine(t, p)
+		if got := totalSupply(t, led); got != before+2*reward {
+			t.Errorf("reward %d: supply after empty block = %d, want %d", reward, got, before+2*reward)
+		}
+	}
+}
+
+func TestNoCoinbaseBurnsFees(t *testing.T) {
+	c := newTestChain(t)
+	led := newTestLedger(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	if err := led.FaucetCredit(alice.addr, 10_000); err != nil {
+		t.Fatal(err)
+	}
+	p := NewBlockProducer(c, led, 0, nil)
+
+	before := totalSupply(t, led)
+	mine(t, p, alice.transfer(t, bob.addr, 1_000, 10, 1))
+	if got := totalSupply(t, led); got != before-10 {
+		t.Errorf("supply %d -> %d, want the fee burned", before, got)
+	}
+}
+
+func TestAcceptBlockRejectsExcessReward(t *testing.T) {
+	c := newTestChain(t)
+	params := DefaultParams()
+	params.BlockReward = 50
+	c.SetParams(params)
+	led := newTestLedger(t)
+	miner := newTestKey(t)
+
+	blk, err := BuildBlock(c.TipHash(), nil, &Coinbase{To: miner.addr, Reward: 51}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AcceptBlock(blk, led); !errors.Is(err, ErrCoinbaseReward) {
+		t.Fatalf("err = %v, want ErrCoinbaseReward", err)
+	}
+	if c.Height() != 0 || totalSupply(t, led) != 0 {
+		t.Error("rejected block changed the chain or the supply")
+	}
+}
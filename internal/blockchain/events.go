@@ -0,0 +1,42 @@
+package blockchain
+
+import "github.com/VeltarosLabs/Veltaros/internal/events"
+
+// SetEvents makes the chain publish events.TxAdded for txs entering the
+// mempool (not those restored at startup) and events.BlockAdded for each
+// linked block. Call it at startup.
+func (c *Chain) SetEvents(bus *events.Bus) {
+	c.mu.Lock()
+	c.events = bus
+	c.mu.Unlock()
+}
+
+func txAddedEvent(e MempoolEntry, replaces string) map[string]any {
+	ev := map[string]any{
+		"txId":      e.Tx.TxID,
+		"from":      e.Tx.Draft.From,
+		"to":        e.Tx.Draft.To,
+		"amount":    e.Tx.Draft.Amount,
+		"fee":       e.Tx.Draft.Fee,
+		"nonce":     e.Tx.Draft.Nonce,
+		"sizeBytes": e.SizeBytes,
+		"source":    e.Source.String(),
+	}
+	if e.Tx.Draft.IsMulti() {
+		ev["outputs"] = e.Tx.Draft.Outputs
+	}
+	if replaces != "" {
+		ev["replaces"] = replaces
+	}
+	return ev
+}
+
+func blockAddedEvent(sb StoredBlock) map[string]any {
+	return map[string]any{
+		"height":    sb.Height,
+		"hash":      sb.HashHex,
+		"prevHash":  sb.PrevHashHex,
+		"timestamp": sb.Timestamp,
+		"txCount":   sb.TxCount,
+	}
+}
@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnfundedTx is returned for a block holding a tx its sender's confirmed
+// balance (less stake) cannot cover once the block's earlier txs are
+// applied. Such a tx would be linked without moving any funds.
+var ErrUnfundedTx = errors.New("tx not covered by sender's confirmed balance")
+
+// FundsReader is the part of the ledger a funds dry run reads.
+type FundsReader interface {
+	ConfirmedBalance(addr string) uint64
+	StakedBalance(addr string) uint64
+}
+
+// fundsCheck dry-runs txs, in block order, against confirmed balances with
+// the same debit and credit rules the ledger applies, so a tx that would fail
+// to settle is found before its block is built or linked. Nothing is changed
+// on the ledger.
+type fundsCheck struct {
+	led     FundsReader
+	balance map[string]uint64 // addr -> confirmed balance after txs so far
+}
+
+func newFundsCheck(led FundsReader) *fundsCheck {
+	return &fundsCheck{led: led, balance: make(map[string]uint64)}
+}
+
+func (f *fundsCheck) confirmed(addr string) uint64 {
+	if b, ok := f.balance[addr]; ok {
+		return b
+	}
+	b := f.led.ConfirmedBalance(addr)
+	f.balance[addr] = b
+	return b
+}
+
+// apply debits and credits tx if its sender can pay for it, or returns
+// ErrUnfundedTx and leaves the running balances as they were.
+func (f *fundsCheck) apply(tx SignedTx) error {
+	d := tx.Draft
+	spend := d.SpendAmount()
+	have := f.confirmed(d.From)
+	if have < spend || have-spend < f.led.StakedBalance(d.From) {
+		return fmt.Errorf("%w: %s spends %d of %d (staked %d)", ErrUnfundedTx, tx.TxID, spend, have, f.led.StakedBalance(d.From))
+	}
+	f.balance[d.From] = have - spend
+	switch {
+	case d.IsMulti():
+		for _, o := range d.Outputs {
+			f.balance[o.To] = f.confirmed(o.To) + o.Amount
+		}
+	case !d.IsCancel():
+		f.balance[d.To] = f.confirmed(d.To) + d.Amount - d.Fee
+	}
+	return nil
+}
+
+// checkFunds dry-runs txs in order and returns the first that would not
+// settle.
+func checkFunds(led FundsReader, txs []SignedTx) error {
+	f := newFundsCheck(led)
+	for _, tx := range txs {
+		if err := f.apply(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
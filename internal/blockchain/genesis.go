@@ -0,0 +1,123 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
+)
+
+// ErrGenesisMismatch is returned by LoadBlocks when the stored chain was
+// built on a different genesis than the one configured, i.e. it belongs to
+// another network.
+var ErrGenesisMismatch = errors.New("stored chain does not build on the configured genesis")
+
+// GenesisConfig is a network's starting state, read from a JSON file. Every
+// node given the same file derives the same genesis block (see Block).
+type GenesisConfig struct {
+	NetworkID   string            `json:"networkID"`
+	Timestamp   int64             `json:"timestamp"`
+	Allocations map[string]uint64 `json:"allocations"` // address -> balance
+}
+
+// LoadGenesisConfig reads and validates a genesis file. Unknown fields are
+// rejected, since a misspelt key would silently yield a different network.
+func LoadGenesisConfig(path string) (GenesisConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return GenesisConfig{}, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var g GenesisConfig
+	if err := dec.Decode(&g); err != nil {
+		return GenesisConfig{}, fmt.Errorf("genesis %s: %w", path, err)
+	}
+	if err := g.Validate(); err != nil {
+		return GenesisConfig{}, fmt.Errorf("genesis %s: %w", path, err)
+	}
+	return g, nil
+}
+
+func (g GenesisConfig) Validate() error {
+	if g.NetworkID == "" {
+		return errors.New("networkID required")
+	}
+	if g.Timestamp <= 0 {
+		return errors.New("timestamp must be > 0")
+	}
+	var total uint64
+	for addr, bal := range g.Allocations {
+		if err := ValidateAddress(addr); err != nil {
+			return fmt.Errorf("allocation %q: %w", addr, err)
+		}
+		if bal == 0 {
+			return fmt.Errorf("allocation %q: balance must be > 0", addr)
+		}
+		if total+bal < total {
+			return errors.New("allocations overflow total supply")
+		}
+		total += bal
+	}
+	return nil
+}
+
+// AllocationRoot commits to the network ID and allocations:
+// doubleSha256("veltaros-genesis-v1" || [4] len + networkID ||
+// per address in ascending order: [4] len + address + [8] balance),
+// integers little-endian. It stands in for the genesis merkle root.
+func (g GenesisConfig) AllocationRoot() [32]byte {
+	addrs := make([]string, 0, len(g.Allocations))
+	for addr := range g.Allocations {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	buf := []byte("veltaros-genesis-v1")
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(g.NetworkID)))
+	buf = append(buf, g.NetworkID...)
+	for _, addr := range addrs {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(addr)))
+		buf = append(buf, addr...)
+		buf = binary.LittleEndian.AppendUint64(buf, g.Allocations[addr])
+	}
+	return vcrypto.DoubleSha256(buf)
+}
+
+// Block returns the genesis block for g. It carries no txs; its MerkleRoot
+// is AllocationRoot, so the genesis hash commits to the whole config.
+func (g GenesisConfig) Block() Block {
+	return Block{
+		Header: BlockHeader{
+			Version:    1,
+			MerkleRoot: g.AllocationRoot(),
+			Timestamp:  g.Timestamp,
+		},
+		Transactions: []SignedTx{},
+	}
+}
+
+// SetGenesis replaces the built-in genesis (NewGenesisBlock). Call it at
+// startup, before LoadBlocks, which then checks the stored chain against it.
+func (c *Chain) SetGenesis(g Block) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.genesis = g
+	if c.height == 0 {
+		c.tipHash = g.Header.Hash()
+	}
+}
+
+// GenesisHashHex is the hex header hash of the chain's genesis block.
+func (c *Chain) GenesisHashHex() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	h := c.genesis.Header.Hash()
+	return hex.EncodeToString(h[:])
+}
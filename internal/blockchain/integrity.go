@@ -0,0 +1,153 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// IntegrityReport describes the result of a block store integrity pass.
+type IntegrityReport struct {
+	Checked   int    `json:"checked"`
+	OK        bool   `json:"ok"`
+	BadHeight uint64 `json:"badHeight,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// CheckIntegrity reads the block store back through its backend, batch
+// blocks at a time, verifying heights, header hashes, prev-hash linkage,
+// merkle roots, and txIds, so damage to what is on disk is found even though
+// the blocks were validated when they were added. A block that cannot be read
+// counts as corrupt at its height. It does not hold the chain lock, yields
+// between batches, and stops early if ctx is cancelled (the only error
+// returned). Blocks not yet saved are not checked; signatures are not
+// re-verified.
+func (c *Chain) CheckIntegrity(ctx context.Context, batch int) (IntegrityReport, error) {
+	if batch <= 0 {
+		batch = 256
+	}
+
+	c.mu.RLock()
+	store := c.blockStore
+	c.mu.RUnlock()
+
+	prev := c.genesis.Header.Hash()
+	var prevHeight uint64
+
+	rep := IntegrityReport{OK: true}
+	for {
+		select {
+		case <-ctx.Done():
+			return rep, ctx.Err()
+		default:
+		}
+
+		blocks, err := store.BlocksFrom(prevHeight+1, batch)
+		if err != nil {
+			rep.OK = false
+			rep.BadHeight, rep.Reason = firstUnreadable(store, prevHeight+1, batch, err)
+			return rep, nil
+		}
+		if len(blocks) == 0 {
+			return rep, nil
+		}
+		for _, sb := range blocks {
+			if err := verifyStoredBlock(sb, prevHeight+1, prev); err != nil {
+				rep.OK = false
+				rep.BadHeight = prevHeight + 1
+				rep.Reason = err.Error()
+				return rep, nil
+			}
+			prev = sb.Block.Header.Hash()
+			prevHeight = sb.Height
+			rep.Checked++
+		}
+	}
+}
+
+// firstUnreadable narrows a failed batch read of up to n blocks from height
+// down to the first block that cannot be read on its own. If none fails
+// alone (the whole file is unreadable, say) it blames height.
+func firstUnreadable(store BlockStore, height uint64, n int, batchErr error) (uint64, string) {
+	for h := height; h < height+uint64(n); h++ {
+		got, err := store.BlocksFrom(h, 1)
+		if err != nil {
+			return h, fmt.Sprintf("unreadable block at height %d: %v", h, err)
+		}
+		if len(got) == 0 {
+			break
+		}
+	}
+	return height, fmt.Sprintf("unreadable block store from height %d: %v", height, batchErr)
+}
+
+func verifyStoredBlock(sb StoredBlock, wantHeight uint64, wantPrev [32]byte) error {
+	if sb.Height != wantHeight {
+		return fmt.Errorf("height gap: got %d want %d", sb.Height, wantHeight)
+	}
+	h := sb.Block.Header.Hash()
+	if hex.EncodeToString(h[:]) != sb.HashHex {
+		return fmt.Errorf("header hash mismatch at height %d", sb.Height)
+	}
+	if sb.Block.Header.PrevHash != wantPrev {
+		return fmt.Errorf("prev hash does not link at height %d", sb.Height)
+	}
+
+	for _, tx := range sb.Block.Transactions {
+		th, err := TxHash(tx.Draft)
+		if err != nil {
+			return err
+		}
+		if hex.EncodeToString(th[:]) != tx.TxID {
+			return fmt.Errorf("txId mismatch at height %d", sb.Height)
+		}
+	}
+	root, err := MerkleRootFromTxIDs(sb.Block.merkleLeaves())
+	if err != nil {
+		return err
+	}
+	if root != sb.Block.Header.MerkleRoot {
+		return fmt.Errorf("merkle root mismatch at height %d", sb.Height)
+	}
+	return nil
+}
+
+// QuarantineFrom removes blocks at and above height from the chain, writes them
+// next to the block store as "<store>.quarantine-<unix>.json", and unwinds
+// the chain and led to the last good block through UnwindTo. If the ledger
+// cannot be reverted that far (e.g. its journal is too shallow) nothing is
+// removed and the error says why. The removed blocks are returned, oldest
+// first, so the caller can return their txs to the mempool.
+func (c *Chain) QuarantineFrom(height uint64, led LedgerReverter) ([]StoredBlock, error) {
+	c.settleMu.Lock()
+	defer c.settleMu.Unlock()
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	height = max(height, 1)
+	c.mu.RLock()
+	tail := c.tailLocked(height)
+	c.mu.RUnlock()
+	if len(tail) == 0 {
+		return nil, nil
+	}
+
+	qpath := fmt.Sprintf("%s.quarantine-%d.json", filepath.Clean(c.blockStorePath), c.clock.Now().Unix())
+	data, err := json.MarshalIndent(tail, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(qpath, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	removed, err := c.unwindSettled(height-1, led)
+	if err != nil {
+		_ = os.Remove(qpath)
+		return nil, err
+	}
+	return removed, nil
+}
@@ -0,0 +1,180 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VeltarosLabs/Veltaros/internal/ledger"
+	bolt "go.etcd.io/bbolt"
+)
+
+// TestCheckIntegrityReadsStore damages block 2 on disk, after it was added
+// and saved intact, and expects each backend's check to find it there.
+func TestCheckIntegrityReadsStore(t *testing.T) {
+	// retime rewrites block 2's header timestamp, leaving its stored hash.
+	retime := func(t *testing.T, raw []byte) []byte {
+		t.Helper()
+		var b StoredBlock
+		if err := json.Unmarshal(raw, &b); err != nil {
+			t.Fatal(err)
+		}
+		b.Block.Header.Timestamp++
+		out, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	damage := map[string]func(t *testing.T, c *Chain, path string){
+		BlockBackendJSON: func(t *testing.T, _ *Chain, path string) {
+			var blocks []json.RawMessage
+			raw, err := os.ReadFile(path)
+			if err == nil {
+				err = json.Unmarshal(raw, &blocks)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			blocks[1] = retime(t, blocks[1])
+			if raw, err = json.Marshal(blocks); err == nil {
+				err = os.WriteFile(path, raw, 0o600)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		},
+		BlockBackendLog: func(t *testing.T, _ *Chain, path string) {
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := bytes.Split(bytes.TrimSuffix(raw, []byte("\n")), []byte("\n"))
+			lines[1] = retime(t, lines[1])
+			if err := os.WriteFile(path, append(bytes.Join(lines, []byte("\n")), '\n'), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		},
+		BlockBackendBolt: func(t *testing.T, c *Chain, _ string) {
+			db := c.blockStore.(*BoltBlockStore).db
+			err := db.Update(func(tx *bolt.Tx) error {
+				bb := tx.Bucket(boltBlocks)
+				return bb.Put(heightKey(2), retime(t, bb.Get(heightKey(2))))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		},
+	}
+
+	for backend, damage := range damage {
+		t.Run(backend, func(t *testing.T) {
+			c := newTestChain(t)
+			store, err := OpenBlockStore(backend, c.blockStorePath, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetBlockStore(store)
+			defer c.CloseBlockStore()
+			led := newTestLedger(t)
+			alice, bob := newTestKey(t), newTestKey(t)
+			if err := led.FaucetCredit(alice.addr, 10_000); err != nil {
+				t.Fatal(err)
+			}
+			p := NewBlockProducer(c, led, 0, nil)
+			for n := range uint64(3) {
+				mine(t, p, alice.transfer(t, bob.addr, 100, 10, n+1))
+			}
+			if err := c.SaveBlocks(); err != nil {
+				t.Fatal(err)
+			}
+
+			rep, err := c.CheckIntegrity(context.Background(), 2)
+			if err != nil || !rep.OK || rep.Checked != 3 {
+				t.Fatalf("intact store: %+v, %v", rep, err)
+			}
+
+			damage(t, c, c.blockStorePath)
+			rep, err = c.CheckIntegrity(context.Background(), 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rep.OK || rep.BadHeight != 2 || rep.Checked != 1 {
+				t.Errorf("damaged store: %+v, want block 2 reported after 1 checked", rep)
+			}
+		})
+	}
+}
+
+func TestQuarantineFromRevertsLedger(t *testing.T) {
+	c := newTestChain(t)
+	led := newTestLedger(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	if err := led.FaucetCredit(alice.addr, 10_000); err != nil {
+		t.Fatal(err)
+	}
+	p := NewBlockProducer(c, led, 0, nil)
+
+	mine(t, p, alice.transfer(t, bob.addr, 1_000, 10, 1))
+	late := alice.transfer(t, bob.addr, 2_000, 10, 2)
+	mine(t, p, late)
+
+	removed, err := c.QuarantineFrom(2, led)
+	if err != nil {
+		t.Fatalf("quarantine: %v", err)
+	}
+	if c.Height() != 1 {
+		t.Fatalf("height = %d, want 1", c.Height())
+	}
+	if len(removed) != 1 || removed[0].Block.Transactions[0].TxID != late.TxID {
+		t.Fatalf("removed = %+v, want the block holding %s", removed, late.TxID)
+	}
+	if got := led.ConfirmedBalance(alice.addr); got != 9_000 {
+		t.Errorf("alice = %d, want 9000", got)
+	}
+	if got := led.ConfirmedBalance(bob.addr); got != 990 {
+		t.Errorf("bob = %d, want 990", got)
+	}
+	if _, found := c.FindTx(late.TxID); found {
+		t.Error("quarantined tx still indexed")
+	}
+}
+
+func TestQuarantineFromRefusesShallowJournal(t *testing.T) {
+	dir := t.TempDir()
+	c := New(
+		filepath.Join(dir, "nonces.json"),
+		filepath.Join(dir, "blocks.json"),
+		filepath.Join(dir, "mempool.json"),
+	)
+	led := newTestLedger(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	if err := led.FaucetCredit(alice.addr, 10_000); err != nil {
+		t.Fatal(err)
+	}
+	p := NewBlockProducer(c, led, 0, nil)
+	mine(t, p, alice.transfer(t, bob.addr, 1_000, 10, 1))
+	mine(t, p, alice.transfer(t, bob.addr, 2_000, 10, 2))
+
+	// As after a restart at height 2: the journal starts there.
+	led.ResetJournal(2)
+
+	_, err := c.QuarantineFrom(2, led)
+	if !errors.Is(err, ledger.ErrJournalTooShallow) {
+		t.Fatalf("err = %v, want ErrJournalTooShallow", err)
+	}
+	if c.Height() != 2 {
+		t.Errorf("height = %d, want the chain left at 2", c.Height())
+	}
+	if got := led.ConfirmedBalance(bob.addr); got != 990+1_990 {
+		t.Errorf("bob = %d, want the ledger left at 2980", got)
+	}
+	left, _ := filepath.Glob(filepath.Join(dir, "blocks.json.quarantine-*"))
+	if len(left) != 0 {
+		t.Errorf("quarantine file left behind: %v", left)
+	}
+}
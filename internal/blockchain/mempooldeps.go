@@ -0,0 +1,164 @@
+package blockchain
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// Pending txs from one sender form a nonce chain: nonce N+1 can only be
+// included after N. A tx is "ready" when every nonce between the sender's
+// confirmed nonce and its own is filled by a pending tx.
+
+// ConfirmedNonce returns the highest nonce from addr included in a block.
+// It is 0 if the block store cannot be read; AddBlock fails instead.
+func (c *Chain) ConfirmedNonce(addr string) uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	n, _ := c.confirmedNonceLocked(addr)
+	return n
+}
+
+// NonceState is a sender's nonce usage: the highest nonce included in a
+// block, the nonces reserved by its pending txs (ascending), and the nonce
+// the node will accept next.
+type NonceState struct {
+	LastConfirmed uint64
+	ExpectedNext  uint64
+	Reserved      []uint64
+}
+
+// NonceState reports addr's confirmed and reserved nonces in one consistent
+// view.
+func (c *Chain) NonceState(addr string) NonceState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	confirmed, _ := c.confirmedNonceLocked(addr)
+	st := NonceState{
+		LastConfirmed: confirmed,
+		ExpectedNext:  c.nonces.ExpectedNext(addr),
+		Reserved:      make([]uint64, 0, len(c.bySender[addr])),
+	}
+	for n := range c.bySender[addr] {
+		st.Reserved = append(st.Reserved, n)
+	}
+	sort.Slice(st.Reserved, func(i, j int) bool { return st.Reserved[i] < st.Reserved[j] })
+	return st
+}
+
+// MempoolReady returns the txs that can be included now, in inclusion order:
+// a sender's txs appear in nonce order with no gaps, starting right after its
+// confirmed nonce, and the next tx is picked from the senders' heads by the
+// usual priority (class if honored, then fee). maxTxs <= 0 means no limit.
+func (c *Chain) MempoolReady(maxTxs int) []SignedTx {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.readyLocked(maxTxs, nil)
+}
+
+// readyLocked walks the ready set in inclusion order. If fits rejects a tx,
+// the rest of that sender's chain is skipped since it depends on it.
+func (c *Chain) readyLocked(maxTxs int, fits func(SignedTx) bool) []SignedTx {
+	h := &readyHeap{honorClass: c.params.HonorTxClass}
+	for from, txs := range c.chainsLocked() {
+		confirmed, _ := c.confirmedNonceLocked(from)
+		next := confirmed + 1
+		run := 0
+		for run < len(txs) && txs[run].Draft.Nonce == next {
+			run++
+			next++
+		}
+		if run > 0 {
+			h.queues = append(h.queues, txs[:run])
+		}
+	}
+	heap.Init(h)
+
+	out := make([]SignedTx, 0, len(c.mempool))
+	for h.Len() > 0 && (maxTxs <= 0 || len(out) < maxTxs) {
+		q := h.queues[0]
+		if fits != nil && !fits(q[0]) {
+			heap.Pop(h)
+			continue
+		}
+		out = append(out, q[0])
+		if len(q) == 1 {
+			heap.Pop(h)
+		} else {
+			h.queues[0] = q[1:]
+			heap.Fix(h, 0)
+		}
+	}
+	return out
+}
+
+// chainsLocked groups pending txs by sender, each in ascending nonce order.
+func (c *Chain) chainsLocked() map[string][]SignedTx {
+	out := make(map[string][]SignedTx)
+	for _, e := range c.mempool {
+		out[e.Tx.Draft.From] = append(out[e.Tx.Draft.From], e.Tx)
+	}
+	for _, txs := range out {
+		sort.Slice(txs, func(i, j int) bool { return txs[i].Draft.Nonce < txs[j].Draft.Nonce })
+	}
+	return out
+}
+
+// MempoolEvict removes txID and its dependents (see MempoolEvictFrom). It
+// returns nil if txID is not pending.
+func (c *Chain) MempoolEvict(txID string) []SignedTx {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.mempool[txID]
+	if !ok {
+		return nil
+	}
+	return c.evictFromLocked(e.Tx.Draft.From, e.Tx.Draft.Nonce)
+}
+
+// MempoolEvictFrom removes every pending tx from addr with nonce >= nonce:
+// once nonce is gone, none of them can be next. The sender's nonce
+// reservation is rolled back to the highest nonce still confirmed or pending,
+// so the gap can be refilled. The removed txs are returned so the caller can
+// release their staged spends.
+func (c *Chain) MempoolEvictFrom(addr string, nonce uint64) []SignedTx {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictFromLocked(addr, nonce)
+}
+
+func (c *Chain) evictFromLocked(addr string, nonce uint64) []SignedTx {
+	var out []SignedTx
+	floor, _ := c.confirmedNonceLocked(addr)
+	for n, id := range c.bySender[addr] {
+		if n >= nonce {
+			out = append(out, c.mempool[id].Tx)
+			c.mempoolDeleteLocked(id)
+		} else if n > floor {
+			floor = n
+		}
+	}
+	c.nonces.Rollback(addr, floor)
+	sort.Slice(out, func(i, j int) bool { return out[i].Draft.Nonce < out[j].Draft.Nonce })
+	return out
+}
+
+// readyHeap orders per-sender queues by the priority of their head tx.
+type readyHeap struct {
+	queues     [][]SignedTx
+	honorClass bool
+}
+
+func (h *readyHeap) Len() int { return len(h.queues) }
+func (h *readyHeap) Less(i, j int) bool {
+	return mempoolLess(h.queues[i][0], h.queues[j][0], h.honorClass)
+}
+func (h *readyHeap) Swap(i, j int) { h.queues[i], h.queues[j] = h.queues[j], h.queues[i] }
+func (h *readyHeap) Push(x any)    { h.queues = append(h.queues, x.([]SignedTx)) }
+func (h *readyHeap) Pop() any {
+	old := h.queues
+	q := old[len(old)-1]
+	h.queues = old[:len(old)-1]
+	return q
+}
@@ -0,0 +1,219 @@
+package blockchain
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// admit reserves each tx's nonce and adds it to the mempool, as the node's
+// admission path does.
+func admit(t *testing.T, c *Chain, txs ...SignedTx) {
+	t.Helper()
+	for _, tx := range txs {
+		if !c.ReserveNonce(tx.Draft.From, tx.Draft.Nonce) {
+			t.Fatalf("nonce %d of %s rejected", tx.Draft.Nonce, tx.TxID)
+		}
+		if err := c.MempoolAdd(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func txIDs(txs []SignedTx) []string {
+	ids := make([]string, len(txs))
+	for i, tx := range txs {
+		ids[i] = tx.TxID
+	}
+	return ids
+}
+
+func TestMempoolEvictCascades(t *testing.T) {
+	c := newTestChain(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	a1 := alice.transfer(t, bob.addr, 100, 10, 1)
+	a2 := alice.transfer(t, bob.addr, 100, 10, 2)
+	a3 := alice.transfer(t, bob.addr, 100, 10, 3)
+	b1 := bob.transfer(t, alice.addr, 100, 10, 1)
+	admit(t, c, a1, a2, a3, b1)
+
+	evicted := c.MempoolEvict(a2.TxID)
+	if want := txIDs([]SignedTx{a2, a3}); !slices.Equal(txIDs(evicted), want) {
+		t.Fatalf("evicted %v, want %v", txIDs(evicted), want)
+	}
+	if !c.MempoolHas(a1.TxID) || !c.MempoolHas(b1.TxID) {
+		t.Error("eviction removed txs it does not depend on")
+	}
+	// The reservation rolls back so the gap can be refilled.
+	if got := c.ExpectedNonce(alice.addr); got != 2 {
+		t.Errorf("expected nonce = %d, want 2", got)
+	}
+	if !c.ReserveNonce(alice.addr, 2) {
+		t.Error("freed nonce 2 cannot be reserved again")
+	}
+}
+
+func TestEvictExpiredCascades(t *testing.T) {
+	c := newTestChain(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	now := time.Now()
+	a1 := alice.transferAt(t, now, bob.addr, 100, 10, 1)
+	a2 := alice.transferAt(t, now.Add(-time.Hour), bob.addr, 100, 10, 2) // expired
+	a3 := alice.transferAt(t, now, bob.addr, 100, 10, 3)
+	admit(t, c, a1, a2, a3)
+
+	evicted := c.EvictExpired(30 * time.Minute)
+	if len(evicted) != 2 || c.MempoolHas(a2.TxID) || c.MempoolHas(a3.TxID) {
+		t.Fatalf("evicted %v, want nonces 2 and 3", txIDs(evicted))
+	}
+	if !c.MempoolHas(a1.TxID) {
+		t.Error("nonce 1 evicted with its dependents")
+	}
+}
+
+func TestMempoolReadyOrder(t *testing.T) {
+	c := newTestChain(t)
+	alice, bob, carol, dave := newTestKey(t), newTestKey(t), newTestKey(t), newTestKey(t)
+	// alice's later txs pay more, but must still follow nonce 1.
+	a1 := alice.transfer(t, dave.addr, 100, 10, 1)
+	a2 := alice.transfer(t, dave.addr, 100, 90, 2)
+	a3 := alice.transfer(t, dave.addr, 100, 80, 3)
+	b1 := bob.transfer(t, dave.addr, 100, 50, 1)
+	// carol's nonce 1 is missing, so no carol tx is ready.
+	c2 := carol.transfer(t, dave.addr, 100, 99, 2)
+	admit(t, c, a1, a2, a3, b1)
+	if err := c.MempoolAdd(c2); err != nil {
+		t.Fatal(err)
+	}
+
+	want := txIDs([]SignedTx{b1, a1, a2, a3})
+	if got := txIDs(c.MempoolReady(0)); !slices.Equal(got, want) {
+		t.Fatalf("ready = %v, want %v", got, want)
+	}
+	if got := txIDs(c.MempoolReady(2)); !slices.Equal(got, want[:2]) {
+		t.Errorf("ready(2) = %v, want %v", got, want[:2])
+	}
+
+	// Evicting alice's head takes the rest of that chain with it.
+	c.MempoolEvict(a1.TxID)
+	if got := txIDs(c.MempoolReady(0)); !slices.Equal(got, want[:1]) {
+		t.Errorf("ready after evicting a1 = %v, want %v", got, want[:1])
+	}
+}
+
+func TestSenderIndexFollowsMempool(t *testing.T) {
+	c := newTestChain(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	a1 := alice.transfer(t, bob.addr, 100, 10, 1)
+	a2 := alice.transfer(t, bob.addr, 100, 10, 2)
+	a3 := alice.transfer(t, bob.addr, 100, 10, 3)
+	admit(t, c, a1, a2, a3)
+
+	// A replacement takes over its nonce's slot.
+	r2 := alice.transfer(t, bob.addr, 100, 20, 2)
+	if err := c.MempoolReplace(a2.TxID, r2, TxSourceLocal); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := c.MempoolFindByNonce(alice.addr, 2); !ok || got.TxID != r2.TxID {
+		t.Fatalf("nonce 2 -> %s, %v; want the replacement", got.TxID, ok)
+	}
+	if want := txIDs([]SignedTx{a1, r2, a3}); !slices.Equal(txIDs(c.MempoolBySender(alice.addr)), want) {
+		t.Errorf("by sender = %v, want %v", txIDs(c.MempoolBySender(alice.addr)), want)
+	}
+
+	c.MempoolEvictFrom(alice.addr, 2)
+	if _, ok := c.MempoolFindByNonce(alice.addr, 3); ok {
+		t.Error("evicted nonce 3 still indexed")
+	}
+	c.MempoolRemove(a1.TxID)
+	if len(c.bySender) != 0 {
+		t.Errorf("index holds %d senders after the mempool emptied", len(c.bySender))
+	}
+}
+
+func TestNonceStateReserved(t *testing.T) {
+	c := newTestChain(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	admit(t, c,
+		alice.transfer(t, bob.addr, 100, 10, 1),
+		alice.transfer(t, bob.addr, 100, 10, 3),
+		bob.transfer(t, alice.addr, 100, 10, 1),
+	)
+
+	st := c.NonceState(alice.addr)
+	if !slices.Equal(st.Reserved, []uint64{1, 3}) || st.ExpectedNext != 4 || st.LastConfirmed != 0 {
+		t.Errorf("state = %+v, want reserved [1 3], next 4", st)
+	}
+	if st := c.NonceState(newTestKey(t).addr); st.Reserved == nil || len(st.Reserved) != 0 {
+		t.Errorf("unknown sender reserved = %#v, want empty", st.Reserved)
+	}
+}
+
+func TestMempoolReadyIgnoresClassByDefault(t *testing.T) {
+	c := newTestChain(t)
+	alice, bob, carol := newTestKey(t), newTestKey(t), newTestKey(t)
+	paid := bob.transfer(t, carol.addr, 100, 50, 1)
+	// A min-fee tx claiming the top class.
+	claimed := alice.transferClass(t, TxClassSystem, carol.addr, 100, MinFee, 1)
+	admit(t, c, claimed, paid)
+
+	if c.Params().HonorTxClass {
+		t.Fatal("HonorTxClass on by default")
+	}
+	if got, want := txIDs(c.MempoolReady(0)), txIDs([]SignedTx{paid, claimed}); !slices.Equal(got, want) {
+		t.Errorf("ready = %v, want fee order %v", got, want)
+	}
+
+	p := c.Params()
+	p.HonorTxClass = true
+	c.SetParams(p)
+	if got, want := txIDs(c.MempoolReady(0)), txIDs([]SignedTx{claimed, paid}); !slices.Equal(got, want) {
+		t.Errorf("ready with class honored = %v, want %v", got, want)
+	}
+}
+
+func TestLoadMempoolDropsUsedNoncesAndTrims(t *testing.T) {
+	c := newTestChain(t)
+	led := newTestLedger(t)
+	alice, bob, carol, dave := newTestKey(t), newTestKey(t), newTestKey(t), newTestKey(t)
+	if err := led.FaucetCredit(alice.addr, 10_000); err != nil {
+		t.Fatal(err)
+	}
+	mine(t, NewBlockProducer(c, led, 0, nil), alice.transfer(t, dave.addr, 100, 10, 1))
+
+	// As if the mempool store lagged the block: a different tx at the
+	// confirmed nonce is still in it, next to three that are not confirmed.
+	replaced := alice.transfer(t, dave.addr, 200, 10, 1)
+	a2 := alice.transfer(t, dave.addr, 100, 50, 2)
+	b1 := bob.transfer(t, dave.addr, 100, 20, 1)
+	c1 := carol.transfer(t, dave.addr, 100, 5, 1)
+	if err := c.mempoolStore.Save([]SignedTx{replaced, a2, b1, c1}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := c.Params()
+	p.MaxMempoolTxs = 2
+	c.SetParams(p)
+	staged := make(map[string]bool)
+	drops, err := c.LoadMempool(
+		func(tx SignedTx) error { staged[tx.TxID] = true; return nil },
+		func(tx SignedTx) { delete(staged, tx.TxID) },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dropped := make(map[string]string)
+	for _, d := range drops {
+		dropped[d.TxID] = d.Reason
+	}
+	if len(dropped) != 2 || dropped[replaced.TxID] == "" || dropped[c1.TxID] != "mempool limits" {
+		t.Errorf("drops = %v, want the used nonce and the lowest fee", drops)
+	}
+	if staged[replaced.TxID] || staged[c1.TxID] || !staged[a2.TxID] || !staged[b1.TxID] {
+		t.Errorf("staged spends = %v, want only a2 and b1", staged)
+	}
+	if got, want := txIDs(c.MempoolList()), txIDs([]SignedTx{a2, b1}); !slices.Equal(got, want) {
+		t.Errorf("mempool = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"sort"
+	"time"
+)
+
+// TxSource records how a pending tx reached this node.
+type TxSource uint8
+
+const (
+	TxSourceLocal    TxSource = iota // broadcast to this node's API
+	TxSourceGossip                   // relayed by a peer
+	TxSourceRestored                 // reloaded from the mempool store at startup, or from unwound blocks
+)
+
+func (s TxSource) String() string {
+	switch s {
+	case TxSourceLocal:
+		return "local"
+	case TxSourceGossip:
+		return "gossip"
+	case TxSourceRestored:
+		return "restored"
+	}
+	return "unknown"
+}
+
+func (s TxSource) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// MempoolEntry is a pending tx with its arrival metadata. ReceivedAt is when
+// this node admitted it (for restored txs, when they were reloaded), not the
+// draft timestamp; SizeBytes is its block encoding size.
+type MempoolEntry struct {
+	Tx         SignedTx  `json:"tx"`
+	ReceivedAt time.Time `json:"receivedAt"`
+	SizeBytes  int       `json:"sizeBytes"`
+	Source     TxSource  `json:"source"`
+}
+
+// FeePerByte is the fee paid per byte of block space.
+func (e MempoolEntry) FeePerByte() float64 {
+	if e.SizeBytes <= 0 {
+		return 0
+	}
+	return float64(e.Tx.Draft.Fee) / float64(e.SizeBytes)
+}
+
+func newMempoolEntry(tx SignedTx, src TxSource, now time.Time) MempoolEntry {
+	return MempoolEntry{
+		Tx:         tx,
+		ReceivedAt: now,
+		SizeBytes:  mempoolTxSize(tx),
+		Source:     src,
+	}
+}
+
+// MempoolEntries is MempoolList with arrival metadata, in the same order.
+func (c *Chain) MempoolEntries() []MempoolEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make([]MempoolEntry, 0, len(c.mempool))
+	for _, e := range c.mempool {
+		out = append(out, e)
+	}
+	honorClass := c.params.HonorTxClass
+	sort.Slice(out, func(i, j int) bool { return mempoolLess(out[i].Tx, out[j].Tx, honorClass) })
+	return out
+}
@@ -0,0 +1,149 @@
+package blockchain
+
+import "errors"
+
+// ErrMempoolFull is reported for a tx that MempoolTrim evicted on arrival:
+// it ranked lowest with the mempool at its count or byte limit.
+var ErrMempoolFull = errors.New("mempool full: fee too low to displace pending txs")
+
+// MempoolStats summarizes mempool occupancy against the configured limits
+// (0 means unlimited).
+type MempoolStats struct {
+	Count    int `json:"count"`
+	Bytes    int `json:"bytes"`
+	MaxTxs   int `json:"maxTxs"`
+	MaxBytes int `json:"maxBytes"`
+}
+
+func (c *Chain) MempoolStats() MempoolStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return MempoolStats{
+		Count:    len(c.mempool),
+		Bytes:    c.mempoolBytes,
+		MaxTxs:   c.params.MaxMempoolTxs,
+		MaxBytes: c.params.MaxMempoolBytes,
+	}
+}
+
+// MempoolBytes is the total serialized size of pending txs.
+func (c *Chain) MempoolBytes() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.mempoolBytes
+}
+
+// MempoolCheckRoom is the admission pre-check for a new tx (not a
+// replacement): it returns ErrMempoolFull if adding tx would exceed a limit
+// and tx does not pay more than the current eviction candidate, so it could
+// not displace anything. It lets callers reject before staging funds; MempoolTrim
+// remains authoritative after the add.
+func (c *Chain) MempoolCheckRoom(tx SignedTx) error {
+	n := mempoolTxSize(tx)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	fits := (c.params.MaxMempoolTxs <= 0 || len(c.mempool)+1 <= c.params.MaxMempoolTxs) &&
+		(c.params.MaxMempoolBytes <= 0 || c.mempoolBytes+n <= c.params.MaxMempoolBytes)
+	if fits {
+		return nil
+	}
+	if c.params.MaxMempoolBytes > 0 && n > c.params.MaxMempoolBytes {
+		return ErrMempoolFull
+	}
+	victim, ok := c.trimVictimLocked()
+	if !ok || !mempoolLess(tx, victim, false) {
+		return ErrMempoolFull
+	}
+	return nil
+}
+
+// MempoolTrim evicts pending txs until the mempool is within both
+// MaxMempoolTxs and MaxMempoolBytes, and returns them so the caller can
+// release their staged spends. Only the last pending nonce of a sender is a
+// candidate, so eviction never strands a dependent; among those the
+// lowest-fee tx goes first. Class is never considered here, even when
+// HonorTxClass orders blocks by it: it is self-declared, so a min-fee tx
+// claiming a high class must not be able to hold its place in a full mempool.
+func (c *Chain) MempoolTrim() []SignedTx {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var out []SignedTx
+	for c.overLimitsLocked() {
+		victim, ok := c.trimVictimLocked()
+		if !ok {
+			break
+		}
+		out = append(out, c.evictFromLocked(victim.Draft.From, victim.Draft.Nonce)...)
+	}
+	return out
+}
+
+func (c *Chain) overLimitsLocked() bool {
+	if c.params.MaxMempoolTxs > 0 && len(c.mempool) > c.params.MaxMempoolTxs {
+		return true
+	}
+	return c.params.MaxMempoolBytes > 0 && c.mempoolBytes > c.params.MaxMempoolBytes
+}
+
+func (c *Chain) trimVictimLocked() (SignedTx, bool) {
+	var (
+		victim SignedTx
+		found  bool
+	)
+	for _, byNonce := range c.bySender {
+		var tail uint64
+		for n := range byNonce {
+			tail = max(tail, n)
+		}
+		tx := c.mempool[byNonce[tail]].Tx
+		if !found || mempoolLess(victim, tx, false) {
+			victim, found = tx, true
+		}
+	}
+	return victim, found
+}
+
+// mempoolPutLocked and mempoolDeleteLocked are the only writers of the
+// mempool map, so mempoolBytes and the bySender index stay in step with it.
+func (c *Chain) mempoolPutLocked(e MempoolEntry) {
+	if old, ok := c.mempool[e.Tx.TxID]; ok {
+		c.mempoolBytes -= old.SizeBytes
+	}
+	c.mempool[e.Tx.TxID] = e
+	c.mempoolBytes += e.SizeBytes
+
+	d := e.Tx.Draft
+	byNonce := c.bySender[d.From]
+	if byNonce == nil {
+		byNonce = make(map[uint64]string)
+		c.bySender[d.From] = byNonce
+	}
+	byNonce[d.Nonce] = e.Tx.TxID
+}
+
+func (c *Chain) mempoolDeleteLocked(txID string) {
+	old, ok := c.mempool[txID]
+	if !ok {
+		return
+	}
+	c.mempoolBytes -= old.SizeBytes
+	delete(c.mempool, txID)
+
+	d := old.Tx.Draft
+	if c.bySender[d.From][d.Nonce] == txID {
+		delete(c.bySender[d.From], d.Nonce)
+		if len(c.bySender[d.From]) == 0 {
+			delete(c.bySender, d.From)
+		}
+	}
+}
+
+// mempoolTxSize is the tx's block encoding size; mempool txs are validated,
+// so the encoding cannot fail.
+func mempoolTxSize(tx SignedTx) int {
+	n, _ := signedTxSize(tx)
+	return n
+}
@@ -0,0 +1,39 @@
+package blockchain
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// TestMempoolLimitsEvictByFee fills a capped mempool with a low-fee tx
+// claiming the top class, on a node that honors class, and checks that a
+// better-paying bulk tx still displaces it.
+func TestMempoolLimitsEvictByFee(t *testing.T) {
+	c := newTestChain(t)
+	p := c.Params()
+	p.HonorTxClass = true
+	p.MaxMempoolTxs = 2
+	c.SetParams(p)
+
+	alice, bob, carol, dave := newTestKey(t), newTestKey(t), newTestKey(t), newTestKey(t)
+	claimed := alice.transferClass(t, TxClassSystem, dave.addr, 100, MinFee+1, 1)
+	mid := bob.transfer(t, dave.addr, 100, 20, 1)
+	admit(t, c, claimed, mid)
+
+	if err := c.MempoolCheckRoom(carol.transfer(t, dave.addr, 100, MinFee, 1)); !errors.Is(err, ErrMempoolFull) {
+		t.Errorf("min-fee tx into a full mempool: err = %v, want ErrMempoolFull", err)
+	}
+
+	paid := carol.transfer(t, dave.addr, 100, 50, 1)
+	if err := c.MempoolCheckRoom(paid); err != nil {
+		t.Fatalf("higher-fee tx refused: %v", err)
+	}
+	admit(t, c, paid)
+	if got, want := txIDs(c.MempoolTrim()), txIDs([]SignedTx{claimed}); !slices.Equal(got, want) {
+		t.Fatalf("trimmed %v, want the low-fee tx %v", got, want)
+	}
+	if !c.MempoolHas(mid.TxID) || !c.MempoolHas(paid.TxID) {
+		t.Error("a fee-paying tx was evicted")
+	}
+}
@@ -0,0 +1,75 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/VeltarosLabs/Veltaros/internal/storage"
+)
+
+// MempoolStore persists pending txs so broadcasts survive a restart.
+type MempoolStore struct {
+	path string
+}
+
+func NewMempoolStore(path string) *MempoolStore {
+	return &MempoolStore{path: filepath.Clean(path)}
+}
+
+// Load returns the raw stored entries. Entries are decoded individually by
+// the caller so one corrupt record does not discard the rest.
+func (s *MempoolStore) Load() ([]json.RawMessage, error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []json.RawMessage{}, nil
+		}
+		return nil, err
+	}
+
+	var entries []json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (s *MempoolStore) Save(txs []SignedTx) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := encodeMempool(txs)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	_ = os.Chmod(s.path, 0o600)
+	return nil
+}
+
+// Stage queues txs on b instead of writing them now (see storage.Batch).
+func (s *MempoolStore) Stage(b *storage.Batch, txs []SignedTx) error {
+	data, err := encodeMempool(txs)
+	if err != nil {
+		return err
+	}
+	b.Add(s.path, data, false)
+	return nil
+}
+
+func encodeMempool(txs []SignedTx) ([]byte, error) {
+	sort.Slice(txs, func(i, j int) bool { return txs[i].TxID < txs[j].TxID })
+	return json.MarshalIndent(txs, "", "  ")
+}
@@ -3,6 +3,7 @@ package blockchain
 import (
 	"encoding/hex"
 	"errors"
+	"math/bits"
 
 	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
 )
@@ -55,3 +56,87 @@ func MerkleRootFromTxIDs(txIDs []string) ([32]byte, error) {
 	copy(root[:], nodes[0])
 	return root, nil
 }
+
+// ErrNotInMerkleTree is returned by MerkleProof for a txId not among the
+// leaves.
+var ErrNotInMerkleTree = errors.New("txId not in merkle tree")
+
+// MerkleProof returns the inclusion proof of target in the tree
+// MerkleRootFromTxIDs builds over txIDs: path[i] is the sibling at level i,
+// leaf level first, and dirs[i] reports whether that sibling is on the right.
+// A single-leaf tree has an empty path. If target occurs more than once, the
+// first occurrence is proven.
+func MerkleProof(txIDs []string, target string) ([][32]byte, []bool, error) {
+	idx := -1
+	level := make([][32]byte, len(txIDs))
+	for i, id := range txIDs {
+		b, err := hex.DecodeString(id)
+		if err != nil {
+			return nil, nil, errors.New("invalid txId hex")
+		}
+		if len(b) != 32 {
+			return nil, nil, errors.New("invalid txId length")
+		}
+		copy(level[i][:], b)
+		if idx < 0 && id == target {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return nil, nil, ErrNotInMerkleTree
+	}
+
+	path := make([][32]byte, 0, bits.Len(uint(len(level))))
+	dirs := make([]bool, 0, cap(path))
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		if idx%2 == 0 {
+			path = append(path, level[idx+1])
+			dirs = append(dirs, true)
+		} else {
+			path = append(path, level[idx-1])
+			dirs = append(dirs, false)
+		}
+
+		next := make([][32]byte, len(level)/2)
+		for i := range next {
+			next[i] = merkleParent(level[2*i], level[2*i+1])
+		}
+		level = next
+		idx /= 2
+	}
+	return path, dirs, nil
+}
+
+// VerifyMerkleProof reports whether path and dirs, as returned by
+// MerkleProof, lead from txHash to root.
+func VerifyMerkleProof(root [32]byte, txHash [32]byte, path [][32]byte, dirs []bool) bool {
+	if len(path) != len(dirs) {
+		return false
+	}
+	h := txHash
+	for i, sib := range path {
+		if dirs[i] {
+			h = merkleParent(h, sib)
+		} else {
+			h = merkleParent(sib, h)
+		}
+	}
+	return h == root
+}
+
+func merkleParent(left, right [32]byte) [32]byte {
+	concat := make([]byte, 0, 64)
+	concat = append(concat, left[:]...)
+	concat = append(concat, right[:]...)
+	return vcrypto.DoubleSha256(concat)
+}
+
+// MerkleProof proves txID is in b against b.Header.MerkleRoot. The leaves
+// include the coinbase ID, if any, so the proof is the one a light client
+// checks with VerifyMerkleProof and the header alone.
+func (b *Block) MerkleProof(txID string) ([][32]byte, []bool, error) {
+	return MerkleProof(b.merkleLeaves(), txID)
+}
@@ -0,0 +1,104 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
+)
+
+func merkleTestIDs(n int) ([]string, [][32]byte) {
+	ids := make([]string, n)
+	hashes := make([][32]byte, n)
+	for i := range n {
+		hashes[i] = vcrypto.DoubleSha256([]byte(fmt.Sprintf("tx-%d", i)))
+		ids[i] = hex.EncodeToString(hashes[i][:])
+	}
+	return ids, hashes
+}
+
+func TestMerkleProofMatchesRoot(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 5, 8} {
+		ids, hashes := merkleTestIDs(n)
+		root, err := MerkleRootFromTxIDs(ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Every leaf is covered, including the last leaf of an odd level,
+		// which is paired with its own duplicate.
+		for i, id := range ids {
+			path, dirs, err := MerkleProof(ids, id)
+			if err != nil {
+				t.Fatalf("n=%d leaf %d: %v", n, i, err)
+			}
+			if n == 1 && len(path) != 0 {
+				t.Fatalf("n=1: path has %d entries, want 0", len(path))
+			}
+			if !VerifyMerkleProof(root, hashes[i], path, dirs) {
+				t.Fatalf("n=%d leaf %d: proof does not reach the root", n, i)
+			}
+			if i == n-1 && n%2 == 1 && n > 1 {
+				if path[0] != hashes[i] || !dirs[0] {
+					t.Fatalf("n=%d last leaf: first sibling should be its own duplicate on the right", n)
+				}
+			}
+
+			for j := range path {
+				tampered := append([][32]byte(nil), path...)
+				tampered[j][0] ^= 1
+				if VerifyMerkleProof(root, hashes[i], tampered, dirs) {
+					t.Fatalf("n=%d leaf %d: tampered sibling %d verified", n, i, j)
+				}
+
+				// Flipping the side of a duplicated sibling still hashes the
+				// same pair, so only flips with distinct halves must fail.
+				if path[j] == merkleNodeAt(hashes, i, j) {
+					continue
+				}
+				flipped := append([]bool(nil), dirs...)
+				flipped[j] = !flipped[j]
+				if VerifyMerkleProof(root, hashes[i], path, flipped) {
+					t.Fatalf("n=%d leaf %d: flipped direction %d verified", n, i, j)
+				}
+			}
+			if len(dirs) > 0 && VerifyMerkleProof(root, hashes[i], path, dirs[:len(dirs)-1]) {
+				t.Fatalf("n=%d leaf %d: mismatched path and dirs verified", n, i)
+			}
+		}
+	}
+}
+
+// merkleNodeAt is the node on leaf i's path at the given level (the hash the
+// proof's sibling at that level is combined with).
+func merkleNodeAt(leaves [][32]byte, i, level int) [32]byte {
+	nodes := append([][32]byte(nil), leaves...)
+	for range level {
+		if len(nodes)%2 == 1 {
+			nodes = append(nodes, nodes[len(nodes)-1])
+		}
+		next := make([][32]byte, len(nodes)/2)
+		for k := range next {
+			next[k] = merkleParent(nodes[2*k], nodes[2*k+1])
+		}
+		nodes = next
+		i /= 2
+	}
+	return nodes[i]
+}
+
+func TestMerkleProofErrors(t *testing.T) {
+	ids, _ := merkleTestIDs(3)
+	missing, _ := merkleTestIDs(4)
+	if _, _, err := MerkleProof(ids, missing[3]); !errors.Is(err, ErrNotInMerkleTree) {
+		t.Fatalf("missing txId: err = %v, want ErrNotInMerkleTree", err)
+	}
+	if _, _, err := MerkleProof(append(ids, "zz"), ids[0]); err == nil {
+		t.Fatal("invalid txId hex: accepted")
+	}
+	if _, _, err := MerkleProof(append(ids, "00"), ids[0]); err == nil {
+		t.Fatal("short txId: accepted")
+	}
+}
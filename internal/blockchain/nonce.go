@@ -3,13 +3,16 @@ package blockchain
 import (
 	"sync"
 	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/clock"
 )
 
 // NonceTracker tracks the highest seen nonce per sender address.
 // Policy: strictly increasing nonces (nonce must be > last).
 type NonceTracker struct {
-	mu   sync.RWMutex
-	last map[string]nonceEntry
+	mu    sync.RWMutex
+	last  map[string]nonceEntry
+	clock clock.Clock // stamps updatedAt
 }
 
 type nonceEntry struct {
@@ -19,7 +22,8 @@ type nonceEntry struct {
 
 func NewNonceTracker() *NonceTracker {
 	return &NonceTracker{
-		last: make(map[string]nonceEntry),
+		last:  make(map[string]nonceEntry),
+		clock: clock.Real{},
 	}
 }
 
@@ -46,10 +50,38 @@ func (n *NonceTracker) CheckAndUpdate(addr string, nonce uint64) bool {
 	if nonce <= prev {
 		return false
 	}
-	n.last[addr] = nonceEntry{nonce: nonce, updatedAt: time.Now().UTC()}
+	n.last[addr] = nonceEntry{nonce: nonce, updatedAt: n.clock.Now()}
 	return true
 }
 
+// Advance raises addr's last nonce to nonce (never lowers it), e.g. for a
+// nonce confirmed in a block whether or not it was reserved here.
+func (n *NonceTracker) Advance(addr string, nonce uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if nonce <= n.last[addr].nonce {
+		return
+	}
+	n.last[addr] = nonceEntry{nonce: nonce, updatedAt: n.clock.Now()}
+}
+
+// Rollback lowers addr's last nonce to nonce (never raises it), so nonces
+// above it can be used again.
+func (n *NonceTracker) Rollback(addr string, nonce uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if nonce >= n.last[addr].nonce {
+		return
+	}
+	if nonce == 0 {
+		delete(n.last, addr)
+		return
+	}
+	n.last[addr] = nonceEntry{nonce: nonce, updatedAt: n.clock.Now()}
+}
+
 // Snapshot returns a compact view for persistence.
 func (n *NonceTracker) Snapshot() []NonceSnapshot {
 	n.mu.RLock()
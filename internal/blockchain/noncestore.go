@@ -1,12 +1,13 @@
 package blockchain
 
 import (
-	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
 	"sort"
 	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/storage"
 )
 
 type NonceSnapshot struct {
@@ -15,8 +16,15 @@ type NonceSnapshot struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+// nonceStoreFormat versions the nonce file ([]NonceSnapshot).
+var nonceStoreFormat = storage.Format{Name: "nonce", Version: 1}
+
 type NonceStore struct {
 	path string
+
+	// KeepBackup keeps the previous file as <path>.bak on each Save; Load
+	// falls back to it if the primary is corrupt.
+	KeepBackup bool
 }
 
 func NewNonceStore(path string) *NonceStore {
@@ -24,7 +32,11 @@ func NewNonceStore(path string) *NonceStore {
 }
 
 func (s *NonceStore) Load() ([]NonceSnapshot, error) {
-	raw, err := os.ReadFile(s.path)
+	var snaps []NonceSnapshot
+	err := storage.ReadFileWithFallback(s.path, func(raw []byte) error {
+		snaps = nil
+		return nonceStoreFormat.Decode(raw, &snaps)
+	})
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return []NonceSnapshot{}, nil
@@ -32,11 +44,6 @@ func (s *NonceStore) Load() ([]NonceSnapshot, error) {
 		return nil, err
 	}
 
-	var snaps []NonceSnapshot
-	if err := json.Unmarshal(raw, &snaps); err != nil {
-		return nil, err
-	}
-
 	// Normalize and de-dup by addr (keep highest nonce)
 	out := make([]NonceSnapshot, 0, len(snaps))
 	seen := make(map[string]NonceSnapshot, len(snaps))
@@ -65,19 +72,11 @@ func (s *NonceStore) Save(snaps []NonceSnapshot) error {
 
 	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Addr < snaps[j].Addr })
 
-	data, err := json.MarshalIndent(snaps, "", "  ")
-	if err != nil {
-		return err
-	}
+	return storage.WriteVersioned(s.path, nonceStoreFormat, snaps, s.KeepBackup)
+}
 
-	tmp := s.path + ".tmp"
-	if err := os.WriteFile(tmp, data, 0o600); err != nil {
-		return err
-	}
-	if err := os.Rename(tmp, s.path); err != nil {
-		_ = os.Remove(tmp)
-		return err
-	}
-	_ = os.Chmod(s.path, 0o600)
-	return nil
+// Stage queues snaps on b instead of writing them now (see storage.Batch).
+func (s *NonceStore) Stage(b *storage.Batch, snaps []NonceSnapshot) error {
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Addr < snaps[j].Addr })
+	return b.AddVersioned(s.path, nonceStoreFormat, snaps, s.KeepBackup)
 }
@@ -0,0 +1,53 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/VeltarosLabs/Veltaros/internal/clock"
+)
+
+// Option customizes a Chain at construction.
+type Option func(*Chain)
+
+// WithClock makes the chain's timekeeping (mempool arrival times, expiry and
+// the timestamp window, nonce reservations, produced block timestamps) read c
+// instead of the system clock.
+func WithClock(c clock.Clock) Option {
+	return func(ch *Chain) { ch.clock = clock.OrReal(c) }
+}
+
+// HeaderValidator checks the consensus proof on a block header; it has the
+// shape of consensus.Engine (e.g. consensus.PoW).
+type HeaderValidator interface {
+	ValidateBlockHeader(headerBytes []byte) error
+}
+
+// HeaderSealer is a HeaderValidator that can also produce the proof, as
+// consensus.PoW does by mining a nonce.
+type HeaderSealer interface {
+	HeaderValidator
+	Mine(h BlockHeader) (uint64, error)
+}
+
+// WithConsensus makes AddBlock reject blocks whose header fails v, whether
+// produced here, gossiped or synced. If v is also a HeaderSealer, produced
+// blocks are sealed with it. Without it headers are not checked, as on a
+// network with ChainParams.PowDifficulty 0.
+func WithConsensus(v HeaderValidator) Option {
+	return func(ch *Chain) { ch.consensus = v }
+}
+
+// seal fills in b's consensus proof, if the chain's validator can produce
+// one.
+func (c *Chain) seal(b *Block) error {
+	s, ok := c.consensus.(HeaderSealer)
+	if !ok {
+		return nil
+	}
+	nonce, err := s.Mine(b.Header)
+	if err != nil {
+		return fmt.Errorf("seal block: %w", err)
+	}
+	b.Header.Nonce = nonce
+	return nil
+}
@@ -0,0 +1,88 @@
+package blockchain
+
+// ChainParams describes the rules a node enforces. The consensus limits are
+// copied from package constants so clients see exactly what validation
+// checks; the policy fields may differ between deployments (e.g. private
+// networks) without changing consensus encoding.
+type ChainParams struct {
+	TxVersion       uint32 `json:"txVersion"`
+	MinFee          uint64 `json:"minFee"`
+	MaxTxBytes      int    `json:"maxTxBytes"`
+	MaxMemoLen      int    `json:"maxMemoLen"`
+	MaxClientRefLen int    `json:"maxClientRefLen"`
+	MaxTxClass      uint8  `json:"maxTxClass"`
+
+	// MaxFutureSkewSec is how far ahead of the node's clock the mempool
+	// admits a tx timestamp; it is node policy (see Chain.CheckTxTime).
+	MaxFutureSkewSec int64 `json:"maxFutureSkewSec"`
+	MaxPastSkewSec   int64 `json:"maxPastSkewSec"`
+
+	MaxBlockBytes         int   `json:"maxBlockBytes"`
+	TargetBlockSpacingSec int64 `json:"targetBlockSpacingSec"`
+
+	// AddressFormat documents the address encoding checked by ValidateAddress.
+	AddressFormat   string `json:"addressFormat"`
+	AddressLenBytes int    `json:"addressLenBytes"`
+
+	// RequireKnownRecipient rejects transfers to addresses the ledger has
+	// never seen. Intended as a typo guard on permissioned networks.
+	RequireKnownRecipient bool `json:"requireKnownRecipient"`
+
+	// HonorTxClass orders the mempool by tx class before fee. When false
+	// (the default), ordering is purely by fee. Class is chosen freely by the
+	// sender and costs nothing, so honor it only on permissioned networks
+	// where every sender is trusted not to claim a class it should not.
+	HonorTxClass bool `json:"honorTxClass"`
+
+	// MaxMempoolTxs and MaxMempoolBytes (sum of block-encoded tx sizes) cap
+	// the mempool; past either, the lowest-fee txs are evicted (class is not
+	// considered). 0 disables a limit.
+	MaxMempoolTxs   int `json:"maxMempoolTxs"`
+	MaxMempoolBytes int `json:"maxMempoolBytes"`
+
+	// MinRelayFee and MinRelayFeePerByte (of the canonical draft) raise the
+	// fee the mempool admits above MinFee; see RequiredFee. 0 disables each.
+	MinRelayFee        uint64 `json:"minRelayFee"`
+	MinRelayFeePerByte uint64 `json:"minRelayFeePerByte"`
+
+	// BlockReward is the most a block's coinbase may mint on top of its
+	// fees. Every node on a network must agree on it: a block claiming more
+	// is rejected. 0 means coinbases only pass on fees.
+	BlockReward uint64 `json:"blockReward"`
+
+	// PowDifficulty is the number of leading zero bits a block header hash
+	// must have (see consensus.PoW). Like BlockReward it must match across
+	// the network; 0 disables the check.
+	PowDifficulty uint `json:"powDifficulty"`
+}
+
+const (
+	DefaultMaxMempoolTxs   = 50_000
+	DefaultMaxMempoolBytes = 64 << 20
+)
+
+func DefaultParams() ChainParams {
+	return ChainParams{
+		TxVersion:       TxVersion,
+		MinFee:          MinFee,
+		MaxTxBytes:      MaxTxBytes,
+		MaxMemoLen:      MaxMemoLen,
+		MaxClientRefLen: MaxClientRefLen,
+		MaxTxClass:      MaxTxClass,
+
+		MaxFutureSkewSec: MaxFutureSkewSec,
+		MaxPastSkewSec:   MaxPastSkewSec,
+
+		MaxBlockBytes:         MaxBlockBytes,
+		TargetBlockSpacingSec: TargetBlockSpacingSec,
+
+		AddressFormat:   "hex(h20 || doubleSha256(h20)[:4]), h20 = sha256(ed25519PubKey)[:20]",
+		AddressLenBytes: AddressLenBytes,
+
+		RequireKnownRecipient: false,
+		HonorTxClass:          false,
+
+		MaxMempoolTxs:   DefaultMaxMempoolTxs,
+		MaxMempoolBytes: DefaultMaxMempoolBytes,
+	}
+}
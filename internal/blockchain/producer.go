@@ -0,0 +1,309 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/ledger"
+)
+
+// LedgerApplier is the subset of the ledger the producer needs to settle
+// included txs. Txs are applied with their block height so they can be
+// reverted if the block is later unwound (see Chain.UnwindTo).
+type LedgerApplier interface {
+	ApplyConfirmedTxAt(height uint64, from string, to string, amount uint64, fee uint64) error
+	ApplyConfirmedMultiTxAt(height uint64, from string, outputs []ledger.Output, fee uint64) error
+	ApplyCoinbaseAt(height uint64, to string, amount uint64) error
+	UnstageMempoolSpend(from string, amount uint64)
+	// SetAppliedHeight is called once all of a block's txs are applied.
+	SetAppliedHeight(height uint64)
+	// FundsReader lets blocks be dry-run before they are built or linked.
+	FundsReader
+}
+
+// applyConfirmed settles tx, confirmed in the block at height, on led.
+func applyConfirmed(led LedgerApplier, height uint64, tx SignedTx) error {
+	d := tx.Draft
+	if !d.IsMulti() {
+		return led.ApplyConfirmedTxAt(height, d.From, d.To, d.Amount, d.Fee)
+	}
+	outs := make([]ledger.Output, len(d.Outputs))
+	for i, o := range d.Outputs {
+		outs[i] = ledger.Output{To: o.To, Amount: o.Amount}
+	}
+	return led.ApplyConfirmedMultiTxAt(height, d.From, outs, d.Fee)
+}
+
+// ProducedBlock summarizes one block built from the mempool.
+type ProducedBlock struct {
+	Block   StoredBlock
+	Applied int
+	Failed  int
+	Dropped int
+	// Reward is what the coinbase credited: the block reward plus the fees
+	// of the applied txs. 0 without a coinbase.
+	Reward uint64
+}
+
+// BlockProducer turns mempool contents into blocks on the local chain.
+type BlockProducer struct {
+	chain  *Chain
+	ledger LedgerApplier
+	maxTxs int
+	log    *slog.Logger
+
+	onBlock    func(StoredBlock)
+	rewardAddr string
+
+	mu   sync.Mutex
+	done chan struct{}
+}
+
+var ErrEmptyMempool = errors.New("mempool is empty")
+
+// NewBlockProducer returns a producer that includes at most maxTxs txs per
+// block (<= 0 means only MaxBlockBytes limits the block).
+func NewBlockProducer(chain *Chain, ledger LedgerApplier, maxTxs int, log *slog.Logger) *BlockProducer {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &BlockProducer{
+		chain:  chain,
+		ledger: ledger,
+		maxTxs: maxTxs,
+		log:    log,
+	}
+}
+
+// SetOnBlock registers a callback run after each produced block is linked
+// and settled (e.g. to announce it to peers). Set it before Start.
+func (p *BlockProducer) SetOnBlock(fn func(StoredBlock)) {
+	p.mu.Lock()
+	p.onBlock = fn
+	p.mu.Unlock()
+}
+
+// SetRewardAddress makes produced blocks carry a coinbase paying addr the
+// chain's BlockReward plus the block's fees. "" (the default) produces
+// blocks without one, whose fees are burned. Set it before Start.
+func (p *BlockProducer) SetRewardAddress(addr string) {
+	p.mu.Lock()
+	p.rewardAddr = addr
+	p.mu.Unlock()
+}
+
+// coinbase returns the coinbase for the next block, or nil. It requires p.mu.
+func (p *BlockProducer) coinbase() *Coinbase {
+	if p.rewardAddr == "" {
+		return nil
+	}
+	return &Coinbase{To: p.rewardAddr, Reward: p.chain.Params().BlockReward}
+}
+
+// ProduceOnce builds a single block from the highest-priority ready mempool
+// txs (see MempoolTopN) and applies them to the ledger. The txs stay pending
+// until the block is linked; only the included ones are then removed. With
+// allowEmpty false it returns ErrEmptyMempool instead of producing an empty
+// block. Txs that no longer validate (e.g. after a consensus rule change) or
+// that the sender can no longer pay for are evicted, along with the sender's
+// later nonces, rather than failing the whole block.
+func (p *BlockProducer) ProduceOnce(allowEmpty bool) (ProducedBlock, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !allowEmpty && p.chain.MempoolCount() == 0 {
+		return ProducedBlock{}, ErrEmptyMempool
+	}
+
+	txs, broken, _ := p.selectTxs()
+	if len(txs) == 0 && !allowEmpty {
+		return ProducedBlock{Dropped: p.evictBroken(broken)}, ErrEmptyMempool
+	}
+
+	blk, err := BuildBlock(p.chain.TipHash(), txs, p.coinbase(), p.chain.clock.Now())
+	if err == nil {
+		err = p.chain.seal(&blk)
+	}
+	if err != nil {
+		p.evictBroken(broken)
+		return ProducedBlock{}, err
+	}
+
+	// AddBlock runs ValidateBasic before linking the block.
+	p.chain.settleMu.Lock()
+	sb, err := p.chain.AddBlock(blk)
+	if err != nil {
+		p.chain.settleMu.Unlock()
+		p.evictBroken(broken)
+		return ProducedBlock{}, err
+	}
+
+	out := ProducedBlock{Block: sb, Dropped: p.evictBroken(broken)}
+	ids := make([]string, len(txs))
+	for i, tx := range txs {
+		ids[i] = tx.TxID
+	}
+	removed := make(map[string]bool, len(txs))
+	for _, tx := range p.chain.MempoolRemoveAll(ids) {
+		removed[tx.TxID] = true
+	}
+	var fees uint64
+	for _, tx := range txs {
+		from := tx.Draft.From
+		if removed[tx.TxID] {
+			p.ledger.UnstageMempoolSpend(from, tx.Draft.SpendAmount())
+		} else if other, ok := p.chain.MempoolFindByNonce(from, tx.Draft.Nonce); ok {
+			// Replaced by fee while the block was built: the included tx
+			// wins and the replacement is dropped.
+			p.chain.MempoolRemove(other.TxID)
+			p.ledger.UnstageMempoolSpend(from, other.Draft.SpendAmount())
+			out.Dropped++
+		}
+		if err := applyConfirmed(p.ledger, sb.Height, tx); err != nil {
+			out.Failed++
+			continue
+		}
+		out.Applied++
+		fees += tx.Draft.Fee
+	}
+	if amount, err := settleCoinbase(p.ledger, sb.Height, blk.Coinbase, fees); err != nil {
+		p.log.Warn("coinbase not credited", "height", sb.Height, "err", err)
+	} else {
+		out.Reward = amount
+	}
+	p.ledger.SetAppliedHeight(sb.Height)
+	p.chain.settleMu.Unlock()
+	if p.onBlock != nil {
+		p.onBlock(sb)
+	}
+	return out, nil
+}
+
+// selectTxs picks the txs for the next block: the highest-priority ready
+// mempool txs, minus any that no longer validate and their senders' later
+// nonces. A tx no longer validates if it fails its checks or if, applied
+// after the txs picked before it, its sender's confirmed balance could not
+// pay for it. broken maps each such sender to its lowest dropped nonce;
+// skipped counts every tx left out. Nothing is modified.
+func (p *BlockProducer) selectTxs() (txs []SignedTx, broken map[string]uint64, skipped int) {
+	top := p.chain.MempoolTopN(p.maxTxs)
+	txs = make([]SignedTx, 0, len(top))
+	broken = make(map[string]uint64) // sender -> lowest dropped nonce
+	funds := newFundsCheck(p.ledger)
+	for _, tx := range top {
+		from := tx.Draft.From
+		if _, ok := broken[from]; ok {
+			// In nonce order, so this depends on the dropped tx.
+			skipped++
+			continue
+		}
+		if err := ValidateSignedTx(tx); err != nil {
+			broken[from] = tx.Draft.Nonce
+			skipped++
+			continue
+		}
+		if err := funds.apply(tx); err != nil {
+			broken[from] = tx.Draft.Nonce
+			skipped++
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	return txs, broken, skipped
+}
+
+// AssembledBlock is a candidate block built by Assemble. It is never linked.
+type AssembledBlock struct {
+	Height    uint64 // height the block would have
+	Block     Block
+	TxIDs     []string
+	TotalFees uint64
+	Skipped   int // ready txs left out because they no longer validate
+}
+
+// Assemble previews the next block: it selects txs exactly as ProduceOnce
+// does and builds a candidate on the current tip, but links nothing, evicts
+// nothing and leaves the ledger alone. An empty mempool gives an empty block.
+// The candidate is not sealed, so its header nonce is 0.
+func (p *BlockProducer) Assemble() (AssembledBlock, error) {
+	// Held so a concurrent ProduceOnce cannot move the tip mid-preview.
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	height, tip := p.chain.tip()
+	txs, _, skipped := p.selectTxs()
+	blk, err := BuildBlock(tip, txs, p.coinbase(), p.chain.clock.Now())
+	if err != nil {
+		return AssembledBlock{}, err
+	}
+
+	out := AssembledBlock{
+		Height:  height + 1,
+		Block:   blk,
+		TxIDs:   make([]string, len(txs)),
+		Skipped: skipped,
+	}
+	for i, tx := range txs {
+		out.TxIDs[i] = tx.TxID
+		out.TotalFees += tx.Draft.Fee
+	}
+	return out, nil
+}
+
+// evictBroken removes the pending successors of dropped txs, which can no
+// longer be included, releasing their staged spends. Run it after the block
+// is linked so nonce rollback sees the newly confirmed nonces.
+func (p *BlockProducer) evictBroken(broken map[string]uint64) int {
+	n := 0
+	for from, nonce := range broken {
+		for _, tx := range p.chain.MempoolEvictFrom(from, nonce) {
+			p.ledger.UnstageMempoolSpend(from, tx.Draft.SpendAmount())
+			n++
+		}
+	}
+	return n
+}
+
+// Start produces a block every interval until ctx is cancelled, skipping
+// ticks with an empty mempool. Done is closed once the loop has exited and no
+// block is being produced; persisting the chain is left to the owner.
+func (p *BlockProducer) Start(ctx context.Context, interval time.Duration) {
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+
+			res, err := p.ProduceOnce(false)
+			if errors.Is(err, ErrEmptyMempool) {
+				continue
+			}
+			if err != nil {
+				p.log.Warn("block production failed", "err", err)
+				continue
+			}
+			p.log.Info("produced block",
+				"height", res.Block.Height,
+				"hash", res.Block.HashHex,
+				"txs", res.Block.TxCount,
+				"failed", res.Failed,
+				"dropped", res.Dropped,
+			)
+		}
+	}()
+}
+
+// Done is closed after a started producer has stopped and flushed. It is nil
+// if Start was never called.
+func (p *BlockProducer) Done() <-chan struct{} {
+	return p.done
+}
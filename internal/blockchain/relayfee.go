@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// RequiredFee is the lowest fee this node's policy admits for d: the larger
+// of MinFee (consensus), MinRelayFee, and MinRelayFeePerByte times the size
+// of CanonicalDraftBytes(d). size is that draft size in bytes.
+func (p ChainParams) RequiredFee(d TxDraft) (fee uint64, size int, err error) {
+	fee = max(uint64(MinFee), p.MinRelayFee)
+	if p.MinRelayFeePerByte == 0 {
+		return fee, 0, nil
+	}
+	b, err := CanonicalDraftBytes(d)
+	if err != nil {
+		return 0, 0, err
+	}
+	hi, perByte := bits.Mul64(p.MinRelayFeePerByte, uint64(len(b)))
+	if hi != 0 {
+		perByte = ^uint64(0)
+	}
+	return max(fee, perByte), len(b), nil
+}
+
+// MinReplaceBumpPercent is how much a replacement must pay on top of the
+// pending tx's fee, as a percentage of it; see MinReplacementFee.
+const MinReplaceBumpPercent = 10
+
+// MinReplacementFee is the lowest fee a replacement d for a pending tx paying
+// oldFee is admitted with: oldFee plus the larger of MinReplaceBumpPercent of
+// oldFee and RequiredFee(d). Each replacement thus pays at least what
+// relaying it as a new tx would, so a sender cannot churn the mempool (and
+// the network) by bumping one unit at a time.
+func (p ChainParams) MinReplacementFee(oldFee uint64, d TxDraft) (uint64, error) {
+	relay, _, err := p.RequiredFee(d)
+	if err != nil {
+		return 0, err
+	}
+	bump := oldFee/100*MinReplaceBumpPercent + oldFee%100*MinReplaceBumpPercent/100
+	need, carry := bits.Add64(oldFee, max(bump, relay), 0)
+	if carry != 0 {
+		need = ^uint64(0)
+	}
+	return need, nil
+}
+
+// checkRelayFeeLocked rejects tx with ErrFeeTooLow if it pays less than
+// RequiredFee. It requires c.mu (read or write).
+func (c *Chain) checkRelayFeeLocked(tx SignedTx) error {
+	need, size, err := c.params.RequiredFee(tx.Draft)
+	if err != nil {
+		return err
+	}
+	if tx.Draft.Fee >= need {
+		return nil
+	}
+	if size > 0 {
+		return fmt.Errorf("%w: fee %d, node requires %d for %d draft bytes", ErrFeeTooLow, tx.Draft.Fee, need, size)
+	}
+	return fmt.Errorf("%w: fee %d, node requires %d", ErrFeeTooLow, tx.Draft.Fee, need)
+}
+
+// CheckRelayFee reports whether tx meets the node's fee policy; see
+// ChainParams.RequiredFee. MempoolAdd, MempoolReplace and LoadMempool apply
+// it too, so callers only need it to reject a tx early.
+func (c *Chain) CheckRelayFee(tx SignedTx) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.checkRelayFeeLocked(tx)
+}
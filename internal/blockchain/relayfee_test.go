@@ -0,0 +1,57 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMinReplacementFee(t *testing.T) {
+	p := DefaultParams()
+	for _, tc := range []struct {
+		old, want uint64
+	}{
+		{1, 2},     // the relay fee (MinFee) outweighs 10% of 1
+		{100, 110}, // 10%
+		{105, 115}, // rounded down
+		{^uint64(0), ^uint64(0)},
+	} {
+		got, err := p.MinReplacementFee(tc.old, TxDraft{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("MinReplacementFee(%d) = %d, want %d", tc.old, got, tc.want)
+		}
+	}
+
+	p.MinRelayFee = 50
+	if got, _ := p.MinReplacementFee(100, TxDraft{}); got != 150 {
+		t.Errorf("with MinRelayFee 50: got %d, want 150", got)
+	}
+}
+
+func TestMempoolReplaceRequiresBump(t *testing.T) {
+	c := newTestChain(t)
+	alice, bob := newTestKey(t), newTestKey(t)
+	old := alice.transfer(t, bob.addr, 1_000, 100, 1)
+	if err := c.MempoolAdd(old); err != nil {
+		t.Fatal(err)
+	}
+
+	// One unit more used to be enough.
+	cheap := alice.transfer(t, bob.addr, 1_000, 101, 1)
+	if err := c.MempoolReplace(old.TxID, cheap, TxSourceLocal); !errors.Is(err, ErrReplaceFeeTooLow) {
+		t.Fatalf("err = %v, want ErrReplaceFeeTooLow", err)
+	}
+	if !c.MempoolHas(old.TxID) {
+		t.Fatal("rejected replacement evicted the pending tx")
+	}
+
+	bumped := alice.transfer(t, bob.addr, 1_000, 110, 1)
+	if err := c.MempoolReplace(old.TxID, bumped, TxSourceLocal); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if c.MempoolHas(old.TxID) || !c.MempoolHas(bumped.TxID) {
+		t.Fatal("replacement not swapped in")
+	}
+}
@@ -0,0 +1,64 @@
+package blockchain
+
+import "fmt"
+
+// LedgerReverter undoes confirmed txs applied above a block height; see
+// ledger.Ledger.RevertTo.
+type LedgerReverter interface {
+	RevertTo(height uint64) (int, error)
+}
+
+// UnwindTo rewinds the chain to height, e.g. to the common ancestor before
+// applying a competing branch. The ledger is reverted first, so if it cannot
+// reach height (its journal is too shallow, or a balance would underflow)
+// nothing changes. The removed blocks are returned, oldest first, so the
+// caller can return their txs to the mempool; nonce reservations of their
+// senders are rolled back to the highest nonce still confirmed or pending.
+// Blocks produced or accepted meanwhile wait for it; persisting the result
+// is left to the caller. Unwinding under the blocks kept in memory reads
+// the ones it goes back to from the block store.
+func (c *Chain) UnwindTo(height uint64, led LedgerReverter) ([]StoredBlock, error) {
+	c.settleMu.Lock()
+	defer c.settleMu.Unlock()
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	return c.unwindSettled(height, led)
+}
+
+// unwindSettled is UnwindTo; it requires c.settleMu and c.saveMu.
+func (c *Chain) unwindSettled(height uint64, led LedgerReverter) ([]StoredBlock, error) {
+	c.mu.RLock()
+	if height >= c.height {
+		c.mu.RUnlock()
+		return nil, nil
+	}
+	removed := c.tailLocked(height + 1)
+	window, err := c.windowAtLocked(height)
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("unwind to height %d: %w", height, err)
+	}
+	if _, err := led.RevertTo(height); err != nil {
+		return nil, fmt.Errorf("unwind to height %d: %w", height, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resetWindowLocked(window)
+
+	senders := make(map[string]bool)
+	for _, b := range removed {
+		for _, tx := range b.Block.Transactions {
+			senders[tx.Draft.From] = true
+		}
+	}
+	for addr := range senders {
+		floor, _ := c.confirmedNonceLocked(addr)
+		for n := range c.bySender[addr] {
+			floor = max(floor, n)
+		}
+		c.nonces.Rollback(addr, floor)
+	}
+	return removed, nil
+}
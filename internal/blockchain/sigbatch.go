@@ -0,0 +1,81 @@
+package blockchain
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// minSigsPerWorker keeps small blocks on the calling goroutine, where
+// starting workers would cost more than it saves.
+const minSigsPerWorker = 32
+
+// ValidateSignatures verifies every tx signature in b. It applies the other
+// per-tx rules too, because a tx that fails them has no signature worth
+// checking; ValidateBasic runs the structural checks and then this.
+//
+// The standard library has no ed25519 batch verifier, so the signatures are
+// checked together across CPUs instead. If any fails, they are rechecked one
+// by one so the error names the first bad tx.
+func (b *Block) ValidateSignatures() error {
+	checks := make([]sigCheck, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		sc, err := checkSignedTx(tx)
+		if err != nil {
+			return err
+		}
+		checks[i] = sc
+	}
+	return b.verifySigChecks(checks)
+}
+
+// verifySigChecks runs checks, one per tx of b, and reports the first
+// failure as ErrBadSignature.
+func (b *Block) verifySigChecks(checks []sigCheck) error {
+	if verifyAll(checks) {
+		return nil
+	}
+	for i, sc := range checks {
+		if !sc.verify() {
+			return fmt.Errorf("tx %s: %w", b.Transactions[i].TxID, ErrBadSignature)
+		}
+	}
+	// verifyAll and the serial pass disagree only if ed25519.Verify does.
+	return ErrBadSignature
+}
+
+// verifyAll reports whether every check passes, splitting the work across up
+// to GOMAXPROCS goroutines. It stops early once any check fails.
+func verifyAll(checks []sigCheck) bool {
+	workers := min(runtime.GOMAXPROCS(0), len(checks)/minSigsPerWorker)
+	if workers <= 1 {
+		for _, sc := range checks {
+			if !sc.verify() {
+				return false
+			}
+		}
+		return true
+	}
+
+	var (
+		bad  atomic.Bool
+		next atomic.Int64
+		wg   sync.WaitGroup
+	)
+	for range workers {
+		wg.Go(func() {
+			for !bad.Load() {
+				i := int(next.Add(1)) - 1
+				if i >= len(checks) {
+					return
+				}
+				if !checks[i].verify() {
+					bad.Store(true)
+				}
+			}
+		})
+	}
+	wg.Wait()
+	return !bad.Load()
+}
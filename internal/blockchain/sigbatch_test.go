@@ -0,0 +1,64 @@
+package blockchain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockOfTxs builds a block of n signed transfers from one sender.
+func blockOfTxs(t testing.TB, n int) Block {
+	t.Helper()
+	alice, bob := newTestKey(t), newTestKey(t)
+	txs := make([]SignedTx, n)
+	for i := range txs {
+		txs[i] = alice.transfer(t, bob.addr, 1_000, 10, uint64(i+1))
+	}
+	b, err := BuildBlock([32]byte{1}, txs, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestValidateSignaturesNamesBadTx(t *testing.T) {
+	b := blockOfTxs(t, 500)
+	if err := b.ValidateSignatures(); err != nil {
+		t.Fatalf("valid block: %v", err)
+	}
+
+	// Swap in another tx's signature: well formed, but wrong.
+	bad := b.Transactions[317].TxID
+	b.Transactions[317].SignatureHex = b.Transactions[318].SignatureHex
+	err := b.ValidateSignatures()
+	if !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("err = %v, want ErrBadSignature", err)
+	}
+	if !strings.Contains(err.Error(), bad) {
+		t.Errorf("err = %v, want it to name tx %s", err, bad)
+	}
+	if err := b.ValidateBasic(); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("ValidateBasic: err = %v, want ErrBadSignature", err)
+	}
+}
+
+func BenchmarkBlockSignatures(b *testing.B) {
+	blk := blockOfTxs(b, 500)
+	b.Run("batch", func(b *testing.B) {
+		for b.Loop() {
+			if err := blk.ValidateSignatures(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("serial", func(b *testing.B) {
+		for b.Loop() {
+			for _, tx := range blk.Transactions {
+				if err := ValidateSignedTx(tx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -2,11 +2,11 @@ package blockchain
 
 import (
 	"crypto/ed25519"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"time"
 
 	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
 )
@@ -14,12 +14,40 @@ import (
 const (
 	TxVersion uint32 = 1
 
+	// TxVersionMulti drafts pay Outputs instead of To/Amount; see TxOutput.
+	TxVersionMulti uint32 = 2
+
+	// MaxTxOutputs bounds the outputs of a TxVersionMulti draft.
+	MaxTxOutputs = 64
+
 	MaxMemoLen       = 256
-	MaxFutureSkewSec = 5 * 60
+	MaxClientRefLen  = 64
+	MaxFutureSkewSec = 2 * 60 // default; see ChainParams.MaxFutureSkewSec
 	MaxPastSkewSec   = 24 * 3600
 	MinFee           = 1
+
+	// MaxTxBytes bounds CanonicalSignedTxBytes for a single transaction.
+	MaxTxBytes = 4 * 1024
+)
+
+// Transaction classes for mempool QoS; higher classes drain first on nodes
+// that honor class (ChainParams.HonorTxClass, off by default). TxClassBulk is
+// the default.
+const (
+	TxClassBulk     uint8 = 0
+	TxClassStandard uint8 = 1
+	TxClassPriority uint8 = 2
+	TxClassSystem   uint8 = 3
+
+	MaxTxClass = TxClassSystem
 )
 
+// A TxDraft takes one of two forms, by Version:
+//
+//   - TxVersion (1): To receives Amount minus Fee; the sender pays Amount.
+//   - TxVersionMulti (2): each of Outputs receives its Amount in full; the
+//     sender pays their total plus Fee, debited once. To and Amount are left
+//     empty.
 type TxDraft struct {
 	Version   uint32 `json:"version"`
 	NetworkID string `json:"networkId"`
@@ -30,10 +58,28 @@ type TxDraft struct {
 	Amount uint64 `json:"amount"`
 	Fee    uint64 `json:"fee"`
 
+	// Outputs is set only on TxVersionMulti drafts. It is omitted when empty,
+	// so version 1 drafts encode (and hash) as before.
+	Outputs []TxOutput `json:"outputs,omitempty"`
+
 	Nonce     uint64 `json:"nonce"`
 	Timestamp int64  `json:"timestamp"`
 
 	Memo string `json:"memo,omitempty"`
+
+	// ClientRef is an optional caller-chosen correlation reference (e.g. a
+	// merchant order ID). It is covered by the txId and signature but carries
+	// no consensus meaning; idempotent resubmission relies on the txId.
+	ClientRef string `json:"clientRef,omitempty"`
+
+	// Class is an optional QoS hint (0..MaxTxClass) used for mempool ordering.
+	Class uint8 `json:"class,omitempty"`
+}
+
+// TxOutput is one recipient of a TxVersionMulti draft.
+type TxOutput struct {
+	To     string `json:"to"`
+	Amount uint64 `json:"amount"`
 }
 
 type SignedTx struct {
@@ -43,13 +89,85 @@ type SignedTx struct {
 	TxID         string  `json:"txId"`         // hex doubleSha256(canonicalDraftBytes)
 }
 
-func CanonicalDraftBytes(d TxDraft) ([]byte, error) {
-	if d.Version == 0 {
-		d.Version = TxVersion
+// IsCancel reports whether d is a cancel transaction: a zero-value transfer to
+// self. Broadcast at the same (from, nonce) as a pending tx with a higher fee,
+// it replaces that tx in the mempool; once confirmed only the fee is debited.
+func (d TxDraft) IsCancel() bool {
+	return !d.IsMulti() && d.From == d.To && d.Amount == 0
+}
+
+// IsMulti reports whether d is a multi-output (TxVersionMulti) draft.
+func (d TxDraft) IsMulti() bool {
+	return d.Version == TxVersionMulti
+}
+
+// OutputTotal is the sum of d's output amounts; ok is false if it overflows.
+func (d TxDraft) OutputTotal() (total uint64, ok bool) {
+	for _, o := range d.Outputs {
+		if total+o.Amount < total {
+			return 0, false
+		}
+		total += o.Amount
 	}
+	return total, true
+}
+
+// SpendAmount is what the sender's balance is debited: the amount (which
+// includes the fee) for transfers, just the fee for cancels, or the output
+// total plus the fee for multi-output txs. For an invalid multi-output draft
+// whose sum overflows it returns the maximum uint64, which no balance covers.
+func (d TxDraft) SpendAmount() uint64 {
+	switch {
+	case d.IsMulti():
+		total, ok := d.OutputTotal()
+		if !ok || total+d.Fee < total {
+			return ^uint64(0)
+		}
+		return total + d.Fee
+	case d.IsCancel():
+		return d.Fee
+	}
+	return d.Amount
+}
+
+// Recipients lists the addresses d credits, in order: To for a transfer,
+// each output's To for a multi-output tx, none for a cancel.
+func (d TxDraft) Recipients() []string {
+	switch {
+	case d.IsMulti():
+		out := make([]string, len(d.Outputs))
+		for i, o := range d.Outputs {
+			out[i] = o.To
+		}
+		return out
+	case d.IsCancel():
+		return nil
+	}
+	return []string{d.To}
+}
+
+func CanonicalDraftBytes(d TxDraft) ([]byte, error) {
+	d.Version = draftVersion(d)
 	return json.Marshal(d)
 }
 
+// draftVersion is d.Version, or if unset the version its form implies.
+func draftVersion(d TxDraft) uint32 {
+	switch {
+	case d.Version != 0:
+		return d.Version
+	case len(d.Outputs) > 0:
+		return TxVersionMulti
+	}
+	return TxVersion
+}
+
+// TxHash is the txId of d: doubleSha256 of its canonical bytes.
+//
+// Idempotency: the txId is fully determined by the draft, so a client can
+// compute it locally before broadcasting. Re-broadcasting the same signed
+// draft is a no-op; clients should poll by txId rather than re-sign with a
+// fresh nonce, which would produce a distinct transfer.
 func TxHash(d TxDraft) ([32]byte, error) {
 	b, err := CanonicalDraftBytes(d)
 	if err != nil {
@@ -58,6 +176,74 @@ func TxHash(d TxDraft) ([32]byte, error) {
 	return vcrypto.DoubleSha256(b), nil
 }
 
+// CanonicalSignedTxBytes serializes a signed tx for block encoding:
+// [4] draftLen (uint32) + [draftLen] canonical draft bytes
+// [32] ed25519 public key
+// [64] ed25519 signature
+// The txId is omitted since it is derived from the draft.
+func CanonicalSignedTxBytes(st SignedTx) ([]byte, error) {
+	draft, err := CanonicalDraftBytes(st.Draft)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := hex.DecodeString(st.PublicKeyHex)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid publicKeyHex")
+	}
+	sig, err := hex.DecodeString(st.SignatureHex)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, errors.New("invalid signatureHex")
+	}
+
+	buf := make([]byte, 0, 4+len(draft)+len(pub)+len(sig))
+	tmp4 := make([]byte, 4)
+	binary.LittleEndian.PutUint32(tmp4, uint32(len(draft)))
+	buf = append(buf, tmp4...)
+	buf = append(buf, draft...)
+	buf = append(buf, pub...)
+	buf = append(buf, sig...)
+	return buf, nil
+}
+
+// ParseSignedTxBytes decodes the CanonicalSignedTxBytes form. The embedded
+// draft must be in canonical encoding, so the bytes received are exactly the
+// bytes the txId commits to; TxID is derived rather than transmitted.
+func ParseSignedTxBytes(b []byte) (SignedTx, error) {
+	if len(b) > MaxTxBytes {
+		return SignedTx{}, fmt.Errorf("tx too large: %d bytes (max %d)", len(b), MaxTxBytes)
+	}
+	if len(b) < 4+ed25519.PublicKeySize+ed25519.SignatureSize {
+		return SignedTx{}, errors.New("binary tx too short")
+	}
+	draftLen := int(binary.LittleEndian.Uint32(b[:4]))
+	if draftLen != len(b)-4-ed25519.PublicKeySize-ed25519.SignatureSize {
+		return SignedTx{}, errors.New("binary tx length mismatch")
+	}
+	draftBytes := b[4 : 4+draftLen]
+	pub := b[4+draftLen : 4+draftLen+ed25519.PublicKeySize]
+	sig := b[4+draftLen+ed25519.PublicKeySize:]
+
+	var d TxDraft
+	if err := json.Unmarshal(draftBytes, &d); err != nil {
+		return SignedTx{}, errors.New("invalid draft encoding")
+	}
+	canon, err := CanonicalDraftBytes(d)
+	if err != nil {
+		return SignedTx{}, err
+	}
+	if string(canon) != string(draftBytes) {
+		return SignedTx{}, errors.New("draft is not canonically encoded")
+	}
+
+	h := vcrypto.DoubleSha256(canon)
+	return SignedTx{
+		Draft:        d,
+		PublicKeyHex: hex.EncodeToString(pub),
+		SignatureHex: hex.EncodeToString(sig),
+		TxID:         hex.EncodeToString(h[:]),
+	}, nil
+}
+
 // SignatureMessage = sha256("veltaros-tx-sign" || networkID || txHash)
 func SignatureMessage(networkID string, txHash [32]byte) [32]byte {
 	domain := []byte("veltaros-tx-sign")
@@ -68,96 +254,203 @@ func SignatureMessage(networkID string, txHash [32]byte) [32]byte {
 	return vcrypto.Sha256(msg)
 }
 
-func ValidateSignedTx(st SignedTx) error {
-	d := st.Draft
-
-	if d.Version != TxVersion {
-		return fmt.Errorf("unsupported tx version: %d", d.Version)
+// SignDraft computes the txId and signs d with priv.
+func SignDraft(priv ed25519.PrivateKey, d TxDraft) (SignedTx, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return SignedTx{}, errors.New("invalid ed25519 private key size")
 	}
-	if d.NetworkID == "" {
-		return errors.New("networkId is required")
+	d.Version = draftVersion(d)
+	h, err := TxHash(d)
+	if err != nil {
+		return SignedTx{}, err
 	}
+	sm := SignatureMessage(d.NetworkID, h)
+	sig := ed25519.Sign(priv, sm[:])
+	pub := priv.Public().(ed25519.PublicKey)
 
-	// Address format validation
-	if err := ValidateAddress(d.From); err != nil {
-		return fmt.Errorf("invalid from address: %w", err)
+	return SignedTx{
+		Draft:        d,
+		PublicKeyHex: hex.EncodeToString(pub),
+		SignatureHex: hex.EncodeToString(sig),
+		TxID:         hex.EncodeToString(h[:]),
+	}, nil
+}
+
+// validateTransfer checks the To/Amount/Fee of a version 1 draft.
+func validateTransfer(d TxDraft) error {
+	if len(d.Outputs) > 0 {
+		return ErrMixedTxForm
 	}
 	if err := ValidateAddress(d.To); err != nil {
-		return fmt.Errorf("invalid to address: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidTo, err)
 	}
-	if d.From == d.To {
-		return errors.New("from and to must differ")
+	cancel := d.IsCancel()
+	if d.From == d.To && !cancel {
+		return ErrSelfTransfer
 	}
 
-	if d.Amount == 0 {
-		return errors.New("amount must be > 0")
+	if d.Amount == 0 && !cancel {
+		return ErrAmountZero
+	}
+	if d.Fee < MinFee {
+		return fmt.Errorf("%w: must be >= %d", ErrFeeTooLow, MinFee)
+	}
+	if d.Fee > d.Amount && !cancel {
+		return ErrFeeTooHigh
+	}
+	return nil
+}
+
+// validateOutputs checks the Outputs/Fee of a TxVersionMulti draft: 1 to
+// MaxTxOutputs distinct recipients other than the sender, each paid a
+// non-zero amount, with the total plus fee fitting in a uint64.
+func validateOutputs(d TxDraft) error {
+	if d.To != "" || d.Amount != 0 {
+		return ErrMixedTxForm
+	}
+	if len(d.Outputs) == 0 {
+		return ErrNoOutputs
+	}
+	if len(d.Outputs) > MaxTxOutputs {
+		return fmt.Errorf("%w: %d (max %d)", ErrTooManyOutputs, len(d.Outputs), MaxTxOutputs)
+	}
+	seen := make(map[string]bool, len(d.Outputs))
+	for i, o := range d.Outputs {
+		if err := ValidateAddress(o.To); err != nil {
+			return fmt.Errorf("%w: output %d: %w", ErrInvalidTo, i, err)
+		}
+		if o.To == d.From {
+			return fmt.Errorf("%w: output %d", ErrSelfTransfer, i)
+		}
+		if seen[o.To] {
+			return fmt.Errorf("%w: output %d", ErrDuplicateOutput, i)
+		}
+		seen[o.To] = true
+		if o.Amount == 0 {
+			return fmt.Errorf("%w: output %d", ErrAmountZero, i)
+		}
 	}
 	if d.Fee < MinFee {
-		return fmt.Errorf("fee must be >= %d", MinFee)
+		return fmt.Errorf("%w: must be >= %d", ErrFeeTooLow, MinFee)
+	}
+	total, ok := d.OutputTotal()
+	if !ok || total+d.Fee < total {
+		return ErrAmountOverflow
+	}
+	return nil
+}
+
+func ValidateSignedTx(st SignedTx) error {
+	sc, err := checkSignedTx(st)
+	if err != nil {
+		return err
+	}
+	if !sc.verify() {
+		return ErrBadSignature
 	}
-	if d.Fee > d.Amount {
-		return errors.New("fee must be <= amount")
+	return nil
+}
+
+// sigCheck is the ed25519 verification a tx's validity ends with, kept
+// apart so a block can run all of its checks together (see
+// Block.ValidateSignatures).
+type sigCheck struct {
+	pub ed25519.PublicKey
+	msg [32]byte
+	sig []byte
+}
+
+func (c sigCheck) verify() bool {
+	return ed25519.Verify(c.pub, c.msg[:], c.sig)
+}
+
+// checkSignedTx runs every ValidateSignedTx rule except the signature
+// verification itself, which it returns for the caller to run.
+func checkSignedTx(st SignedTx) (sigCheck, error) {
+	d := st.Draft
+
+	if d.Version != TxVersion && d.Version != TxVersionMulti {
+		return sigCheck{}, fmt.Errorf("%w: %d", ErrTxVersion, d.Version)
+	}
+	if d.NetworkID == "" {
+		return sigCheck{}, ErrNetworkIDRequired
+	}
+
+	// Address format validation
+	if err := ValidateAddress(d.From); err != nil {
+		return sigCheck{}, fmt.Errorf("%w: %w", ErrInvalidFrom, err)
+	}
+	if d.IsMulti() {
+		if err := validateOutputs(d); err != nil {
+			return sigCheck{}, err
+		}
+	} else if err := validateTransfer(d); err != nil {
+		return sigCheck{}, err
 	}
 	if d.Nonce == 0 {
-		return errors.New("nonce must be > 0")
+		return sigCheck{}, ErrNonceZero
 	}
 	if d.Timestamp <= 0 {
-		return errors.New("timestamp required")
+		return sigCheck{}, ErrTimestampRequired
 	}
 	if len(d.Memo) > MaxMemoLen {
-		return errors.New("memo too long")
+		return sigCheck{}, ErrMemoTooLong
 	}
-
-	// Timestamp skew policy
-	now := time.Now().UTC().Unix()
-	if d.Timestamp > now+MaxFutureSkewSec {
-		return errors.New("timestamp too far in future")
+	if d.Class > MaxTxClass {
+		return sigCheck{}, fmt.Errorf("%w: must be <= %d", ErrClassTooHigh, MaxTxClass)
 	}
-	if d.Timestamp < now-MaxPastSkewSec {
-		return errors.New("timestamp too far in past")
+	if len(d.ClientRef) > MaxClientRefLen {
+		return sigCheck{}, ErrClientRefTooLong
+	}
+	for i := 0; i < len(d.ClientRef); i++ {
+		if c := d.ClientRef[i]; c < 0x20 || c == 0x7f {
+			return sigCheck{}, ErrClientRefInvalid
+		}
+	}
+
+	if raw, err := CanonicalSignedTxBytes(st); err == nil && len(raw) > MaxTxBytes {
+		return sigCheck{}, fmt.Errorf("%w: %d bytes (max %d)", ErrTxTooLarge, len(raw), MaxTxBytes)
 	}
 
 	// Parse signer public key
 	pubBytes, err := hex.DecodeString(st.PublicKeyHex)
 	if err != nil {
-		return errors.New("invalid publicKeyHex")
+		return sigCheck{}, ErrBadPublicKey
 	}
 	if len(pubBytes) != ed25519.PublicKeySize {
-		return errors.New("invalid publicKeyHex size")
+		return sigCheck{}, fmt.Errorf("%w: wrong size", ErrBadPublicKey)
 	}
 
 	// Bind signer -> from address (critical)
 	derivedFrom, err := AddressFromEd25519PublicKeyHex(st.PublicKeyHex)
 	if err != nil {
-		return err
+		return sigCheck{}, err
 	}
 	if derivedFrom != d.From {
-		return errors.New("from address does not match signer public key")
+		return sigCheck{}, ErrFromKeyMismatch
 	}
 
 	// Signature bytes
 	sigBytes, err := hex.DecodeString(st.SignatureHex)
 	if err != nil {
-		return errors.New("invalid signatureHex")
+		return sigCheck{}, ErrBadSignatureFormat
 	}
 	if len(sigBytes) != ed25519.SignatureSize {
-		return errors.New("invalid signatureHex size")
+		return sigCheck{}, fmt.Errorf("%w: wrong size", ErrBadSignatureFormat)
 	}
 
 	// Tx ID correctness
 	h, err := TxHash(d)
 	if err != nil {
-		return err
+		return sigCheck{}, err
 	}
 	if hex.EncodeToString(h[:]) != st.TxID {
-		return errors.New("txId mismatch")
-	}
-
-	// Signature correctness
-	sm := SignatureMessage(d.NetworkID, h)
-	if !ed25519.Verify(ed25519.PublicKey(pubBytes), sm[:], sigBytes) {
-		return errors.New("invalid signature")
+		return sigCheck{}, ErrTxIDMismatch
 	}
 
-	return nil
+	return sigCheck{
+		pub: ed25519.PublicKey(pubBytes),
+		msg: SignatureMessage(d.NetworkID, h),
+		sig: sigBytes,
+	}, nil
 }
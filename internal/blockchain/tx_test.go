@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+// signerKey is an ed25519 key with the address it owns.
+type signerKey struct {
+	priv ed25519.PrivateKey
+	addr string
+}
+
+func newSignerKey(t *testing.T) signerKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := AddressFromEd25519PublicKeyHex(hex.EncodeToString(pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signerKey{priv: priv, addr: addr}
+}
+
+func TestValidateSignedTxSignerMismatch(t *testing.T) {
+	alice, bob, carol := newSignerKey(t), newSignerKey(t), newSignerKey(t)
+	draft := func(from string) TxDraft {
+		return TxDraft{
+			NetworkID: "veltaros-testnet",
+			From:      from,
+			To:        carol.addr,
+			Amount:    10,
+			Fee:       1,
+			Nonce:     1,
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	ok, err := SignDraft(alice.priv, draft(alice.addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateSignedTx(ok); err != nil {
+		t.Fatalf("well-formed tx rejected: %v", err)
+	}
+
+	// Alice signs a draft spending from Bob's address. The signature is
+	// valid for Alice's key, but that key does not own From.
+	forged, err := SignDraft(alice.priv, draft(bob.addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateSignedTx(forged); !errors.Is(err, ErrFromKeyMismatch) {
+		t.Errorf("tx signed by a key not owning From: err = %v, want ErrFromKeyMismatch", err)
+	}
+
+	// Swapping in Bob's public key fails the same check, before the
+	// signature is looked at.
+	swapped := ok
+	swapped.PublicKeyHex = hex.EncodeToString(bob.priv.Public().(ed25519.PublicKey))
+	if err := ValidateSignedTx(swapped); !errors.Is(err, ErrFromKeyMismatch) {
+		t.Errorf("tx carrying another key: err = %v, want ErrFromKeyMismatch", err)
+	}
+	if code := TxErrorCode(ErrFromKeyMismatch); code != "SIGNER_MISMATCH" {
+		t.Errorf("code = %q, want SIGNER_MISMATCH", code)
+	}
+}
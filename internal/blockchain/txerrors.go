@@ -0,0 +1,66 @@
+package blockchain
+
+import "errors"
+
+// TxError is a reason a tx is rejected. Code is a stable identifier API
+// clients can match on instead of the message; errors returned by
+// ValidateSignedTx wrap one of the Err* values below, so errors.Is works
+// too.
+type TxError struct {
+	Code string
+	msg  string
+}
+
+func (e *TxError) Error() string { return e.msg }
+
+func txError(code, msg string) *TxError { return &TxError{Code: code, msg: msg} }
+
+var (
+	ErrTxVersion          = txError("UNSUPPORTED_VERSION", "unsupported tx version")
+	ErrNetworkIDRequired  = txError("NETWORK_ID_REQUIRED", "networkId is required")
+	ErrInvalidFrom        = txError("INVALID_FROM", "invalid from address")
+	ErrInvalidTo          = txError("INVALID_TO", "invalid to address")
+	ErrSelfTransfer       = txError("SELF_TRANSFER", "from and to must differ")
+	ErrAmountZero         = txError("AMOUNT_ZERO", "amount must be > 0")
+	ErrFeeTooLow          = txError("FEE_TOO_LOW", "fee below minimum")
+	ErrFeeTooHigh         = txError("FEE_TOO_HIGH", "fee must be <= amount")
+	ErrNonceZero          = txError("NONCE_ZERO", "nonce must be > 0")
+	ErrTimestampRequired  = txError("TIMESTAMP_REQUIRED", "timestamp required")
+	ErrMemoTooLong        = txError("MEMO_TOO_LONG", "memo too long")
+	ErrClassTooHigh       = txError("CLASS_TOO_HIGH", "class too high")
+	ErrClientRefTooLong   = txError("CLIENT_REF_TOO_LONG", "clientRef too long")
+	ErrClientRefInvalid   = txError("CLIENT_REF_INVALID", "clientRef contains control characters")
+	ErrTimestampFuture    = txError("TIMESTAMP_FUTURE", "timestamp too far in future")
+	ErrTimestampPast      = txError("TIMESTAMP_PAST", "timestamp too far in past")
+	ErrTxTooLarge         = txError("TX_TOO_LARGE", "tx too large")
+	ErrBadPublicKey       = txError("BAD_PUBLIC_KEY", "invalid publicKeyHex")
+	ErrSignerMismatch     = txError("SIGNER_MISMATCH", "from address does not match signer public key")
+	ErrBadSignatureFormat = txError("BAD_SIGNATURE_FORMAT", "invalid signatureHex")
+	ErrTxIDMismatch       = txError("TXID_MISMATCH", "txId mismatch")
+	ErrBadSignature       = txError("BAD_SIGNATURE", "invalid signature")
+
+	// Multi-output (TxVersionMulti) drafts.
+	ErrMixedTxForm     = txError("MIXED_TX_FORM", "version 1 txs use to/amount and version 2 txs use outputs, not both")
+	ErrNoOutputs       = txError("NO_OUTPUTS", "outputs required")
+	ErrTooManyOutputs  = txError("TOO_MANY_OUTPUTS", "too many outputs")
+	ErrDuplicateOutput = txError("DUPLICATE_OUTPUT", "duplicate output recipient")
+	ErrAmountOverflow  = txError("AMOUNT_OVERFLOW", "output total plus fee overflows")
+
+	// Admission (mempool) rejections.
+	ErrNonceTooLow      = txError("NONCE_TOO_LOW", "nonce too low")
+	ErrReplaceFeeTooLow = txError("REPLACEMENT_FEE_TOO_LOW", "replacement fee must exceed pending fee by the minimum bump")
+)
+
+// ErrFromKeyMismatch is ErrSignerMismatch: the draft's From is not the
+// address derived from PublicKeyHex, so even a valid signature cannot spend
+// from it. Both names match the same error and report SIGNER_MISMATCH.
+var ErrFromKeyMismatch = ErrSignerMismatch
+
+// TxErrorCode returns the Code of the TxError err wraps, or "".
+func TxErrorCode(err error) string {
+	var te *TxError
+	if errors.As(err, &te) {
+		return te.Code
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+package blockchain
+
+import (
+	"fmt"
+	"time"
+)
+
+// CheckTxTimestamp applies the timestamp window to ts as of now: at most
+// maxFuture ahead, at most MaxPastSkewSec behind. Taking both as arguments
+// keeps it deterministic for callers that need a fixed clock.
+func CheckTxTimestamp(ts int64, now time.Time, maxFuture time.Duration) error {
+	if ts <= 0 {
+		return ErrTimestampRequired
+	}
+	n := now.Unix()
+	if ahead := ts - n; ahead > int64(maxFuture/time.Second) {
+		return fmt.Errorf("%w: %ds ahead of node clock (max %s)", ErrTimestampFuture, ahead, maxFuture)
+	}
+	if ts < n-MaxPastSkewSec {
+		return ErrTimestampPast
+	}
+	return nil
+}
+
+// CheckTxTime applies the timestamp window to tx as of the chain's clock,
+// with ChainParams.MaxFutureSkewSec as the future limit. It is mempool
+// policy, not validity: a block may carry txs of any age, so a node that is
+// far behind can still sync, and ValidateSignedTx does not check it. Like a
+// stale nonce, a tx outside the window is dropped without blaming the peer
+// that relayed it.
+func (c *Chain) CheckTxTime(tx SignedTx) error {
+	c.mu.RLock()
+	maxFuture := time.Duration(c.params.MaxFutureSkewSec) * time.Second
+	c.mu.RUnlock()
+	return CheckTxTimestamp(tx.Draft.Timestamp, c.clock.Now(), maxFuture)
+}
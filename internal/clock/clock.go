@@ -0,0 +1,61 @@
+// Package clock abstracts the wall clock so time-dependent code (score
+// decay, ban expiry, mempool TTL) can be driven by a fake in tests instead
+// of sleeping.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is the system clock, in UTC.
+type Real struct{}
+
+func (Real) Now() time.Time { return time.Now().UTC() }
+
+// OrReal returns c, or Real if c is nil, so a nil Clock field means the
+// system clock.
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real{}
+	}
+	return c
+}
+
+// Fake is a Clock that only moves when told to. It is safe for concurrent
+// use.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a Fake reading t (converted to UTC).
+func NewFake(t time.Time) *Fake {
+	return &Fake{now: t.UTC()}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Set moves the clock to t, which may be in the past.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	f.now = t.UTC()
+	f.mu.Unlock()
+}
+
+// Advance moves the clock forward by d and returns the new time.
+func (f *Fake) Advance(d time.Duration) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	return f.now
+}
@@ -4,28 +4,54 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/api"
 )
 
 type Config struct {
-	Network NetworkConfig
-	API     APIConfig
-	Log     LogConfig
-	Storage StorageConfig
-	Ledger  LedgerConfig
+	Network  NetworkConfig
+	API      APIConfig
+	Log      LogConfig
+	Storage  StorageConfig
+	Ledger   LedgerConfig
+	Chain    ChainConfig
+	Producer ProducerConfig
 }
 
 type NetworkConfig struct {
 	ListenAddr       string
 	ExternalAddr     string
 	BootstrapPeers   []string
+	BootstrapFile    string
 	MaxPeers         int
 	DialTimeout      time.Duration
 	HandshakeTimeout time.Duration
 
+	MaxPendingHandshakes int
+	MaxUnverifiedPeers   int // 0 means MaxPeers/4
+	MaxInbound           int // 0 means what MaxOutbound leaves of MaxPeers
+	MaxOutbound          int // 0 means max(4, MaxPeers/3)
+	ProtectedPeers       int // 0 means min(8, MaxInbound)
+	MaxDialAttempts      int // failed dials in a row before a non-bootstrap peer is forgotten
+	MaxKnownPeers        int // known peers kept; the least recently seen are evicted
+	PuzzleBits           int // proof-of-work bits inbound peers must solve; 0 = off
+	TxGossipBudget       int
+	TxGossipInterval     time.Duration
+	PingInterval         time.Duration
+	PongTimeout          time.Duration
+	PeerIdleTimeout      time.Duration
+	AllowPrivateAddrs    bool // accept loopback/private peer addresses (also in mainnet gossip)
+
+	// Encrypt offers an encrypted transport to peers; MinEncryption is
+	// "none" (plaintext peers still accepted) or "aead" (required).
+	Encrypt       bool
+	MinEncryption string
+
 	NetworkID          string
 	IdentityKeyPath    string
 	IdentityRecordPath string
@@ -33,14 +59,72 @@ type NetworkConfig struct {
 	PeerStorePath      string
 	ScoreStorePath     string
 
-	NonceStorePath string
-	BlockStorePath string
+	NonceStorePath   string
+	BlockStorePath   string
+	MempoolStorePath string
 }
 
 type LedgerConfig struct {
 	StorePath string
 }
 
+type ChainConfig struct {
+	// GenesisPath is a genesis JSON file (network ID, timestamp, initial
+	// allocations); "" uses the built-in empty genesis.
+	GenesisPath string
+
+	RequireKnownRecipient bool
+	HonorTxClass          bool
+
+	IntegrityInterval time.Duration // 0 disables the background block store check
+	QuarantineCorrupt bool
+
+	MempoolMaxAge time.Duration // pending txs older than this are evicted; 0 disables
+
+	// MaxFutureSkew is how far ahead of the node's clock a tx may be dated.
+	MaxFutureSkew time.Duration
+
+	// MempoolMaxTxs and MempoolMaxBytes cap the mempool by count and by
+	// serialized size; the lowest-fee txs are evicted past either. 0 disables.
+	MempoolMaxTxs   int
+	MempoolMaxBytes int
+
+	// MinRelayFee and MinRelayFeePerByte (of the canonical draft) are the
+	// node's fee floor for admitting txs, above the consensus MinFee. 0
+	// disables each.
+	MinRelayFee        uint64
+	MinRelayFeePerByte uint64
+
+	// BlockReward is the most a block's coinbase may mint besides fees; it
+	// must match across the network.
+	BlockReward uint64
+
+	// PowDifficulty is the leading zero bits every block header hash must
+	// have; it must match across the network. 0 disables proof of work.
+	PowDifficulty int
+
+	// NonceGrace, when > 0, holds txs whose nonce is ahead of the sender's
+	// expected nonce for up to this long so out-of-order bursts admit in
+	// sequence; at most NonceGraceMax are held per sender.
+	NonceGrace    time.Duration
+	NonceGraceMax int
+
+	// StrictNonces admits a tx only at the sender's expected nonce. Txs
+	// ahead of it are held (up to NonceGraceMax per sender) until the gap
+	// fills and are dropped, not admitted with the gap, if it never does.
+	StrictNonces bool
+}
+
+type ProducerConfig struct {
+	Enabled  bool
+	Interval time.Duration
+	MaxTxs   int // 0 means only the block byte limit applies
+
+	// RewardAddr, when set, is paid each produced block's reward and fees
+	// through its coinbase. Empty produces blocks that burn their fees.
+	RewardAddr string
+}
+
 type APIConfig struct {
 	Enabled      bool
 	ListenAddr   string
@@ -49,11 +133,52 @@ type APIConfig struct {
 	IdleTimeout  time.Duration
 
 	AllowedOrigins []string
-	APIKey         string
+	APIKey         Secret
 	KeyOnValidate  bool
 	KeyOnBroadcast bool
+	// KeyOnPeerState requires the API key for /bans and /scores.
+	KeyOnPeerState bool
+	// KeyOnEvents requires the API key for the /events stream.
+	KeyOnEvents bool
 
 	FaucetEnabled bool
+
+	// TxRateLimit (tokens/sec), TxBurst and TxCost (tokens per request)
+	// configure the per-client limiter on /tx/validate and /tx/broadcast.
+	TxRateLimit float64
+	TxBurst     float64
+	TxCost      float64
+
+	// RateLimitByKey gives requests carrying the valid X-API-Key a limiter
+	// bucket of their own per client IP, apart from that IP's other traffic;
+	// others are still limited by IP alone.
+	RateLimitByKey bool
+
+	// MinPeers, when > 0, flags /tx/broadcast responses while the node has
+	// fewer connected peers; MinPeersStrict turns the warning into a 503.
+	MinPeers       int
+	MinPeersStrict bool
+
+	// AuditLogPath is the hash-chained log of admin actions ("" disables).
+	AuditLogPath string
+
+	// BinaryTx accepts Content-Type application/octet-stream bodies (the
+	// blockchain.CanonicalSignedTxBytes encoding) on the tx endpoints.
+	BinaryTx bool
+
+	// TrustedProxies (CIDRs or IPs) are reverse proxies whose
+	// X-Forwarded-For the rate limiter honors. Empty ignores the header.
+	TrustedProxies []string
+
+	// Metrics serves Prometheus metrics on /metrics (behind the API key, if
+	// one is set).
+	Metrics bool
+
+	// WatchdogInterval, when > 0, probes the node's own /healthz (and the
+	// chain lock) that often; after WatchdogFailures consecutive failed
+	// probes the process exits so a supervisor can restart it.
+	WatchdogInterval time.Duration
+	WatchdogFailures int
 }
 
 type LogConfig struct {
@@ -63,6 +188,21 @@ type LogConfig struct {
 
 type StorageConfig struct {
 	DataDir string
+
+	// KeepBackups keeps one <file>.bak of the ledger, block, nonce and peer
+	// stores before each overwrite. A store that fails to load falls back to
+	// its backup with a warning.
+	KeepBackups bool
+
+	// BlockBackend selects the block store: "json" rewrites one JSON array on
+	// every save; "log" appends one JSON block per line; "bolt" keeps a bbolt
+	// database keyed by height and hash. json and log read each other's file,
+	// so switching converts it on the next save; bolt imports either file once
+	// when its database is empty.
+	BlockBackend string
+
+	// RuntimeMetaPath persists restart count and cumulative uptime (see /status).
+	RuntimeMetaPath string
 }
 
 func Default() Config {
@@ -75,6 +215,16 @@ func Default() Config {
 			DialTimeout:      7 * time.Second,
 			HandshakeTimeout: 7 * time.Second,
 
+			MaxPendingHandshakes: 32,
+			MaxDialAttempts:      10,
+			MaxKnownPeers:        1024,
+			TxGossipBudget:       100,
+			TxGossipInterval:     10 * time.Second,
+			PingInterval:         15 * time.Second,
+			PongTimeout:          10 * time.Second,
+			PeerIdleTimeout:      2 * time.Minute,
+			MinEncryption:        "none",
+
 			NetworkID:          "veltaros-mainnet",
 			IdentityKeyPath:    "data/node/identity.key",
 			IdentityRecordPath: "data/node/identity.json",
@@ -83,10 +233,33 @@ func Default() Config {
 			ScoreStorePath:     "data/node/scores.json",
 			NonceStorePath:     "data/node/nonces.json",
 			BlockStorePath:     "data/node/blocks.json",
+			MempoolStorePath:   "data/node/mempool.json",
 		},
 		Ledger: LedgerConfig{
 			StorePath: "data/node/ledger.json",
 		},
+		Chain: ChainConfig{
+			RequireKnownRecipient: false,
+			HonorTxClass:          false,
+
+			IntegrityInterval: 10 * time.Minute,
+			QuarantineCorrupt: false,
+
+			MempoolMaxAge: 30 * time.Minute,
+			MaxFutureSkew: 2 * time.Minute,
+
+			MempoolMaxTxs:   50_000,
+			MempoolMaxBytes: 64 << 20,
+
+			NonceGrace:    0,
+			NonceGraceMax: 16,
+			StrictNonces:  true,
+		},
+		Producer: ProducerConfig{
+			Enabled:  false,
+			Interval: 10 * time.Second,
+			MaxTxs:   1000,
+		},
 		API: APIConfig{
 			Enabled:      true,
 			ListenAddr:   "127.0.0.1:8080",
@@ -98,34 +271,91 @@ func Default() Config {
 			APIKey:         "",
 			KeyOnValidate:  false,
 			KeyOnBroadcast: false,
+			KeyOnPeerState: false,
+			KeyOnEvents:    false,
 
 			FaucetEnabled: false,
+
+			TxRateLimit: 2,
+			TxBurst:     10,
+			TxCost:      1,
+
+			MinPeers:       0,
+			MinPeersStrict: false,
+
+			AuditLogPath: "data/node/audit.log",
+
+			BinaryTx: true,
+
+			WatchdogInterval: 0,
+			WatchdogFailures: 3,
 		},
 		Log: LogConfig{
 			Level:  "info",
 			Format: "json",
 		},
 		Storage: StorageConfig{
-			DataDir: "data",
+			DataDir:         "data",
+			KeepBackups:     true,
+			BlockBackend:    "json",
+			RuntimeMetaPath: "data/node/runtime.json",
 		},
 	}
 }
 
 type Parsed struct {
 	Config Config
+
+	// PrintConfig asks the caller to print Config (see Marshal) and exit.
+	PrintConfig bool
 }
 
+// ParseNodeFlags builds the node config from, in increasing precedence:
+// Default(), the --config file, VELTAROS_* environment variables and flags.
 func ParseNodeFlags(args []string) (Parsed, error) {
 	cfg := Default()
 
+	configPath := envOr("VELTAROS_CONFIG", "")
+	if p, ok := configPathArg(args); ok {
+		configPath = strings.TrimSpace(p)
+	}
+	if configPath != "" {
+		if err := LoadFile(configPath, &cfg); err != nil {
+			return Parsed{}, err
+		}
+	}
+
 	fs := flag.NewFlagSet("veltaros-node", flag.ContinueOnError)
 	fs.SetOutput(os.Stdout)
 
+	apiKey := Secret(envOr("VELTAROS_API_KEY", cfg.API.APIKey.Reveal()))
+	fs.Var(&apiKey, "api.key", "Optional API key (X-API-Key)")
+
 	var (
-		listenAddr   = fs.String("p2p.listen", envOr("VELTAROS_P2P_LISTEN", cfg.Network.ListenAddr), "P2P listen address (ip:port)")
-		externalAddr = fs.String("p2p.external", envOr("VELTAROS_P2P_EXTERNAL", cfg.Network.ExternalAddr), "P2P external advertised address (optional)")
-		bootstrap    = fs.String("p2p.bootstrap", envOr("VELTAROS_P2P_BOOTSTRAP", ""), "CSV bootstrap peers")
-		maxPeers     = fs.Int("p2p.maxPeers", envOrInt("VELTAROS_P2P_MAXPEERS", cfg.Network.MaxPeers), "Maximum peers")
+		_           = fs.String("config", configPath, "JSON config file; environment variables and flags override it")
+		printConfig = fs.Bool("config.print", false, "Print the effective config in config file format and exit (api.key shows as ***)")
+
+		listenAddr    = fs.String("p2p.listen", envOr("VELTAROS_P2P_LISTEN", cfg.Network.ListenAddr), "P2P listen address (ip:port)")
+		externalAddr  = fs.String("p2p.external", envOr("VELTAROS_P2P_EXTERNAL", cfg.Network.ExternalAddr), "P2P external advertised address (optional)")
+		bootstrap     = fs.String("p2p.bootstrap", envOr("VELTAROS_P2P_BOOTSTRAP", ""), "CSV bootstrap peers")
+		bootFile      = fs.String("p2p.bootstrapFile", envOr("VELTAROS_P2P_BOOTSTRAP_FILE", cfg.Network.BootstrapFile), "File of bootstrap peers (one host:port per line, # comments)")
+		allowPrivate  = fs.Bool("p2p.allowPrivateAddrs", envOrBool("VELTAROS_P2P_ALLOW_PRIVATE_ADDRS", cfg.Network.AllowPrivateAddrs), "Accept loopback/private peer addresses in HELLOs and, on mainnet, in peer gossip and dialing (local test networks)")
+		maxPeers      = fs.Int("p2p.maxPeers", envOrInt("VELTAROS_P2P_MAXPEERS", cfg.Network.MaxPeers), "Maximum peers")
+		maxHandshake  = fs.Int("p2p.maxHandshakes", envOrInt("VELTAROS_P2P_MAX_HANDSHAKES", cfg.Network.MaxPendingHandshakes), "Maximum concurrent in-progress handshakes")
+		maxUnverified = fs.Int("p2p.maxUnverified", envOrInt("VELTAROS_P2P_MAX_UNVERIFIED", cfg.Network.MaxUnverifiedPeers), "Maximum peer slots held by unverified peers; the rest of p2p.maxPeers is reserved for verified peers (0 = maxPeers/4)")
+		maxInbound    = fs.Int("p2p.maxInbound", envOrInt("VELTAROS_P2P_MAX_INBOUND", cfg.Network.MaxInbound), "Maximum accepted peers, within p2p.maxPeers (0 = the slots p2p.maxOutbound leaves)")
+		maxOutbound   = fs.Int("p2p.maxOutbound", envOrInt("VELTAROS_P2P_MAX_OUTBOUND", cfg.Network.MaxOutbound), "Maximum and target dialed peers, within p2p.maxPeers (0 = max(4, maxPeers/3))")
+		protected     = fs.Int("p2p.protectedPeers", envOrInt("VELTAROS_P2P_PROTECTED_PEERS", cfg.Network.ProtectedPeers), "Longest-connected verified inbound peers never evicted for a new inbound peer when full (0 = min(8, maxInbound))")
+		maxDialTries  = fs.Int("p2p.maxDialAttempts", envOrInt("VELTAROS_P2P_MAX_DIAL_ATTEMPTS", cfg.Network.MaxDialAttempts), "Failed dials in a row before a non-bootstrap peer is forgotten")
+		maxKnown      = fs.Int("p2p.maxKnownPeers", envOrInt("VELTAROS_P2P_MAX_KNOWN_PEERS", cfg.Network.MaxKnownPeers), "Known peer addresses kept; the least recently seen non-bootstrap ones are evicted")
+		puzzleBits    = fs.Int("p2p.puzzleBits", envOrInt("VELTAROS_P2P_PUZZLE_BITS", cfg.Network.PuzzleBits), "Proof-of-work bits inbound peers must solve in the handshake (0 = disabled, max 24)")
+		txBudget      = fs.Int("p2p.txBudget", envOrInt("VELTAROS_P2P_TX_BUDGET", cfg.Network.TxGossipBudget), "Relayed txs accepted per peer per p2p.txBudgetInterval")
+		txBudgetIvl   = fs.Duration("p2p.txBudgetInterval", envOrDuration("VELTAROS_P2P_TX_BUDGET_INTERVAL", cfg.Network.TxGossipInterval), "Window for the per-peer tx gossip budget")
+		pingInterval  = fs.Duration("p2p.pingInterval", envOrDuration("VELTAROS_P2P_PING_INTERVAL", cfg.Network.PingInterval), "Ping a peer after this long without traffic from it")
+		pongTimeout   = fs.Duration("p2p.pongTimeout", envOrDuration("VELTAROS_P2P_PONG_TIMEOUT", cfg.Network.PongTimeout), "Drop a pinged peer that does not answer within this")
+		encrypt       = fs.Bool("p2p.encrypt", envOrBool("VELTAROS_P2P_ENCRYPT", cfg.Network.Encrypt), "Offer an encrypted transport (X25519 + AES-GCM) to peers")
+		minEncryption = fs.String("p2p.minEncryption", envOr("VELTAROS_P2P_MIN_ENCRYPTION", cfg.Network.MinEncryption), "none (accept plaintext peers) or aead (require encryption; needs p2p.encrypt)")
+		peerIdle      = fs.Duration("p2p.peerIdleTimeout", envOrDuration("VELTAROS_P2P_PEER_IDLE_TIMEOUT", cfg.Network.PeerIdleTimeout), "Disconnect a peer that sends nothing but its own pings for this long (at least 2*pingInterval + pongTimeout)")
 
 		networkID      = fs.String("p2p.network", envOr("VELTAROS_NETWORK_ID", cfg.Network.NetworkID), "Network ID")
 		identityKey    = fs.String("p2p.identityKey", envOr("VELTAROS_IDENTITY_KEY", cfg.Network.IdentityKeyPath), "Identity private key path")
@@ -134,24 +364,65 @@ func ParseNodeFlags(args []string) (Parsed, error) {
 		peerStore      = fs.String("p2p.peerStore", envOr("VELTAROS_PEERSTORE_PATH", cfg.Network.PeerStorePath), "Peer store path")
 		scoreStore     = fs.String("p2p.scoreStore", envOr("VELTAROS_SCORESTORE_PATH", cfg.Network.ScoreStorePath), "Score store path")
 
-		nonceStore = fs.String("tx.nonceStore", envOr("VELTAROS_NONCESTORE_PATH", cfg.Network.NonceStorePath), "Nonce store path")
-		blockStore = fs.String("chain.blockStore", envOr("VELTAROS_BLOCKSTORE_PATH", cfg.Network.BlockStorePath), "Block store path")
+		nonceStore   = fs.String("tx.nonceStore", envOr("VELTAROS_NONCESTORE_PATH", cfg.Network.NonceStorePath), "Nonce store path")
+		blockStore   = fs.String("chain.blockStore", envOr("VELTAROS_BLOCKSTORE_PATH", cfg.Network.BlockStorePath), "Block store path")
+		mempoolStore = fs.String("tx.mempoolStore", envOr("VELTAROS_MEMPOOLSTORE_PATH", cfg.Network.MempoolStorePath), "Mempool store path")
 
 		ledgerStore = fs.String("ledger.store", envOr("VELTAROS_LEDGER_STORE", cfg.Ledger.StorePath), "Ledger store path")
 
+		requireKnownRecipient = fs.Bool("chain.requireKnownRecipient", envOrBool("VELTAROS_REQUIRE_KNOWN_RECIPIENT", cfg.Chain.RequireKnownRecipient), "Reject txs to addresses unknown to the ledger")
+		honorTxClass          = fs.Bool("chain.honorTxClass", envOrBool("VELTAROS_HONOR_TX_CLASS", cfg.Chain.HonorTxClass), "Order mempool by tx class before fee (permissioned networks only: class is self-declared)")
+		genesisPath           = fs.String("chain.genesis", envOr("VELTAROS_GENESIS", cfg.Chain.GenesisPath), "Genesis file with initial allocations (empty uses the built-in genesis)")
+		integrityInterval     = fs.Duration("chain.integrityInterval", envOrDuration("VELTAROS_INTEGRITY_INTERVAL", cfg.Chain.IntegrityInterval), "Block store integrity check interval (0 disables)")
+		quarantineCorrupt     = fs.Bool("chain.quarantineCorrupt", envOrBool("VELTAROS_QUARANTINE_CORRUPT", cfg.Chain.QuarantineCorrupt), "Move a corrupt block store tail aside when detected")
+		mempoolMaxTxs         = fs.Int("chain.mempoolMaxTxs", envOrInt("VELTAROS_MEMPOOL_MAX_TXS", cfg.Chain.MempoolMaxTxs), "Maximum pending txs; lowest-fee txs are evicted beyond it (0 disables)")
+		mempoolMaxBytes       = fs.Int("chain.mempoolMaxBytes", envOrInt("VELTAROS_MEMPOOL_MAX_BYTES", cfg.Chain.MempoolMaxBytes), "Maximum total serialized size of pending txs (0 disables)")
+		maxFutureSkew         = fs.Duration("chain.maxFutureSkew", envOrDuration("VELTAROS_MAX_FUTURE_SKEW", cfg.Chain.MaxFutureSkew), "Reject txs dated further than this ahead of the node clock")
+		mempoolMaxAge         = fs.Duration("chain.mempoolMaxAge", envOrDuration("VELTAROS_MEMPOOL_MAX_AGE", cfg.Chain.MempoolMaxAge), "Evict pending txs older than this (0 disables)")
+		nonceGrace            = fs.Duration("chain.nonceGrace", envOrDuration("VELTAROS_NONCE_GRACE", cfg.Chain.NonceGrace), "Hold txs with a nonce gap this long so bursts admit in order (e.g. 500ms; 0 disables)")
+		nonceGraceMax         = fs.Int("chain.nonceGraceMax", envOrInt("VELTAROS_NONCE_GRACE_MAX", cfg.Chain.NonceGraceMax), "Maximum txs held per sender by chain.nonceGrace or chain.strictNonces")
+		minRelayFee           = fs.Uint64("chain.minRelayFee", envOrUint64("VELTAROS_MIN_RELAY_FEE", cfg.Chain.MinRelayFee), "Lowest fee admitted to the mempool (0 = consensus minimum only)")
+		minRelayFeePerByte    = fs.Uint64("chain.minRelayFeePerByte", envOrUint64("VELTAROS_MIN_RELAY_FEE_PER_BYTE", cfg.Chain.MinRelayFeePerByte), "Lowest fee admitted per byte of canonical tx draft (0 disables)")
+		blockReward           = fs.Uint64("chain.blockReward", envOrUint64("VELTAROS_BLOCK_REWARD", cfg.Chain.BlockReward), "Most a block may mint for its producer besides fees; must match the network")
+		powDifficulty         = fs.Int("chain.powDifficulty", envOrInt("VELTAROS_POW_DIFFICULTY", cfg.Chain.PowDifficulty), "Leading zero bits required of block header hashes; must match the network (0 disables)")
+		strictNonces          = fs.Bool("chain.strictNonces", envOrBool("VELTAROS_STRICT_NONCES", cfg.Chain.StrictNonces), "Admit txs only at the expected nonce; hold later nonces until the gap fills")
+
+		producerEnabled  = fs.Bool("producer.enabled", envOrBool("VELTAROS_PRODUCER_ENABLED", cfg.Producer.Enabled), "Periodically build blocks from the mempool")
+		producerInterval = fs.Duration("producer.interval", envOrDuration("VELTAROS_PRODUCER_INTERVAL", cfg.Producer.Interval), "Block production interval")
+		producerMaxTxs   = fs.Int("producer.maxTxs", envOrInt("VELTAROS_PRODUCER_MAX_TXS", cfg.Producer.MaxTxs), "Maximum txs per produced block (0 = byte limit only)")
+		producerReward   = fs.String("producer.rewardAddr", envOr("VELTAROS_PRODUCER_REWARD_ADDR", cfg.Producer.RewardAddr), "Address credited each produced block's reward and fees (empty burns fees)")
+
 		apiEnabled = fs.Bool("api.enabled", envOrBool("VELTAROS_API_ENABLED", cfg.API.Enabled), "Enable HTTP API")
 		apiListen  = fs.String("api.listen", envOr("VELTAROS_API_LISTEN", cfg.API.ListenAddr), "HTTP API listen address")
 
+		txRate         = fs.Float64("api.txRate", envOrFloat("VELTAROS_API_TX_RATE", cfg.API.TxRateLimit), "Tx endpoint rate limit per client (tokens/sec)")
+		txBurst        = fs.Float64("api.txBurst", envOrFloat("VELTAROS_API_TX_BURST", cfg.API.TxBurst), "Tx endpoint burst per client (tokens)")
+		txCost         = fs.Float64("api.txCost", envOrFloat("VELTAROS_API_TX_COST", cfg.API.TxCost), "Tokens charged per tx endpoint request")
+		rateLimitByKey = fs.Bool("api.rateLimitByKey", envOrBool("VELTAROS_API_RATE_LIMIT_BY_KEY", cfg.API.RateLimitByKey), "Rate-limit requests with a valid X-API-Key per key and IP, apart from the IP's other requests")
+		trustedProxies = fs.String("api.trustedProxies", envOr("VELTAROS_API_TRUSTED_PROXIES", strings.Join(cfg.API.TrustedProxies, ",")), "CSV of proxy CIDRs/IPs whose X-Forwarded-For is used for rate limiting")
 		allowedOrigins = fs.String("api.allowedOrigins", envOr("VELTAROS_API_ALLOWED_ORIGINS", strings.Join(cfg.API.AllowedOrigins, ",")), "CSV allowlist for CORS origins")
-		apiKey         = fs.String("api.key", envOr("VELTAROS_API_KEY", cfg.API.APIKey), "Optional API key (X-API-Key)")
 		keyOnValidate  = fs.Bool("api.keyOnValidate", envOrBool("VELTAROS_API_KEY_ON_VALIDATE", cfg.API.KeyOnValidate), "Require API key for /tx/validate")
 		keyOnBroadcast = fs.Bool("api.keyOnBroadcast", envOrBool("VELTAROS_API_KEY_ON_BROADCAST", cfg.API.KeyOnBroadcast), "Require API key for /tx/broadcast")
+		keyOnPeerState = fs.Bool("api.keyOnPeerState", envOrBool("VELTAROS_API_KEY_ON_PEER_STATE", cfg.API.KeyOnPeerState), "Require API key for /bans and /scores")
+		keyOnEvents    = fs.Bool("api.keyOnEvents", envOrBool("VELTAROS_API_KEY_ON_EVENTS", cfg.API.KeyOnEvents), "Require API key for /events")
 		faucetEnabled  = fs.Bool("api.faucet", envOrBool("VELTAROS_FAUCET_ENABLED", cfg.API.FaucetEnabled), "Enable faucet endpoint")
+		minPeers       = fs.Int("api.minPeers", envOrInt("VELTAROS_API_MIN_PEERS", cfg.API.MinPeers), "Minimum connected peers for /tx/broadcast (0 disables)")
+		minPeersStrict = fs.Bool("api.minPeersStrict", envOrBool("VELTAROS_API_MIN_PEERS_STRICT", cfg.API.MinPeersStrict), "Reject /tx/broadcast with 503 below api.minPeers instead of warning")
+
+		auditLog = fs.String("api.auditLog", envOr("VELTAROS_AUDIT_LOG", cfg.API.AuditLogPath), "Tamper-evident admin audit log path (empty disables)")
+		binaryTx = fs.Bool("api.binaryTx", envOrBool("VELTAROS_API_BINARY_TX", cfg.API.BinaryTx), "Accept application/octet-stream binary txs on /tx/validate and /tx/broadcast")
+		metrics  = fs.Bool("api.metrics", envOrBool("VELTAROS_API_METRICS", cfg.API.Metrics), "Serve Prometheus metrics on /metrics (requires the API key if set)")
+
+		watchdog         = fs.Duration("api.watchdog", envOrDuration("VELTAROS_API_WATCHDOG", cfg.API.WatchdogInterval), "Probe the node's own API this often and exit if it wedges (0 disables)")
+		watchdogFailures = fs.Int("api.watchdogFailures", envOrInt("VELTAROS_API_WATCHDOG_FAILURES", cfg.API.WatchdogFailures), "Consecutive failed watchdog probes before exiting")
 
 		logLevel  = fs.String("log.level", envOr("VELTAROS_LOG_LEVEL", cfg.Log.Level), "Log level")
 		logFormat = fs.String("log.format", envOr("VELTAROS_LOG_FORMAT", cfg.Log.Format), "Log format")
 
-		dataDir = fs.String("data.dir", envOr("VELTAROS_DATA_DIR", cfg.Storage.DataDir), "Data directory")
+		dataDir      = fs.String("data.dir", envOr("VELTAROS_DATA_DIR", cfg.Storage.DataDir), "Data directory")
+		keepBackups  = fs.Bool("data.keepBackups", envOrBool("VELTAROS_DATA_KEEP_BACKUPS", cfg.Storage.KeepBackups), "Keep a .bak of critical stores before each save (used if the primary is corrupt)")
+		blockBackend = fs.String("storage.backend", envOr("VELTAROS_STORAGE_BACKEND", cfg.Storage.BlockBackend), "Block store backend: json (rewrite on save), log (append-only) or bolt (bbolt database)")
+		runtimeMeta  = fs.String("data.runtimeMeta", envOr("VELTAROS_RUNTIME_META_PATH", cfg.Storage.RuntimeMetaPath), "Runtime metadata path (restarts, cumulative uptime)")
 	)
 
 	if err := fs.Parse(args); err != nil {
@@ -161,6 +432,22 @@ func ParseNodeFlags(args []string) (Parsed, error) {
 	cfg.Network.ListenAddr = strings.TrimSpace(*listenAddr)
 	cfg.Network.ExternalAddr = strings.TrimSpace(*externalAddr)
 	cfg.Network.MaxPeers = *maxPeers
+	cfg.Network.AllowPrivateAddrs = *allowPrivate
+	cfg.Network.MaxPendingHandshakes = *maxHandshake
+	cfg.Network.MaxUnverifiedPeers = *maxUnverified
+	cfg.Network.MaxInbound = *maxInbound
+	cfg.Network.MaxOutbound = *maxOutbound
+	cfg.Network.ProtectedPeers = *protected
+	cfg.Network.MaxDialAttempts = *maxDialTries
+	cfg.Network.MaxKnownPeers = *maxKnown
+	cfg.Network.PuzzleBits = *puzzleBits
+	cfg.Network.TxGossipBudget = *txBudget
+	cfg.Network.TxGossipInterval = *txBudgetIvl
+	cfg.Network.PingInterval = *pingInterval
+	cfg.Network.PongTimeout = *pongTimeout
+	cfg.Network.PeerIdleTimeout = *peerIdle
+	cfg.Network.Encrypt = *encrypt
+	cfg.Network.MinEncryption = strings.ToLower(strings.TrimSpace(*minEncryption))
 	cfg.Network.NetworkID = strings.TrimSpace(*networkID)
 	cfg.Network.IdentityKeyPath = strings.TrimSpace(*identityKey)
 	cfg.Network.IdentityRecordPath = strings.TrimSpace(*identityRecord)
@@ -169,30 +456,77 @@ func ParseNodeFlags(args []string) (Parsed, error) {
 	cfg.Network.ScoreStorePath = strings.TrimSpace(*scoreStore)
 	cfg.Network.NonceStorePath = strings.TrimSpace(*nonceStore)
 	cfg.Network.BlockStorePath = strings.TrimSpace(*blockStore)
+	cfg.Network.MempoolStorePath = strings.TrimSpace(*mempoolStore)
 
 	cfg.Ledger.StorePath = strings.TrimSpace(*ledgerStore)
+	cfg.Chain.RequireKnownRecipient = *requireKnownRecipient
+	cfg.Chain.HonorTxClass = *honorTxClass
+	cfg.Chain.GenesisPath = strings.TrimSpace(*genesisPath)
+	cfg.Chain.IntegrityInterval = *integrityInterval
+	cfg.Chain.QuarantineCorrupt = *quarantineCorrupt
+	cfg.Chain.MempoolMaxAge = *mempoolMaxAge
+	cfg.Chain.MaxFutureSkew = *maxFutureSkew
+	cfg.Chain.MempoolMaxTxs = *mempoolMaxTxs
+	cfg.Chain.MempoolMaxBytes = *mempoolMaxBytes
+	cfg.Chain.MinRelayFee = *minRelayFee
+	cfg.Chain.MinRelayFeePerByte = *minRelayFeePerByte
+	cfg.Chain.BlockReward = *blockReward
+	cfg.Chain.PowDifficulty = *powDifficulty
+	cfg.Chain.NonceGrace = *nonceGrace
+	cfg.Chain.NonceGraceMax = *nonceGraceMax
+	cfg.Chain.StrictNonces = *strictNonces
+
+	cfg.Producer.Enabled = *producerEnabled
+	cfg.Producer.Interval = *producerInterval
+	cfg.Producer.MaxTxs = *producerMaxTxs
+	cfg.Producer.RewardAddr = strings.TrimSpace(*producerReward)
 
 	cfg.API.Enabled = *apiEnabled
 	cfg.API.ListenAddr = strings.TrimSpace(*apiListen)
 	cfg.API.AllowedOrigins = splitCSV(strings.TrimSpace(*allowedOrigins))
-	cfg.API.APIKey = strings.TrimSpace(*apiKey)
+	cfg.API.TrustedProxies = splitCSV(strings.TrimSpace(*trustedProxies))
+	cfg.API.TxRateLimit = *txRate
+	cfg.API.TxBurst = *txBurst
+	cfg.API.TxCost = *txCost
+	cfg.API.RateLimitByKey = *rateLimitByKey
+	cfg.API.APIKey = Secret(strings.TrimSpace(apiKey.Reveal()))
 	cfg.API.KeyOnValidate = *keyOnValidate
 	cfg.API.KeyOnBroadcast = *keyOnBroadcast
+	cfg.API.KeyOnPeerState = *keyOnPeerState
+	cfg.API.KeyOnEvents = *keyOnEvents
 	cfg.API.FaucetEnabled = *faucetEnabled
+	cfg.API.MinPeers = *minPeers
+	cfg.API.MinPeersStrict = *minPeersStrict
+	cfg.API.AuditLogPath = strings.TrimSpace(*auditLog)
+	cfg.API.BinaryTx = *binaryTx
+	cfg.API.Metrics = *metrics
+	cfg.API.WatchdogInterval = *watchdog
+	cfg.API.WatchdogFailures = *watchdogFailures
 
 	cfg.Log.Level = strings.TrimSpace(*logLevel)
 	cfg.Log.Format = strings.TrimSpace(*logFormat)
 	cfg.Storage.DataDir = strings.TrimSpace(*dataDir)
+	cfg.Storage.KeepBackups = *keepBackups
+	cfg.Storage.BlockBackend = strings.ToLower(strings.TrimSpace(*blockBackend))
+	cfg.Storage.RuntimeMetaPath = strings.TrimSpace(*runtimeMeta)
 
 	if b := strings.TrimSpace(*bootstrap); b != "" {
 		cfg.Network.BootstrapPeers = splitCSV(b)
 	}
+	cfg.Network.BootstrapFile = strings.TrimSpace(*bootFile)
+	if cfg.Network.BootstrapFile != "" {
+		filePeers, err := readBootstrapFile(cfg.Network.BootstrapFile)
+		if err != nil {
+			return Parsed{}, err
+		}
+		cfg.Network.BootstrapPeers = mergeUnique(cfg.Network.BootstrapPeers, filePeers)
+	}
 
 	if err := validate(cfg); err != nil {
 		return Parsed{}, err
 	}
 
-	return Parsed{Config: cfg}, nil
+	return Parsed{Config: cfg, PrintConfig: *printConfig}, nil
 }
 
 func validate(cfg Config) error {
@@ -202,14 +536,72 @@ func validate(cfg Config) error {
 	if cfg.Network.MaxPeers <= 0 || cfg.Network.MaxPeers > 4096 {
 		return fmt.Errorf("p2p.maxPeers out of range: %d", cfg.Network.MaxPeers)
 	}
+	if cfg.Network.ExternalAddr != "" {
+		if err := validatePeerAddr(cfg.Network.ExternalAddr); err != nil {
+			return fmt.Errorf("invalid p2p.external %q: %w", cfg.Network.ExternalAddr, err)
+		}
+	}
+	for _, p := range cfg.Network.BootstrapPeers {
+		if err := validatePeerAddr(p); err != nil {
+			return fmt.Errorf("invalid bootstrap peer %q: %w", p, err)
+		}
+	}
+	if cfg.Network.MaxPendingHandshakes <= 0 || cfg.Network.MaxPendingHandshakes > 4096 {
+		return fmt.Errorf("p2p.maxHandshakes out of range: %d", cfg.Network.MaxPendingHandshakes)
+	}
+	if cfg.Network.MaxUnverifiedPeers < 0 || cfg.Network.MaxUnverifiedPeers > cfg.Network.MaxPeers {
+		return fmt.Errorf("p2p.maxUnverified out of range (0..p2p.maxPeers): %d", cfg.Network.MaxUnverifiedPeers)
+	}
+	if cfg.Network.MaxInbound < 0 || cfg.Network.MaxInbound > cfg.Network.MaxPeers {
+		return fmt.Errorf("p2p.maxInbound out of range (0..p2p.maxPeers): %d", cfg.Network.MaxInbound)
+	}
+	if cfg.Network.MaxOutbound < 0 || cfg.Network.MaxOutbound > cfg.Network.MaxPeers {
+		return fmt.Errorf("p2p.maxOutbound out of range (0..p2p.maxPeers): %d", cfg.Network.MaxOutbound)
+	}
+	if cfg.Network.ProtectedPeers < 0 || cfg.Network.ProtectedPeers > cfg.Network.MaxPeers {
+		return fmt.Errorf("p2p.protectedPeers out of range (0..p2p.maxPeers): %d", cfg.Network.ProtectedPeers)
+	}
+	if cfg.Network.MaxDialAttempts <= 0 || cfg.Network.MaxDialAttempts > 1000 {
+		return fmt.Errorf("p2p.maxDialAttempts out of range (1..1000): %d", cfg.Network.MaxDialAttempts)
+	}
+	if cfg.Network.MaxKnownPeers <= 0 || cfg.Network.MaxKnownPeers > 100000 {
+		return fmt.Errorf("p2p.maxKnownPeers out of range (1..100000): %d", cfg.Network.MaxKnownPeers)
+	}
+	if cfg.Network.PuzzleBits < 0 || cfg.Network.PuzzleBits > 24 {
+		return fmt.Errorf("p2p.puzzleBits out of range (0..24): %d", cfg.Network.PuzzleBits)
+	}
+	if cfg.Network.TxGossipBudget <= 0 || cfg.Network.TxGossipBudget > 100000 {
+		return fmt.Errorf("p2p.txBudget out of range: %d", cfg.Network.TxGossipBudget)
+	}
+	if cfg.Network.TxGossipInterval <= 0 {
+		return errors.New("p2p.txBudgetInterval must be > 0")
+	}
+	if cfg.Network.PingInterval < time.Second {
+		return fmt.Errorf("p2p.pingInterval must be at least 1s: %s", cfg.Network.PingInterval)
+	}
+	if cfg.Network.PongTimeout < time.Second {
+		return fmt.Errorf("p2p.pongTimeout must be at least 1s: %s", cfg.Network.PongTimeout)
+	}
+	switch cfg.Network.MinEncryption {
+	case "none":
+	case "aead":
+		if !cfg.Network.Encrypt {
+			return errors.New("p2p.minEncryption aead requires p2p.encrypt")
+		}
+	default:
+		return fmt.Errorf("p2p.minEncryption must be none or aead: %q", cfg.Network.MinEncryption)
+	}
+	if minIdle := 2*cfg.Network.PingInterval + cfg.Network.PongTimeout; cfg.Network.PeerIdleTimeout < minIdle {
+		return fmt.Errorf("p2p.peerIdleTimeout must be at least 2*p2p.pingInterval + p2p.pongTimeout (%s): %s", minIdle, cfg.Network.PeerIdleTimeout)
+	}
 	if cfg.Network.NetworkID == "" {
 		return errors.New("p2p.network must not be empty")
 	}
 	if cfg.Network.IdentityKeyPath == "" || cfg.Network.IdentityRecordPath == "" {
 		return errors.New("identity key/record paths must not be empty")
 	}
-	if cfg.Network.NonceStorePath == "" || cfg.Network.BlockStorePath == "" {
-		return errors.New("nonce/block store paths must not be empty")
+	if cfg.Network.NonceStorePath == "" || cfg.Network.BlockStorePath == "" || cfg.Network.MempoolStorePath == "" {
+		return errors.New("nonce/block/mempool store paths must not be empty")
 	}
 	if cfg.Ledger.StorePath == "" {
 		return errors.New("ledger.store must not be empty")
@@ -217,9 +609,70 @@ func validate(cfg Config) error {
 	if cfg.API.Enabled && cfg.API.ListenAddr == "" {
 		return errors.New("api.listen must not be empty when api.enabled=true")
 	}
+	if !(cfg.API.TxRateLimit > 0) || !(cfg.API.TxBurst > 0) {
+		return fmt.Errorf("api.txRate and api.txBurst must be > 0 (got %g, %g)", cfg.API.TxRateLimit, cfg.API.TxBurst)
+	}
+	if !(cfg.API.TxCost > 0) || cfg.API.TxCost > cfg.API.TxBurst {
+		return fmt.Errorf("api.txCost must be > 0 and <= api.txBurst (got %g)", cfg.API.TxCost)
+	}
+	if cfg.API.RateLimitByKey && !cfg.API.APIKey.IsSet() {
+		return errors.New("api.rateLimitByKey requires api.key")
+	}
+	if _, err := api.ParsePrefixes(cfg.API.TrustedProxies); err != nil {
+		return fmt.Errorf("api.trustedProxies: %w", err)
+	}
+	if cfg.API.WatchdogInterval != 0 {
+		if !cfg.API.Enabled {
+			return errors.New("api.watchdog requires api.enabled=true")
+		}
+		if cfg.API.WatchdogInterval < 5*time.Second {
+			return fmt.Errorf("api.watchdog must be 0 or >= 5s (got %s)", cfg.API.WatchdogInterval)
+		}
+		if cfg.API.WatchdogFailures < 1 {
+			return fmt.Errorf("api.watchdogFailures must be >= 1 (got %d)", cfg.API.WatchdogFailures)
+		}
+	}
+	if cfg.API.MinPeers < 0 || cfg.API.MinPeers > cfg.Network.MaxPeers {
+		return fmt.Errorf("api.minPeers out of range: %d", cfg.API.MinPeers)
+	}
+	if cfg.Chain.IntegrityInterval < 0 {
+		return errors.New("chain.integrityInterval must not be negative")
+	}
+	if cfg.Chain.MaxFutureSkew < time.Second {
+		return errors.New("chain.maxFutureSkew must be at least 1s")
+	}
+	if cfg.Chain.PowDifficulty < 0 || cfg.Chain.PowDifficulty > 256 {
+		return fmt.Errorf("chain.powDifficulty out of range (0..256): %d", cfg.Chain.PowDifficulty)
+	}
+	if cfg.Chain.MempoolMaxAge < 0 {
+		return errors.New("chain.mempoolMaxAge must not be negative")
+	}
+	if cfg.Chain.MempoolMaxTxs < 0 || cfg.Chain.MempoolMaxBytes < 0 {
+		return errors.New("chain.mempoolMaxTxs and chain.mempoolMaxBytes must not be negative")
+	}
+	if cfg.Chain.NonceGrace < 0 || cfg.Chain.NonceGrace > 10*time.Second {
+		return fmt.Errorf("chain.nonceGrace out of range (0..10s): %s", cfg.Chain.NonceGrace)
+	}
+	if cfg.Chain.NonceGraceMax <= 0 || cfg.Chain.NonceGraceMax > 256 {
+		return fmt.Errorf("chain.nonceGraceMax out of range: %d", cfg.Chain.NonceGraceMax)
+	}
+	if cfg.Producer.Interval <= 0 {
+		return errors.New("producer.interval must be > 0")
+	}
+	if cfg.Producer.MaxTxs < 0 {
+		return fmt.Errorf("producer.maxTxs must not be negative: %d", cfg.Producer.MaxTxs)
+	}
 	if cfg.Storage.DataDir == "" {
 		return errors.New("data.dir must not be empty")
 	}
+	if cfg.Storage.RuntimeMetaPath == "" {
+		return errors.New("data.runtimeMeta must not be empty")
+	}
+	switch cfg.Storage.BlockBackend {
+	case "json", "log", "bolt":
+	default:
+		return fmt.Errorf("storage.backend must be json, log or bolt: %q", cfg.Storage.BlockBackend)
+	}
 	return nil
 }
 
@@ -243,6 +696,42 @@ func envOrInt(key string, def int) int {
 	return n
 }
 
+func envOrUint64(key string, def uint64) uint64 {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+func envOrFloat(key string, def float64) float64 {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
+
+func envOrDuration(key string, def time.Duration) time.Duration {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
 func envOrBool(key string, def bool) bool {
 	v := strings.TrimSpace(os.Getenv(key))
 	if v == "" {
@@ -258,6 +747,60 @@ func envOrBool(key string, def bool) bool {
 	}
 }
 
+// readBootstrapFile parses a newline-delimited list of host:port entries.
+// Blank lines and anything after '#' are ignored.
+func readBootstrapFile(path string) ([]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("p2p.bootstrapFile: %w", err)
+	}
+	out := make([]string, 0, 16)
+	for i, line := range strings.Split(string(raw), "\n") {
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := validatePeerAddr(line); err != nil {
+			return nil, fmt.Errorf("p2p.bootstrapFile line %d: %w", i+1, err)
+		}
+		out = append(out, line)
+	}
+	return out, nil
+}
+
+func validatePeerAddr(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return errors.New("missing host")
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n <= 0 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+func mergeUnique(a, b []string) []string {
+	out := make([]string, 0, len(a)+len(b))
+	seen := make(map[string]struct{}, len(a)+len(b))
+	for _, list := range [][]string{a, b} {
+		for _, v := range list {
+			if _, ok := seen[v]; ok {
+				continue
+			}
+			seen[v] = struct{}{}
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 func splitCSV(s string) []string {
 	raw := strings.Split(s, ",")
 	out := make([]string, 0, len(raw))
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ---- Config file ----
+//
+// A config file is a JSON object with one section per Config field and one
+// key per setting, named like the Go fields with a lowercase first word:
+//
+//	{"network": {"listenAddr": "0.0.0.0:30303", "maxPeers": 32},
+//	 "chain": {"mempoolMaxAge": "15m"}}
+//
+// Durations are strings in time.ParseDuration syntax. Omitted keys keep
+// their defaults; unknown keys are an error, so a typo cannot silently leave
+// a setting at its default. The file is applied on top of Default(), before
+// environment variables and flags, which both override it.
+
+// LoadFile applies the config file at path to cfg.
+func LoadFile(path string, cfg *Config) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	if err := decodeObject("", bytes.TrimSpace(raw), reflect.ValueOf(cfg).Elem()); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Marshal encodes cfg in the config file format. Loading the result over any
+// Config reproduces cfg, except that Secret values are written as "***",
+// which LoadFile refuses, so the output is safe to show but a real key has
+// to be filled back in to load it.
+func Marshal(cfg Config) ([]byte, error) {
+	out, err := json.MarshalIndent(encodeObject(reflect.ValueOf(cfg)), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+var (
+	durationType = reflect.TypeFor[time.Duration]()
+	secretType   = reflect.TypeFor[Secret]()
+)
+
+func decodeObject(prefix string, raw json.RawMessage, v reflect.Value) error {
+	var obj map[string]json.RawMessage
+	err := json.Unmarshal(raw, &obj)
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) || (err == nil && obj == nil) {
+		return fmt.Errorf("%s: want a JSON object", orTop(prefix))
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", orTop(prefix), err)
+	}
+
+	fields := make(map[string]int, v.NumField())
+	for i := range v.NumField() {
+		fields[fileKey(v.Type().Field(i).Name)] = i
+	}
+
+	// Sorted so the first error reported does not depend on map order.
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		i, ok := fields[k]
+		if !ok {
+			return fmt.Errorf("unknown key %q", prefix+k)
+		}
+		if err := decodeValue(prefix+k, obj[k], v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeValue(key string, raw json.RawMessage, f reflect.Value) error {
+	switch {
+	case f.Type() == durationType:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return fmt.Errorf("%s: want a duration string such as \"30s\"", key)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		f.SetInt(int64(d))
+		return nil
+	case f.Type() == secretType:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return fmt.Errorf("%s: want a string", key)
+		}
+		if s == redactedSecret {
+			return fmt.Errorf("%s: %q is a redacted placeholder; set the real value or remove the key", key, s)
+		}
+		f.SetString(strings.TrimSpace(s))
+		return nil
+	case f.Kind() == reflect.Struct:
+		return decodeObject(key+".", raw, f)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if err := dec.Decode(f.Addr().Interface()); err != nil {
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &te) {
+			return fmt.Errorf("%s: want %s, got %s", key, f.Type(), te.Value)
+		}
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+func encodeObject(v reflect.Value) map[string]any {
+	out := make(map[string]any, v.NumField())
+	for i := range v.NumField() {
+		f := v.Field(i)
+		key := fileKey(v.Type().Field(i).Name)
+		switch {
+		case f.Type() == durationType:
+			out[key] = time.Duration(f.Int()).String()
+		case f.Kind() == reflect.Struct:
+			out[key] = encodeObject(f)
+		default:
+			out[key] = f.Interface()
+		}
+	}
+	return out
+}
+
+// fileKey lowercases a field name's leading word: ListenAddr → listenAddr,
+// API → api, APIKey → apiKey, NetworkID → networkID.
+func fileKey(name string) string {
+	r := []rune(name)
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+	if n > 1 && n < len(r) {
+		n-- // the last capital starts the next word
+	}
+	for i := range n {
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
+
+func orTop(prefix string) string {
+	if prefix == "" {
+		return "top level"
+	}
+	return strings.TrimSuffix(prefix, ".")
+}
+
+// configPathArg finds --config in args ahead of the real flag parse, since
+// the file supplies the defaults the flags are declared with. It scans up to
+// "--" rather than the first non-flag, which may just be another flag's
+// value; the node takes no positional arguments.
+func configPathArg(args []string) (string, bool) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			break
+		}
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+		name, val, hasVal := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if name != "config" {
+			continue
+		}
+		if hasVal {
+			return val, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+		return "", true
+	}
+	return "", false
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "node.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFileRoundTrip(t *testing.T) {
+	want := Default()
+	want.Network.ListenAddr = "0.0.0.0:40404"
+	want.Network.BootstrapPeers = []string{"seed1.example.org:30303", "seed2.example.org:30303"}
+	want.Network.MaxPeers = 12
+	want.Network.PingInterval = 20 * time.Second
+	want.Chain.MempoolMaxAge = 15 * time.Minute
+	want.Chain.MinRelayFeePerByte = 2
+	want.Producer.Enabled = true
+	want.API.TrustedProxies = []string{"10.0.0.0/8"}
+	want.Storage.KeepBackups = false
+
+	out, err := Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Loaded over a zero Config, the file alone reproduces every field.
+	var got Config
+	if err := LoadFile(writeConfigFile(t, string(out)), &got); err != nil {
+		t.Fatalf("load marshalled config: %v\n%s", err, out)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip changed the config:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestConfigFilePartial(t *testing.T) {
+	cfg := Default()
+	path := writeConfigFile(t, `{"network": {"maxPeers": 9}, "chain": {"mempoolMaxAge": "5m"}}`)
+	if err := LoadFile(path, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := Default()
+	want.Network.MaxPeers = 9
+	want.Chain.MempoolMaxAge = 5 * time.Minute
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("partial file touched other settings:\ngot  %+v\nwant %+v", cfg, want)
+	}
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	for _, tc := range []struct {
+		content, want string
+	}{
+		{`{"network": {"maxPeer": 9}}`, `unknown key "network.maxPeer"`},
+		{`{"netwrk": {}}`, `unknown key "netwrk"`},
+		{`{"chain": {"mempoolMaxAge": 300}}`, "chain.mempoolMaxAge: want a duration"},
+		{`{"chain": {"mempoolMaxAge": "5 minutes"}}`, "chain.mempoolMaxAge"},
+		{`{"network": {"maxPeers": "nine"}}`, "network.maxPeers: want int"},
+		{`{"network": []}`, "network: want a JSON object"},
+		{`[]`, "top level: want a JSON object"},
+		{`{"api": {"apiKey": "***"}}`, "redacted placeholder"},
+	} {
+		cfg := Default()
+		err := LoadFile(writeConfigFile(t, tc.content), &cfg)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("LoadFile(%s) = %v, want an error containing %q", tc.content, err, tc.want)
+		}
+	}
+}
+
+func TestParseNodeFlagsPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `{"network": {"maxPeers": 9, "maxKnownPeers": 99}, "log": {"level": "debug"}}`)
+	t.Setenv("VELTAROS_P2P_MAXPEERS", "")
+	t.Setenv("VELTAROS_LOG_LEVEL", "warn")
+
+	parsed, err := ParseNodeFlags([]string{"--config", path, "-p2p.maxPeers=11"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := parsed.Config
+	if cfg.Network.MaxPeers != 11 {
+		t.Errorf("maxPeers = %d, want the flag's 11", cfg.Network.MaxPeers)
+	}
+	if cfg.Log.Level != "warn" {
+		t.Errorf("log level = %q, want the environment's warn", cfg.Log.Level)
+	}
+	if cfg.Network.MaxKnownPeers != 99 {
+		t.Errorf("maxKnownPeers = %d, want the file's 99", cfg.Network.MaxKnownPeers)
+	}
+}
+
+func TestConfigPathArg(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want string
+		ok   bool
+	}{
+		{[]string{"--config", "a.json"}, "a.json", true},
+		{[]string{"-config=a.json", "-p2p.maxPeers", "3"}, "a.json", true},
+		{[]string{"-p2p.listen", "x", "--config", "b.json"}, "b.json", true},
+		{[]string{"--", "--config", "a.json"}, "", false},
+		{[]string{"--config"}, "", true},
+		{nil, "", false},
+	} {
+		got, ok := configPathArg(tc.args)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("configPathArg(%q) = %q, %v; want %q, %v", tc.args, got, ok, tc.want, tc.ok)
+		}
+	}
+}
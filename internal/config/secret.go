@@ -0,0 +1,42 @@
+package config
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+)
+
+// redactedSecret is what a set Secret prints and marshals as.
+const redactedSecret = "***"
+
+// Secret is a config value that must not leak through logs, fmt or JSON: it
+// prints and marshals as "***" when set and "" when not, including inside
+// structs such as APIConfig. Reveal is the one way to the raw value.
+type Secret string
+
+func (s Secret) Reveal() string { return string(s) }
+
+func (s Secret) IsSet() bool { return s != "" }
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redactedSecret
+}
+
+// GoString covers %#v, which bypasses String.
+func (s Secret) GoString() string { return `"` + s.String() + `"` }
+
+func (s Secret) LogValue() slog.Value { return slog.StringValue(s.String()) }
+
+func (s Secret) MarshalJSON() ([]byte, error) { return json.Marshal(s.String()) }
+
+func (s Secret) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// Set makes *Secret a flag.Value, so -help shows "***" rather than a key
+// that came from the environment or a config file as the default.
+func (s *Secret) Set(v string) error {
+	*s = Secret(strings.TrimSpace(v))
+	return nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testAPIKey = "k3y-that-must-not-leak"
+
+func TestSecretNeverPrintsRawKey(t *testing.T) {
+	cfg := Default()
+	cfg.API.APIKey = Secret(testAPIKey)
+
+	file, err := Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logged bytes.Buffer
+	slog.New(slog.NewJSONHandler(&logged, nil)).Info("config", "api", cfg.API, "key", cfg.API.APIKey)
+
+	for name, out := range map[string]string{
+		"Marshal":      string(file),
+		"json.Marshal": string(js),
+		"%v":           fmt.Sprintf("%v", cfg),
+		"%+v":          fmt.Sprintf("%+v", cfg),
+		"%#v":          fmt.Sprintf("%#v", cfg),
+		"%s":           fmt.Sprintf("%s", cfg.API.APIKey),
+		"slog":         logged.String(),
+	} {
+		if strings.Contains(out, testAPIKey) {
+			t.Errorf("%s output contains the raw API key", name)
+		}
+		if !strings.Contains(out, redactedSecret) {
+			t.Errorf("%s output does not show the key as %s", name, redactedSecret)
+		}
+	}
+
+	if cfg.API.APIKey.Reveal() != testAPIKey {
+		t.Error("Reveal does not return the raw key")
+	}
+	if s := Secret("").String(); s != "" {
+		t.Errorf("unset secret prints %q, want empty", s)
+	}
+}
+
+func TestHelpHidesAPIKey(t *testing.T) {
+	t.Setenv("VELTAROS_API_KEY", testAPIKey)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := make(chan []byte)
+	go func() {
+		out, _ := io.ReadAll(r)
+		read <- out
+	}()
+	stdout := os.Stdout
+	os.Stdout = w
+	_, perr := ParseNodeFlags([]string{"-help"})
+	os.Stdout = stdout
+	_ = w.Close()
+	help := <-read
+
+	if perr == nil {
+		t.Error("-help did not stop parsing")
+	}
+	if !strings.Contains(string(help), "api.key") {
+		t.Fatalf("help output lacks api.key:\n%s", help)
+	}
+	if strings.Contains(string(help), testAPIKey) {
+		t.Error("help output contains the raw API key")
+	}
+}
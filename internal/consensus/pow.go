@@ -1,14 +1,72 @@
 package consensus
 
-import "errors"
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
 
-// PoW is a placeholder for Proof-of-Work rules.
-// We will implement real difficulty targets, header work, and verification next.
-type PoW struct{}
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
+)
 
-func NewPoW() *PoW { return &PoW{} }
+// MaxDifficulty is the largest meaningful target: every hash bit zero.
+const MaxDifficulty = 256
 
-func (p *PoW) ValidateBlockHeader(_ []byte) error {
-	// TODO: real PoW validation
-	return errors.New("pow not implemented")
+// PoW validates Proof-of-Work headers. The header hash is
+// doubleSha256(BlockHeader.Bytes()), the same hash used as the block ID, and
+// it must start with at least Difficulty zero bits.
+type PoW struct {
+	Difficulty uint
+}
+
+func NewPoW(difficulty uint) *PoW { return &PoW{Difficulty: difficulty} }
+
+var ErrNonceExhausted = errors.New("pow: nonce space exhausted")
+
+func (p *PoW) ValidateBlockHeader(headerBytes []byte) error {
+	if p.Difficulty > MaxDifficulty {
+		return fmt.Errorf("pow: difficulty %d exceeds %d", p.Difficulty, MaxDifficulty)
+	}
+	if _, err := blockchain.ParseBlockHeader(headerBytes); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConsensus, err)
+	}
+	h := vcrypto.DoubleSha256(headerBytes)
+	if got := LeadingZeroBits(h); got < p.Difficulty {
+		return fmt.Errorf("%w: hash has %d leading zero bits, need %d", ErrInvalidConsensus, got, p.Difficulty)
+	}
+	return nil
+}
+
+// Mine searches nonces upward from header.Nonce and returns the first one
+// whose header hash meets the difficulty.
+func (p *PoW) Mine(header blockchain.BlockHeader) (uint64, error) {
+	if p.Difficulty > MaxDifficulty {
+		return 0, fmt.Errorf("pow: difficulty %d exceeds %d", p.Difficulty, MaxDifficulty)
+	}
+	buf := header.Bytes()
+	nonceAt := len(buf) - 8 // Nonce is the trailing header field
+
+	for nonce := header.Nonce; ; nonce++ {
+		binary.LittleEndian.PutUint64(buf[nonceAt:], nonce)
+		if LeadingZeroBits(vcrypto.DoubleSha256(buf)) >= p.Difficulty {
+			return nonce, nil
+		}
+		if nonce == math.MaxUint64 {
+			return 0, ErrNonceExhausted
+		}
+	}
+}
+
+// LeadingZeroBits counts zero bits from the most significant end of h.
+func LeadingZeroBits(h [32]byte) uint {
+	var n uint
+	for _, b := range h {
+		if b != 0 {
+			return n + uint(bits.LeadingZeros8(b))
+		}
+		n += 8
+	}
+	return n
 }
@@ -0,0 +1,141 @@
+package consensus
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+	"github.com/VeltarosLabs/Veltaros/internal/ledger"
+)
+
+// testHeader is fixed so mining (and so every check below) is deterministic.
+func testHeader() blockchain.BlockHeader {
+	return blockchain.BlockHeader{
+		Version:    1,
+		PrevHash:   [32]byte{0x01, 0x02, 0x03},
+		MerkleRoot: [32]byte{0xaa, 0xbb, 0xcc},
+		Timestamp:  1_767_225_600,
+	}
+}
+
+func TestMineThenValidate(t *testing.T) {
+	p := NewPoW(12)
+	h := testHeader()
+	nonce, err := p.Mine(h)
+	if err != nil {
+		t.Fatalf("mine: %v", err)
+	}
+	h.Nonce = nonce
+	if err := p.ValidateBlockHeader(h.Bytes()); err != nil {
+		t.Fatalf("mined header rejected: %v", err)
+	}
+	if got := LeadingZeroBits(h.Hash()); got < 12 {
+		t.Fatalf("mined hash has %d leading zero bits", got)
+	}
+	// Mine returns the first solution, so every lower nonce fails.
+	if nonce > 0 {
+		h.Nonce = nonce - 1
+		if err := p.ValidateBlockHeader(h.Bytes()); !errors.Is(err, ErrInvalidConsensus) {
+			t.Errorf("nonce below the solution: err = %v", err)
+		}
+	}
+}
+
+func TestTamperedHeaderFails(t *testing.T) {
+	p := NewPoW(12)
+	h := testHeader()
+	nonce, err := p.Mine(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Nonce = nonce
+
+	for name, tamper := range map[string]func(*blockchain.BlockHeader){
+		"merkle root": func(h *blockchain.BlockHeader) { h.MerkleRoot[31] ^= 1 },
+		"prev hash":   func(h *blockchain.BlockHeader) { h.PrevHash[0] ^= 0x80 },
+		"timestamp":   func(h *blockchain.BlockHeader) { h.Timestamp++ },
+		"nonce":       func(h *blockchain.BlockHeader) { h.Nonce++ },
+	} {
+		bad := h
+		tamper(&bad)
+		if err := p.ValidateBlockHeader(bad.Bytes()); !errors.Is(err, ErrInvalidConsensus) {
+			t.Errorf("%s tampered: err = %v, want ErrInvalidConsensus", name, err)
+		}
+	}
+}
+
+func TestValidateBlockHeaderInput(t *testing.T) {
+	if err := NewPoW(0).ValidateBlockHeader(testHeader().Bytes()); err != nil {
+		t.Errorf("difficulty 0 rejected a header: %v", err)
+	}
+	if err := NewPoW(0).ValidateBlockHeader([]byte("short")); !errors.Is(err, ErrInvalidConsensus) {
+		t.Errorf("malformed header: err = %v", err)
+	}
+	if err := NewPoW(MaxDifficulty + 1).ValidateBlockHeader(testHeader().Bytes()); err == nil {
+		t.Error("difficulty above MaxDifficulty accepted")
+	}
+	if _, err := NewPoW(MaxDifficulty + 1).Mine(testHeader()); err == nil {
+		t.Error("Mine accepted a difficulty above MaxDifficulty")
+	}
+}
+
+func TestChainChecksHeaderPoW(t *testing.T) {
+	dir := t.TempDir()
+	p := NewPoW(8)
+	chain := blockchain.New(
+		filepath.Join(dir, "nonces.json"),
+		filepath.Join(dir, "blocks.json"),
+		filepath.Join(dir, "mempool.json"),
+		blockchain.WithConsensus(p),
+	)
+	led := ledger.New(filepath.Join(dir, "ledger.json"))
+
+	// An unmined header is refused before anything is linked.
+	unmined, err := blockchain.BuildBlock(chain.TipHash(), nil, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ValidateBlockHeader(unmined.Header.Bytes()) == nil {
+		unmined.Header.Nonce++ // nonce 0 happened to meet the target
+	}
+	if _, err := chain.AcceptBlock(unmined, led); !errors.Is(err, ErrInvalidConsensus) {
+		t.Fatalf("unmined block: err = %v, want ErrInvalidConsensus", err)
+	}
+	if h := chain.Height(); h != 0 {
+		t.Fatalf("height = %d after a rejected block, want 0", h)
+	}
+
+	// The producer seals its blocks, so they pass the same check.
+	prod := blockchain.NewBlockProducer(chain, led, 10, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	out, err := prod.ProduceOnce(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ValidateBlockHeader(out.Block.Block.Header.Bytes()); err != nil {
+		t.Errorf("produced block: %v", err)
+	}
+	if h := chain.Height(); h != 1 {
+		t.Errorf("height = %d, want 1", h)
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	for _, tc := range []struct {
+		h    [32]byte
+		want uint
+	}{
+		{[32]byte{0x80}, 0},
+		{[32]byte{0x01}, 7},
+		{[32]byte{0x00, 0x40}, 9},
+		{[32]byte{0x00, 0x00, 0x00, 0x0f}, 28},
+		{[32]byte{}, 256},
+	} {
+		if got := LeadingZeroBits(tc.h); got != tc.want {
+			t.Errorf("LeadingZeroBits(%x...) = %d, want %d", tc.h[:4], got, tc.want)
+		}
+	}
+}
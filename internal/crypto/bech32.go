@@ -0,0 +1,173 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+)
+
+// Bech32m (BIP-350) string encoding: hrp + "1" + base32 data + a 6-character
+// checksum over both. The checksum catches any 4 substitutions and covers the
+// HRP, so a string cannot be moved to another prefix without invalidating it.
+
+const (
+	bech32Charset   = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32mConst    = 0x2bc830a3
+	bech32MaxLen    = 90
+	bech32ChecksumN = 6
+)
+
+var (
+	errBech32Length    = errors.New("bech32: invalid length")
+	errBech32Case      = errors.New("bech32: mixed case")
+	errBech32Char      = errors.New("bech32: invalid character")
+	errBech32Separator = errors.New("bech32: missing separator")
+	errBech32Checksum  = errors.New("bech32: invalid checksum")
+	errBech32Padding   = errors.New("bech32: invalid padding")
+)
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := range gen {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// convertBits regroups a bit stream from fromBits-wide to toBits-wide values.
+// Decoding (pad false) rejects leftover bits that are non-zero or a whole
+// group long, so each string has one valid decoding.
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var (
+		acc  uint32
+		bits uint
+		out  = make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+		maxv = uint32(1)<<toBits - 1
+	)
+	for _, v := range data {
+		if uint32(v)>>fromBits != 0 {
+			return nil, errBech32Char
+		}
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil, errBech32Padding
+	}
+	return out, nil
+}
+
+// Bech32Encode encodes data under hrp, which must be lowercase ASCII.
+func Bech32Encode(hrp string, data []byte) (string, error) {
+	if hrp == "" || hrp != strings.ToLower(hrp) {
+		return "", errBech32Case
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", errBech32Char
+		}
+	}
+	values, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	if len(hrp)+1+len(values)+bech32ChecksumN > bech32MaxLen {
+		return "", errBech32Length
+	}
+
+	in := append(bech32HRPExpand(hrp), values...)
+	in = append(in, make([]byte, bech32ChecksumN)...)
+	mod := bech32Polymod(in) ^ bech32mConst
+
+	var sb strings.Builder
+	sb.Grow(len(hrp) + 1 + len(values) + bech32ChecksumN)
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range values {
+		sb.WriteByte(bech32Charset[v])
+	}
+	for i := range bech32ChecksumN {
+		sb.WriteByte(bech32Charset[mod>>(5*(5-i))&31])
+	}
+	return sb.String(), nil
+}
+
+// Bech32Decode splits s into its HRP (lowercased) and data bytes. Either case
+// is accepted, but not a mix of both.
+func Bech32Decode(s string) (string, []byte, error) {
+	hrp, values, err := bech32DecodeValues(s)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := convertBits(values, 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
+
+// bech32DecodeValues checks s and its checksum and returns the HRP with the
+// 5-bit data values, checksum stripped.
+func bech32DecodeValues(s string) (string, []byte, error) {
+	if len(s) < 1+1+bech32ChecksumN || len(s) > bech32MaxLen {
+		return "", nil, errBech32Length
+	}
+	lower := strings.ToLower(s)
+	if lower != s && strings.ToUpper(s) != s {
+		return "", nil, errBech32Case
+	}
+	for i := 0; i < len(lower); i++ {
+		if lower[i] < 33 || lower[i] > 126 {
+			return "", nil, errBech32Char
+		}
+	}
+
+	sep := strings.LastIndexByte(lower, '1')
+	if sep < 1 {
+		return "", nil, errBech32Separator
+	}
+	if len(lower)-sep-1 < bech32ChecksumN {
+		return "", nil, errBech32Length
+	}
+	hrp := lower[:sep]
+
+	values := make([]byte, 0, len(lower)-sep-1)
+	for i := sep + 1; i < len(lower); i++ {
+		v := strings.IndexByte(bech32Charset, lower[i])
+		if v < 0 {
+			return "", nil, errBech32Char
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != bech32mConst {
+		return "", nil, errBech32Checksum
+	}
+
+	return hrp, values[:len(values)-bech32ChecksumN], nil
+}
@@ -0,0 +1,103 @@
+package crypto
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// BIP-350 test vectors for the bech32m checksum.
+var bech32mValid = []string{
+	"A1LQFN3A",
+	"a1lqfn3a",
+	"an83characterlonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11sg7hg6",
+	"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx",
+	"11" + strings.Repeat("l", 83) + "udsr8",
+	"split1checkupstagehandshakeupstreamerranterredcaperredlc445v",
+	"?1v759aa",
+}
+
+var bech32mInvalid = []struct {
+	in, why string
+}{
+	{"\x201xj0phk", "HRP character out of range"},
+	{"\x7f1g6xzxy", "HRP character out of range"},
+	{"\x801vctc34", "HRP character out of range"},
+	{"an84characterslonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11d6pts4", "overall max length exceeded"},
+	{"qyrz8wqd2c9m", "no separator character"},
+	{"1qyrz8wqd2c9m", "empty HRP"},
+	{"y1b0jsk6g", "invalid data character"},
+	{"lt1igcx5c0", "invalid data character"},
+	{"in1muywd", "too short checksum"},
+	{"mm1crxm3i", "invalid character in checksum"},
+	{"au1s5cgom", "invalid character in checksum"},
+	{"M1VUXWEZ", "checksum calculated with uppercase form of HRP"},
+	{"16plkw9", "empty HRP"},
+	{"1p2gdwpf", "empty HRP"},
+}
+
+func TestBech32mVectors(t *testing.T) {
+	for _, s := range bech32mValid {
+		hrp, _, err := bech32DecodeValues(s)
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if want := strings.ToLower(s[:strings.LastIndexByte(s, '1')]); hrp != want {
+			t.Errorf("%q: hrp = %q, want %q", s, hrp, want)
+		}
+	}
+	for _, tc := range bech32mInvalid {
+		if _, _, err := bech32DecodeValues(tc.in); err == nil {
+			t.Errorf("%q (%s): accepted", tc.in, tc.why)
+		}
+		if _, _, err := Bech32Decode(tc.in); err == nil {
+			t.Errorf("%q (%s): Bech32Decode accepted", tc.in, tc.why)
+		}
+	}
+}
+
+func TestBech32RoundTrip(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for n := range 41 {
+		data := make([]byte, n)
+		for i := range data {
+			data[i] = byte(r.Uint32())
+		}
+		s, err := Bech32Encode("tvel", data)
+		if err != nil {
+			t.Fatalf("encode %d bytes: %v", n, err)
+		}
+		for _, in := range []string{s, strings.ToUpper(s)} {
+			hrp, got, err := Bech32Decode(in)
+			if err != nil {
+				t.Fatalf("decode %q: %v", in, err)
+			}
+			if hrp != "tvel" || !bytes.Equal(got, data) {
+				t.Fatalf("decode %q = %q %x, want tvel %x", in, hrp, got, data)
+			}
+		}
+
+		// Any single substituted character must break the checksum.
+		i := len("tvel1") + r.IntN(len(s)-len("tvel1"))
+		c := bech32Charset[(strings.IndexByte(bech32Charset, s[i])+1+r.IntN(31))%32]
+		if _, _, err := Bech32Decode(s[:i] + string(c) + s[i+1:]); err == nil {
+			t.Fatalf("%q with position %d changed: accepted", s, i)
+		}
+	}
+
+	if _, err := Bech32Encode("Vel", []byte{1}); err == nil {
+		t.Fatal("uppercase hrp: accepted")
+	}
+	if _, err := Bech32Encode("vel", make([]byte, 60)); err == nil {
+		t.Fatal("over-length data: accepted")
+	}
+	s, err := Bech32Encode("tvel", []byte{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Bech32Decode(s[:5] + strings.ToUpper(s[5:])); err == nil {
+		t.Fatal("mixed case: accepted")
+	}
+}
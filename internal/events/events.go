@@ -0,0 +1,146 @@
+// Package events is a small in-process publish/subscribe bus for node
+// activity (new txs and blocks, peer changes), used to stream updates to API
+// clients instead of having them poll.
+package events
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	TxAdded       = "tx_added"
+	BlockAdded    = "block_added"
+	PeerConnected = "peer_connected"
+	PeerBanned    = "peer_banned"
+)
+
+// DefaultBuffer is the per-subscriber queue length used when NewBus is given
+// 0.
+const DefaultBuffer = 64
+
+// Event is one published occurrence. Data is marshalled to JSON as is.
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// Bus fans published events out to subscribers. Publishing never blocks: a
+// subscriber whose queue is full misses the event, and the drop is counted
+// and logged. A nil *Bus accepts Publish and does nothing, so publishers need
+// not check whether anyone is listening.
+type Bus struct {
+	log    *slog.Logger
+	buffer int
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewBus(log *slog.Logger, buffer int) *Bus {
+	if log == nil {
+		log = slog.Default()
+	}
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Bus{log: log, buffer: buffer, subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives events on C until Close, or until the bus is closed,
+// which closes C.
+type Subscription struct {
+	C <-chan Event
+
+	ch      chan Event
+	bus     *Bus
+	dropped uint64 // guarded by bus.mu
+	lagging bool   // dropping since the last delivery; guarded by bus.mu
+}
+
+// Subscribe registers a new subscriber. On a closed bus the returned
+// subscription's C is already closed.
+func (b *Bus) Subscribe() *Subscription {
+	ch := make(chan Event, b.buffer)
+	s := &Subscription{C: ch, ch: ch, bus: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Close unregisters s and closes C. It is safe to call more than once.
+func (s *Subscription) Close() {
+	b := s.bus
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Dropped is how many events s has missed because its queue was full.
+func (s *Subscription) Dropped() uint64 {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.dropped
+}
+
+// Publish sends an event of type typ to every subscriber.
+func (b *Bus) Publish(typ string, data any) {
+	if b == nil {
+		return
+	}
+	ev := Event{Type: typ, Time: time.Now().UTC(), Data: data}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		select {
+		case s.ch <- ev:
+			s.lagging = false
+		default:
+			s.dropped++
+			if !s.lagging {
+				// Once per run of drops, not per event.
+				s.lagging = true
+				b.log.Warn("event subscriber too slow; dropping events", "type", typ, "dropped", s.dropped)
+			}
+		}
+	}
+}
+
+// Subscribers is the number of open subscriptions.
+func (b *Bus) Subscribers() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Close closes every subscription; later ones are closed at once. Call it on
+// shutdown so streaming clients are released.
+func (b *Bus) Close() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
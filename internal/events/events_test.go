@@ -0,0 +1,97 @@
+package events
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func quietBus(buffer int) *Bus {
+	return NewBus(slog.New(slog.NewTextHandler(io.Discard, nil)), buffer)
+}
+
+func TestPublishDelivers(t *testing.T) {
+	b := quietBus(0)
+	s1, s2 := b.Subscribe(), b.Subscribe()
+	if n := b.Subscribers(); n != 2 {
+		t.Fatalf("subscribers = %d, want 2", n)
+	}
+
+	b.Publish(BlockAdded, 7)
+	for i, s := range []*Subscription{s1, s2} {
+		ev := <-s.C
+		if ev.Type != BlockAdded || ev.Data != 7 || ev.Time.IsZero() {
+			t.Errorf("subscriber %d got %+v", i+1, ev)
+		}
+	}
+
+	s1.Close()
+	s1.Close() // safe twice
+	if _, ok := <-s1.C; ok {
+		t.Error("closed subscription still open")
+	}
+	b.Publish(TxAdded, nil)
+	if ev := <-s2.C; ev.Type != TxAdded {
+		t.Errorf("got %q after unsubscribing the other, want %q", ev.Type, TxAdded)
+	}
+	if n := b.Subscribers(); n != 1 {
+		t.Errorf("subscribers = %d, want 1", n)
+	}
+
+	var nilBus *Bus
+	nilBus.Publish(TxAdded, nil) // a nil bus is a no-op
+}
+
+func TestPublishCountsDrops(t *testing.T) {
+	var logs bytes.Buffer
+	b := NewBus(slog.New(slog.NewTextHandler(&logs, nil)), 1)
+	s := b.Subscribe()
+
+	for range 3 {
+		b.Publish(TxAdded, nil)
+	}
+	if got := s.Dropped(); got != 2 {
+		t.Errorf("dropped = %d, want 2", got)
+	}
+	if n := strings.Count(logs.String(), "too slow"); n != 1 {
+		t.Errorf("warned %d times for one run of drops, want 1", n)
+	}
+
+	// Draining ends the run; the next drop warns again.
+	<-s.C
+	b.Publish(TxAdded, nil)
+	b.Publish(TxAdded, nil)
+	if got := s.Dropped(); got != 3 {
+		t.Errorf("dropped = %d, want 3", got)
+	}
+	if n := strings.Count(logs.String(), "too slow"); n != 2 {
+		t.Errorf("warned %d times for two runs of drops, want 2", n)
+	}
+}
+
+func TestCloseClosesSubscribers(t *testing.T) {
+	b := quietBus(0)
+	s := b.Subscribe()
+	b.Publish(PeerConnected, nil)
+
+	b.Close()
+	// Queued events are still delivered before the channel reports closed.
+	if ev, ok := <-s.C; !ok || ev.Type != PeerConnected {
+		t.Fatalf("got %+v, %v; want the queued event", ev, ok)
+	}
+	if _, ok := <-s.C; ok {
+		t.Fatal("subscription open after bus Close")
+	}
+	if n := b.Subscribers(); n != 0 {
+		t.Errorf("subscribers = %d, want 0", n)
+	}
+	s.Close() // no double close
+
+	late := b.Subscribe()
+	if _, ok := <-late.C; ok {
+		t.Error("subscription on a closed bus is open")
+	}
+	b.Publish(TxAdded, nil) // must not panic on a closed bus
+}
@@ -6,11 +6,22 @@ import "errors"
 // - subtract amount from sender
 // - add (amount - fee) to recipient
 //
+// A cancel tx (zero amount to self) only debits the fee from the sender.
+//
 // Note: fee accounting is a later phase (miner/validator reward).
 func (l *Ledger) ApplyConfirmedTx(from string, to string, amount uint64, fee uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.applyLocked(from, to, amount, fee)
+}
+
+func (l *Ledger) applyLocked(from string, to string, amount uint64, fee uint64) error {
 	if from == "" || to == "" {
 		return errors.New("from/to required")
 	}
+	if from == to && amount == 0 {
+		return l.applyFeeLocked(from, fee)
+	}
 	if amount == 0 {
 		return errors.New("amount must be > 0")
 	}
@@ -20,11 +31,8 @@ func (l *Ledger) ApplyConfirmedTx(from string, to string, amount uint64, fee uin
 
 	receive := amount - fee
 
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
 	fromBal := l.balances[from]
-	if fromBal < amount {
+	if fromBal < amount || fromBal-amount < l.staked[from] {
 		return errors.New("insufficient confirmed balance")
 	}
 
@@ -34,3 +42,56 @@ func (l *Ledger) ApplyConfirmedTx(from string, to string, amount uint64, fee uin
 	// Pending out will be rebuilt by mempool staging; confirm clears are handled elsewhere.
 	return nil
 }
+
+func (l *Ledger) applyFeeLocked(from string, fee uint64) error {
+	if fee == 0 {
+		return errors.New("fee must be > 0")
+	}
+
+	fromBal := l.balances[from]
+	if fromBal < fee || fromBal-fee < l.staked[from] {
+		return errors.New("insufficient confirmed balance")
+	}
+	l.balances[from] = fromBal - fee
+	return nil
+}
+
+// RevertConfirmedTx undoes ApplyConfirmedTx with the same arguments: the
+// recipient gives back (amount - fee) and the sender is re-credited amount
+// (just the fee for a cancel). It fails without changing anything if the
+// recipient no longer holds the received funds outside its stake, e.g.
+// because they were spent since.
+func (l *Ledger) RevertConfirmedTx(from string, to string, amount uint64, fee uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.revertLocked(from, to, amount, fee)
+}
+
+func (l *Ledger) revertLocked(from string, to string, amount uint64, fee uint64) error {
+	if from == "" || to == "" {
+		return errors.New("from/to required")
+	}
+	if from == to && amount == 0 {
+		if l.balances[from]+fee < fee {
+			return errors.New("revert overflows sender balance")
+		}
+		l.balances[from] += fee
+		return nil
+	}
+	if amount == 0 || fee > amount {
+		return errors.New("not a valid confirmed tx")
+	}
+
+	receive := amount - fee
+	toBal := l.balances[to]
+	if toBal < receive || toBal-receive < l.staked[to] {
+		return errors.New("revert underflows recipient balance")
+	}
+	if l.balances[from]+amount < amount {
+		return errors.New("revert overflows sender balance")
+	}
+
+	l.balances[to] = toBal - receive
+	l.balances[from] += amount
+	return nil
+}
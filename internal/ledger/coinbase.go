@@ -0,0 +1,33 @@
+package ledger
+
+import "errors"
+
+// ApplyCoinbaseAt credits a block producer with amount (its block reward
+// plus the fees collected) for the block at height, journaled like
+// ApplyConfirmedTxAt so RevertTo takes the credit back.
+func (l *Ledger) ApplyCoinbaseAt(height uint64, to string, amount uint64) error {
+	return l.applyJournaled(journalEntry{height: height, to: to, amount: amount, coinbase: true}, []string{to})
+}
+
+func (l *Ledger) applyCoinbaseLocked(to string, amount uint64) error {
+	if to == "" {
+		return errors.New("coinbase recipient required")
+	}
+	if amount == 0 {
+		return errors.New("coinbase amount must be > 0")
+	}
+	if l.balances[to]+amount < amount {
+		return errors.New("coinbase overflows recipient balance")
+	}
+	l.balances[to] += amount
+	return nil
+}
+
+func (l *Ledger) revertCoinbaseLocked(to string, amount uint64) error {
+	bal := l.balances[to]
+	if bal < amount || bal-amount < l.staked[to] {
+		return errors.New("revert underflows coinbase recipient balance")
+	}
+	l.balances[to] = bal - amount
+	return nil
+}
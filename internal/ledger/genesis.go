@@ -0,0 +1,46 @@
+package ledger
+
+import "errors"
+
+// ErrLedgerNotEmpty is returned by ApplyGenesis when balances already exist.
+var ErrLedgerNotEmpty = errors.New("ledger already has balances")
+
+// ApplyGenesis credits the genesis allocations and records genesisHash as
+// the genesis the balances start from (see Genesis). It only applies to an
+// empty ledger, so the allocations can never be credited twice.
+func (l *Ledger) ApplyGenesis(genesisHash string, allocs map[string]uint64) error {
+	if genesisHash == "" {
+		return errors.New("genesis hash required")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.applyGenesisLocked(genesisHash, allocs); err != nil {
+		return err
+	}
+	l.logLocked(walRecord{Op: walGenesis, Genesis: genesisHash, Allocs: allocs}, true)
+	return nil
+}
+
+func (l *Ledger) applyGenesisLocked(genesisHash string, allocs map[string]uint64) error {
+	if len(l.balances) > 0 || l.genesis != "" {
+		return ErrLedgerNotEmpty
+	}
+	for addr, bal := range allocs {
+		if addr == "" || bal == 0 {
+			continue
+		}
+		l.balances[addr] = bal
+	}
+	l.genesis = genesisHash
+	l.height, l.heightKnown = 0, true
+	return nil
+}
+
+// Genesis is the hash passed to ApplyGenesis, persisted with the balances,
+// or "" if no genesis allocations were ever applied.
+func (l *Ledger) Genesis() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.genesis
+}
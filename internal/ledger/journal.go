@@ -0,0 +1,176 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+)
+
+// JournalDepth is how many recent block heights the ledger can unwind
+// (see RevertTo). The journal is in memory only; after a restart it covers
+// just the blocks applied since.
+const JournalDepth = 128
+
+// ErrJournalTooShallow is returned by RevertTo for a height the journal no
+// longer (or never did) cover.
+var ErrJournalTooShallow = errors.New("ledger journal does not reach that height")
+
+// journalEntry is one confirmed tx applied at a block height: a transfer
+// (to, amount), a multi-output tx when outputs is set, or a block's
+// coinbase credit (to, amount) when coinbase is set.
+type journalEntry struct {
+	height   uint64
+	from, to string
+	amount   uint64
+	outputs  []Output
+	fee      uint64
+	coinbase bool
+	created  []string // recipients whose balance entry the apply created
+}
+
+func (l *Ledger) applyEntryLocked(e journalEntry) error {
+	if e.coinbase {
+		return l.applyCoinbaseLocked(e.to, e.amount)
+	}
+	if e.outputs != nil {
+		return l.applyMultiLocked(e.from, e.outputs, e.fee)
+	}
+	return l.applyLocked(e.from, e.to, e.amount, e.fee)
+}
+
+func (l *Ledger) revertEntryLocked(e journalEntry) error {
+	if e.coinbase {
+		return l.revertCoinbaseLocked(e.to, e.amount)
+	}
+	if e.outputs != nil {
+		return l.revertMultiLocked(e.from, e.outputs, e.fee)
+	}
+	return l.revertLocked(e.from, e.to, e.amount, e.fee)
+}
+
+// ResetJournal empties the journal and marks the current balances as those
+// at height, the lowest height RevertTo can then reach. Call it once the
+// chain is loaded.
+func (l *Ledger) ResetJournal(height uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.journal = nil
+	l.journalBase = height
+	l.journalStarted = true
+}
+
+// ApplyConfirmedTxAt is ApplyConfirmedTx for a tx in the block at height,
+// recording it so RevertTo can undo it. Heights must not decrease between
+// calls unless the journal was unwound past them first.
+func (l *Ledger) ApplyConfirmedTxAt(height uint64, from string, to string, amount uint64, fee uint64) error {
+	if from == to && amount == 0 {
+		return l.applyJournaled(journalEntry{height: height, from: from, to: to, fee: fee}, nil)
+	}
+	return l.applyJournaled(journalEntry{height: height, from: from, to: to, amount: amount, fee: fee}, []string{to})
+}
+
+// ApplyConfirmedMultiTxAt is ApplyConfirmedMultiTx for a tx in the block at
+// height, journaled like ApplyConfirmedTxAt.
+func (l *Ledger) ApplyConfirmedMultiTxAt(height uint64, from string, outputs []Output, fee uint64) error {
+	recipients := make([]string, len(outputs))
+	for i, o := range outputs {
+		recipients[i] = o.To
+	}
+	outs := append([]Output(nil), outputs...)
+	if outs == nil {
+		outs = []Output{}
+	}
+	return l.applyJournaled(journalEntry{height: height, from: from, outputs: outs, fee: fee}, recipients)
+}
+
+// applyJournaled applies e and records it, noting which of recipients did
+// not have a balance entry before.
+func (l *Ledger) applyJournaled(e journalEntry, recipients []string) error {
+	height := e.height
+	if height == 0 {
+		return errors.New("height must be > 0")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.journalStarted {
+		l.journalStarted = true
+		l.journalBase = height - 1
+	}
+	if height <= l.journalBase {
+		return fmt.Errorf("height %d is below the ledger journal (base %d)", height, l.journalBase)
+	}
+
+	for _, to := range recipients {
+		if _, existed := l.balances[to]; !existed {
+			e.created = append(e.created, to)
+		}
+	}
+	if err := l.applyEntryLocked(e); err != nil {
+		return err
+	}
+	l.logLocked(walRecord{Op: walApply, Height: height, Entries: []walEntry{walEntryOf(e)}}, false)
+	l.journal = append(l.journal, e)
+
+	if height > JournalDepth && height-JournalDepth > l.journalBase {
+		l.journalBase = height - JournalDepth
+		i := 0
+		for i < len(l.journal) && l.journal[i].height <= l.journalBase {
+			i++
+		}
+		l.journal = append(l.journal[:0:0], l.journal[i:]...)
+	}
+	return nil
+}
+
+// RevertTo undoes, newest first, every journaled tx above height, restoring
+// confirmed balances to what they were once that block was applied. It is
+// all or nothing: if any revert would underflow a balance (funds spent
+// outside the journal since), nothing is changed. It returns the number of
+// txs reverted.
+func (l *Ledger) RevertTo(height uint64) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.journalStarted || height < l.journalBase {
+		return 0, ErrJournalTooShallow
+	}
+
+	cut := len(l.journal)
+	for cut > 0 && l.journal[cut-1].height > height {
+		cut--
+	}
+	tail := l.journal[cut:]
+	for i := len(tail) - 1; i >= 0; i-- {
+		e := tail[i]
+		if err := l.revertEntryLocked(e); err != nil {
+			// Re-apply what was already reverted, oldest first; each of these
+			// applied cleanly moments ago.
+			for _, done := range tail[i+1:] {
+				_ = l.applyEntryLocked(done)
+			}
+			return 0, fmt.Errorf("revert tx at height %d: %w", e.height, err)
+		}
+		l.dropCreatedLocked(e)
+	}
+	logged := make([]walEntry, len(tail))
+	for i, e := range tail {
+		logged[i] = walEntryOf(e)
+	}
+	l.logLocked(walRecord{Op: walRevert, Height: height, Entries: logged}, true)
+	l.journal = l.journal[:cut]
+	if l.height > height {
+		l.height = height
+	}
+	return len(tail), nil
+}
+
+// dropCreatedLocked removes the empty balance entries that applying e
+// created, once e is reverted.
+func (l *Ledger) dropCreatedLocked(e journalEntry) {
+	for _, to := range e.created {
+		if l.balances[to] == 0 && l.staked[to] == 0 {
+			delete(l.balances, to)
+		}
+	}
+}
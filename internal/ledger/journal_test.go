@@ -0,0 +1,144 @@
+package ledger
+
+import (
+	"errors"
+	"maps"
+	"testing"
+)
+
+// balancesOf copies l's confirmed and staked balances.
+func balancesOf(l *Ledger) (balances, staked map[string]uint64) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return maps.Clone(l.balances), maps.Clone(l.staked)
+}
+
+func TestRevertToRestoresSnapshot(t *testing.T) {
+	l := newTestLedger(t)
+	if err := l.FaucetCredit("alice", 10_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.FaucetCredit("bob", 500); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Stake("bob", 200); err != nil {
+		t.Fatal(err)
+	}
+	l.ResetJournal(3)
+	wantBal, wantStaked := balancesOf(l)
+
+	steps := []func() error{
+		func() error { return l.ApplyConfirmedTxAt(4, "alice", "bob", 1_000, 10) },
+		func() error { return l.ApplyConfirmedTxAt(4, "alice", "carol", 300, 3) }, // creates carol
+		func() error { return l.ApplyCoinbaseAt(4, "miner", 13) },
+		func() error {
+			return l.ApplyConfirmedMultiTxAt(5, "bob", []Output{{To: "alice", Amount: 100}, {To: "dave", Amount: 50}}, 5)
+		},
+		func() error { return l.ApplyConfirmedTxAt(5, "alice", "alice", 0, 7) }, // cancel
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+
+	n, err := l.RevertTo(3)
+	if err != nil {
+		t.Fatalf("revert: %v", err)
+	}
+	if n != len(steps) {
+		t.Errorf("reverted %d txs, want %d", n, len(steps))
+	}
+	gotBal, gotStaked := balancesOf(l)
+	if !maps.Equal(gotBal, wantBal) {
+		t.Errorf("balances = %v, want %v", gotBal, wantBal)
+	}
+	if !maps.Equal(gotStaked, wantStaked) {
+		t.Errorf("staked = %v, want %v", gotStaked, wantStaked)
+	}
+	if l.HasAccount("carol") || l.HasAccount("dave") || l.HasAccount("miner") {
+		t.Error("accounts created by reverted txs were kept")
+	}
+}
+
+func TestRevertToPartialHeight(t *testing.T) {
+	l := newTestLedger(t)
+	if err := l.FaucetCredit("alice", 10_000); err != nil {
+		t.Fatal(err)
+	}
+	l.ResetJournal(0)
+	if err := l.ApplyConfirmedTxAt(1, "alice", "bob", 1_000, 10); err != nil {
+		t.Fatal(err)
+	}
+	wantBal, _ := balancesOf(l)
+	if err := l.ApplyConfirmedTxAt(2, "alice", "bob", 2_000, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.RevertTo(1); err != nil {
+		t.Fatal(err)
+	}
+	if gotBal, _ := balancesOf(l); !maps.Equal(gotBal, wantBal) {
+		t.Errorf("balances = %v, want %v", gotBal, wantBal)
+	}
+}
+
+func TestRevertToRefusesUnderflow(t *testing.T) {
+	l := newTestLedger(t)
+	if err := l.FaucetCredit("alice", 10_000); err != nil {
+		t.Fatal(err)
+	}
+	l.ResetJournal(0)
+	if err := l.ApplyConfirmedTxAt(1, "alice", "bob", 1_000, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ApplyConfirmedTxAt(2, "alice", "carol", 500, 5); err != nil {
+		t.Fatal(err)
+	}
+	// bob spends the funds received at height 1 outside the journal.
+	if err := l.ApplyConfirmedTx("bob", "erin", 900, 9); err != nil {
+		t.Fatal(err)
+	}
+	wantBal, wantStaked := balancesOf(l)
+
+	if _, err := l.RevertTo(0); err == nil {
+		t.Fatal("revert underflowing bob succeeded")
+	}
+	gotBal, gotStaked := balancesOf(l)
+	if !maps.Equal(gotBal, wantBal) || !maps.Equal(gotStaked, wantStaked) {
+		t.Errorf("failed revert changed balances: %v, want %v", gotBal, wantBal)
+	}
+	// The height-2 tx is still journaled and still revertible on its own.
+	if _, err := l.RevertTo(1); err != nil {
+		t.Errorf("revert to 1: %v", err)
+	}
+}
+
+func TestRevertConfirmedTxRefusesStakedFunds(t *testing.T) {
+	l := newTestLedger(t)
+	if err := l.FaucetCredit("alice", 1_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ApplyConfirmedTx("alice", "bob", 500, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Stake("bob", 400); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.RevertConfirmedTx("alice", "bob", 500, 5); err == nil {
+		t.Fatal("revert took back staked funds")
+	}
+	if got := l.ConfirmedBalance("bob"); got != 495 {
+		t.Errorf("bob = %d, want 495", got)
+	}
+}
+
+func TestRevertToBelowJournal(t *testing.T) {
+	l := newTestLedger(t)
+	l.ResetJournal(10)
+	if _, err := l.RevertTo(9); !errors.Is(err, ErrJournalTooShallow) {
+		t.Errorf("err = %v, want ErrJournalTooShallow", err)
+	}
+	if _, err := l.RevertTo(10); err != nil {
+		t.Errorf("revert to the journal base: %v", err)
+	}
+}
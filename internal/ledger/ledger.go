@@ -3,10 +3,14 @@ package ledger
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/storage"
 )
 
 type Ledger struct {
@@ -18,83 +22,200 @@ type Ledger struct {
 	// staged spends due to mempool txs (not persisted)
 	pendingOut map[string]uint64
 
-	storePath string
+	// funds locked by staking (persisted); a subset of confirmed balance
+	staked map[string]uint64
+
+	// recent confirmed txs by block height, for unwinding (not persisted)
+	journal        []journalEntry
+	journalBase    uint64 // lowest height RevertTo can reach
+	journalStarted bool
+
+	// the block height balances reflect (persisted); see AppliedHeight
+	height      uint64
+	heightKnown bool
+
+	// hash of the genesis whose allocations were applied (persisted); see
+	// ApplyGenesis
+	genesis string
+
+	storePath  string
+	keepBackup bool
+
+	// write-ahead log of changes since the last Save; see wal.go
+	wal       *os.File
+	walSeq    uint64 // last record number logged (or skipped while broken)
+	walBroken bool   // a write failed; log nothing until the next Save
+}
+
+// ErrInsufficientBalance is returned when spendable funds (confirmed minus
+// staged and staked) do not cover a spend or stake.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// storeFormat versions the ledger file. Version 1 was a bare []Snapshot;
+// version 2 (storeFile) adds the block height the balances are at.
+var storeFormat = storage.Format{
+	Name:    "ledger",
+	Version: 2,
+	Migrations: map[int]storage.Migration{
+		1: func(data json.RawMessage) (json.RawMessage, error) {
+			if len(data) == 0 {
+				return nil, errors.New("empty ledger store")
+			}
+			return json.Marshal(map[string]json.RawMessage{"accounts": data})
+		},
+	},
+}
+
+type storeFile struct {
+	Height   *uint64    `json:"height,omitempty"` // nil when migrated from version 1
+	Genesis  string     `json:"genesis,omitempty"`
+	WALSeq   uint64     `json:"walSeq,omitempty"` // last WAL record the file includes
+	Accounts []Snapshot `json:"accounts"`
 }
 
 type Snapshot struct {
 	Addr      string    `json:"addr"`
 	Balance   uint64    `json:"balance"`
+	Staked    uint64    `json:"staked,omitempty"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 func New(storePath string) *Ledger {
 	return &Ledger{
-		balances:   make(map[string]uint64),
-		pendingOut: make(map[string]uint64),
-		storePath:  filepath.Clean(storePath),
+		balances:    make(map[string]uint64),
+		pendingOut:  make(map[string]uint64),
+		staked:      make(map[string]uint64),
+		heightKnown: true, // empty, i.e. at genesis
+		storePath:   filepath.Clean(storePath),
 	}
 }
 
+// SetKeepBackup makes Save keep the previous store as <path>.bak; Load falls
+// back to it if the primary is corrupt.
+func (l *Ledger) SetKeepBackup(keep bool) {
+	l.mu.Lock()
+	l.keepBackup = keep
+	l.mu.Unlock()
+}
+
+// Load reads the ledger store and replays the WAL on top of it.
 func (l *Ledger) Load() error {
+	return l.LoadUpTo(math.MaxUint64)
+}
+
+// LoadUpTo is Load, but stops replaying the WAL at the first record for a
+// block above height and discards the rest, so the balances never run ahead
+// of a block store that lost its unsaved tail.
+func (l *Ledger) LoadUpTo(height uint64) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	raw, err := os.ReadFile(l.storePath)
+	var file storeFile
+	err := storage.ReadFileWithFallback(l.storePath, func(raw []byte) error {
+		file = storeFile{}
+		if err := storeFormat.Decode(raw, &file); err != nil {
+			return err
+		}
+		return checkSnapshots(file.Accounts)
+	})
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return nil
+			// Nothing saved yet, but the WAL may hold changes since.
+			_, err = l.replayWALLocked(0, height)
+			return err
 		}
 		return err
 	}
 
-	var snaps []Snapshot
-	if err := json.Unmarshal(raw, &snaps); err != nil {
-		return err
+	l.balances = make(map[string]uint64, len(file.Accounts))
+	l.staked = make(map[string]uint64)
+	l.journal, l.journalStarted = nil, false
+	l.height, l.heightKnown = 0, file.Height != nil
+	l.genesis = file.Genesis
+	if file.Height != nil {
+		l.height = *file.Height
 	}
-
-	l.balances = make(map[string]uint64, len(snaps))
-	for _, s := range snaps {
+	for _, s := range file.Accounts {
 		if s.Addr == "" {
 			continue
 		}
 		l.balances[s.Addr] = s.Balance
+		if s.Staked > 0 {
+			l.staked[s.Addr] = s.Staked
+		}
+	}
+	_, err = l.replayWALLocked(file.WALSeq, height)
+	return err
+}
+
+// checkSnapshots rejects a store that decodes but is inconsistent, so Load
+// can fall back to the backup.
+func checkSnapshots(snaps []Snapshot) error {
+	for _, s := range snaps {
+		if s.Addr != "" && s.Staked > s.Balance {
+			return errors.New("ledger store invalid: staked exceeds balance for " + s.Addr)
+		}
 	}
 	return nil
 }
 
 func (l *Ledger) Save() error {
+	var b storage.Batch
+	if err := l.Stage(&b); err != nil {
+		return err
+	}
+	return b.Commit()
+}
+
+// Stage queues the ledger store on b, to be written together with other
+// stores (see storage.Batch).
+func (l *Ledger) Stage(b *storage.Batch) error {
 	l.mu.RLock()
-	snaps := make([]Snapshot, 0, len(l.balances))
+	file := storeFile{Accounts: make([]Snapshot, 0, len(l.balances))}
 	now := time.Now().UTC()
 	for addr, bal := range l.balances {
 		if addr == "" {
 			continue
 		}
-		snaps = append(snaps, Snapshot{Addr: addr, Balance: bal, UpdatedAt: now})
+		file.Accounts = append(file.Accounts, Snapshot{Addr: addr, Balance: bal, Staked: l.staked[addr], UpdatedAt: now})
 	}
-	l.mu.RUnlock()
-
-	if err := os.MkdirAll(filepath.Dir(l.storePath), 0o700); err != nil {
-		return err
+	if l.heightKnown {
+		h := l.height
+		file.Height = &h
 	}
+	file.Genesis = l.genesis
+	file.WALSeq = l.walSeq
+	keep := l.keepBackup
+	l.mu.RUnlock()
 
-	data, err := json.MarshalIndent(snaps, "", "  ")
-	if err != nil {
-		return err
-	}
+	sort.Slice(file.Accounts, func(i, j int) bool { return file.Accounts[i].Addr < file.Accounts[j].Addr })
 
-	tmp := l.storePath + ".tmp"
-	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+	if err := b.AddVersioned(l.storePath, storeFormat, file, keep); err != nil {
 		return err
 	}
-	if err := os.Rename(tmp, l.storePath); err != nil {
-		_ = os.Remove(tmp)
-		return err
-	}
-	_ = os.Chmod(l.storePath, 0o600)
+	b.OnCommit(func() { l.checkpointWAL(file.WALSeq) })
 	return nil
 }
 
+// SetAppliedHeight records that the confirmed balances reflect every block up
+// to height. Block settlement calls it once a block's txs are applied, so
+// empty blocks count too.
+func (l *Ledger) SetAppliedHeight(height uint64) {
+	l.mu.Lock()
+	l.height, l.heightKnown = height, true
+	l.logLocked(walRecord{Op: walHeight, Height: height}, true)
+	l.mu.Unlock()
+}
+
+// AppliedHeight returns the block height the confirmed balances reflect, as
+// persisted with them. ok is false for a store written before heights were
+// recorded, until the next block is applied.
+func (l *Ledger) AppliedHeight() (height uint64, ok bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.height, l.heightKnown
+}
+
 func (l *Ledger) ResetPending() {
 	l.mu.Lock()
 	l.pendingOut = make(map[string]uint64)
@@ -107,46 +228,68 @@ func (l *Ledger) ConfirmedBalance(addr string) uint64 {
 	return l.balances[addr]
 }
 
+// HasAccount reports whether addr has ever held a balance or transacted.
+func (l *Ledger) HasAccount(addr string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.balances[addr]
+	return ok
+}
+
 func (l *Ledger) PendingOut(addr string) uint64 {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 	return l.pendingOut[addr]
 }
 
+// SpendableBalance is confirmed - pendingOut - staked, floored at zero.
 func (l *Ledger) SpendableBalance(addr string) uint64 {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
+	return l.spendableLocked(addr)
+}
 
-	confirmed := l.balances[addr]
-	pending := l.pendingOut[addr]
-	if pending >= confirmed {
-		return 0
+// spendableLocked requires l.mu. The subtraction is done stepwise so a
+// pending+staked sum exceeding confirmed (or overflowing) floors at zero.
+func (l *Ledger) spendableLocked(addr string) uint64 {
+	avail := l.balances[addr]
+	for _, locked := range []uint64{l.pendingOut[addr], l.staked[addr]} {
+		if locked >= avail {
+			return 0
+		}
+		avail -= locked
 	}
-	return confirmed - pending
+	return avail
 }
 
 // StageMempoolSpend reserves funds for a mempool tx.
 // It does NOT change confirmed balances, only pending outflow.
-// It enforces spendable >= required.
+// It enforces spendable >= required, so staked funds cannot be staged.
 func (l *Ledger) StageMempoolSpend(from string, required uint64) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	confirmed := l.balances[from]
-	pending := l.pendingOut[from]
-
-	if pending > confirmed {
-		return errors.New("ledger pending state invalid")
-	}
-	spendable := confirmed - pending
-	if spendable < required {
-		return errors.New("insufficient balance")
+	if l.spendableLocked(from) < required {
+		return ErrInsufficientBalance
 	}
-
-	l.pendingOut[from] = pending + required
+	l.pendingOut[from] += required
 	return nil
 }
 
+// UnstageMempoolSpend releases funds previously reserved by StageMempoolSpend,
+// e.g. when a pending tx is replaced or evicted.
+func (l *Ledger) UnstageMempoolSpend(from string, amount uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pending := l.pendingOut[from]
+	if amount >= pending {
+		delete(l.pendingOut, from)
+		return
+	}
+	l.pendingOut[from] = pending - amount
+}
+
 // FaucetCredit increases confirmed balance. Intended for testnet/dev flows.
 func (l *Ledger) FaucetCredit(addr string, amount uint64) error {
 	if addr == "" {
@@ -157,7 +300,15 @@ func (l *Ledger) FaucetCredit(addr string, amount uint64) error {
 	}
 
 	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.creditLocked(addr, amount); err != nil {
+		return err
+	}
+	l.logLocked(walRecord{Op: walCredit, Addr: addr, Amount: amount}, true)
+	return nil
+}
+
+func (l *Ledger) creditLocked(addr string, amount uint64) error {
 	l.balances[addr] = l.balances[addr] + amount
-	l.mu.Unlock()
 	return nil
 }
@@ -0,0 +1,102 @@
+package ledger
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// newTestLedger returns an empty ledger stored in a temp dir.
+func newTestLedger(t *testing.T) *Ledger {
+	t.Helper()
+	return New(filepath.Join(t.TempDir(), "ledger.json"))
+}
+
+func TestSpendableExcludesPendingAndStaked(t *testing.T) {
+	l := newTestLedger(t)
+	if err := l.FaucetCredit("alice", 1_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Stake("alice", 300); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.StageMempoolSpend("alice", 500); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.SpendableBalance("alice"); got != 200 {
+		t.Fatalf("spendable = %d, want 1000-500-300", got)
+	}
+
+	// Neither a spend nor a stake can dip into the other's funds.
+	if err := l.StageMempoolSpend("alice", 201); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("staging past spendable: err = %v", err)
+	}
+	if err := l.Stake("alice", 201); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("staking past spendable: err = %v", err)
+	}
+	if err := l.Stake("alice", 200); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.PendingOut("alice") + l.StakedBalance("alice"); got > l.ConfirmedBalance("alice") {
+		t.Fatalf("pending+staked = %d exceeds confirmed %d", got, l.ConfirmedBalance("alice"))
+	}
+	if got := l.SpendableBalance("alice"); got != 0 {
+		t.Errorf("spendable = %d, want 0", got)
+	}
+
+	// Releasing the stake frees it for spending again.
+	if err := l.Unstake("alice", 500); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.SpendableBalance("alice"); got != 500 {
+		t.Errorf("spendable after unstake = %d, want 500", got)
+	}
+}
+
+func TestConfirmedSpendCannotTouchStake(t *testing.T) {
+	l := newTestLedger(t)
+	if err := l.FaucetCredit("alice", 1_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Stake("alice", 800); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ApplyConfirmedTx("alice", "bob", 201, 1); err == nil {
+		t.Fatal("confirmed tx spent staked funds")
+	}
+	if err := l.ApplyConfirmedTx("alice", "bob", 200, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got, staked := l.ConfirmedBalance("alice"), l.StakedBalance("alice"); got != 800 || staked != 800 {
+		t.Errorf("confirmed %d, staked %d; want 800, 800", got, staked)
+	}
+}
+
+func TestSpendableFloorsAtZero(t *testing.T) {
+	l := newTestLedger(t)
+	if err := l.FaucetCredit("alice", 1_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Stake("alice", 400); err != nil {
+		t.Fatal(err)
+	}
+	// Staged spends are not checked against later confirmed changes, so a
+	// block paying out of the same funds can leave pending+staked above
+	// confirmed until the mempool catches up.
+	if err := l.StageMempoolSpend("alice", 600); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ApplyConfirmedTx("alice", "bob", 500, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.SpendableBalance("alice"); got != 0 {
+		t.Errorf("spendable = %d, want 0 rather than an underflow", got)
+	}
+	// Likewise with a pending total so large it would overflow the sum.
+	l.mu.Lock()
+	l.pendingOut["alice"] = ^uint64(0)
+	l.mu.Unlock()
+	if got := l.SpendableBalance("alice"); got != 0 {
+		t.Errorf("spendable = %d with an overflowing pending total, want 0", got)
+	}
+}
@@ -0,0 +1,100 @@
+package ledger
+
+import "errors"
+
+// Output is one recipient of a multi-output tx.
+type Output struct {
+	To     string
+	Amount uint64
+}
+
+// ApplyConfirmedMultiTx applies a confirmed multi-output tx: the sender is
+// debited the output total plus fee once, and each recipient is credited its
+// amount in full. Nothing changes if any check fails.
+func (l *Ledger) ApplyConfirmedMultiTx(from string, outputs []Output, fee uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.applyMultiLocked(from, outputs, fee)
+}
+
+// RevertConfirmedMultiTx undoes ApplyConfirmedMultiTx with the same
+// arguments. It fails without changing anything if any recipient no longer
+// holds its credit outside its stake.
+func (l *Ledger) RevertConfirmedMultiTx(from string, outputs []Output, fee uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.revertMultiLocked(from, outputs, fee)
+}
+
+// multiSpend checks outputs and returns what the sender pays for them.
+func multiSpend(from string, outputs []Output, fee uint64) (uint64, error) {
+	if from == "" {
+		return 0, errors.New("from required")
+	}
+	if len(outputs) == 0 {
+		return 0, errors.New("outputs required")
+	}
+	if fee == 0 {
+		return 0, errors.New("fee must be > 0")
+	}
+	spend := fee
+	for _, o := range outputs {
+		if o.To == "" || o.To == from {
+			return 0, errors.New("invalid output recipient")
+		}
+		if o.Amount == 0 {
+			return 0, errors.New("output amount must be > 0")
+		}
+		if spend+o.Amount < spend {
+			return 0, errors.New("output total overflows")
+		}
+		spend += o.Amount
+	}
+	return spend, nil
+}
+
+func (l *Ledger) applyMultiLocked(from string, outputs []Output, fee uint64) error {
+	spend, err := multiSpend(from, outputs, fee)
+	if err != nil {
+		return err
+	}
+
+	fromBal := l.balances[from]
+	if fromBal < spend || fromBal-spend < l.staked[from] {
+		return errors.New("insufficient confirmed balance")
+	}
+
+	l.balances[from] = fromBal - spend
+	for _, o := range outputs {
+		l.balances[o.To] += o.Amount
+	}
+	return nil
+}
+
+func (l *Ledger) revertMultiLocked(from string, outputs []Output, fee uint64) error {
+	spend, err := multiSpend(from, outputs, fee)
+	if err != nil {
+		return err
+	}
+
+	// Check every recipient before touching any (one may appear twice).
+	owed := make(map[string]uint64, len(outputs))
+	for _, o := range outputs {
+		owed[o.To] += o.Amount
+	}
+	for to, amt := range owed {
+		bal := l.balances[to]
+		if bal < amt || bal-amt < l.staked[to] {
+			return errors.New("revert underflows recipient balance")
+		}
+	}
+	if l.balances[from]+spend < spend {
+		return errors.New("revert overflows sender balance")
+	}
+
+	for to, amt := range owed {
+		l.balances[to] -= amt
+	}
+	l.balances[from] += spend
+	return nil
+}
@@ -0,0 +1,65 @@
+package ledger
+
+import "errors"
+
+// StakedBalance returns funds locked by staking for addr. Staked funds stay
+// part of the confirmed balance but are excluded from SpendableBalance.
+func (l *Ledger) StakedBalance(addr string) uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.staked[addr]
+}
+
+// Stake locks amount of addr's spendable balance.
+func (l *Ledger) Stake(addr string, amount uint64) error {
+	if addr == "" {
+		return errors.New("address required")
+	}
+	if amount == 0 {
+		return errors.New("amount must be > 0")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.stakeLocked(addr, amount); err != nil {
+		return err
+	}
+	l.logLocked(walRecord{Op: walStake, Addr: addr, Amount: amount}, true)
+	return nil
+}
+
+func (l *Ledger) stakeLocked(addr string, amount uint64) error {
+	if l.spendableLocked(addr) < amount {
+		return ErrInsufficientBalance
+	}
+	l.staked[addr] += amount
+	return nil
+}
+
+// Unstake releases amount of addr's staked balance back to spendable.
+func (l *Ledger) Unstake(addr string, amount uint64) error {
+	if amount == 0 {
+		return errors.New("amount must be > 0")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.unstakeLocked(addr, amount); err != nil {
+		return err
+	}
+	l.logLocked(walRecord{Op: walUnstake, Addr: addr, Amount: amount}, true)
+	return nil
+}
+
+func (l *Ledger) unstakeLocked(addr string, amount uint64) error {
+	staked := l.staked[addr]
+	if amount > staked {
+		return errors.New("amount exceeds staked balance")
+	}
+	if amount == staked {
+		delete(l.staked, addr)
+		return nil
+	}
+	l.staked[addr] = staked - amount
+	return nil
+}
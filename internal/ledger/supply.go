@@ -0,0 +1,60 @@
+package ledger
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// ErrSupplyOverflow is returned when confirmed balances sum past uint64, which
+// only a corrupt ledger (or unbounded faucet use) can produce.
+var ErrSupplyOverflow = errors.New("total supply overflows uint64")
+
+// Supply is a consistent snapshot of ledger-wide totals. Staked funds are
+// part of Total; Circulating is Total minus Staked.
+type Supply struct {
+	Total       uint64
+	Staked      uint64
+	Circulating uint64
+	Accounts    int
+	Height      uint64 // see AppliedHeight; 0 when unknown
+}
+
+// Supply sums every confirmed and staked balance under one lock.
+func (l *Ledger) Supply() (Supply, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	s := Supply{Accounts: len(l.balances)}
+	if l.heightKnown {
+		s.Height = l.height
+	}
+	var carry uint64
+	for _, bal := range l.balances {
+		if s.Total, carry = bits.Add64(s.Total, bal, 0); carry != 0 {
+			return Supply{}, ErrSupplyOverflow
+		}
+	}
+	for _, st := range l.staked {
+		if s.Staked, carry = bits.Add64(s.Staked, st, 0); carry != 0 {
+			return Supply{}, ErrSupplyOverflow
+		}
+	}
+	if s.Staked > s.Total {
+		return Supply{}, errors.New("staked supply exceeds total supply")
+	}
+	s.Circulating = s.Total - s.Staked
+	return s, nil
+}
+
+// TotalSupply is the sum of all confirmed balances.
+func (l *Ledger) TotalSupply() (uint64, error) {
+	s, err := l.Supply()
+	return s.Total, err
+}
+
+// AccountCount is the number of accounts that have ever held a balance.
+func (l *Ledger) AccountCount() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.balances)
+}
@@ -0,0 +1,233 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ---- Write-ahead log ----
+//
+// Save rewrites every balance, so the node only runs it periodically. The
+// WAL covers the time in between: each change to balances, stakes or the
+// applied height is appended to <store>.wal as one JSON line, numbered by a
+// sequence that the snapshot written by Save records as walSeq. Load
+// replays the records newer than the snapshot, and a Save that captured
+// every record so far truncates the log.
+//
+// Records are written as they happen but fsynced only once per block (at
+// SetAppliedHeight) and for rare changes such as reverts and faucet
+// credits. A process crash loses nothing; a power loss loses at most the
+// current block's unsynced records.
+
+// walSuffix names the write-ahead log kept next to the ledger store.
+const walSuffix = ".wal"
+
+const (
+	walApply   = "apply"   // Entries[0] applied at Height
+	walRevert  = "revert"  // Entries (oldest first) reverted down to Height
+	walHeight  = "height"  // SetAppliedHeight(Height)
+	walCredit  = "credit"  // FaucetCredit(Addr, Amount)
+	walStake   = "stake"   // Stake(Addr, Amount)
+	walUnstake = "unstake" // Unstake(Addr, Amount)
+	walGenesis = "genesis" // ApplyGenesis(Genesis, Allocs)
+)
+
+type walRecord struct {
+	Seq     uint64            `json:"seq"`
+	Op      string            `json:"op"`
+	Height  uint64            `json:"height,omitempty"`
+	Entries []walEntry        `json:"entries,omitempty"`
+	Addr    string            `json:"addr,omitempty"`
+	Amount  uint64            `json:"amount,omitempty"`
+	Genesis string            `json:"genesis,omitempty"`
+	Allocs  map[string]uint64 `json:"allocs,omitempty"`
+}
+
+// walEntry is a journalEntry as logged; the height is the record's.
+type walEntry struct {
+	From     string   `json:"from,omitempty"`
+	To       string   `json:"to,omitempty"`
+	Amount   uint64   `json:"amount,omitempty"`
+	Outputs  []Output `json:"outputs,omitempty"`
+	Fee      uint64   `json:"fee,omitempty"`
+	Coinbase bool     `json:"coinbase,omitempty"`
+	Created  []string `json:"created,omitempty"`
+}
+
+func walEntryOf(e journalEntry) walEntry {
+	return walEntry{From: e.from, To: e.to, Amount: e.amount, Outputs: e.outputs, Fee: e.fee, Coinbase: e.coinbase, Created: e.created}
+}
+
+func (w walEntry) journalEntry(height uint64) journalEntry {
+	return journalEntry{height: height, from: w.From, to: w.To, amount: w.Amount, outputs: w.Outputs, fee: w.Fee, coinbase: w.Coinbase, created: w.Created}
+}
+
+// ErrWALGap is returned by Load when the WAL does not continue the snapshot,
+// e.g. because Load fell back to an older .bak; its records are not applied.
+var ErrWALGap = errors.New("ledger WAL does not continue the ledger store")
+
+// logLocked appends rec to the WAL, fsyncing if sync is set. It requires
+// l.mu. A failed write stops logging until the next Save, which captures
+// the state the missing records would have rebuilt.
+func (l *Ledger) logLocked(rec walRecord, sync bool) {
+	l.walSeq++
+	if l.walBroken {
+		return
+	}
+	rec.Seq = l.walSeq
+	line, err := json.Marshal(rec)
+	if err == nil {
+		err = l.walWriteLocked(append(line, '\n'), sync)
+	}
+	if err != nil {
+		l.walBroken = true
+	}
+}
+
+func (l *Ledger) walWriteLocked(line []byte, sync bool) error {
+	if l.wal == nil {
+		if err := os.MkdirAll(filepath.Dir(l.storePath), 0o700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(l.storePath+walSuffix, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return err
+		}
+		l.wal = f
+	}
+	if _, err := l.wal.Write(line); err != nil {
+		return err
+	}
+	if sync {
+		return l.wal.Sync()
+	}
+	return nil
+}
+
+// checkpointWAL truncates the WAL once a snapshot covering records up to
+// seq is on disk, unless newer records have been logged since.
+func (l *Ledger) checkpointWAL(seq uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.walSeq != seq {
+		return
+	}
+	var err error
+	if l.wal != nil {
+		err = l.wal.Truncate(0)
+	} else {
+		err = os.Truncate(l.storePath+walSuffix, 0)
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+	}
+	if err == nil {
+		l.walBroken = false
+	}
+}
+
+// replayWALLocked applies the WAL records after the snapshot's walSeq, up to
+// the first one for a block above maxHeight; that record and the rest are
+// cut from the file, as is a torn final line (a crash mid-write). It returns
+// how many records were applied.
+func (l *Ledger) replayWALLocked(snapSeq, maxHeight uint64) (int, error) {
+	l.walSeq = snapSeq
+	path := l.storePath + walSuffix
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var (
+		recs   []walRecord
+		starts []int // offset of each record's line
+	)
+	good := 0 // bytes of complete, parseable lines
+	for rest := raw; len(rest) > 0; {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break // torn
+		}
+		var rec walRecord
+		if err := json.Unmarshal(rest[:i], &rec); err != nil {
+			if i+1 < len(rest) {
+				return 0, fmt.Errorf("ledger WAL line %d: %w", len(recs)+1, err)
+			}
+			break // torn
+		}
+		recs = append(recs, rec)
+		starts = append(starts, good)
+		good += i + 1
+		rest = rest[i+1:]
+	}
+	if good < len(raw) {
+		if err := os.Truncate(path, int64(good)); err != nil {
+			return 0, err
+		}
+	}
+
+	n := 0
+	for i, rec := range recs {
+		if rec.Seq <= snapSeq {
+			continue
+		}
+		if (rec.Op == walApply || rec.Op == walHeight) && rec.Height > maxHeight {
+			return n, os.Truncate(path, int64(starts[i]))
+		}
+		if rec.Seq != l.walSeq+1 {
+			err = fmt.Errorf("%w: record %d follows %d", ErrWALGap, rec.Seq, l.walSeq)
+		} else if err = l.replayLocked(rec); err != nil {
+			err = fmt.Errorf("ledger WAL record %d (%s): %w", rec.Seq, rec.Op, err)
+		}
+		if err != nil {
+			// Keep what replayed; log nothing more until a Save snapshots it.
+			l.walSeq = recs[len(recs)-1].Seq
+			l.walBroken = true
+			return n, err
+		}
+		l.walSeq = rec.Seq
+		n++
+	}
+	return n, nil
+}
+
+func (l *Ledger) replayLocked(rec walRecord) error {
+	switch rec.Op {
+	case walApply:
+		if len(rec.Entries) != 1 {
+			return errors.New("want one entry")
+		}
+		return l.applyEntryLocked(rec.Entries[0].journalEntry(rec.Height))
+	case walRevert:
+		for i := len(rec.Entries) - 1; i >= 0; i-- {
+			e := rec.Entries[i].journalEntry(0)
+			if err := l.revertEntryLocked(e); err != nil {
+				return err
+			}
+			l.dropCreatedLocked(e)
+		}
+		if l.height > rec.Height {
+			l.height = rec.Height
+		}
+		return nil
+	case walHeight:
+		l.height, l.heightKnown = rec.Height, true
+		return nil
+	case walCredit:
+		return l.creditLocked(rec.Addr, rec.Amount)
+	case walStake:
+		return l.stakeLocked(rec.Addr, rec.Amount)
+	case walUnstake:
+		return l.unstakeLocked(rec.Addr, rec.Amount)
+	case walGenesis:
+		return l.applyGenesisLocked(rec.Genesis, rec.Allocs)
+	}
+	return fmt.Errorf("unknown op %q", rec.Op)
+}
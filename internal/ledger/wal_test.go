@@ -0,0 +1,160 @@
+package ledger
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// crashAndLoad reopens the ledger at path as after a crash: whatever l did
+// not Save is only in the WAL.
+func crashAndLoad(t *testing.T, path string, maxHeight uint64) *Ledger {
+	t.Helper()
+	l := New(path)
+	if err := l.LoadUpTo(maxHeight); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return l
+}
+
+func TestWALReplaysOntoOlderSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	l := New(path)
+	if err := l.FaucetCredit("alice", 10_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything from here on is newer than the snapshot.
+	if err := l.ApplyConfirmedTxAt(1, "alice", "bob", 1_000, 10); err != nil {
+		t.Fatal(err)
+	}
+	l.SetAppliedHeight(1)
+	if err := l.ApplyConfirmedMultiTxAt(2, "alice", []Output{{To: "carol", Amount: 300}}, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ApplyCoinbaseAt(2, "miner", 13); err != nil {
+		t.Fatal(err)
+	}
+	l.SetAppliedHeight(2)
+	if err := l.Stake("bob", 400); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.FaucetCredit("dave", 50); err != nil {
+		t.Fatal(err)
+	}
+	wantBal, wantStaked := balancesOf(l)
+
+	got := crashAndLoad(t, path, ^uint64(0))
+	gotBal, gotStaked := balancesOf(got)
+	if !maps.Equal(gotBal, wantBal) {
+		t.Errorf("balances = %v, want %v", gotBal, wantBal)
+	}
+	if !maps.Equal(gotStaked, wantStaked) {
+		t.Errorf("staked = %v, want %v", gotStaked, wantStaked)
+	}
+	if h, ok := got.AppliedHeight(); !ok || h != 2 {
+		t.Errorf("applied height = %d, %v; want 2", h, ok)
+	}
+
+	// A Save that captures the replay truncates the WAL.
+	if err := got.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path + walSuffix); err != nil || fi.Size() != 0 {
+		t.Errorf("WAL after save: %v, %v", fi, err)
+	}
+}
+
+func TestWALReplayIncludesReverts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	l := New(path)
+	if err := l.FaucetCredit("alice", 10_000); err != nil {
+		t.Fatal(err)
+	}
+	l.ResetJournal(0)
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ApplyConfirmedTxAt(1, "alice", "bob", 1_000, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ApplyConfirmedTxAt(2, "alice", "carol", 500, 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.RevertTo(1); err != nil {
+		t.Fatal(err)
+	}
+	wantBal, _ := balancesOf(l)
+
+	gotBal, _ := balancesOf(crashAndLoad(t, path, ^uint64(0)))
+	if !maps.Equal(gotBal, wantBal) {
+		t.Errorf("balances = %v, want %v", gotBal, wantBal)
+	}
+}
+
+func TestWALTornFinalLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	l := New(path)
+	if err := l.FaucetCredit("alice", 10_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ApplyConfirmedTxAt(1, "alice", "bob", 1_000, 10); err != nil {
+		t.Fatal(err)
+	}
+	wantBal, _ := balancesOf(l)
+
+	// A crash in the middle of appending the next record.
+	f, err := os.OpenFile(path+walSuffix, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"seq":3,"op":"cre`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	got := crashAndLoad(t, path, ^uint64(0))
+	if gotBal, _ := balancesOf(got); !maps.Equal(gotBal, wantBal) {
+		t.Errorf("balances = %v, want %v", gotBal, wantBal)
+	}
+	// The torn line is cut, so the next record starts on a fresh line.
+	if err := got.FaucetCredit("carol", 5); err != nil {
+		t.Fatal(err)
+	}
+	if got := crashAndLoad(t, path, ^uint64(0)).ConfirmedBalance("carol"); got != 5 {
+		t.Errorf("carol = %d after a second replay, want 5", got)
+	}
+}
+
+func TestWALLoadUpToDropsBlocksAbove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	l := New(path)
+	if err := l.FaucetCredit("alice", 10_000); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ApplyConfirmedTxAt(1, "alice", "bob", 1_000, 10); err != nil {
+		t.Fatal(err)
+	}
+	l.SetAppliedHeight(1)
+	wantBal, _ := balancesOf(l)
+	if err := l.ApplyConfirmedTxAt(2, "alice", "bob", 2_000, 10); err != nil {
+		t.Fatal(err)
+	}
+	l.SetAppliedHeight(2)
+
+	// The block store only got as far as height 1.
+	got := crashAndLoad(t, path, 1)
+	if gotBal, _ := balancesOf(got); !maps.Equal(gotBal, wantBal) {
+		t.Errorf("balances = %v, want %v", gotBal, wantBal)
+	}
+	if h, _ := got.AppliedHeight(); h != 1 {
+		t.Errorf("applied height = %d, want 1", h)
+	}
+}
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
 	"strings"
@@ -9,31 +10,58 @@ import (
 type Config struct {
 	Level  string // debug|info|warn|error
 	Format string // json|text
+
+	// LevelVar, if set, is initialized from Level and installed as the
+	// handler's level so it can be changed at runtime.
+	LevelVar *slog.LevelVar
 }
 
 func New(cfg Config) *slog.Logger {
-	level := parseLevel(cfg.Level)
+	lv := cfg.LevelVar
+	if lv == nil {
+		lv = new(slog.LevelVar)
+	}
+	lv.Set(parseLevel(cfg.Level))
+
+	opts := &slog.HandlerOptions{Level: lv}
 
 	var handler slog.Handler
 	switch strings.ToLower(strings.TrimSpace(cfg.Format)) {
 	case "text":
-		handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+		handler = slog.NewTextHandler(os.Stdout, opts)
 	default:
-		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+		handler = slog.NewJSONHandler(os.Stdout, opts)
 	}
 
 	return slog.New(handler)
 }
 
-func parseLevel(s string) slog.Level {
+// ParseLevel is the strict form of level parsing used for runtime changes:
+// unknown names are an error rather than falling back to info.
+func ParseLevel(s string) (slog.Level, error) {
 	switch strings.ToLower(strings.TrimSpace(s)) {
 	case "debug":
-		return slog.LevelDebug
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
 	case "warn", "warning":
-		return slog.LevelWarn
+		return slog.LevelWarn, nil
 	case "error":
-		return slog.LevelError
+		return slog.LevelError, nil
 	default:
+		return 0, fmt.Errorf("unknown log level %q (want debug|info|warn|error)", s)
+	}
+}
+
+// LevelName returns the lowercase name accepted by ParseLevel.
+func LevelName(l slog.Level) string {
+	return strings.ToLower(l.String())
+}
+
+func parseLevel(s string) slog.Level {
+	l, err := ParseLevel(s)
+	if err != nil {
 		return slog.LevelInfo
 	}
+	return l
 }
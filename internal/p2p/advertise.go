@@ -0,0 +1,176 @@
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+)
+
+// advertisedAddr validates cfg.ExternalAddr and decides whether it is worth
+// sharing. It returns "" (and no error) when the address is empty or cannot
+// be dialed by remote peers, e.g. an unspecified or multicast IP.
+func advertisedAddr(external string) (string, error) {
+	if external == "" {
+		return "", nil
+	}
+	host, port, err := net.SplitHostPort(external)
+	if err != nil {
+		return "", fmt.Errorf("invalid ExternalAddr: %w", err)
+	}
+	if host == "" {
+		return "", errors.New("invalid ExternalAddr: missing host")
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return "", fmt.Errorf("invalid ExternalAddr port %q", port)
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsUnspecified() || ip.IsMulticast()) {
+		return "", nil
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// peerAdvertisedAddr checks the listen address a peer sent in its HELLO.
+// It must be a host:port with a usable port and an IP or host name that
+// remote nodes could dial; loopback, private and link-local IPs (and
+// "localhost") are only accepted with Config.AllowPrivateAddrs.
+func (n *Node) peerAdvertisedAddr(addr string) (string, bool) {
+	addr = sanitizeHelloString(addr)
+	if addr == "" || len(addr) > maxPeerAddrLen {
+		return "", false
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return "", false
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return "", false
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		if strings.ContainsAny(host, " /@") {
+			return "", false
+		}
+		if strings.EqualFold(host, "localhost") && !n.cfg.AllowPrivateAddrs {
+			return "", false
+		}
+	case ip.IsUnspecified() || ip.IsMulticast():
+		return "", false
+	case ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast():
+		if !n.cfg.AllowPrivateAddrs {
+			return "", false
+		}
+	}
+	return net.JoinHostPort(host, port), true
+}
+
+// peersForReply builds a MsgPeers list: our own advertised address first (so
+// peers can reach us directly), then a sample of known peers.
+func (n *Node) peersForReply(limit int) []string {
+	if n.advertise == "" {
+		return n.sampleKnownPeers(limit)
+	}
+	out := make([]string, 0, limit)
+	out = append(out, n.advertise)
+	for _, a := range n.sampleKnownPeers(limit) {
+		if len(out) >= limit {
+			break
+		}
+		if a != n.advertise {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// isSelfAddr reports whether addr is one of our own listen/advertised
+// addresses, or one a dial found to lead back to us; such addresses must
+// never be dialed or stored as a peer.
+func (n *Node) isSelfAddr(addr string) bool {
+	if addr == n.advertise || addr == n.cfg.ListenAddr {
+		return true
+	}
+	n.knownMu.RLock()
+	defer n.knownMu.RUnlock()
+	_, ok := n.selfAddrs[addr]
+	return ok
+}
+
+// forgetSelfAddr records that dialing addr reached this node (e.g. our own
+// public address, learned from a peer) and drops it from the known peers.
+func (n *Node) forgetSelfAddr(addr string) {
+	if addr == "" {
+		return
+	}
+	n.knownMu.Lock()
+	_, seen := n.selfAddrs[addr]
+	n.selfAddrs[addr] = struct{}{}
+	delete(n.knownPeers, addr)
+	n.knownMu.Unlock()
+	if !seen {
+		n.log.Info("dialed address is this node; forgetting it", "addr", addr)
+	}
+}
+
+// nonRoutable lists the address blocks that never lead to a peer on the
+// public internet: loopback, private, CGNAT, link-local, documentation,
+// benchmarking, multicast and reserved space.
+var nonRoutable = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// routableAddr reports whether the host:port addr could reach a peer across
+// the public internet. Host names other than "localhost" pass, since they
+// are only resolved when dialed.
+func routableAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return !strings.EqualFold(host, "localhost")
+	}
+	ip = ip.Unmap()
+	for _, p := range nonRoutable {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// gossipAddr reports whether addr may be learned, offered to peers or
+// dialed. On mainnet only routable addresses qualify unless
+// Config.AllowPrivateAddrs is set; other networks take any address.
+func (n *Node) gossipAddr(addr string) bool {
+	if n.cfg.AllowPrivateAddrs || n.cfg.NetworkID != blockchain.MainnetNetworkID {
+		return true
+	}
+	return routableAddr(addr)
+}
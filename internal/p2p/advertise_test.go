@@ -0,0 +1,95 @@
+package p2p
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+)
+
+func TestRoutableAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"8.8.8.8:30303":           true,
+		"[2606:4700::1111]:30303": true,
+		"seed.example.org:30303":  true,
+		"127.0.0.1:30303":         false,
+		"10.1.2.3:30303":          false,
+		"172.20.0.1:30303":        false,
+		"192.168.1.1:30303":       false,
+		"100.64.0.1:30303":        false,
+		"169.254.1.1:30303":       false,
+		"[::1]:30303":             false,
+		"[fd00::1]:30303":         false,
+		"[fe80::1]:30303":         false,
+		"[::ffff:10.0.0.1]:30303": false,
+		"localhost:30303":         false,
+		"8.8.8.8":                 false,
+		":30303":                  false,
+	} {
+		if got := routableAddr(addr); got != want {
+			t.Errorf("routableAddr(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestPrivateAddrsFilteredOnMainnet(t *testing.T) {
+	const (
+		public    = "8.8.8.8:30303"
+		private   = "192.168.1.20:30303"
+		loopback  = "127.0.0.1:30304"
+		bootstrap = "10.0.0.5:30303"
+	)
+	for _, tc := range []struct {
+		name        string
+		networkID   string
+		allow       bool
+		wantPrivate bool
+	}{
+		{"mainnet", blockchain.MainnetNetworkID, false, false},
+		{"mainnet with AllowPrivateAddrs", blockchain.MainnetNetworkID, true, true},
+		{"testnet", "veltaros-testnet", false, true},
+	} {
+		cfg := testConfig(t, t.TempDir())
+		cfg.NetworkID = tc.networkID
+		cfg.AllowPrivateAddrs = tc.allow
+		cfg.BootstrapPeers = []string{bootstrap}
+		n := newTestNode(t, cfg)
+
+		for _, a := range []string{public, private, loopback} {
+			n.learnPeer(a, "learned")
+		}
+		n.knownMu.RLock()
+		_, learnedPrivate := n.knownPeers[private]
+		_, learnedPublic := n.knownPeers[public]
+		n.knownMu.RUnlock()
+		if !learnedPublic {
+			t.Errorf("%s: public address not learned", tc.name)
+		}
+		if learnedPrivate != tc.wantPrivate {
+			t.Errorf("%s: private address learned = %v, want %v", tc.name, learnedPrivate, tc.wantPrivate)
+		}
+
+		// Entries already known, e.g. from a peer store written before the
+		// filter, are not offered to peers either.
+		n.knownMu.Lock()
+		n.addKnownLocked(StoredPeer{Addr: loopback, Source: "learned"})
+		n.knownMu.Unlock()
+		offered := n.sampleKnownPeers(64)
+		if !slices.Contains(offered, public) {
+			t.Errorf("%s: public address not offered: %v", tc.name, offered)
+		}
+		if got := slices.Contains(offered, loopback); got != tc.wantPrivate {
+			t.Errorf("%s: loopback offered = %v, want %v", tc.name, got, tc.wantPrivate)
+		}
+
+		// A bootstrap peer is the operator's choice and is dialed anyway.
+		dial := n.pickDialCandidates(64)
+		if !slices.Contains(dial, bootstrap) {
+			t.Errorf("%s: private bootstrap peer not dialed: %v", tc.name, dial)
+		}
+		if got := slices.Contains(dial, loopback); got != tc.wantPrivate {
+			t.Errorf("%s: loopback dialed = %v, want %v", tc.name, got, tc.wantPrivate)
+		}
+		n.Close()
+	}
+}
@@ -1,30 +1,52 @@
 package p2p
 
 import (
-	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/clock"
+	"github.com/VeltarosLabs/Veltaros/internal/storage"
 )
 
+// BanEntry is one banned address. A Permanent ban ignores Until; otherwise
+// the ban lapses at Until (a zero Until is not a ban).
 type BanEntry struct {
 	Addr      string    `json:"addr"`
 	Until     time.Time `json:"until"`
+	Permanent bool      `json:"permanent,omitempty"`
 	Reason    string    `json:"reason"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+func (e BanEntry) activeAt(now time.Time) bool {
+	return e.Permanent || (!e.Until.IsZero() && e.Until.After(now))
+}
+
+// outlasts reports whether e bans for longer than cur.
+func (e BanEntry) outlasts(cur BanEntry) bool {
+	if cur.Permanent {
+		return false
+	}
+	return e.Permanent || e.Until.After(cur.Until)
+}
+
+// banlistFormat versions the banlist file ([]BanEntry).
+var banlistFormat = storage.Format{Name: "banlist", Version: 1}
+
 type Banlist struct {
 	mu    sync.RWMutex
 	path  string
+	clock clock.Clock
 	items map[string]BanEntry
 }
 
-func NewBanlist(path string) *Banlist {
+func NewBanlist(path string, opts ...Option) *Banlist {
 	return &Banlist{
 		path:  filepath.Clean(path),
+		clock: applyOptions(opts).clock,
 		items: make(map[string]BanEntry),
 	}
 }
@@ -42,33 +64,85 @@ func (b *Banlist) Load() error {
 	}
 
 	var entries []BanEntry
-	if err := json.Unmarshal(raw, &entries); err != nil {
+	if err := banlistFormat.Decode(raw, &entries); err != nil {
 		return err
 	}
 
 	b.items = make(map[string]BanEntry, len(entries))
-	now := time.Now().UTC()
+	now := b.clock.Now()
 	for _, e := range entries {
 		if e.Addr == "" {
 			continue
 		}
 		// Drop expired on load
-		if !e.Until.IsZero() && e.Until.After(now) {
+		if e.activeAt(now) {
 			b.items[e.Addr] = e
 		}
 	}
 	return nil
 }
 
+// ReloadResult summarizes how a Reload merged on-disk entries into memory.
+type ReloadResult struct {
+	Added     []string `json:"added"`
+	Extended  []string `json:"extended"`
+	Unchanged int      `json:"unchanged"`
+	Expired   int      `json:"expired"`
+}
+
+// Reload re-reads the banlist file and merges it into memory. Active in-memory
+// bans are kept; a file entry only replaces one if it bans for longer (a
+// permanent ban outlasts any other).
+// A missing or malformed file leaves the current state untouched.
+func (b *Banlist) Reload() (ReloadResult, error) {
+	res := ReloadResult{Added: []string{}, Extended: []string{}}
+
+	raw, err := os.ReadFile(b.path)
+	if err != nil {
+		return res, err
+	}
+
+	var entries []BanEntry
+	if err := banlistFormat.Decode(raw, &entries); err != nil {
+		return res, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	for _, e := range entries {
+		if e.Addr == "" {
+			continue
+		}
+		if !e.activeAt(now) {
+			res.Expired++
+			continue
+		}
+		cur, ok := b.items[e.Addr]
+		switch {
+		case !ok || !cur.activeAt(now):
+			b.items[e.Addr] = e
+			res.Added = append(res.Added, e.Addr)
+		case e.outlasts(cur):
+			b.items[e.Addr] = e
+			res.Extended = append(res.Extended, e.Addr)
+		default:
+			res.Unchanged++
+		}
+	}
+	return res, nil
+}
+
 func (b *Banlist) Save() error {
 	b.mu.RLock()
 	entries := make([]BanEntry, 0, len(b.items))
-	now := time.Now().UTC()
+	now := b.clock.Now()
 	for _, e := range b.items {
 		if e.Addr == "" {
 			continue
 		}
-		if e.Until.IsZero() || !e.Until.After(now) {
+		if !e.activeAt(now) {
 			continue
 		}
 		entries = append(entries, e)
@@ -79,21 +153,7 @@ func (b *Banlist) Save() error {
 		return err
 	}
 
-	tmp := b.path + ".tmp"
-	data, err := json.MarshalIndent(entries, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	if err := os.WriteFile(tmp, data, 0o600); err != nil {
-		return err
-	}
-	if err := os.Rename(tmp, b.path); err != nil {
-		_ = os.Remove(tmp)
-		return err
-	}
-	_ = os.Chmod(b.path, 0o600)
-	return nil
+	return storage.WriteVersioned(b.path, banlistFormat, entries, false)
 }
 
 func (b *Banlist) IsBanned(addr string) (bool, BanEntry) {
@@ -104,46 +164,66 @@ func (b *Banlist) IsBanned(addr string) (bool, BanEntry) {
 	if !ok {
 		return false, BanEntry{}
 	}
-	if e.Until.IsZero() {
-		return false, BanEntry{}
-	}
-	if time.Now().UTC().After(e.Until) {
+	if !e.activeAt(b.clock.Now()) {
 		return false, BanEntry{}
 	}
 	return true, e
 }
 
+// Ban bans addr for duration. An existing permanent ban is left as is.
 func (b *Banlist) Ban(addr string, duration time.Duration, reason string) {
 	if addr == "" {
 		return
 	}
-	now := time.Now().UTC()
-	entry := BanEntry{
+	now := b.clock.Now()
+	b.put(BanEntry{
 		Addr:      addr,
 		Until:     now.Add(duration),
 		Reason:    reason,
 		UpdatedAt: now,
+	})
+}
+
+// BanPermanent bans addr until it is explicitly unbanned.
+func (b *Banlist) BanPermanent(addr string, reason string) {
+	if addr == "" {
+		return
 	}
+	b.put(BanEntry{
+		Addr:      addr,
+		Permanent: true,
+		Reason:    reason,
+		UpdatedAt: b.clock.Now(),
+	})
+}
 
+func (b *Banlist) put(e BanEntry) {
 	b.mu.Lock()
-	b.items[addr] = entry
-	b.mu.Unlock()
+	defer b.mu.Unlock()
+	if cur, ok := b.items[e.Addr]; ok && cur.Permanent && !e.Permanent {
+		return
+	}
+	b.items[e.Addr] = e
 }
 
-func (b *Banlist) Unban(addr string) {
+// Unban lifts any ban on addr, permanent or not, and reports whether there
+// was one.
+func (b *Banlist) Unban(addr string) bool {
 	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.items[addr]
 	delete(b.items, addr)
-	b.mu.Unlock()
+	return ok
 }
 
 func (b *Banlist) CountActive() int {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	now := time.Now().UTC()
+	now := b.clock.Now()
 	n := 0
 	for _, e := range b.items {
-		if !e.Until.IsZero() && e.Until.After(now) {
+		if e.activeAt(now) {
 			n++
 		}
 	}
@@ -154,10 +234,10 @@ func (b *Banlist) ListActive() []BanEntry {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	now := time.Now().UTC()
+	now := b.clock.Now()
 	out := make([]BanEntry, 0, len(b.items))
 	for _, e := range b.items {
-		if !e.Until.IsZero() && e.Until.After(now) {
+		if e.activeAt(now) {
 			out = append(out, e)
 		}
 	}
@@ -0,0 +1,96 @@
+package p2p
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+)
+
+// BlockAnnounce is the MsgBlock payload (JSON). Height is the sender's height
+// for Block, letting receivers tell a peer that is ahead from one on a fork.
+type BlockAnnounce struct {
+	Height uint64           `json:"height"`
+	Block  blockchain.Block `json:"block"`
+}
+
+// BlockHandler links a block announced by a peer. Returning nil marks it new
+// (it is re-announced); blockchain.ErrKnownBlock and ErrUnknownParent are
+// expected gossip outcomes; any other error penalizes the sender.
+type BlockHandler func(from string, height uint64, b blockchain.Block) error
+
+func (n *Node) SetBlockHandler(h BlockHandler) {
+	n.blockHandlerMu.Lock()
+	n.blockHandler = h
+	n.blockHandlerMu.Unlock()
+}
+
+// BroadcastBlock announces a block at height to all verified peers except
+// exceptAddr. It returns the number of peers written to.
+func (n *Node) BroadcastBlock(height uint64, b blockchain.Block, exceptAddr string) int {
+	payload, err := json.Marshal(BlockAnnounce{Height: height, Block: b})
+	if err != nil {
+		return 0
+	}
+	sent := 0
+	for _, conn := range n.verifiedConns() {
+		if conn.RemoteAddr().String() == exceptAddr {
+			continue
+		}
+		if err := n.send(conn, MsgBlock, payload); err == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// PeerHeight returns the best height a peer has shown us (0 if unknown).
+func (n *Node) PeerHeight(addr string) uint64 {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.peers[addr].bestHeight
+}
+
+func (n *Node) notePeerHeight(conn net.Conn, height uint64) {
+	n.updatePeer(conn, func(p peerConn) peerConn {
+		if height > p.bestHeight {
+			p.bestHeight = height
+		}
+		return p
+	})
+}
+
+func (n *Node) handleBlockFrame(conn net.Conn, payload []byte) {
+	remote := conn.RemoteAddr().String()
+
+	var ann BlockAnnounce
+	if err := json.Unmarshal(payload, &ann); err != nil {
+		n.penalize(remote, 3, "invalid block announce")
+		return
+	}
+	n.notePeerHeight(conn, ann.Height)
+
+	n.blockHandlerMu.RLock()
+	h := n.blockHandler
+	n.blockHandlerMu.RUnlock()
+	if h == nil {
+		return
+	}
+
+	err := h(remote, ann.Height, ann.Block)
+	switch {
+	case err == nil:
+		n.BroadcastBlock(ann.Height, ann.Block, remote)
+	case errors.Is(err, blockchain.ErrKnownBlock):
+	case errors.Is(err, blockchain.ErrUnknownParent):
+		// The peer is ahead of us or on another branch; if it is ahead,
+		// fetch what we are missing.
+		n.log.Debug("block does not extend our tip", "remote", remote, "height", ann.Height)
+		if src := n.source(); src != nil && ann.Height > src.Height() {
+			n.SyncFrom(remote)
+		}
+	default:
+		n.penalize(remote, 3, "invalid block: "+err.Error())
+	}
+}
@@ -0,0 +1,31 @@
+package p2p
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/events"
+)
+
+func (n *Node) publishConnected(key string, p peerConn) {
+	n.cfg.Events.Publish(events.PeerConnected, map[string]any{
+		"remoteAddr":   key,
+		"inbound":      p.inbound,
+		"publicKeyHex": hex.EncodeToString(p.pubKey),
+		"nodeVersion":  p.nodeVersion,
+	})
+}
+
+// publishBan reports a ban of addr for d (0 = permanent).
+func (n *Node) publishBan(addr string, d time.Duration, reason string, byAdmin bool) {
+	ev := map[string]any{
+		"addr":      addr,
+		"permanent": d == 0,
+		"reason":    reason,
+		"admin":     byAdmin,
+	}
+	if d > 0 {
+		ev["until"] = time.Now().UTC().Add(d)
+	}
+	n.cfg.Events.Publish(events.PeerBanned, ev)
+}
@@ -0,0 +1,56 @@
+package p2p
+
+import "sort"
+
+// evictWorstPeerLocked makes room for a new inbound peer at newKey by
+// dropping the verified inbound peer with the highest misbehaviour score
+// above the newcomer's. Peers still in the handshake are never chosen: the
+// newcomer would only take their place as another unverified peer, and the
+// handshake timeout and MaxUnverifiedPeers already bound them. Neither are
+// the ProtectedPeers longest-connected verified inbound peers, nor outbound
+// peers, which fillOutbound would only redial. It reports whether a peer was
+// evicted. n.mu must be held.
+func (n *Node) evictWorstPeerLocked(newKey string) bool {
+	var verified []string
+	for key, p := range n.peers {
+		if p.inbound && p.verified {
+			verified = append(verified, key)
+		}
+	}
+	if len(verified) <= n.cfg.ProtectedPeers {
+		return false
+	}
+	sort.Slice(verified, func(i, j int) bool {
+		return n.peers[verified[i]].connectedAt.Before(n.peers[verified[j]].connectedAt)
+	})
+
+	newScore := n.scorer.Get(newKey)
+	victim := ""
+	for _, key := range verified[n.cfg.ProtectedPeers:] {
+		p := n.peers[key]
+		if p.score <= newScore {
+			continue
+		}
+		if victim == "" || worsePeer(p, n.peers[victim]) {
+			victim = key
+		}
+	}
+	if victim == "" {
+		return false
+	}
+
+	p := n.peers[victim]
+	n.removePeerLocked(victim)
+	_ = p.conn.Close()
+	n.log.Info("peer evicted to make room", "remote", victim, "score", p.score, "for", newKey)
+	return true
+}
+
+// worsePeer orders eviction candidates: higher score first, then the older
+// connection.
+func worsePeer(a, b peerConn) bool {
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	return a.connectedAt.Before(b.connectedAt)
+}
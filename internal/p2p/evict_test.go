@@ -0,0 +1,121 @@
+package p2p
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// addrConn is one end of a pipe reporting a chosen remote address, so peers
+// can be keyed as they are for TCP connections.
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c addrConn) RemoteAddr() net.Addr { return c.remote }
+
+func testConn(t *testing.T, addr string) net.Conn {
+	t.Helper()
+	a, b := net.Pipe()
+	t.Cleanup(func() {
+		_ = a.Close()
+		_ = b.Close()
+	})
+	return addrConn{Conn: a, remote: net.TCPAddrFromAddrPort(netip.MustParseAddrPort(addr))}
+}
+
+// addTestPeer registers an inbound peer at addr directly, with the given
+// state, as if it had connected at the given time.
+func addTestPeer(t *testing.T, n *Node, addr string, verified bool, score int, at time.Time) {
+	t.Helper()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.peers[addr] = peerConn{
+		conn:        testConn(t, addr),
+		inbound:     true,
+		verified:    verified,
+		connectedAt: at,
+		score:       score,
+	}
+	if !verified {
+		n.unverified++
+	}
+}
+
+func dropTestPeer(n *Node, addr string) {
+	n.mu.Lock()
+	n.removePeerLocked(addr)
+	n.mu.Unlock()
+}
+
+func hasPeer(n *Node, addr string) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	_, ok := n.peers[addr]
+	return ok
+}
+
+func TestEvictionOnlyForAdmissiblePeers(t *testing.T) {
+	cfg := testConfig(t, t.TempDir())
+	cfg.MaxPeers = 4
+	cfg.MaxInbound = 4
+	cfg.MaxOutbound = 1
+	cfg.MaxUnverifiedPeers = 2
+	cfg.ProtectedPeers = 1
+	n := newTestNode(t, cfg)
+	defer n.Close()
+
+	const (
+		protected = "8.8.8.1:30303"
+		bad       = "8.8.8.2:30303"
+		hs1       = "8.8.8.3:30303"
+		hs2       = "8.8.8.4:30303"
+		good      = "8.8.8.5:30303"
+	)
+	t0 := time.Now().UTC().Add(-time.Hour)
+	addTestPeer(t, n, protected, true, 9, t0)
+	addTestPeer(t, n, bad, true, 5, t0.Add(time.Minute))
+	addTestPeer(t, n, hs1, false, 0, t0.Add(2*time.Minute))
+	addTestPeer(t, n, hs2, false, 0, t0.Add(3*time.Minute))
+
+	// Unverified slots are full: the newcomer is refused before anyone is
+	// evicted on its behalf.
+	err := n.tryRegisterPeer(testConn(t, "9.9.9.1:40000"), true)
+	if !errors.Is(err, errUnverifiedFull) {
+		t.Fatalf("register with unverified slots full = %v, want errUnverifiedFull", err)
+	}
+	if !hasPeer(n, bad) || n.PeerCount() != 4 {
+		t.Fatal("a peer was evicted for a newcomer that was refused")
+	}
+
+	// With an unverified slot free, the worst unprotected verified peer
+	// makes room; the handshaking peer and the protected one stay.
+	dropTestPeer(n, hs2)
+	addTestPeer(t, n, good, true, 0, t0.Add(4*time.Minute))
+	if err := n.tryRegisterPeer(testConn(t, "9.9.9.2:40000"), true); err != nil {
+		t.Fatalf("register with a worse verified peer present: %v", err)
+	}
+	if hasPeer(n, bad) {
+		t.Error("misbehaving verified peer not evicted")
+	}
+	for _, a := range []string{protected, hs1, good} {
+		if !hasPeer(n, a) {
+			t.Errorf("%s evicted", a)
+		}
+	}
+
+	// No verified peer scores worse than the newcomer: handshaking peers
+	// are not evicted instead.
+	dropTestPeer(n, "9.9.9.2:40000")
+	addTestPeer(t, n, "8.8.8.6:30303", true, 0, t0.Add(5*time.Minute))
+	err = n.tryRegisterPeer(testConn(t, "9.9.9.3:40000"), true)
+	if !errors.Is(err, errPeersFull) {
+		t.Fatalf("register with no worse peer = %v, want errPeersFull", err)
+	}
+	if !hasPeer(n, hs1) {
+		t.Error("handshaking peer evicted for another unverified peer")
+	}
+}
@@ -0,0 +1,315 @@
+package p2p
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+)
+
+// rawPeer drives the wire protocol by hand, speaking a chosen version, so
+// tests can stand in for older or misbehaving nodes.
+type rawPeer struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	priv    ed25519.PrivateKey
+	version uint16
+
+	// nodeHello is the HELLO the node answered with.
+	nodeHello Hello
+}
+
+// dialRaw connects to n and exchanges HELLOs at version.
+func dialRaw(t *testing.T, n *Node, version uint16) *rawPeer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", n.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	p := &rawPeer{conn: conn, br: bufio.NewReader(conn), priv: priv, version: version}
+	h, err := NewHello(n.cfg.NetworkID, priv.Public().(ed25519.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.ProtocolVersion = version
+	payload, err := h.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.write(t, MsgHello, payload)
+
+	f, err := ReadFrame(p.br)
+	if err != nil {
+		t.Fatalf("read node hello: %v", err)
+	}
+	if f.Type != MsgHello {
+		t.Fatalf("node answered with message type %d, want HELLO", f.Type)
+	}
+	if p.nodeHello, err = DecodeHello(f.Payload); err != nil {
+		t.Fatalf("v%d peer cannot decode the node's hello: %v", version, err)
+	}
+	return p
+}
+
+func (p *rawPeer) write(t *testing.T, typ MessageType, payload []byte) {
+	t.Helper()
+	if err := WriteFrame(p.conn, typ, payload); err != nil {
+		t.Fatalf("write message type %d: %v", typ, err)
+	}
+}
+
+// prove runs the challenge exchange: it answers the node's challenge
+// (with solve producing the puzzle nonce, if one is set) and checks the
+// node's answer to its own. It returns the challenge payload the node sent.
+func (p *rawPeer) prove(t *testing.T, networkID string, solve func(c [challengeSize]byte, bits int) []byte) ([]byte, error) {
+	t.Helper()
+	ours, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.write(t, MsgChallenge, ours[:])
+
+	var theirs []byte
+	answered, checked := false, false
+	for !answered || !checked {
+		f, err := ReadFrame(p.br)
+		if err != nil {
+			return theirs, err
+		}
+		switch f.Type {
+		case MsgChallenge:
+			theirs = f.Payload
+			var c [challengeSize]byte
+			copy(c[:], f.Payload)
+			resp, err := SignChallenge(p.priv, networkID, nil, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(f.Payload) > challengeSize && solve != nil {
+				resp = append(resp, solve(c, int(f.Payload[challengeSize]))...)
+			}
+			p.write(t, MsgChallengeResp, resp)
+			answered = true
+		case MsgChallengeResp:
+			if err := VerifyChallengeResp(p.nodeHello.PublicKey, networkID, nil, f.Payload, ours); err != nil {
+				t.Fatalf("node's challenge response: %v", err)
+			}
+			checked = true
+		case MsgGoodbye:
+			return theirs, errors.New("goodbye: " + string(f.Payload))
+		}
+	}
+	return theirs, nil
+}
+
+// waitFor reads frames until one of type typ arrives or d passes.
+func (p *rawPeer) waitFor(typ MessageType, d time.Duration) bool {
+	_ = p.conn.SetReadDeadline(time.Now().Add(d))
+	defer func() { _ = p.conn.SetReadDeadline(time.Time{}) }()
+	for {
+		f, err := ReadFrame(p.br)
+		if err != nil {
+			return false
+		}
+		if f.Type == typ {
+			return true
+		}
+	}
+}
+
+// waitVerified polls until n lists the peer at local as verified and
+// returns its entry.
+func waitVerified(t *testing.T, n *Node, local net.Addr) PeerInfo {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, p := range n.Peers() {
+			if p.RemoteAddr == local.String() && p.Verified {
+				return p
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("peer %s never verified", local)
+	return PeerInfo{}
+}
+
+func TestHandshakeAcrossVersions(t *testing.T) {
+	n := newTestNode(t, testConfig(t, t.TempDir()))
+	if err := n.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	peers := map[uint16]*rawPeer{}
+	for _, v := range []uint16{1, 2} {
+		p := dialRaw(t, n, v)
+		if p.nodeHello.ProtocolVersion != v {
+			t.Errorf("node answered a v%d hello in v%d", v, p.nodeHello.ProtocolVersion)
+		}
+		if _, err := p.prove(t, n.cfg.NetworkID, nil); err != nil {
+			t.Fatalf("v%d challenge exchange: %v", v, err)
+		}
+		if info := waitVerified(t, n, p.conn.LocalAddr()); info.ProtocolVersion != v {
+			t.Errorf("v%d peer recorded at protocol version %d", v, info.ProtocolVersion)
+		}
+		peers[v] = p
+	}
+
+	// MsgTx is v2+: the broadcast reaches the v2 peer only.
+	if sent := n.BroadcastTx([]byte(`{}`), ""); sent != 1 {
+		t.Errorf("BroadcastTx sent to %d peers, want 1", sent)
+	}
+	if !peers[2].waitFor(MsgTx, time.Second) {
+		t.Error("v2 peer did not receive the tx")
+	}
+	if peers[1].waitFor(MsgTx, 200*time.Millisecond) {
+		t.Error("v1 peer received a tx")
+	}
+
+	// Block gossip is v2+ as well.
+	if sent := n.BroadcastBlock(1, blockchain.Block{}, ""); sent != 1 {
+		t.Errorf("BroadcastBlock sent to %d peers, want 1", sent)
+	}
+	if !peers[2].waitFor(MsgBlock, time.Second) {
+		t.Error("v2 peer did not receive the block")
+	}
+	if peers[1].waitFor(MsgBlock, 200*time.Millisecond) {
+		t.Error("v1 peer received a block")
+	}
+
+	// A v1 peer sending MsgTx anyway is penalized, not disconnected.
+	peers[1].write(t, MsgTx, []byte(`{}`))
+	local := peers[1].conn.LocalAddr().String()
+	deadline := time.Now().Add(5 * time.Second)
+	for n.scorer.Get(local) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("v1 peer sending MsgTx was not penalized")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	connected := false
+	for _, p := range n.Peers() {
+		connected = connected || p.RemoteAddr == local
+	}
+	if !connected {
+		t.Error("v1 peer disconnected for sending MsgTx")
+	}
+}
+
+// closedBy reports whether the node closes the connection within d.
+func (p *rawPeer) closedBy(d time.Duration) bool {
+	_ = p.conn.SetReadDeadline(time.Now().Add(d))
+	for {
+		if _, err := ReadFrame(p.br); err != nil {
+			var ne net.Error
+			return !errors.As(err, &ne) || !ne.Timeout()
+		}
+	}
+}
+
+func TestPuzzle(t *testing.T) {
+	var c [challengeSize]byte
+	copy(c[:], "veltaros admission puzzle test!!")
+	const bits = 12
+
+	nonce, err := SolvePuzzle(c, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPuzzle(c, nonce[:], bits); err != nil {
+		t.Errorf("solved puzzle rejected: %v", err)
+	}
+
+	// A nonce meeting fewer bits than asked for is refused.
+	under := underDifficulty(c, bits)
+	if err := VerifyPuzzle(c, under, bits); err == nil {
+		t.Error("under-difficulty nonce accepted")
+	}
+	if err := VerifyPuzzle(c, nonce[:4], bits); err == nil {
+		t.Error("short nonce accepted")
+	}
+	if _, err := SolvePuzzle(c, MaxPuzzleBits+1); err == nil {
+		t.Error("SolvePuzzle above MaxPuzzleBits succeeded")
+	}
+}
+
+// underDifficulty returns a nonce whose hash falls short of bits.
+func underDifficulty(c [challengeSize]byte, bits int) []byte {
+	for i := byte(0); ; i++ {
+		n := []byte{i, 0, 0, 0, 0, 0, 0, 0}
+		if VerifyPuzzle(c, n, bits) != nil {
+			return n
+		}
+	}
+}
+
+func TestHandshakePuzzle(t *testing.T) {
+	const bits = 8
+	cfg := testConfig(t, t.TempDir())
+	cfg.PuzzleBits = bits
+	n := newTestNode(t, cfg)
+	if err := n.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	solve := func(c [challengeSize]byte, bits int) []byte {
+		nonce, err := SolvePuzzle(c, bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return nonce[:]
+	}
+
+	// A v5 peer is set the puzzle and verified once it solves it.
+	p := dialRaw(t, n, ProtocolVersion)
+	chal, err := p.prove(t, cfg.NetworkID, solve)
+	if err != nil {
+		t.Fatalf("solving peer: %v", err)
+	}
+	if len(chal) != challengeSize+1 || chal[challengeSize] != bits {
+		t.Errorf("challenge to a v%d peer = %d bytes, want the %d-bit puzzle appended", ProtocolVersion, len(chal), bits)
+	}
+	waitVerified(t, n, p.conn.LocalAddr())
+
+	// An under-difficulty solution, or none, is refused.
+	for name, answer := range map[string]func([challengeSize]byte, int) []byte{
+		"under difficulty": func(c [challengeSize]byte, bits int) []byte { return underDifficulty(c, bits) },
+		"no solution":      func([challengeSize]byte, int) []byte { return nil },
+	} {
+		p := dialRaw(t, n, ProtocolVersion)
+		if _, err := p.prove(t, cfg.NetworkID, answer); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !p.closedBy(2 * time.Second) {
+			t.Errorf("%s: connection kept open", name)
+		}
+		if n.scorer.Get(p.conn.LocalAddr().String()) == 0 {
+			t.Errorf("%s: peer not penalized", name)
+		}
+	}
+
+	// A v4 peer cannot be set the puzzle: it is told why and turned away
+	// instead of being sent a challenge it would reject as malformed.
+	old := dialRaw(t, n, puzzleMinVersion-1)
+	f, err := ReadFrame(old.br)
+	if err != nil {
+		t.Fatalf("v%d peer: %v", puzzleMinVersion-1, err)
+	}
+	if f.Type != MsgGoodbye || !strings.Contains(string(f.Payload), "admission puzzle") {
+		t.Errorf("v%d peer got message type %d %q, want a goodbye naming the puzzle", puzzleMinVersion-1, f.Type, f.Payload)
+	}
+}
@@ -0,0 +1,102 @@
+package p2p
+
+import (
+	"net"
+	"time"
+)
+
+// idleTimeout is how long the message loop waits for the next frame. Our
+// keepalive checks every PingInterval, so an idle peer is pinged at most
+// 2*PingInterval after its last frame and has PongTimeout to answer: a live
+// peer always sends something within it, and a dead one is caught by the
+// keepalive (and penalized) before the read deadline fires.
+func (n *Node) idleTimeout() time.Duration {
+	return 2*n.cfg.PingInterval + n.cfg.PongTimeout
+}
+
+// keepalive pings conn whenever nothing has been received from it for
+// PingInterval and closes it, with a small penalty, if no MsgPong arrives on
+// pongs within PongTimeout. Any frame from the peer but its own pings
+// counts as activity, so busy connections are never pinged. It returns when
+// done is closed.
+func (n *Node) keepalive(conn net.Conn, pongs <-chan struct{}, done <-chan struct{}) {
+	t := time.NewTicker(n.cfg.PingInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-n.ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		if time.Since(n.lastMsgAt(conn)) < n.cfg.PingInterval {
+			continue
+		}
+		// Drop a pong left over from a ping the peer answered late.
+		select {
+		case <-pongs:
+		default:
+		}
+		if err := n.send(conn, MsgPing, []byte("ping")); err != nil {
+			return
+		}
+
+		select {
+		case <-done:
+			return
+		case <-n.ctx.Done():
+			return
+		case <-pongs:
+		case <-time.After(n.cfg.PongTimeout):
+			n.penalize(conn.RemoteAddr().String(), 1, "keepalive timeout")
+			n.log.Info("peer unresponsive; closing", "remote", conn.RemoteAddr().String(), "pongTimeout", n.cfg.PongTimeout.String())
+			_ = conn.Close()
+			return
+		}
+	}
+}
+
+func (n *Node) lastMsgAt(conn net.Conn) time.Time {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.peers[conn.RemoteAddr().String()].lastMsgAt
+}
+
+// idleReapLoop periodically disconnects peers idle beyond PeerIdleTimeout.
+func (n *Node) idleReapLoop() {
+	ticker := time.NewTicker(max(time.Second, n.cfg.PeerIdleTimeout/4))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+			n.reapIdlePeers()
+		}
+	}
+}
+
+// reapIdlePeers closes verified peers that have sent nothing that counts as
+// activity for PeerIdleTimeout. Unverified peers are left to the handshake
+// deadline. Their handleConn goroutines unregister them.
+func (n *Node) reapIdlePeers() {
+	now := time.Now().UTC()
+
+	n.mu.RLock()
+	var idle []peerConn
+	for _, p := range n.peers {
+		if p.verified && now.Sub(p.lastMsgAt) > n.cfg.PeerIdleTimeout {
+			idle = append(idle, p)
+		}
+	}
+	n.mu.RUnlock()
+
+	for _, p := range idle {
+		n.log.Info("peer idle; disconnecting", "remote", p.conn.RemoteAddr().String(), "idleFor", now.Sub(p.lastMsgAt).Round(time.Second).String(), "peerIdleTimeout", n.cfg.PeerIdleTimeout.String())
+		_ = p.conn.Close()
+	}
+}
@@ -0,0 +1,46 @@
+package p2p
+
+import (
+	"crypto/ed25519"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewRequiresStorePaths(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name  string
+		clear func(*Config)
+	}{
+		{"BanlistPath", func(c *Config) { c.BanlistPath = "" }},
+		{"PeerStorePath", func(c *Config) { c.PeerStorePath = "" }},
+		{"ScoreStorePath", func(c *Config) { c.ScoreStorePath = "" }},
+	} {
+		dir := t.TempDir()
+		cfg := Config{
+			ListenAddr:      "127.0.0.1:0",
+			MaxPeers:        8,
+			NetworkID:       "veltaros-testnet",
+			IdentityPrivKey: priv,
+			BanlistPath:     filepath.Join(dir, "banlist.json"),
+			PeerStorePath:   filepath.Join(dir, "peers.json"),
+			ScoreStorePath:  filepath.Join(dir, "scores.json"),
+		}
+		tc.clear(&cfg)
+		n, err := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if err == nil {
+			_ = n.Close()
+			t.Errorf("New without %s succeeded", tc.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.name) {
+			t.Errorf("New without %s: error %q does not name it", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package p2p
+
+import "github.com/VeltarosLabs/Veltaros/internal/clock"
+
+// Option customizes a Scorer or Banlist at construction.
+type Option func(*options)
+
+type options struct {
+	clock clock.Clock
+}
+
+// WithClock makes time-dependent decisions (score decay, ban expiry) read c
+// instead of the system clock.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) { o.clock = c }
+}
+
+func applyOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.clock = clock.OrReal(o.clock)
+	return o
+}
@@ -3,15 +3,22 @@ package p2p
 import (
 	"bufio"
 	"context"
+	"crypto/ecdh"
 	"crypto/ed25519"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"math/rand"
 	"net"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/VeltarosLabs/Veltaros/internal/clock"
 	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
+	"github.com/VeltarosLabs/Veltaros/internal/events"
 )
 
 type Config struct {
@@ -22,12 +29,90 @@ type Config struct {
 	DialTimeout      time.Duration
 	HandshakeTimeout time.Duration
 
+	// MaxPendingHandshakes caps connections that are still in the
+	// unauthenticated HELLO/challenge phase, independent of MaxPeers.
+	MaxPendingHandshakes int
+
+	// MaxUnverifiedPeers caps the MaxPeers slots held by peers that have not
+	// completed the challenge handshake, so MaxPeers-MaxUnverifiedPeers are
+	// always left for verified peers. 0 means MaxPeers/4 (at least 1).
+	MaxUnverifiedPeers int
+
+	// MaxOutbound caps dialed peers and is the target fillOutbound dials
+	// towards; 0 means max(4, MaxPeers/3), capped at MaxPeers. MaxInbound
+	// caps accepted peers; 0 means the MaxPeers slots MaxOutbound leaves
+	// free, or MaxPeers if it leaves none. MaxPeers still bounds the total.
+	MaxInbound  int
+	MaxOutbound int
+
+	// ProtectedPeers is how many of the longest-connected verified inbound
+	// peers are never evicted to make room for a new inbound peer; 0 means
+	// 8, capped at MaxInbound.
+	ProtectedPeers int
+
+	// TxGossipBudget is how many relayed txs a single peer may send per
+	// TxGossipInterval before being penalized.
+	TxGossipBudget   int
+	TxGossipInterval time.Duration
+
+	// PingInterval is how long a connection may stay silent before we ping
+	// it; a peer that does not answer within PongTimeout is dropped. The
+	// read deadline on established connections is 2*PingInterval +
+	// PongTimeout.
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+
+	// PeerIdleTimeout disconnects a verified peer that has sent nothing but
+	// its own pings for this long. Pongs to our keepalive count, so quiet
+	// but responsive peers stay. 0 means 2m; it must leave room for a
+	// keepalive round (2*PingInterval + PongTimeout).
+	PeerIdleTimeout time.Duration
+
+	// MaxDialAttempts is how many dials in a row may fail before a peer is
+	// dropped from the known peers; 0 means 10. MaxKnownPeers caps the known
+	// peers, evicting the least recently seen to make room; 0 means 1024.
+	// Bootstrap peers and ones added by ConnectPeer are never dropped or
+	// evicted.
+	MaxDialAttempts int
+	MaxKnownPeers   int
+
+	// PuzzleBits makes inbound peers solve a proof-of-work puzzle of this
+	// many bits during the challenge handshake before they are verified,
+	// raising the cost of connection floods; 0 disables it. At most
+	// MaxPuzzleBits. While it is set, inbound peers older than protocol v5
+	// cannot be set the puzzle and are turned away.
+	PuzzleBits int
+
+	// AllowPrivateAddrs accepts loopback, private and link-local addresses
+	// advertised in peers' HELLOs and, on mainnet, lets non-routable
+	// addresses into peer gossip and dialing. Only useful for local test
+	// networks.
+	AllowPrivateAddrs bool
+
 	NetworkID       string
 	IdentityPrivKey ed25519.PrivateKey
 
 	BanlistPath    string
 	PeerStorePath  string
 	ScoreStorePath string
+
+	// KeepStoreBackups keeps a .bak of the peer store before each save.
+	KeepStoreBackups bool
+
+	// Encrypt offers an encrypted session (X25519 + AES-GCM) in the HELLO;
+	// it is used with every peer that offers one too. MinEncryption is
+	// EncryptionNone ("" means the same) to keep plaintext peers as a
+	// fallback, or EncryptionAEAD to reject them; it requires Encrypt.
+	Encrypt       bool
+	MinEncryption string
+
+	// Events, if set, receives events.PeerConnected once a peer completes
+	// the handshake and events.PeerBanned for every ban.
+	Events *events.Bus
+
+	// Clock, if set, replaces the system clock for score decay, ban expiry,
+	// the HELLO skew check and known-peer sampling.
+	Clock clock.Clock
 }
 
 type PeerInfo struct {
@@ -38,6 +123,10 @@ type PeerInfo struct {
 	NodeVersion  string `json:"nodeVersion"`
 	Verified     bool   `json:"verified"`
 	Score        int    `json:"score"`
+	Height       uint64 `json:"height"`
+
+	ProtocolVersion uint16 `json:"protocolVersion"`
+	Encrypted       bool   `json:"encrypted"`
 }
 
 type Node struct {
@@ -46,20 +135,46 @@ type Node struct {
 	ln     net.Listener
 	ctx    context.Context
 	cancel context.CancelFunc
+	wg     sync.WaitGroup // loops and peer handlers; Close waits for them
 
-	mu     sync.RWMutex
-	closed bool
-	peers  map[string]peerConn
+	mu         sync.RWMutex
+	closed     bool
+	peers      map[string]peerConn
+	unverified int // peers not yet verified; guarded by mu
+
+	// connectedIdentities maps each verified peer's public key (hex) to its
+	// peers key, so one identity holds at most one slot; guarded by mu.
+	connectedIdentities map[string]string
 
 	knownMu    sync.RWMutex
 	knownPeers map[string]StoredPeer
+	selfAddrs  map[string]struct{} // addresses that turned out to be us; guarded by knownMu
+	bootstrap  map[string]struct{} // cfg.BootstrapPeers; read-only after New
 
 	backoffMu sync.Mutex
 	backoff   map[string]dialBackoff
 
+	handshakeSem chan struct{}
+
+	// advertise is the validated ExternalAddr shared in MsgPeers ("" if none).
+	advertise string
+
+	txHandlerMu sync.RWMutex
+	txHandler   TxHandler
+
+	blockHandlerMu sync.RWMutex
+	blockHandler   BlockHandler
+
+	syncMu      sync.Mutex
+	blockSource BlockSource
+	syncPeer    string // peer we have an outstanding MsgGetBlocks with
+	syncSince   time.Time
+
 	banlist   *Banlist
 	peerStore *PeerStore
 	scorer    *Scorer
+
+	stats nodeStats
 }
 
 type peerConn struct {
@@ -70,11 +185,21 @@ type peerConn struct {
 	pubKey      ed25519.PublicKey
 	nodeVersion string
 
+	// protoVersion is the version negotiated in the HELLO exchange: the
+	// peer's, since ValidateHello only admits versions up to ours. 0 until
+	// the HELLO has been read.
+	protoVersion uint16
+	encrypted    bool
+
 	verified bool
 	score    int
 
 	lastMsgAt time.Time
-	lim       limiter
+	lim       *limiter
+	txLim     *limiter
+
+	bestHeight uint64 // highest chain height the peer has announced
+	wmu        *sync.Mutex
 }
 
 type limiter struct {
@@ -86,8 +211,8 @@ type limiter struct {
 	costPerMsg float64
 }
 
-func newLimiter() limiter {
-	return limiter{
+func newLimiter() *limiter {
+	return &limiter{
 		tokens:     30,
 		last:       time.Now().UTC(),
 		rate:       1.0,  // tokens/sec
@@ -138,6 +263,71 @@ func New(cfg Config, log *slog.Logger) (*Node, error) {
 	if cfg.HandshakeTimeout <= 0 {
 		cfg.HandshakeTimeout = 7 * time.Second
 	}
+	if cfg.MaxPendingHandshakes <= 0 {
+		cfg.MaxPendingHandshakes = 32
+	}
+	if cfg.MaxPendingHandshakes > 4096 {
+		return nil, errors.New("MaxPendingHandshakes out of range")
+	}
+	if cfg.MaxUnverifiedPeers <= 0 {
+		cfg.MaxUnverifiedPeers = max(1, cfg.MaxPeers/4)
+	}
+	if cfg.MaxUnverifiedPeers > cfg.MaxPeers {
+		return nil, errors.New("MaxUnverifiedPeers exceeds MaxPeers")
+	}
+	if cfg.MaxOutbound <= 0 {
+		cfg.MaxOutbound = min(max(4, cfg.MaxPeers/3), cfg.MaxPeers)
+	}
+	if cfg.MaxInbound <= 0 {
+		cfg.MaxInbound = cfg.MaxPeers - cfg.MaxOutbound
+		if cfg.MaxInbound == 0 {
+			cfg.MaxInbound = cfg.MaxPeers
+		}
+	}
+	if cfg.MaxInbound > cfg.MaxPeers || cfg.MaxOutbound > cfg.MaxPeers {
+		return nil, errors.New("MaxInbound and MaxOutbound must not exceed MaxPeers")
+	}
+	if cfg.ProtectedPeers <= 0 {
+		cfg.ProtectedPeers = min(8, cfg.MaxInbound)
+	}
+	if cfg.PuzzleBits < 0 || cfg.PuzzleBits > MaxPuzzleBits {
+		return nil, fmt.Errorf("PuzzleBits out of range (0..%d)", MaxPuzzleBits)
+	}
+	if cfg.MaxDialAttempts <= 0 {
+		cfg.MaxDialAttempts = 10
+	}
+	if cfg.MaxKnownPeers <= 0 {
+		cfg.MaxKnownPeers = 1024
+	}
+	if cfg.TxGossipBudget <= 0 {
+		cfg.TxGossipBudget = 100
+	}
+	if cfg.TxGossipInterval <= 0 {
+		cfg.TxGossipInterval = 10 * time.Second
+	}
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = 15 * time.Second
+	}
+	if cfg.PongTimeout <= 0 {
+		cfg.PongTimeout = 10 * time.Second
+	}
+	switch cfg.MinEncryption {
+	case "":
+		cfg.MinEncryption = EncryptionNone
+	case EncryptionNone:
+	case EncryptionAEAD:
+		if !cfg.Encrypt {
+			return nil, errors.New("MinEncryption aead requires Encrypt")
+		}
+	default:
+		return nil, fmt.Errorf("unknown MinEncryption %q", cfg.MinEncryption)
+	}
+	if cfg.PeerIdleTimeout <= 0 {
+		cfg.PeerIdleTimeout = 2 * time.Minute
+	}
+	if cfg.PeerIdleTimeout < 2*cfg.PingInterval+cfg.PongTimeout {
+		return nil, errors.New("PeerIdleTimeout must be at least 2*PingInterval + PongTimeout")
+	}
 	if cfg.NetworkID == "" {
 		return nil, errors.New("NetworkID is required")
 	}
@@ -153,29 +343,47 @@ func New(cfg Config, log *slog.Logger) (*Node, error) {
 	if cfg.ScoreStorePath == "" {
 		return nil, errors.New("ScoreStorePath is required")
 	}
+	advertise, err := advertisedAddr(cfg.ExternalAddr)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ExternalAddr != "" && advertise == "" {
+		log.Warn("external address is not reachable by peers; not advertising it", "external", cfg.ExternalAddr)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	n := &Node{
-		cfg:        cfg,
-		log:        log.With("component", "p2p"),
-		ctx:        ctx,
-		cancel:     cancel,
-		peers:      make(map[string]peerConn),
-		knownPeers: make(map[string]StoredPeer),
-		backoff:    make(map[string]dialBackoff),
-		banlist:    NewBanlist(cfg.BanlistPath),
-		peerStore:  NewPeerStore(cfg.PeerStorePath),
+		cfg:          cfg,
+		log:          log.With("component", "p2p"),
+		ctx:          ctx,
+		cancel:       cancel,
+		peers:        make(map[string]peerConn),
+		knownPeers:   make(map[string]StoredPeer),
+		selfAddrs:    make(map[string]struct{}),
+		bootstrap:    make(map[string]struct{}),
+		backoff:      make(map[string]dialBackoff),
+		handshakeSem: make(chan struct{}, cfg.MaxPendingHandshakes),
+		advertise:    advertise,
+		banlist:      NewBanlist(cfg.BanlistPath, WithClock(cfg.Clock)),
+		peerStore:    NewPeerStore(cfg.PeerStorePath),
 		scorer: NewScorer(ScoreConfig{
 			DecayInterval: 1 * time.Minute,
 			DecayAmount:   1,
 			BanThreshold:  10,
 			BanDuration:   30 * time.Minute,
-		}),
+		}, WithClock(cfg.Clock)),
+
+		connectedIdentities: make(map[string]string),
 	}
+	n.peerStore.KeepBackup = cfg.KeepStoreBackups
 
-	_ = n.banlist.Load()
-	_ = n.scorer.Load(cfg.ScoreStorePath)
+	if err := n.banlist.Load(); err != nil {
+		n.log.Warn("banlist load failed", "path", cfg.BanlistPath, "err", err)
+	}
+	if err := n.scorer.Load(cfg.ScoreStorePath); err != nil {
+		n.log.Warn("score store load failed", "path", cfg.ScoreStorePath, "err", err)
+	}
 
 	if peers, err := n.peerStore.Load(); err == nil {
 		for _, p := range peers {
@@ -190,6 +398,10 @@ func New(cfg Config, log *slog.Logger) (*Node, error) {
 			continue
 		}
 		n.knownPeers[a] = StoredPeer{Addr: a, SeenAt: now, Source: "bootstrap"}
+		n.bootstrap[a] = struct{}{}
+	}
+	if over := len(n.knownPeers) - cfg.MaxKnownPeers; over > 0 {
+		n.evictKnownLocked(over)
 	}
 
 	return n, nil
@@ -205,14 +417,24 @@ func (n *Node) Start() error {
 	n.log.Info("p2p listening",
 		"addr", n.cfg.ListenAddr,
 		"external", n.cfg.ExternalAddr,
+		"advertised", n.advertise != "",
 		"maxPeers", n.cfg.MaxPeers,
+		"maxInbound", n.cfg.MaxInbound,
+		"maxOutbound", n.cfg.MaxOutbound,
+		"protectedPeers", n.cfg.ProtectedPeers,
+		"encrypt", n.cfg.Encrypt,
+		"minEncryption", n.cfg.MinEncryption,
+		"maxPendingHandshakes", n.cfg.MaxPendingHandshakes,
+		"maxUnverifiedPeers", n.cfg.MaxUnverifiedPeers,
+		"puzzleBits", n.cfg.PuzzleBits,
 		"networkID", n.cfg.NetworkID,
 	)
 
-	go n.acceptLoop()
-	go n.dialLoop()
-	go n.discoveryLoop()
-	go n.persistLoop()
+	n.wg.Go(n.acceptLoop)
+	n.wg.Go(n.dialLoop)
+	n.wg.Go(n.discoveryLoop)
+	n.wg.Go(n.persistLoop)
+	n.wg.Go(n.idleReapLoop)
 
 	return nil
 }
@@ -226,6 +448,7 @@ func (n *Node) Close() error {
 	n.closed = true
 	n.mu.Unlock()
 
+	n.sayGoodbye("shutting down")
 	n.cancel()
 
 	if n.ln != nil {
@@ -237,14 +460,48 @@ func (n *Node) Close() error {
 		_ = p.conn.Close()
 		delete(n.peers, k)
 	}
+	n.unverified = 0
+	clear(n.connectedIdentities)
 	n.mu.Unlock()
 
+	// Peer handlers may still be delivering a tx or block to the node; let
+	// them and the periodic persist finish so this is the last write.
+	n.wg.Wait()
 	_ = n.persistOnce()
 
 	n.log.Info("p2p stopped")
 	return nil
 }
 
+// goodbyeTimeout bounds how long Close spends telling peers it is leaving.
+const goodbyeTimeout = 500 * time.Millisecond
+
+// sayGoodbye sends MsgGoodbye to every verified peer at once, so they drop
+// us cleanly instead of seeing a reset or waiting out a timeout. It returns
+// after goodbyeTimeout at the latest; a send still blocked then fails when
+// Close closes its connection.
+func (n *Node) sayGoodbye(reason string) {
+	conns := n.verifiedConns()
+	if len(conns) == 0 {
+		return
+	}
+	deadline := time.Now().Add(goodbyeTimeout)
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Go(func() { _ = n.sendBy(conn, MsgGoodbye, []byte(reason), deadline) })
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Until(deadline)):
+	}
+	n.log.Debug("sent goodbye to peers", "peers", len(conns))
+}
+
 func (n *Node) PeerCount() int {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
@@ -261,6 +518,158 @@ func (n *Node) BanCount() int {
 	return n.banlist.CountActive()
 }
 
+// BanEntries lists the active bans, permanent ones included, by address.
+func (n *Node) BanEntries() []BanEntry {
+	out := n.banlist.ListActive()
+	sort.Slice(out, func(i, j int) bool { return out[i].Addr < out[j].Addr })
+	return out
+}
+
+// ScoreSnapshots lists peers with a positive (decayed) score, highest first.
+func (n *Node) ScoreSnapshots() []ScoreSnapshot {
+	out := n.scorer.Snapshot()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Addr < out[j].Addr
+	})
+	return out
+}
+
+// ReloadBanlist merges the on-disk banlist into memory and drops any
+// connected peers that are now banned.
+func (n *Node) ReloadBanlist() (ReloadResult, error) {
+	res, err := n.banlist.Reload()
+	if err != nil {
+		n.log.Warn("banlist reload failed; keeping current state", "err", err)
+		return res, err
+	}
+
+	n.log.Info("banlist reloaded",
+		"added", res.Added,
+		"extended", res.Extended,
+		"unchanged", res.Unchanged,
+		"expired", res.Expired,
+	)
+
+	changed := append(append([]string{}, res.Added...), res.Extended...)
+	n.mu.RLock()
+	for _, addr := range changed {
+		if p, ok := n.peers[addr]; ok {
+			_ = p.conn.Close()
+		}
+	}
+	n.mu.RUnlock()
+
+	return res, nil
+}
+
+// BanPeer bans addr for d, or permanently if d is 0, saves the banlist and
+// drops addr if it is connected.
+func (n *Node) BanPeer(addr string, d time.Duration, reason string) error {
+	if d < 0 {
+		return errors.New("ban duration must not be negative")
+	}
+	if d == 0 {
+		n.banlist.BanPermanent(addr, reason)
+	} else {
+		n.banlist.Ban(addr, d, reason)
+	}
+	if err := n.banlist.Save(); err != nil {
+		return err
+	}
+	n.log.Warn("peer banned by admin", "addr", addr, "for", banLabel(d), "reason", reason)
+	n.publishBan(addr, d, reason, true)
+
+	n.mu.RLock()
+	p, ok := n.peers[addr]
+	n.mu.RUnlock()
+	if ok {
+		_ = p.conn.Close()
+	}
+	return nil
+}
+
+// UnbanPeer lifts any ban on addr and clears its score, so it is not banned
+// again by its next small penalty. It reports whether addr was banned.
+func (n *Node) UnbanPeer(addr string) (bool, error) {
+	was := n.banlist.Unban(addr)
+	n.scorer.Forget(addr)
+	if err := n.banlist.Save(); err != nil {
+		return was, err
+	}
+	_ = n.scorer.Save(n.cfg.ScoreStorePath)
+	if was {
+		n.log.Info("peer unbanned by admin", "addr", addr)
+	}
+	return was, nil
+}
+
+// ConnectPeer adds addr to the known peers as a manual peer, which is never
+// evicted or filtered out, and dials it now unless it is already connected.
+func (n *Node) ConnectPeer(addr string) error {
+	addr = sanitizeHelloString(addr)
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return errors.New("addr must be host:port")
+	}
+	if n.isSelfAddr(addr) {
+		return errors.New("addr is this node")
+	}
+	if banned, _ := n.banlist.IsBanned(addr); banned {
+		return errors.New("addr is banned")
+	}
+
+	n.knownMu.Lock()
+	p, ok := n.knownPeers[addr]
+	if !ok {
+		if len(n.knownPeers) >= n.cfg.MaxKnownPeers {
+			n.evictKnownLocked(1)
+		}
+		p = StoredPeer{Addr: addr}
+	}
+	p.Source, p.SeenAt = "manual", time.Now().UTC()
+	n.knownPeers[addr] = p
+	n.knownMu.Unlock()
+
+	n.backoffMu.Lock()
+	delete(n.backoff, addr)
+	n.backoffMu.Unlock()
+
+	if n.isConnectedTo(addr) {
+		return nil
+	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.closed {
+		return errNodeClosed
+	}
+	n.log.Info("dialing peer added by admin", "addr", addr)
+	n.wg.Go(func() { n.dialPeer(addr) })
+	return nil
+}
+
+// DisconnectPeer closes the connection to addr and reports whether there
+// was one. The address stays known, so it may be dialed again later.
+func (n *Node) DisconnectPeer(addr string) bool {
+	n.mu.RLock()
+	p, ok := n.peers[addr]
+	n.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	_ = p.conn.Close()
+	n.log.Info("peer disconnected by admin", "addr", addr)
+	return true
+}
+
+func banLabel(d time.Duration) string {
+	if d == 0 {
+		return "permanent"
+	}
+	return d.String()
+}
+
 func (n *Node) Peers() []PeerInfo {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
@@ -275,6 +684,10 @@ func (n *Node) Peers() []PeerInfo {
 			NodeVersion:  p.nodeVersion,
 			Verified:     p.verified,
 			Score:        p.score,
+			Height:       p.bestHeight,
+
+			ProtocolVersion: p.protoVersion,
+			Encrypted:       p.encrypted,
 		})
 	}
 	return out
@@ -300,12 +713,15 @@ func (n *Node) acceptLoop() {
 			continue
 		}
 
-		if !n.tryRegisterPeer(conn, true) {
+		if err := n.tryRegisterPeer(conn, true); err != nil {
 			_ = conn.Close()
+			if errors.Is(err, errUnverifiedFull) {
+				n.penalize(remote, 1, err.Error())
+			}
 			continue
 		}
 
-		go n.handleConn(conn, true)
+		n.wg.Go(func() { n.handleConn(conn, true, "") })
 	}
 }
 
@@ -359,7 +775,9 @@ func (n *Node) persistLoop() {
 
 func (n *Node) persistOnce() error {
 	_ = n.banlist.Save()
-	_ = n.scorer.Save(n.cfg.ScoreStorePath)
+	if err := n.scorer.Save(n.cfg.ScoreStorePath); err != nil {
+		n.log.Warn("score store save failed", "path", n.cfg.ScoreStorePath, "err", err)
+	}
 
 	n.knownMu.RLock()
 	peers := make([]StoredPeer, 0, len(n.knownPeers))
@@ -372,18 +790,10 @@ func (n *Node) persistOnce() error {
 }
 
 func (n *Node) fillOutbound() {
-	targetOutbound := n.cfg.MaxPeers / 3
-	if targetOutbound < 4 {
-		targetOutbound = 4
-	}
+	targetOutbound := n.cfg.MaxOutbound
 
-	outbound := 0
 	n.mu.RLock()
-	for _, p := range n.peers {
-		if !p.inbound {
-			outbound++
-		}
-	}
+	_, outbound := n.countDirectionsLocked()
 	n.mu.RUnlock()
 
 	if outbound >= targetOutbound {
@@ -392,8 +802,7 @@ func (n *Node) fillOutbound() {
 
 	addrs := n.pickDialCandidates(targetOutbound - outbound)
 	for _, addr := range addrs {
-		addr := addr
-		go n.dialPeer(addr)
+		n.wg.Go(func() { n.dialPeer(addr) })
 	}
 }
 
@@ -405,10 +814,14 @@ func (n *Node) pickDialCandidates(limit int) []string {
 	now := time.Now().UTC()
 	n.knownMu.RLock()
 	candidates := make([]string, 0, len(n.knownPeers))
-	for addr := range n.knownPeers {
+	for addr, p := range n.knownPeers {
 		if addr == "" {
 			continue
 		}
+		// Pinned peers are the operator's choice, private or not.
+		if !n.pinned(addr, p.Source) && !n.gossipAddr(addr) {
+			continue
+		}
 		if n.isConnectedTo(addr) {
 			continue
 		}
@@ -442,6 +855,8 @@ func (n *Node) canDial(addr string, now time.Time) bool {
 }
 
 func (n *Node) recordDialFailure(addr string, err error) {
+	n.stats.dialFailures.Add(1)
+
 	n.backoffMu.Lock()
 	defer n.backoffMu.Unlock()
 
@@ -452,25 +867,36 @@ func (n *Node) recordDialFailure(addr string, err error) {
 	}
 
 	base := 2 * time.Second
-	max := 2 * time.Minute
+	maxDelay := 2 * time.Minute
 	delay := base * time.Duration(1<<minInt(b.Attempts-1, 8))
-	if delay > max {
-		delay = max
+	if delay > maxDelay {
+		delay = maxDelay
 	}
+	// Jitter spreads retries out, but never past maxDelay.
 	j := 0.5 + rand.Float64()
-	delay = time.Duration(float64(delay) * j)
+	delay = min(time.Duration(float64(delay)*j), maxDelay)
+
+	n.knownMu.Lock()
+	defer n.knownMu.Unlock()
+	p, ok := n.knownPeers[addr]
+
+	// A peer that keeps failing is probably gone for good; forget it
+	// rather than retry it forever, unless the operator configured it.
+	if b.Attempts >= n.cfg.MaxDialAttempts && !n.pinned(addr, p.Source) {
+		delete(n.backoff, addr)
+		delete(n.knownPeers, addr)
+		n.log.Info("forgetting unreachable peer", "addr", addr, "attempts", b.Attempts, "err", b.LastErr)
+		return
+	}
 
 	b.NextTryAt = time.Now().UTC().Add(delay)
 	n.backoff[addr] = b
 
-	n.knownMu.Lock()
-	p, ok := n.knownPeers[addr]
 	if ok {
 		p.LastError = b.LastErr
 		p.SeenAt = time.Now().UTC()
 		n.knownPeers[addr] = p
 	}
-	n.knownMu.Unlock()
 }
 
 func (n *Node) recordDialSuccess(addr string) {
@@ -485,11 +911,82 @@ func (n *Node) recordDialSuccess(addr string) {
 		p.SeenAt = time.Now().UTC()
 		n.knownPeers[addr] = p
 	} else {
-		n.knownPeers[addr] = StoredPeer{Addr: addr, SeenAt: time.Now().UTC(), Source: "learned"}
+		n.addKnownLocked(StoredPeer{Addr: addr, SeenAt: time.Now().UTC(), Source: "learned"})
 	}
 	n.knownMu.Unlock()
 }
 
+func (n *Node) isBootstrap(addr string) bool {
+	_, ok := n.bootstrap[addr]
+	return ok
+}
+
+// pinned reports whether a known peer is exempt from eviction and the
+// mainnet address filter: a bootstrap peer or one added by ConnectPeer.
+func (n *Node) pinned(addr, source string) bool {
+	return source == "manual" || n.isBootstrap(addr)
+}
+
+// addKnownLocked stores a newly learned peer, evicting the least recently
+// seen one if that would exceed MaxKnownPeers. The peer is dropped instead
+// if only pinned peers are left to evict. It requires knownMu.
+func (n *Node) addKnownLocked(p StoredPeer) {
+	if len(n.knownPeers) >= n.cfg.MaxKnownPeers && !n.evictKnownLocked(len(n.knownPeers)-n.cfg.MaxKnownPeers+1) {
+		return
+	}
+	n.knownPeers[p.Addr] = p
+}
+
+// evictKnownLocked drops the count least recently seen unpinned known
+// peers and reports whether there were that many. It requires knownMu.
+func (n *Node) evictKnownLocked(count int) bool {
+	victims := make([]StoredPeer, 0, len(n.knownPeers))
+	for _, p := range n.knownPeers {
+		if !n.pinned(p.Addr, p.Source) {
+			victims = append(victims, p)
+		}
+	}
+	sort.Slice(victims, func(i, j int) bool { return victims[i].SeenAt.Before(victims[j].SeenAt) })
+	for i := 0; i < count && i < len(victims); i++ {
+		delete(n.knownPeers, victims[i].Addr)
+	}
+	return len(victims) >= count
+}
+
+// BackoffInfo is the dial state of a peer address, for debugging reconnects.
+// NextTryAt is unix seconds, 0 when the address may be dialed now.
+type BackoffInfo struct {
+	Addr        string `json:"addr"`
+	Known       bool   `json:"known"`
+	Bootstrap   bool   `json:"bootstrap"`
+	Source      string `json:"source,omitempty"`
+	Attempts    int    `json:"attempts"`
+	MaxAttempts int    `json:"maxAttempts"`
+	NextTryAt   int64  `json:"nextTryAt"`
+	LastError   string `json:"lastError,omitempty"`
+}
+
+// DialBackoff reports addr's dial backoff and whether it is a known peer.
+func (n *Node) DialBackoff(addr string) BackoffInfo {
+	info := BackoffInfo{Addr: addr, Bootstrap: n.isBootstrap(addr), MaxAttempts: n.cfg.MaxDialAttempts}
+
+	n.knownMu.RLock()
+	p, ok := n.knownPeers[addr]
+	n.knownMu.RUnlock()
+	info.Known, info.Source = ok, p.Source
+
+	n.backoffMu.Lock()
+	b, ok := n.backoff[addr]
+	n.backoffMu.Unlock()
+	if ok {
+		info.Attempts, info.LastError = b.Attempts, b.LastErr
+		if b.NextTryAt.After(time.Now().UTC()) {
+			info.NextTryAt = b.NextTryAt.Unix()
+		}
+	}
+	return info
+}
+
 func minInt(a, b int) int {
 	if a < b {
 		return a
@@ -527,25 +1024,57 @@ func (n *Node) dialPeer(addr string) {
 		return
 	}
 
-	if !n.tryRegisterPeer(conn, false) {
+	if err := n.tryRegisterPeer(conn, false); err != nil {
 		_ = conn.Close()
 		return
 	}
 
-	n.recordDialSuccess(addr)
-	n.handleConn(conn, false)
+	n.handleConn(conn, false, addr)
 }
 
-func (n *Node) tryRegisterPeer(conn net.Conn, inbound bool) bool {
+var (
+	errNodeClosed     = errors.New("node closed")
+	errPeersFull      = errors.New("max peers reached")
+	errInboundFull    = errors.New("max inbound peers reached")
+	errOutboundFull   = errors.New("max outbound peers reached")
+	errUnverifiedFull = errors.New("unverified peer slots exhausted")
+
+	errDuplicateIdentity = errors.New("identity already connected")
+	errSelfConnect       = errors.New("peer has same identity public key")
+	errPeerGoodbye       = errors.New("peer ended the handshake")
+)
+
+// tryRegisterPeer adds conn as an unverified peer. It fails when the node is
+// closed, MaxPeers or the cap for its direction (MaxInbound, MaxOutbound) is
+// reached, or MaxUnverifiedPeers are already pending.
+func (n *Node) tryRegisterPeer(conn net.Conn, inbound bool) error {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
 	if n.closed {
-		return false
+		return errNodeClosed
+	}
+	// Limits no eviction can lift come first, so nobody is evicted for a
+	// newcomer that would be turned away anyway.
+	in, out := n.countDirectionsLocked()
+	if !inbound && out >= n.cfg.MaxOutbound {
+		n.log.Warn("peer rejected: max outbound peers reached", "remote", conn.RemoteAddr().String(), "outboundPeers", out)
+		return errOutboundFull
+	}
+	if n.unverified >= n.cfg.MaxUnverifiedPeers {
+		n.log.Warn("peer rejected: too many unverified peers", "remote", conn.RemoteAddr().String(), "inbound", inbound, "unverified", n.unverified)
+		return errUnverifiedFull
+	}
+	if inbound && (len(n.peers) >= n.cfg.MaxPeers || in >= n.cfg.MaxInbound) && n.evictWorstPeerLocked(conn.RemoteAddr().String()) {
+		in--
 	}
 	if len(n.peers) >= n.cfg.MaxPeers {
-		n.log.Warn("peer rejected: max peers reached", "remote", conn.RemoteAddr().String())
-		return false
+		n.log.Warn("peer rejected: max peers reached", "remote", conn.RemoteAddr().String(), "inbound", inbound)
+		return errPeersFull
+	}
+	if inbound && in >= n.cfg.MaxInbound {
+		n.log.Warn("peer rejected: max inbound peers reached", "remote", conn.RemoteAddr().String(), "inboundPeers", in)
+		return errInboundFull
 	}
 
 	key := conn.RemoteAddr().String()
@@ -556,10 +1085,52 @@ func (n *Node) tryRegisterPeer(conn net.Conn, inbound bool) bool {
 		lastMsgAt:   time.Now().UTC(),
 		score:       n.scorer.Get(key),
 		lim:         newLimiter(),
+		txLim:       n.newTxLimiter(),
+		wmu:         &sync.Mutex{},
 	}
 
+	n.unverified++
+
 	n.log.Info("peer connected", "remote", key, "inbound", inbound, "peers", len(n.peers))
-	return true
+	return nil
+}
+
+// countDirectionsLocked counts connected peers by direction. n.mu must be
+// held.
+func (n *Node) countDirectionsLocked() (inbound, outbound int) {
+	for _, p := range n.peers {
+		if p.inbound {
+			inbound++
+		} else {
+			outbound++
+		}
+	}
+	return inbound, outbound
+}
+
+// markVerified records that conn completed the challenge handshake, freeing
+// its unverified slot. It returns errDuplicateIdentity, leaving conn
+// unverified, if another connection already holds the same identity; the
+// newer connection is the one to close.
+func (n *Node) markVerified(conn net.Conn) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	key := conn.RemoteAddr().String()
+	p, ok := n.peers[key]
+	if !ok || p.verified {
+		return nil
+	}
+	id := hex.EncodeToString(p.pubKey)
+	if other, dup := n.connectedIdentities[id]; dup && other != key {
+		return fmt.Errorf("%w (already connected via %s)", errDuplicateIdentity, other)
+	}
+	p.verified = true
+	n.peers[key] = p
+	n.unverified--
+	n.connectedIdentities[id] = key
+	n.publishConnected(key, p)
+	return nil
 }
 
 func (n *Node) updatePeer(conn net.Conn, fn func(p peerConn) peerConn) {
@@ -579,15 +1150,30 @@ func (n *Node) unregisterPeer(conn net.Conn) {
 	defer n.mu.Unlock()
 
 	key := conn.RemoteAddr().String()
-	if _, ok := n.peers[key]; ok {
-		delete(n.peers, key)
+	if n.removePeerLocked(key) {
 		n.log.Info("peer disconnected", "remote", key, "peers", len(n.peers))
 	}
 }
 
+// removePeerLocked drops key from the peer table and the slot accounting.
+// n.mu must be held.
+func (n *Node) removePeerLocked(key string) bool {
+	p, ok := n.peers[key]
+	if !ok {
+		return false
+	}
+	if !p.verified {
+		n.unverified--
+	} else if id := hex.EncodeToString(p.pubKey); n.connectedIdentities[id] == key {
+		delete(n.connectedIdentities, id)
+	}
+	delete(n.peers, key)
+	return true
+}
+
 func (n *Node) learnPeer(addr, source string) {
 	addr = sanitizeHelloString(addr)
-	if addr == "" {
+	if addr == "" || n.isSelfAddr(addr) {
 		return
 	}
 	if banned, _ := n.banlist.IsBanned(addr); banned {
@@ -596,6 +1182,10 @@ func (n *Node) learnPeer(addr, source string) {
 
 	n.knownMu.Lock()
 	p, ok := n.knownPeers[addr]
+	if !ok && !n.gossipAddr(addr) {
+		n.knownMu.Unlock()
+		return
+	}
 	if ok {
 		p.SeenAt = time.Now().UTC()
 		if p.Source == "" {
@@ -603,102 +1193,210 @@ func (n *Node) learnPeer(addr, source string) {
 		}
 		n.knownPeers[addr] = p
 	} else {
-		n.knownPeers[addr] = StoredPeer{Addr: addr, SeenAt: time.Now().UTC(), Source: source}
+		n.addKnownLocked(StoredPeer{Addr: addr, SeenAt: time.Now().UTC(), Source: source})
 	}
 	n.knownMu.Unlock()
 }
 
 func (n *Node) requestPeersFromSome() {
-	conns := n.snapshotConns()
+	// Verified only: a connection still in the handshake may be switching
+	// to encryption, and a plaintext frame would break it.
+	conns := n.verifiedConns()
 	for i := 0; i < len(conns) && i < 8; i++ {
 		conn := conns[i]
 		go n.sendGetPeers(conn)
 	}
 }
 
-func (n *Node) snapshotConns() []net.Conn {
-	n.mu.RLock()
-	defer n.mu.RUnlock()
-
-	out := make([]net.Conn, 0, len(n.peers))
-	for _, p := range n.peers {
-		out = append(out, p.conn)
-	}
-	return out
-}
-
 func (n *Node) sendGetPeers(conn net.Conn) {
-	bw := bufio.NewWriterSize(conn, 64*1024)
-	_ = conn.SetWriteDeadline(time.Now().Add(DefaultWriteTimeout))
-	_ = WriteFrame(bw, MsgGetPeers, []byte{1})
-	_ = bw.Flush()
+	_ = n.send(conn, MsgGetPeers, []byte{1})
 }
 
 // ---- Connection lifecycle (HELLO + challenge + messages) ----
 
-func (n *Node) handleConn(conn net.Conn, inbound bool) {
+// handleConn runs the HELLO/challenge handshake and message loop. For
+// outbound connections dialAddr is the address we dialed; handshake failures
+// feed its dial backoff so a peer that connects but fails verification is not
+// immediately redialed.
+func (n *Node) handleConn(conn net.Conn, inbound bool, dialAddr string) {
 	defer func() {
 		_ = conn.Close()
 		n.unregisterPeer(conn)
 	}()
 
+	var hsErr error
+
+	// Bound the number of connections in the unauthenticated phase.
+	if !n.acquireHandshake() {
+		if inbound {
+			n.penalize(conn.RemoteAddr().String(), 1, "handshake slots exhausted")
+		}
+		n.log.Warn("peer rejected: too many pending handshakes", "remote", conn.RemoteAddr().String(), "inbound", inbound)
+		if dialAddr != "" {
+			n.recordDialFailure(dialAddr, errors.New("handshake slots exhausted"))
+		}
+		return
+	}
+	handshaking := true
+	defer func() {
+		if !handshaking {
+			return
+		}
+		n.releaseHandshake()
+		if dialAddr != "" {
+			if hsErr == nil {
+				hsErr = errors.New("handshake aborted")
+			}
+			n.recordDialFailure(dialAddr, hsErr)
+		}
+	}()
+
 	br := bufio.NewReaderSize(conn, 64*1024)
 	bw := bufio.NewWriterSize(conn, 64*1024)
 
 	_ = conn.SetDeadline(time.Now().Add(n.cfg.HandshakeTimeout))
 
+	// An ephemeral key offers encryption in our HELLO.
+	var eph *ecdh.PrivateKey
+	var encKey []byte
+	if n.cfg.Encrypt {
+		var err error
+		if eph, err = newEphemeralKey(); err != nil {
+			hsErr = err
+			return
+		}
+		encKey = eph.PublicKey().Bytes()
+	}
+
 	// HELLO exchange
-	var peerHello Hello
+	var ourHello, peerHello Hello
 	var err error
 	if inbound {
 		peerHello, err = n.readAndValidateHello(br)
+		if errors.Is(err, errSelfConnect) {
+			// Answer so the dialing half, also us, sees its own key and
+			// forgets the address instead of penalizing it.
+			if _, err := n.writeHello(bw, ProtocolVersion, nil); err == nil {
+				_ = bw.Flush()
+			}
+			return
+		}
 		if err != nil {
 			n.penalize(conn.RemoteAddr().String(), 3, "hello invalid: "+err.Error())
 			return
 		}
-		if err := n.writeHello(bw); err != nil {
+		// Answer in the dialer's version so older peers can decode it.
+		if ourHello, err = n.writeHello(bw, peerHello.ProtocolVersion, encKey); err != nil {
 			return
 		}
 		if err := bw.Flush(); err != nil {
 			return
 		}
 	} else {
-		if err := n.writeHello(bw); err != nil {
+		if ourHello, hsErr = n.writeHello(bw, ProtocolVersion, encKey); hsErr != nil {
 			return
 		}
-		if err := bw.Flush(); err != nil {
+		if hsErr = bw.Flush(); hsErr != nil {
 			return
 		}
-		peerHello, err = n.readAndValidateHello(br)
+		peerHello, hsErr = n.readAndValidateHello(br)
+		if errors.Is(hsErr, errSelfConnect) {
+			n.forgetSelfAddr(dialAddr)
+			return
+		}
+		if hsErr != nil {
+			n.penalize(conn.RemoteAddr().String(), 3, "hello invalid: "+hsErr.Error())
+			return
+		}
+	}
+
+	// Switch to the encrypted transport when both sides offered a key.
+	var session []byte
+	if len(ourHello.EncKey) > 0 && len(peerHello.EncKey) > 0 {
+		sc, sid, err := newSecureConn(conn, br, eph, ourHello, peerHello, !inbound, n.cfg.NetworkID)
 		if err != nil {
-			n.penalize(conn.RemoteAddr().String(), 3, "hello invalid: "+err.Error())
+			hsErr = err
+			n.penalize(conn.RemoteAddr().String(), 3, "encryption setup failed: "+err.Error())
 			return
 		}
+		conn, session = sc, sid
+		br = bufio.NewReaderSize(conn, 64*1024)
+	} else if n.cfg.MinEncryption == EncryptionAEAD {
+		hsErr = errors.New("peer does not offer encryption")
+		n.log.Info("peer rejected: encryption required", "remote", conn.RemoteAddr().String(), "inbound", inbound, "protocolVersion", peerHello.ProtocolVersion)
+		// Say why, so the peer does not take the close as misbehaviour.
+		_ = n.send(conn, MsgGoodbye, []byte("encryption required"))
+		return
+	}
+	if inbound && n.cfg.PuzzleBits > 0 && peerHello.ProtocolVersion < puzzleMinVersion {
+		n.log.Info("peer rejected: admission puzzle needs a newer protocol", "remote", conn.RemoteAddr().String(), "protocolVersion", peerHello.ProtocolVersion)
+		_ = n.send(conn, MsgGoodbye, []byte("admission puzzle required"))
+		return
 	}
 
 	// Store peer metadata
 	n.updatePeer(conn, func(p peerConn) peerConn {
+		p.conn = conn
+		p.encrypted = session != nil
 		p.pubKey = peerHello.PublicKey
 		p.nodeVersion = peerHello.NodeVersion
+		p.protoVersion = peerHello.ProtocolVersion
+		p.bestHeight = max(p.bestHeight, peerHello.Height)
 		p.lastMsgAt = time.Now().UTC()
 		p.score = n.scorer.Get(conn.RemoteAddr().String())
 		return p
 	})
-	n.learnPeer(conn.RemoteAddr().String(), "learned")
+	// An inbound peer's source port is ephemeral; only the address it
+	// advertises is worth dialing later.
+	if !inbound {
+		n.learnPeer(conn.RemoteAddr().String(), "learned")
+	}
 
 	// Challenge-response: prove peer controls announced key
-	verified, verr := n.performChallengeHandshake(conn, br, bw, peerHello.PublicKey)
+	verified, verr := n.performChallengeHandshake(conn, br, peerHello, session, inbound)
+	if errors.Is(verr, errPeerGoodbye) {
+		hsErr = verr
+		n.log.Info("peer closed the handshake", "remote", conn.RemoteAddr().String(), "inbound", inbound, "reason", verr.Error())
+		return
+	}
 	if verr != nil || !verified {
-		n.penalize(conn.RemoteAddr().String(), 5, "challenge failed: "+safeErr(verr))
+		hsErr = errors.New("challenge failed: " + safeErr(verr))
+		n.penalize(conn.RemoteAddr().String(), 5, hsErr.Error())
 		return
 	}
-	n.updatePeer(conn, func(p peerConn) peerConn { p.verified = true; return p })
+	if hsErr = n.markVerified(conn); hsErr != nil {
+		// Not misbehaviour: simultaneous dials and NAT'd duplicates both
+		// end up here. The older connection stays.
+		n.log.Info("peer rejected: duplicate identity", "remote", conn.RemoteAddr().String(), "inbound", inbound, "err", hsErr)
+		return
+	}
+	if inbound {
+		if a, ok := n.peerAdvertisedAddr(peerHello.Addr); ok {
+			n.learnPeer(a, "advertised")
+		}
+	}
+
+	handshaking = false
+	n.releaseHandshake()
+	if dialAddr != "" {
+		n.recordDialSuccess(dialAddr)
+	}
 
 	_ = conn.SetDeadline(time.Time{})
 
 	// Seed discovery
 	go n.sendGetPeers(conn)
 
+	// Catch up from the best peer we know of. v1 peers cannot serve blocks.
+	if peerHello.ProtocolVersion >= minVersionFor(MsgGetBlocks) {
+		go n.syncBest()
+	}
+
+	pongs := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go n.keepalive(conn, pongs, done)
+
 	for {
 		select {
 		case <-n.ctx.Done():
@@ -706,8 +1404,8 @@ func (n *Node) handleConn(conn net.Conn, inbound bool) {
 		default:
 		}
 
-		_ = conn.SetReadDeadline(time.Now().Add(DefaultReadTimeout))
-		f, err := ReadFrame(br)
+		_ = conn.SetReadDeadline(time.Now().Add(n.idleTimeout()))
+		f, err := n.readFrame(br)
 		if err != nil {
 			return
 		}
@@ -718,7 +1416,11 @@ func (n *Node) handleConn(conn net.Conn, inbound bool) {
 			if !p.lim.allow() {
 				allowed = false
 			}
-			p.lastMsgAt = time.Now().UTC()
+			// A peer's own pings prove nothing: our keepalive pings it
+			// when it goes quiet, and its pong counts.
+			if f.Type != MsgPing {
+				p.lastMsgAt = time.Now().UTC()
+			}
 			return p
 		})
 		if !allowed {
@@ -726,28 +1428,31 @@ func (n *Node) handleConn(conn net.Conn, inbound bool) {
 			return
 		}
 
+		if peerHello.ProtocolVersion < minVersionFor(f.Type) {
+			n.penalize(conn.RemoteAddr().String(), 1, fmt.Sprintf("message type %d not in protocol v%d", f.Type, peerHello.ProtocolVersion))
+			continue
+		}
+
 		switch f.Type {
 		case MsgPing:
-			_ = conn.SetWriteDeadline(time.Now().Add(DefaultWriteTimeout))
-			if err := WriteFrame(bw, MsgPong, []byte("pong")); err != nil {
+			if err := n.send(conn, MsgPong, []byte("pong")); err != nil {
 				return
 			}
-			if err := bw.Flush(); err != nil {
-				return
+
+		case MsgPong:
+			select {
+			case pongs <- struct{}{}:
+			default:
 			}
 
 		case MsgGetPeers:
-			addrs := n.sampleKnownPeers(64)
+			addrs := n.peersForReply(64)
 			payload, err := EncodePeers(addrs)
 			if err != nil {
 				n.penalize(conn.RemoteAddr().String(), 2, "encode peers failed")
 				return
 			}
-			_ = conn.SetWriteDeadline(time.Now().Add(DefaultWriteTimeout))
-			if err := WriteFrame(bw, MsgPeers, payload); err != nil {
-				return
-			}
-			if err := bw.Flush(); err != nil {
+			if err := n.send(conn, MsgPeers, payload); err != nil {
 				return
 			}
 
@@ -768,15 +1473,11 @@ func (n *Node) handleConn(conn net.Conn, inbound bool) {
 			}
 			var c [challengeSize]byte
 			copy(c[:], f.Payload)
-			resp, err := SignChallenge(n.cfg.IdentityPrivKey, n.cfg.NetworkID, c)
+			resp, err := SignChallenge(n.cfg.IdentityPrivKey, n.cfg.NetworkID, session, c)
 			if err != nil {
 				return
 			}
-			_ = conn.SetWriteDeadline(time.Now().Add(DefaultWriteTimeout))
-			if err := WriteFrame(bw, MsgChallengeResp, resp); err != nil {
-				return
-			}
-			if err := bw.Flush(); err != nil {
+			if err := n.send(conn, MsgChallengeResp, resp); err != nil {
 				return
 			}
 
@@ -784,7 +1485,20 @@ func (n *Node) handleConn(conn net.Conn, inbound bool) {
 			n.penalize(conn.RemoteAddr().String(), 2, "unexpected challenge response")
 			return
 
+		case MsgTx:
+			n.handleTxFrame(conn, f.Payload)
+
+		case MsgBlock:
+			n.handleBlockFrame(conn, f.Payload)
+
+		case MsgGetBlocks:
+			n.handleGetBlocksFrame(conn, f.Payload)
+
+		case MsgBlocks:
+			n.handleBlocksFrame(conn, f.Payload)
+
 		case MsgGoodbye:
+			n.log.Info("peer said goodbye", "remote", conn.RemoteAddr().String(), "reason", sanitizeHelloString(string(f.Payload[:min(len(f.Payload), maxHelloString)])))
 			return
 
 		default:
@@ -793,6 +1507,35 @@ func (n *Node) handleConn(conn net.Conn, inbound bool) {
 	}
 }
 
+func (n *Node) acquireHandshake() bool {
+	select {
+	case n.handshakeSem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (n *Node) releaseHandshake() {
+	select {
+	case <-n.handshakeSem:
+	default:
+	}
+}
+
+// UnverifiedPeers reports connected peers that have not yet completed the
+// challenge handshake.
+func (n *Node) UnverifiedPeers() int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.unverified
+}
+
+// PendingHandshakes reports connections currently in the HELLO/challenge phase.
+func (n *Node) PendingHandshakes() int {
+	return len(n.handshakeSem)
+}
+
 func safeErr(err error) string {
 	if err == nil {
 		return "unknown"
@@ -800,7 +1543,11 @@ func safeErr(err error) string {
 	return err.Error()
 }
 
-func (n *Node) performChallengeHandshake(conn net.Conn, br *bufio.Reader, bw *bufio.Writer, peerPub ed25519.PublicKey) (bool, error) {
+// performChallengeHandshake has each side prove it holds its HELLO key by
+// signing the other's challenge. An inbound peer must also solve the
+// admission puzzle when Config.PuzzleBits is set.
+func (n *Node) performChallengeHandshake(conn net.Conn, br *bufio.Reader, peerHello Hello, session []byte, inbound bool) (bool, error) {
+	peerPub := peerHello.PublicKey
 	if len(peerPub) != ed25519.PublicKeySize {
 		return false, errors.New("peer pubkey invalid")
 	}
@@ -810,10 +1557,13 @@ func (n *Node) performChallengeHandshake(conn net.Conn, br *bufio.Reader, bw *bu
 		return false, err
 	}
 
-	if err := WriteFrame(bw, MsgChallenge, chal[:]); err != nil {
-		return false, err
+	puzzleBits := 0
+	payload := chal[:]
+	if inbound && n.cfg.PuzzleBits > 0 && peerHello.ProtocolVersion >= puzzleMinVersion {
+		puzzleBits = n.cfg.PuzzleBits
+		payload = append(payload, byte(puzzleBits))
 	}
-	if err := bw.Flush(); err != nil {
+	if err := n.send(conn, MsgChallenge, payload); err != nil {
 		return false, err
 	}
 
@@ -821,44 +1571,60 @@ func (n *Node) performChallengeHandshake(conn net.Conn, br *bufio.Reader, bw *bu
 
 	maxFrames := 16
 	for i := 0; i < maxFrames; i++ {
-		f, err := ReadFrame(br)
+		f, err := n.readFrame(br)
 		if err != nil {
 			return false, err
 		}
 
 		switch f.Type {
 		case MsgChallenge:
-			if len(f.Payload) != challengeSize {
+			withPuzzle := len(f.Payload) == challengeSize+1 && peerHello.ProtocolVersion >= puzzleMinVersion
+			if len(f.Payload) != challengeSize && !withPuzzle {
 				return false, errors.New("invalid challenge size")
 			}
 			var c [challengeSize]byte
 			copy(c[:], f.Payload)
-			resp, err := SignChallenge(n.cfg.IdentityPrivKey, n.cfg.NetworkID, c)
+			resp, err := SignChallenge(n.cfg.IdentityPrivKey, n.cfg.NetworkID, session, c)
 			if err != nil {
 				return false, err
 			}
-			_ = conn.SetWriteDeadline(time.Now().Add(DefaultWriteTimeout))
-			if err := WriteFrame(bw, MsgChallengeResp, resp); err != nil {
-				return false, err
+			if len(f.Payload) > challengeSize {
+				nonce, err := SolvePuzzle(c, int(f.Payload[challengeSize]))
+				if err != nil {
+					return false, err
+				}
+				resp = append(resp, nonce[:]...)
 			}
-			if err := bw.Flush(); err != nil {
+			if err := n.send(conn, MsgChallengeResp, resp); err != nil {
 				return false, err
 			}
 
 		case MsgChallengeResp:
-			if err := VerifyChallengeResp(peerPub, n.cfg.NetworkID, f.Payload, chal); err != nil {
+			resp := f.Payload
+			if puzzleBits > 0 {
+				// Checked first: it is one hash, the signature is not.
+				if len(resp) != challengeRespSize+puzzleNonceSize {
+					return false, errors.New("puzzle solution missing")
+				}
+				if err := VerifyPuzzle(chal, resp[challengeRespSize:], puzzleBits); err != nil {
+					return false, err
+				}
+				resp = resp[:challengeRespSize]
+			}
+			if err := VerifyChallengeResp(peerPub, n.cfg.NetworkID, session, resp, chal); err != nil {
 				return false, err
 			}
 			return true, nil
 
 		case MsgPing:
-			_ = conn.SetWriteDeadline(time.Now().Add(DefaultWriteTimeout))
-			_ = WriteFrame(bw, MsgPong, []byte("pong"))
-			_ = bw.Flush()
+			_ = n.send(conn, MsgPong, []byte("pong"))
 
 		case MsgHello:
 			return false, errors.New("unexpected hello during challenge")
 
+		case MsgGoodbye:
+			return false, fmt.Errorf("%w: %q", errPeerGoodbye, sanitizeHelloString(string(f.Payload[:min(len(f.Payload), maxHelloString)])))
+
 		default:
 			// ignore during handshake
 		}
@@ -873,9 +1639,9 @@ func (n *Node) sampleKnownPeers(limit int) []string {
 	}
 
 	n.knownMu.RLock()
-	addrs := make([]string, 0, len(n.knownPeers))
-	for addr := range n.knownPeers {
-		if addr == "" {
+	peers := make([]StoredPeer, 0, len(n.knownPeers))
+	for addr, p := range n.knownPeers {
+		if addr == "" || !n.gossipAddr(addr) {
 			continue
 		}
 		if n.isConnectedTo(addr) {
@@ -884,33 +1650,81 @@ func (n *Node) sampleKnownPeers(limit int) []string {
 		if banned, _ := n.banlist.IsBanned(addr); banned {
 			continue
 		}
-		addrs = append(addrs, addr)
+		p.Addr = addr
+		peers = append(peers, p)
 	}
 	n.knownMu.RUnlock()
 
-	rand.Shuffle(len(addrs), func(i, j int) { addrs[i], addrs[j] = addrs[j], addrs[i] })
+	// Weighted sampling without replacement (Efraimidis-Spirakis): each
+	// peer draws u^(1/weight) and the highest draws win, so healthy peers
+	// are favoured but any peer may be offered, which keeps the lists we
+	// hand out from converging on the same few addresses.
+	type draw struct {
+		addr string
+		key  float64
+	}
+	now := clock.OrReal(n.cfg.Clock).Now()
+	draws := make([]draw, len(peers))
+	for i, p := range peers {
+		w := peerWeight(p, n.scorer.Get(p.Addr), now)
+		draws[i] = draw{addr: p.Addr, key: math.Pow(rand.Float64(), 1/w)}
+	}
+	sort.Slice(draws, func(i, j int) bool { return draws[i].key > draws[j].key })
 
-	if len(addrs) > limit {
-		addrs = addrs[:limit]
+	addrs := make([]string, 0, min(limit, len(draws)))
+	for i := 0; i < len(draws) && i < limit; i++ {
+		addrs = append(addrs, draws[i].addr)
 	}
 	return addrs
 }
 
-func (n *Node) writeHello(bw *bufio.Writer) error {
+// peerWeight rates a known peer for sampleKnownPeers, in (0, 1]: a peer
+// whose last dial failed, that has not been seen for a while, or that has a
+// misbehaviour score counts for less.
+func peerWeight(p StoredPeer, score int, now time.Time) float64 {
+	w := 1.0
+	if p.LastError != "" {
+		w /= 4
+	}
+	if age := now.Sub(p.SeenAt); age > 0 {
+		w /= 1 + age.Hours()/6
+	}
+	if score > 0 {
+		w /= 1 + float64(score)
+	}
+	return max(w, 1e-3)
+}
+
+// writeHello sends our HELLO encoded at version, which must not exceed
+// ProtocolVersion; fields the version predates are left out, encKey
+// included. It returns the HELLO as the peer will decode it.
+func (n *Node) writeHello(bw *bufio.Writer, version uint16, encKey []byte) (Hello, error) {
 	pub := n.cfg.IdentityPrivKey.Public().(ed25519.PublicKey)
 	h, err := NewHello(n.cfg.NetworkID, pub)
 	if err != nil {
-		return err
+		return Hello{}, err
+	}
+	h.ProtocolVersion = version
+	if version >= 4 {
+		h.EncKey = encKey
+	}
+	if src := n.source(); src != nil {
+		h.Height = src.Height()
 	}
+	h.Addr = n.advertise
 	payload, err := h.Encode()
 	if err != nil {
-		return err
+		return Hello{}, err
 	}
-	return WriteFrame(bw, MsgHello, payload)
+	if err := WriteFrame(bw, MsgHello, payload); err != nil {
+		return Hello{}, err
+	}
+	n.stats.framesWritten.Add(1)
+	return h, nil
 }
 
 func (n *Node) readAndValidateHello(br *bufio.Reader) (Hello, error) {
-	frame, err := ReadFrame(br)
+	frame, err := n.readFrame(br)
 	if err != nil {
 		return Hello{}, err
 	}
@@ -925,13 +1739,14 @@ func (n *Node) readAndValidateHello(br *bufio.Reader) (Hello, error) {
 		NetworkID:      n.cfg.NetworkID,
 		MaxClockSkew:   2 * time.Minute,
 		RequireNonZero: true,
+		Clock:          n.cfg.Clock,
 	}); err != nil {
 		return Hello{}, err
 	}
 
 	ourPub := n.cfg.IdentityPrivKey.Public().(ed25519.PublicKey)
 	if vcrypto.ConstantTimeEqual(ourPub, h.PublicKey) {
-		return Hello{}, errors.New("peer has same identity public key")
+		return Hello{}, errSelfConnect
 	}
 	return h, nil
 }
@@ -958,6 +1773,7 @@ func (n *Node) penalize(addr string, points int, reason string) {
 		_ = n.banlist.Save()
 		_ = n.scorer.Save(n.cfg.ScoreStorePath)
 		n.log.Warn("peer banned", "addr", addr, "for", banFor.String(), "reason", reason)
+		n.publishBan(addr, banFor, reason, false)
 
 		// close if connected
 		n.mu.RLock()
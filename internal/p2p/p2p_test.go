@@ -0,0 +1,115 @@
+package p2p
+
+import (
+	"crypto/ed25519"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/clock"
+)
+
+// testConfig is a minimal valid Config with its stores under dir.
+func testConfig(t *testing.T, dir string) Config {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Config{
+		ListenAddr:      "127.0.0.1:0",
+		MaxPeers:        8,
+		NetworkID:       "veltaros-testnet",
+		IdentityPrivKey: priv,
+		BanlistPath:     filepath.Join(dir, "banlist.json"),
+		PeerStorePath:   filepath.Join(dir, "peers.json"),
+		ScoreStorePath:  filepath.Join(dir, "scores.json"),
+	}
+}
+
+func newTestNode(t *testing.T, cfg Config) *Node {
+	t.Helper()
+	n, err := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestNodeScoresSurviveRestart(t *testing.T) {
+	cfg := testConfig(t, t.TempDir())
+	cfg.Clock = clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	n := newTestNode(t, cfg)
+	n.penalize("203.0.113.7:30303", 4, "test")
+	if err := n.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	n = newTestNode(t, cfg)
+	defer n.Close()
+	if got := n.scorer.Get("203.0.113.7:30303"); got != 4 {
+		t.Fatalf("score after restart = %d, want 4", got)
+	}
+}
+
+func TestPeerWeight(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	healthy := StoredPeer{Addr: "8.8.8.8:30303", SeenAt: now}
+	for _, tc := range []struct {
+		name  string
+		p     StoredPeer
+		score int
+		want  float64
+	}{
+		{"healthy", healthy, 0, 1},
+		{"last dial failed", StoredPeer{SeenAt: now, LastError: "refused"}, 0, 0.25},
+		{"unseen for 6h", StoredPeer{SeenAt: now.Add(-6 * time.Hour)}, 0, 0.5},
+		{"score 3", healthy, 3, 0.25},
+		{"floor", StoredPeer{SeenAt: now.Add(-1000 * time.Hour), LastError: "refused"}, 50, 1e-3},
+	} {
+		if got := peerWeight(tc.p, tc.score, now); got != tc.want {
+			t.Errorf("%s: weight = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSampleKnownPeersFavoursHealthy(t *testing.T) {
+	// Far enough in the past that the system clock would age both peers
+	// to the weight floor, making them equally likely.
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := testConfig(t, t.TempDir())
+	cfg.Clock = clock.NewFake(now)
+	n := newTestNode(t, cfg)
+	defer n.Close()
+
+	const healthy, stale = "8.8.8.8:30303", "1.1.1.1:30303"
+	n.knownMu.Lock()
+	n.addKnownLocked(StoredPeer{Addr: healthy, SeenAt: now, Source: "learned"})
+	n.addKnownLocked(StoredPeer{Addr: stale, SeenAt: now.Add(-48 * time.Hour), Source: "learned"})
+	n.knownMu.Unlock()
+
+	// Weights 1 and 1/9: the healthy peer should come first about 90% of
+	// the time.
+	first := 0
+	const rounds = 1000
+	for range rounds {
+		got := n.sampleKnownPeers(1)
+		if len(got) != 1 {
+			t.Fatalf("sample = %v, want one peer", got)
+		}
+		if got[0] == healthy {
+			first++
+		}
+	}
+	if first < rounds*3/4 || first == rounds {
+		t.Errorf("healthy peer sampled first %d/%d times, want about 90%%", first, rounds)
+	}
+
+	// Both are still offered when there is room.
+	if got := n.sampleKnownPeers(2); len(got) != 2 {
+		t.Errorf("sample of 2 = %v", got)
+	}
+}
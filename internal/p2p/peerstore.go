@@ -1,23 +1,31 @@
 package p2p
 
 import (
-	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
 	"sort"
 	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/storage"
 )
 
 type StoredPeer struct {
 	Addr      string    `json:"addr"`
 	SeenAt    time.Time `json:"seenAt"`
-	Source    string    `json:"source"` // bootstrap|learned|manual
+	Source    string    `json:"source"` // bootstrap|learned|advertised|manual
 	LastError string    `json:"lastError,omitempty"`
 }
 
+// peerStoreFormat versions the peer file ([]StoredPeer).
+var peerStoreFormat = storage.Format{Name: "peer", Version: 1}
+
 type PeerStore struct {
 	path string
+
+	// KeepBackup keeps the previous file as <path>.bak on each Save; Load
+	// falls back to it if the primary is corrupt.
+	KeepBackup bool
 }
 
 func NewPeerStore(path string) *PeerStore {
@@ -25,17 +33,17 @@ func NewPeerStore(path string) *PeerStore {
 }
 
 func (ps *PeerStore) Load() ([]StoredPeer, error) {
-	raw, err := os.ReadFile(ps.path)
+	var peers []StoredPeer
+	err := storage.ReadFileWithFallback(ps.path, func(raw []byte) error {
+		peers = nil
+		return peerStoreFormat.Decode(raw, &peers)
+	})
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return []StoredPeer{}, nil
 		}
 		return nil, err
 	}
-	var peers []StoredPeer
-	if err := json.Unmarshal(raw, &peers); err != nil {
-		return nil, err
-	}
 	// Normalize
 	out := make([]StoredPeer, 0, len(peers))
 	seen := make(map[string]struct{}, len(peers))
@@ -62,19 +70,5 @@ func (ps *PeerStore) Save(peers []StoredPeer) error {
 		return peers[i].Addr < peers[j].Addr
 	})
 
-	data, err := json.MarshalIndent(peers, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	tmp := ps.path + ".tmp"
-	if err := os.WriteFile(tmp, data, 0o600); err != nil {
-		return err
-	}
-	if err := os.Rename(tmp, ps.path); err != nil {
-		_ = os.Remove(tmp)
-		return err
-	}
-	_ = os.Chmod(ps.path, 0o600)
-	return nil
+	return storage.WriteVersioned(ps.path, peerStoreFormat, peers, ps.KeepBackup)
 }
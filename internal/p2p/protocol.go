@@ -10,17 +10,21 @@ import (
 	"io"
 	"time"
 
+	"github.com/VeltarosLabs/Veltaros/internal/clock"
+	"github.com/VeltarosLabs/Veltaros/internal/consensus"
 	vcrypto "github.com/VeltarosLabs/Veltaros/internal/crypto"
 	"github.com/VeltarosLabs/Veltaros/pkg/version"
 )
 
 const (
-	MaxFrameSize = 1 << 20 // 1 MiB
+	MaxFrameSize = 4 << 20 // 4 MiB: fits a MaxBlockBytes block in JSON form
 
-	DefaultReadTimeout  = 7 * time.Second
 	DefaultWriteTimeout = 7 * time.Second
 
-	ProtocolVersion uint16 = 1
+	// ProtocolVersion is what we send; peers speaking any version from
+	// MinProtocolVersion up are accepted.
+	ProtocolVersion    uint16 = 5
+	MinProtocolVersion uint16 = 1
 )
 
 type MessageType uint8
@@ -40,8 +44,30 @@ const (
 	// Challenge-response proof of key ownership
 	MsgChallenge     MessageType = 20
 	MsgChallengeResp MessageType = 21
+
+	// Transaction relay (payload: JSON-encoded signed tx)
+	MsgTx MessageType = 30
+
+	// Block announce (payload: JSON BlockAnnounce)
+	MsgBlock MessageType = 31
+
+	// Block sync (payloads: JSON GetBlocks / Blocks)
+	MsgGetBlocks MessageType = 32
+	MsgBlocks    MessageType = 33
 )
 
+// minVersionFor is the lowest negotiated protocol version a message type
+// may be exchanged at. Types not listed date from v1; tx relay, block gossip
+// and block sync arrived with v2 (a v1 node ignores them).
+func minVersionFor(t MessageType) uint16 {
+	switch t {
+	case MsgTx, MsgBlock, MsgGetBlocks, MsgBlocks:
+		return 2
+	default:
+		return 1
+	}
+}
+
 type Frame struct {
 	Type    MessageType
 	Payload []byte
@@ -112,7 +138,7 @@ func ReadFrame(r io.Reader) (Frame, error) {
 	return Frame{Type: msgType, Payload: payload}, nil
 }
 
-// ---- HELLO handshake payload (v1) ----
+// ---- HELLO handshake payload (v5) ----
 // Payload fields (binary, little-endian for ints):
 // [2] protocolVersion (uint16)
 // [2] networkIDLen (uint16) + [N] networkID bytes (utf-8, <= 64)
@@ -120,6 +146,13 @@ func ReadFrame(r io.Reader) (Frame, error) {
 // [8] unixTimeSec (int64)
 // [32] nonce
 // [32] ed25519 public key
+// [8] chain height (uint64) -- v2+ only; v1 payloads end at the key
+// [2] addrLen (uint16) + [addrLen] advertised listen address, addrLen <= 128
+//     and may be 0 -- v3+ only
+// [1] encKeyLen (0 or 32) + [encKeyLen] ephemeral X25519 key offering an
+//     encrypted session -- v4+ only
+//
+// v5 adds no HELLO fields; it marks support for the admission puzzle.
 
 const (
 	maxHelloString = 64
@@ -133,6 +166,17 @@ type Hello struct {
 	TimeUnixSec     int64
 	Nonce           [helloNonceSize]byte
 	PublicKey       ed25519.PublicKey
+
+	// Height is the sender's chain height (always 0 from v1 peers).
+	Height uint64
+
+	// Addr is the host:port the sender accepts connections on, if it has
+	// one to advertise ("" otherwise, and always from v1/v2 peers).
+	Addr string
+
+	// EncKey is the sender's ephemeral X25519 key when it offers an
+	// encrypted session (nil otherwise, and always before v4).
+	EncKey []byte
 }
 
 func NewHello(networkID string, identityPub ed25519.PublicKey) (Hello, error) {
@@ -186,8 +230,15 @@ func (h Hello) Encode() ([]byte, error) {
 	if len(nid) > maxHelloString || len(nver) > maxHelloString {
 		return nil, errors.New("hello string too long")
 	}
+	addr := sanitizeHelloString(h.Addr)
+	if len(addr) > maxPeerAddrLen {
+		return nil, errors.New("hello address too long")
+	}
+	if len(h.EncKey) != 0 && len(h.EncKey) != encKeySize {
+		return nil, errors.New("invalid encryption key size")
+	}
 
-	buf := make([]byte, 0, 2+2+len(nid)+2+len(nver)+8+helloNonceSize+ed25519.PublicKeySize)
+	buf := make([]byte, 0, 2+2+len(nid)+2+len(nver)+8+helloNonceSize+ed25519.PublicKeySize+8+2+len(addr)+1+len(h.EncKey))
 
 	tmp2 := make([]byte, 2)
 	binary.LittleEndian.PutUint16(tmp2, h.ProtocolVersion)
@@ -208,6 +259,22 @@ func (h Hello) Encode() ([]byte, error) {
 	buf = append(buf, h.Nonce[:]...)
 	buf = append(buf, h.PublicKey...)
 
+	if h.ProtocolVersion >= 2 {
+		binary.LittleEndian.PutUint64(tmp8, h.Height)
+		buf = append(buf, tmp8...)
+	}
+
+	if h.ProtocolVersion >= 3 {
+		binary.LittleEndian.PutUint16(tmp2, uint16(len(addr)))
+		buf = append(buf, tmp2...)
+		buf = append(buf, []byte(addr)...)
+	}
+
+	if h.ProtocolVersion >= 4 {
+		buf = append(buf, byte(len(h.EncKey)))
+		buf = append(buf, h.EncKey...)
+	}
+
 	return buf, nil
 }
 
@@ -290,6 +357,49 @@ func DecodeHello(b []byte) (Hello, error) {
 	pubKey := ed25519.PublicKey(make([]byte, ed25519.PublicKeySize))
 	copy(pubKey, pub)
 
+	var height uint64
+	if pv >= 2 {
+		hv, err := readI64()
+		if err != nil {
+			return Hello{}, err
+		}
+		height = uint64(hv)
+	}
+
+	var addr string
+	if pv >= 3 {
+		addrLen, err := readU16()
+		if err != nil {
+			return Hello{}, err
+		}
+		if addrLen > maxPeerAddrLen {
+			return Hello{}, errors.New("invalid addr length")
+		}
+		addrBytes, err := readBytes(int(addrLen))
+		if err != nil {
+			return Hello{}, err
+		}
+		addr = string(addrBytes)
+	}
+
+	var encKey []byte
+	if pv >= 4 {
+		kl, err := readBytes(1)
+		if err != nil {
+			return Hello{}, err
+		}
+		if kl[0] != 0 && kl[0] != encKeySize {
+			return Hello{}, errors.New("invalid encryption key length")
+		}
+		kb, err := readBytes(int(kl[0]))
+		if err != nil {
+			return Hello{}, err
+		}
+		if len(kb) > 0 {
+			encKey = append([]byte(nil), kb...)
+		}
+	}
+
 	if off != len(b) {
 		return Hello{}, errors.New("hello payload has trailing bytes")
 	}
@@ -301,6 +411,9 @@ func DecodeHello(b []byte) (Hello, error) {
 		TimeUnixSec:     tsec,
 		Nonce:           nonce,
 		PublicKey:       pubKey,
+		Height:          height,
+		Addr:            addr,
+		EncKey:          encKey,
 	}, nil
 }
 
@@ -308,11 +421,12 @@ type HelloValidation struct {
 	NetworkID      string
 	MaxClockSkew   time.Duration
 	RequireNonZero bool
+	Clock          clock.Clock // nil means the system clock
 }
 
 func ValidateHello(h Hello, rules HelloValidation) error {
-	if h.ProtocolVersion != ProtocolVersion {
-		return fmt.Errorf("protocol version mismatch: got %d want %d", h.ProtocolVersion, ProtocolVersion)
+	if h.ProtocolVersion < MinProtocolVersion || h.ProtocolVersion > ProtocolVersion {
+		return fmt.Errorf("protocol version mismatch: got %d want %d..%d", h.ProtocolVersion, MinProtocolVersion, ProtocolVersion)
 	}
 	if sanitizeHelloString(h.NetworkID) == "" {
 		return errors.New("networkID is empty")
@@ -330,7 +444,7 @@ func ValidateHello(h Hello, rules HelloValidation) error {
 		}
 	}
 	if rules.MaxClockSkew > 0 {
-		now := time.Now().UTC()
+		now := clock.OrReal(rules.Clock).Now()
 		t := time.Unix(h.TimeUnixSec, 0).UTC()
 		d := now.Sub(t)
 		if d < 0 {
@@ -446,9 +560,11 @@ func DecodePeers(b []byte) ([]string, error) {
 // Challenge payload: [32] random bytes
 // Response payload: [32] challenge bytes + [64] ed25519 signature
 //
-// Signature message = SHA256( "veltaros-p2p-challenge" || networkID || challengeBytes )
+// Signature message = SHA256( "veltaros-p2p-challenge" || networkID || challengeBytes || sessionID )
 //
 // This binds proof to a specific network ID and prevents cross-network reuse.
+// sessionID is empty on plaintext connections; on encrypted ones it ties the
+// proof to the key exchange (see secure.go).
 
 const (
 	challengeSize     = 32
@@ -462,21 +578,22 @@ func NewChallenge() ([challengeSize]byte, error) {
 	return c, err
 }
 
-func ChallengeMessage(networkID string, challenge [challengeSize]byte) [32]byte {
-	// domain separation + bind network ID
+func ChallengeMessage(networkID string, challenge [challengeSize]byte, session []byte) [32]byte {
+	// domain separation + bind network ID (and session, if encrypted)
 	domain := []byte("veltaros-p2p-challenge")
-	msg := make([]byte, 0, len(domain)+len(networkID)+challengeSize)
+	msg := make([]byte, 0, len(domain)+len(networkID)+challengeSize+len(session))
 	msg = append(msg, domain...)
 	msg = append(msg, []byte(networkID)...)
 	msg = append(msg, challenge[:]...)
+	msg = append(msg, session...)
 	return vcrypto.Sha256(msg)
 }
 
-func SignChallenge(identityPriv ed25519.PrivateKey, networkID string, challenge [challengeSize]byte) ([]byte, error) {
+func SignChallenge(identityPriv ed25519.PrivateKey, networkID string, session []byte, challenge [challengeSize]byte) ([]byte, error) {
 	if len(identityPriv) != ed25519.PrivateKeySize {
 		return nil, errors.New("invalid identity private key size")
 	}
-	h := ChallengeMessage(networkID, challenge)
+	h := ChallengeMessage(networkID, challenge, session)
 	sig := ed25519.Sign(identityPriv, h[:])
 
 	out := make([]byte, 0, challengeRespSize)
@@ -485,7 +602,7 @@ func SignChallenge(identityPriv ed25519.PrivateKey, networkID string, challenge
 	return out, nil
 }
 
-func VerifyChallengeResp(pub ed25519.PublicKey, networkID string, resp []byte, expected [challengeSize]byte) error {
+func VerifyChallengeResp(pub ed25519.PublicKey, networkID string, session []byte, resp []byte, expected [challengeSize]byte) error {
 	if len(pub) != ed25519.PublicKeySize {
 		return errors.New("invalid public key size")
 	}
@@ -501,9 +618,65 @@ func VerifyChallengeResp(pub ed25519.PublicKey, networkID string, resp []byte, e
 	}
 
 	sig := resp[challengeSize:]
-	h := ChallengeMessage(networkID, expected)
+	h := ChallengeMessage(networkID, expected, session)
 	if !ed25519.Verify(pub, h[:], sig) {
 		return errors.New("invalid challenge signature")
 	}
 	return nil
 }
+
+// ---- Admission puzzle ----
+//
+// With Config.PuzzleBits set, a node appends the difficulty to the challenge
+// it sends inbound peers: [32] random bytes + [1] bits. Older peers reject a
+// challenge of that size, so the puzzle is only set at puzzleMinVersion and
+// up. The response must then carry an [8] nonce after the signature such
+// that
+//
+//	SHA256( challengeBytes || nonce )
+//
+// starts with that many zero bits, which costs the dialer about 2^bits
+// hashes per connection and the node one.
+
+const (
+	puzzleNonceSize = 8
+
+	// puzzleMinVersion is the first protocol version that can be set the
+	// puzzle.
+	puzzleMinVersion uint16 = 5
+
+	// MaxPuzzleBits is the hardest puzzle a node sets or solves; at 24 bits
+	// a solve takes a few seconds, within the handshake timeout.
+	MaxPuzzleBits = 24
+)
+
+// SolvePuzzle finds a nonce for challenge at the given difficulty in bits.
+func SolvePuzzle(challenge [challengeSize]byte, difficulty int) ([puzzleNonceSize]byte, error) {
+	var nonce [puzzleNonceSize]byte
+	if difficulty < 0 || difficulty > MaxPuzzleBits {
+		return nonce, fmt.Errorf("puzzle difficulty %d out of range (0..%d)", difficulty, MaxPuzzleBits)
+	}
+	buf := make([]byte, challengeSize+puzzleNonceSize)
+	copy(buf, challenge[:])
+	for n := uint64(0); ; n++ {
+		binary.LittleEndian.PutUint64(buf[challengeSize:], n)
+		if h := vcrypto.Sha256(buf); int(consensus.LeadingZeroBits(h)) >= difficulty {
+			copy(nonce[:], buf[challengeSize:])
+			return nonce, nil
+		}
+	}
+}
+
+// VerifyPuzzle checks that nonce solves challenge at difficulty bits.
+func VerifyPuzzle(challenge [challengeSize]byte, nonce []byte, difficulty int) error {
+	if len(nonce) != puzzleNonceSize {
+		return errors.New("invalid puzzle nonce size")
+	}
+	buf := make([]byte, 0, challengeSize+puzzleNonceSize)
+	buf = append(buf, challenge[:]...)
+	buf = append(buf, nonce...)
+	if int(consensus.LeadingZeroBits(vcrypto.Sha256(buf))) < difficulty {
+		return fmt.Errorf("puzzle solution below %d bits", difficulty)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package p2p
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// TxHandler admits a transaction relayed by a peer. It returns accepted=true
+// only for txs that were new to us (so they are re-relayed), and a non-nil
+// error when the payload is invalid (the sender is penalized).
+type TxHandler func(from string, payload []byte) (accepted bool, err error)
+
+// SetTxHandler installs the callback used for inbound MsgTx frames.
+func (n *Node) SetTxHandler(h TxHandler) {
+	n.txHandlerMu.Lock()
+	n.txHandler = h
+	n.txHandlerMu.Unlock()
+}
+
+// BroadcastTx relays a tx payload to all verified peers except the one at
+// exceptAddr (typically the peer we received it from).
+func (n *Node) BroadcastTx(payload []byte, exceptAddr string) int {
+	sent := 0
+	for _, conn := range n.verifiedConns() {
+		if conn.RemoteAddr().String() == exceptAddr {
+			continue
+		}
+		if err := n.send(conn, MsgTx, payload); err == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// newTxLimiter builds the per-connection tx budget: TxGossipBudget tokens
+// refilled evenly over TxGossipInterval.
+func (n *Node) newTxLimiter() *limiter {
+	budget := float64(n.cfg.TxGossipBudget)
+	return &limiter{
+		tokens:     budget,
+		last:       time.Now().UTC(),
+		rate:       budget / n.cfg.TxGossipInterval.Seconds(),
+		burst:      budget,
+		costPerMsg: 1.0,
+	}
+}
+
+func (n *Node) handleTxFrame(conn net.Conn, payload []byte) {
+	remote := conn.RemoteAddr().String()
+
+	// Verification is the expensive part, so the budget is checked first.
+	allowed := true
+	n.updatePeer(conn, func(p peerConn) peerConn {
+		if p.txLim != nil && !p.txLim.allow() {
+			allowed = false
+		}
+		return p
+	})
+	if !allowed {
+		n.penalize(remote, 1, "tx gossip budget exceeded")
+		return
+	}
+
+	n.txHandlerMu.RLock()
+	h := n.txHandler
+	n.txHandlerMu.RUnlock()
+	if h == nil {
+		return
+	}
+
+	accepted, err := h(remote, payload)
+	if err != nil {
+		n.penalize(remote, 2, "invalid tx: "+err.Error())
+		return
+	}
+	if accepted {
+		n.BroadcastTx(payload, remote)
+	}
+}
+
+// errNotNegotiated is returned by send for a message type newer than the
+// protocol version negotiated with the peer.
+var errNotNegotiated = errors.New("message type not supported at the peer's protocol version")
+
+// send writes a single frame to conn, serialized with any other writers.
+// Message types the peer's negotiated version predates are not sent.
+func (n *Node) send(conn net.Conn, msgType MessageType, payload []byte) error {
+	return n.sendBy(conn, msgType, payload, time.Now().Add(DefaultWriteTimeout))
+}
+
+// sendBy is send with an explicit write deadline.
+func (n *Node) sendBy(conn net.Conn, msgType MessageType, payload []byte, deadline time.Time) error {
+	mu, version := n.writeState(conn)
+	if version != 0 && version < minVersionFor(msgType) {
+		return errNotNegotiated
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFrame(&buf, msgType, payload); err != nil {
+		return err
+	}
+
+	if mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
+	_ = conn.SetWriteDeadline(deadline)
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	n.stats.framesWritten.Add(1)
+	return nil
+}
+
+func (n *Node) verifiedConns() []net.Conn {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	out := make([]net.Conn, 0, len(n.peers))
+	for _, p := range n.peers {
+		if p.verified {
+			out = append(out, p.conn)
+		}
+	}
+	return out
+}
+
+// writeState returns conn's write lock and negotiated protocol version (nil
+// and 0 for a connection that is not registered).
+func (n *Node) writeState(conn net.Conn) (*sync.Mutex, uint16) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if p, ok := n.peers[conn.RemoteAddr().String()]; ok {
+		return p.wmu, p.protoVersion
+	}
+	return nil, 0
+}
@@ -1,12 +1,14 @@
 package p2p
 
 import (
-	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/clock"
+	"github.com/VeltarosLabs/Veltaros/internal/storage"
 )
 
 // Production-minded scoring model:
@@ -33,13 +35,17 @@ type ScoreSnapshot struct {
 	LastUpdate time.Time `json:"lastUpdate"`
 }
 
+// scoreStoreFormat versions the peer score file ([]ScoreSnapshot).
+var scoreStoreFormat = storage.Format{Name: "score", Version: 1}
+
 type Scorer struct {
-	mu   sync.Mutex
-	cfg  ScoreConfig
-	data map[string]scoreEntry
+	mu    sync.Mutex
+	cfg   ScoreConfig
+	clock clock.Clock
+	data  map[string]scoreEntry
 }
 
-func NewScorer(cfg ScoreConfig) *Scorer {
+func NewScorer(cfg ScoreConfig, opts ...Option) *Scorer {
 	if cfg.DecayInterval <= 0 {
 		cfg.DecayInterval = 1 * time.Minute
 	}
@@ -53,8 +59,9 @@ func NewScorer(cfg ScoreConfig) *Scorer {
 		cfg.BanDuration = 30 * time.Minute
 	}
 	return &Scorer{
-		cfg:  cfg,
-		data: make(map[string]scoreEntry),
+		cfg:   cfg,
+		clock: applyOptions(opts).clock,
+		data:  make(map[string]scoreEntry),
 	}
 }
 
@@ -63,7 +70,7 @@ func (s *Scorer) Get(addr string) int {
 	defer s.mu.Unlock()
 
 	e := s.data[addr]
-	e = s.applyDecayLocked(e, time.Now().UTC())
+	e = s.applyDecayLocked(e, s.clock.Now())
 	s.data[addr] = e
 	return e.Score
 }
@@ -76,7 +83,7 @@ func (s *Scorer) Add(addr string, points int) (score int, banned bool, banFor ti
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	now := time.Now().UTC()
+	now := s.clock.Now()
 	e := s.data[addr]
 	e = s.applyDecayLocked(e, now)
 	e.Score += points
@@ -89,11 +96,18 @@ func (s *Scorer) Add(addr string, points int) (score int, banned bool, banFor ti
 	return e.Score, false, 0
 }
 
+// Forget clears addr's score.
+func (s *Scorer) Forget(addr string) {
+	s.mu.Lock()
+	delete(s.data, addr)
+	s.mu.Unlock()
+}
+
 func (s *Scorer) Snapshot() []ScoreSnapshot {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	now := time.Now().UTC()
+	now := s.clock.Now()
 	out := make([]ScoreSnapshot, 0, len(s.data))
 	for addr, e := range s.data {
 		if addr == "" {
@@ -125,11 +139,11 @@ func (s *Scorer) Load(path string) error {
 	}
 
 	var snaps []ScoreSnapshot
-	if err := json.Unmarshal(raw, &snaps); err != nil {
+	if err := scoreStoreFormat.Decode(raw, &snaps); err != nil {
 		return err
 	}
 
-	now := time.Now().UTC()
+	now := s.clock.Now()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -156,21 +170,7 @@ func (s *Scorer) Save(path string) error {
 		return err
 	}
 
-	data, err := json.MarshalIndent(snaps, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	tmp := path + ".tmp"
-	if err := os.WriteFile(tmp, data, 0o600); err != nil {
-		return err
-	}
-	if err := os.Rename(tmp, path); err != nil {
-		_ = os.Remove(tmp)
-		return err
-	}
-	_ = os.Chmod(path, 0o600)
-	return nil
+	return storage.WriteVersioned(path, scoreStoreFormat, snaps, false)
 }
 
 func (s *Scorer) applyDecayLocked(e scoreEntry, now time.Time) scoreEntry {
@@ -0,0 +1,62 @@
+package p2p
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/storage"
+)
+
+func TestScorerSaveLoadDecay(t *testing.T) {
+	cfg := ScoreConfig{DecayInterval: time.Minute, DecayAmount: 1, BanThreshold: 10}
+	path := filepath.Join(t.TempDir(), "scores.json")
+
+	s := NewScorer(cfg)
+	s.Add("203.0.113.1:30303", 5)
+	s.Add("203.0.113.2:30303", 2)
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Loaded at once, scores come back as saved.
+	s = NewScorer(cfg)
+	if err := s.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Get("203.0.113.1:30303"); got != 5 {
+		t.Errorf("score after load = %d, want 5", got)
+	}
+
+	// Time spent down counts towards decay: a store last updated three and
+	// a half intervals ago loads with three points recovered, and a peer
+	// that has recovered fully is forgotten.
+	aged := time.Now().UTC().Add(-3*time.Minute - 30*time.Second)
+	snaps := []ScoreSnapshot{
+		{Addr: "203.0.113.1:30303", Score: 5, LastUpdate: aged},
+		{Addr: "203.0.113.2:30303", Score: 2, LastUpdate: aged},
+	}
+	if err := storage.WriteVersioned(path, scoreStoreFormat, snaps, false); err != nil {
+		t.Fatal(err)
+	}
+	s = NewScorer(cfg)
+	if err := s.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Get("203.0.113.1:30303"); got != 2 {
+		t.Errorf("decayed score = %d, want 2", got)
+	}
+	if snaps := s.Snapshot(); len(snaps) != 1 || snaps[0].Addr != "203.0.113.1:30303" {
+		t.Errorf("snapshot = %+v, want only the first peer", snaps)
+	}
+}
+
+func TestScorerLoadMissingFile(t *testing.T) {
+	s := NewScorer(ScoreConfig{})
+	if err := s.Load(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Fatalf("Load of a missing store = %v, want nil", err)
+	}
+	if snaps := s.Snapshot(); len(snaps) != 0 {
+		t.Errorf("snapshot = %+v, want empty", snaps)
+	}
+}
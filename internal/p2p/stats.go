@@ -0,0 +1,36 @@
+package p2p
+
+import (
+	"bufio"
+	"sync/atomic"
+)
+
+// Stats are cumulative traffic and dial counters since the node started.
+type Stats struct {
+	DialFailures  uint64 `json:"dialFailures"`
+	FramesRead    uint64 `json:"framesRead"`
+	FramesWritten uint64 `json:"framesWritten"`
+}
+
+type nodeStats struct {
+	dialFailures  atomic.Uint64
+	framesRead    atomic.Uint64
+	framesWritten atomic.Uint64
+}
+
+func (n *Node) Stats() Stats {
+	return Stats{
+		DialFailures:  n.stats.dialFailures.Load(),
+		FramesRead:    n.stats.framesRead.Load(),
+		FramesWritten: n.stats.framesWritten.Load(),
+	}
+}
+
+// readFrame is ReadFrame counting each frame received.
+func (n *Node) readFrame(br *bufio.Reader) (Frame, error) {
+	f, err := ReadFrame(br)
+	if err == nil {
+		n.stats.framesRead.Add(1)
+	}
+	return f, err
+}
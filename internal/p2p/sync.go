@@ -0,0 +1,228 @@
+package p2p
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+)
+
+const (
+	// maxSyncBlocks caps one MsgBlocks batch; maxSyncPayload keeps the
+	// encoded batch well inside MaxFrameSize.
+	maxSyncBlocks  = 64
+	maxSyncPayload = MaxFrameSize / 2
+
+	// syncTimeout frees the sync slot if a peer never answers.
+	syncTimeout = 30 * time.Second
+)
+
+// GetBlocks is the MsgGetBlocks payload: up to Count blocks starting at
+// FromHeight.
+type GetBlocks struct {
+	FromHeight uint64 `json:"fromHeight"`
+	Count      int    `json:"count"`
+}
+
+// Blocks is the MsgBlocks payload. Blocks[i] is at FromHeight+i; Height is
+// the sender's chain height, so an empty reply still tells us where it is.
+type Blocks struct {
+	Height     uint64             `json:"height"`
+	FromHeight uint64             `json:"fromHeight"`
+	Blocks     []blockchain.Block `json:"blocks"`
+}
+
+// BlockSource serves our chain to syncing peers.
+type BlockSource interface {
+	Height() uint64
+	BlocksFrom(height uint64, limit int) []blockchain.StoredBlock
+}
+
+// SetBlockSource enables serving MsgGetBlocks and syncing from peers that
+// are ahead.
+func (n *Node) SetBlockSource(src BlockSource) {
+	n.syncMu.Lock()
+	n.blockSource = src
+	n.syncMu.Unlock()
+}
+
+func (n *Node) source() BlockSource {
+	n.syncMu.Lock()
+	defer n.syncMu.Unlock()
+	return n.blockSource
+}
+
+// SyncFrom asks peerAddr for the blocks after our tip. Only one sync runs at
+// a time; it continues batch by batch while the peer stays ahead. It returns
+// false if a sync is already in progress or the peer is not connected or
+// predates block sync.
+func (n *Node) SyncFrom(peerAddr string) bool {
+	src := n.source()
+	if src == nil {
+		return false
+	}
+
+	n.mu.RLock()
+	p, ok := n.peers[peerAddr]
+	n.mu.RUnlock()
+	if !ok || !p.verified || p.protoVersion < minVersionFor(MsgGetBlocks) {
+		return false
+	}
+
+	n.syncMu.Lock()
+	if n.syncPeer != "" && n.syncPeer != peerAddr && time.Since(n.syncSince) < syncTimeout {
+		n.syncMu.Unlock()
+		return false
+	}
+	n.syncPeer = peerAddr
+	n.syncSince = time.Now()
+	n.syncMu.Unlock()
+
+	payload, err := json.Marshal(GetBlocks{FromHeight: src.Height() + 1, Count: maxSyncBlocks})
+	if err != nil {
+		n.endSync(peerAddr)
+		return false
+	}
+	if err := n.send(p.conn, MsgGetBlocks, payload); err != nil {
+		n.endSync(peerAddr)
+		return false
+	}
+	return true
+}
+
+func (n *Node) endSync(peerAddr string) {
+	n.syncMu.Lock()
+	if n.syncPeer == peerAddr {
+		n.syncPeer = ""
+	}
+	n.syncMu.Unlock()
+}
+
+// syncBest starts a sync from the verified peer with the highest known
+// height, if it is ahead of us.
+func (n *Node) syncBest() {
+	src := n.source()
+	if src == nil {
+		return
+	}
+	ours := src.Height()
+
+	best, bestHeight := "", ours
+	n.mu.RLock()
+	for addr, p := range n.peers {
+		if p.verified && p.bestHeight > bestHeight {
+			best, bestHeight = addr, p.bestHeight
+		}
+	}
+	n.mu.RUnlock()
+
+	if best != "" {
+		n.SyncFrom(best)
+	}
+}
+
+func (n *Node) handleGetBlocksFrame(conn net.Conn, payload []byte) {
+	remote := conn.RemoteAddr().String()
+
+	var req GetBlocks
+	if err := json.Unmarshal(payload, &req); err != nil || req.Count <= 0 {
+		n.penalize(remote, 3, "invalid getblocks")
+		return
+	}
+	src := n.source()
+	if src == nil {
+		return
+	}
+
+	resp := Blocks{Height: src.Height(), FromHeight: req.FromHeight, Blocks: []blockchain.Block{}}
+	size := 0
+	for _, sb := range src.BlocksFrom(req.FromHeight, min(req.Count, maxSyncBlocks)) {
+		raw, err := json.Marshal(sb.Block)
+		if err != nil {
+			break
+		}
+		if size+len(raw) > maxSyncPayload && len(resp.Blocks) > 0 {
+			break
+		}
+		size += len(raw)
+		resp.Blocks = append(resp.Blocks, sb.Block)
+	}
+
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	_ = n.send(conn, MsgBlocks, out)
+}
+
+func (n *Node) handleBlocksFrame(conn net.Conn, payload []byte) {
+	remote := conn.RemoteAddr().String()
+
+	n.syncMu.Lock()
+	solicited := n.syncPeer == remote
+	n.syncMu.Unlock()
+	if !solicited {
+		n.penalize(remote, 1, "unsolicited blocks")
+		return
+	}
+
+	var resp Blocks
+	if err := json.Unmarshal(payload, &resp); err != nil || len(resp.Blocks) > maxSyncBlocks {
+		n.endSync(remote)
+		n.penalize(remote, 3, "invalid blocks reply")
+		return
+	}
+	n.notePeerHeight(conn, resp.Height)
+
+	if err := checkContiguous(resp.Blocks); err != nil {
+		n.endSync(remote)
+		n.penalize(remote, 3, "blocks reply: "+err.Error())
+		return
+	}
+
+	n.blockHandlerMu.RLock()
+	h := n.blockHandler
+	n.blockHandlerMu.RUnlock()
+
+	linked := 0
+	if h != nil {
+		for i, b := range resp.Blocks {
+			err := h(remote, resp.FromHeight+uint64(i), b)
+			if errors.Is(err, blockchain.ErrKnownBlock) {
+				continue
+			}
+			if errors.Is(err, blockchain.ErrUnknownParent) {
+				// The batch does not build on our tip: the peer is on
+				// another branch, which is not a protocol violation.
+				n.log.Debug("sync batch does not extend our tip", "remote", remote, "height", resp.FromHeight+uint64(i))
+				break
+			}
+			if err != nil {
+				n.endSync(remote)
+				n.penalize(remote, 3, "invalid synced block: "+err.Error())
+				return
+			}
+			linked++
+		}
+	}
+
+	n.endSync(remote)
+	if linked > 0 {
+		n.log.Info("synced blocks", "remote", remote, "count", linked, "peerHeight", resp.Height)
+		// Keep going (from this or a better peer) while someone is ahead.
+		n.syncBest()
+	}
+}
+
+// checkContiguous rejects a batch with gaps: each block must name the
+// previous one as its parent.
+func checkContiguous(blocks []blockchain.Block) error {
+	for i := 1; i < len(blocks); i++ {
+		if blocks[i].Header.PrevHash != blocks[i-1].Header.Hash() {
+			return errors.New("blocks are not contiguous")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package p2p
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VeltarosLabs/Veltaros/internal/blockchain"
+	"github.com/VeltarosLabs/Veltaros/internal/ledger"
+)
+
+// oldBlocks builds count blocks on genesis, each with one transfer from
+// priv, all dated age ago.
+func oldBlocks(t *testing.T, networkID string, priv ed25519.PrivateKey, age time.Duration, count int) []blockchain.Block {
+	t.Helper()
+	from, err := blockchain.AddressFromEd25519PublicKeyHex(hex.EncodeToString(priv.Public().(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, toPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := blockchain.AddressFromEd25519PublicKeyHex(hex.EncodeToString(toPriv.Public().(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := blockchain.NewGenesisBlock().Header.Hash()
+	out := make([]blockchain.Block, 0, count)
+	for i := range count {
+		tx, err := blockchain.SignDraft(priv, blockchain.TxDraft{
+			NetworkID: networkID,
+			From:      from,
+			To:        to,
+			Amount:    100,
+			Fee:       1,
+			Nonce:     uint64(i + 1),
+			Timestamp: time.Now().Add(-age).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := blockchain.BuildBlock(prev, []blockchain.SignedTx{tx}, nil, time.Now().Add(-age))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, b)
+		prev = b.Header.Hash()
+	}
+	return out
+}
+
+func TestSyncBlocksOlderThanTxWindow(t *testing.T) {
+	dir := t.TempDir()
+	n := newTestNode(t, testConfig(t, dir))
+	chain := blockchain.New(
+		filepath.Join(dir, "nonces.json"),
+		filepath.Join(dir, "blocks.json"),
+		filepath.Join(dir, "mempool.json"),
+	)
+	led := ledger.New(filepath.Join(dir, "ledger.json"))
+	n.SetBlockSource(chain)
+	n.SetBlockHandler(func(_ string, _ uint64, b blockchain.Block) error {
+		_, err := chain.AcceptBlock(b, led)
+		return err
+	})
+	if err := n.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	senderPub, sender, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := blockchain.AddressFromEd25519PublicKeyHex(hex.EncodeToString(senderPub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := led.FaucetCredit(from, 1_000); err != nil {
+		t.Fatal(err)
+	}
+	blocks := oldBlocks(t, n.cfg.NetworkID, sender, 48*time.Hour, 2)
+
+	p := dialRaw(t, n, 2)
+	if _, err := p.prove(t, n.cfg.NetworkID, nil); err != nil {
+		t.Fatal(err)
+	}
+	local := p.conn.LocalAddr().String()
+	waitVerified(t, n, p.conn.LocalAddr())
+	if !n.SyncFrom(local) {
+		t.Fatal("sync not started")
+	}
+
+	for {
+		f, err := ReadFrame(p.br)
+		if err != nil {
+			t.Fatalf("waiting for getblocks: %v", err)
+		}
+		if f.Type == MsgGetBlocks {
+			break
+		}
+	}
+	payload, err := json.Marshal(Blocks{Height: uint64(len(blocks)), FromHeight: 1, Blocks: blocks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.write(t, MsgBlocks, payload)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for chain.Height() < uint64(len(blocks)) {
+		if time.Now().After(deadline) {
+			t.Fatalf("synced to height %d, want %d", chain.Height(), len(blocks))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := n.scorer.Get(local); got != 0 {
+		t.Errorf("peer serving old blocks penalized: score %d", got)
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"sync/atomic"
+)
+
+// BackupSuffix names the copy of the previous file kept by WriteFileAtomic.
+const BackupSuffix = ".bak"
+
+var fallbackLog atomic.Pointer[slog.Logger]
+
+// SetLogger sets where ReadFileWithFallback reports a fallback to the
+// backup (slog.Default if never set).
+func SetLogger(log *slog.Logger) {
+	fallbackLog.Store(log)
+}
+
+func logger() *slog.Logger {
+	if l := fallbackLog.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// WriteFileAtomic replaces path with data via a temp file and rename, so a
+// crash leaves either the old or the new contents. With keepBackup, the file
+// being replaced is first kept as path+BackupSuffix (one generation).
+func WriteFileAtomic(path string, data []byte, keepBackup bool) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	if keepBackup {
+		if err := backup(path); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	_ = os.Chmod(path, 0o600)
+	return nil
+}
+
+// backup hard-links the current file to the backup name (falling back to a
+// copy), so path itself is never missing.
+func backup(path string) error {
+	bak := path + BackupSuffix
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := os.Remove(bak); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Link(path, bak); err == nil {
+		return nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(bak, raw, 0o600)
+}
+
+// ReadFileWithFallback reads path and passes it to decode. If the file
+// cannot be read or decoded but path+BackupSuffix decodes, the backup is
+// used instead and a warning is logged. A missing primary with no backup
+// returns an error satisfying errors.Is(err, os.ErrNotExist).
+func ReadFileWithFallback(path string, decode func([]byte) error) error {
+	raw, err := os.ReadFile(path)
+	if err == nil {
+		err = decode(raw)
+	}
+	if err == nil {
+		return nil
+	}
+
+	bakRaw, bakErr := os.ReadFile(path + BackupSuffix)
+	if bakErr != nil {
+		return err
+	}
+	if bakErr := decode(bakRaw); bakErr != nil {
+		return err
+	}
+	logger().Warn("store unreadable, loaded backup", "path", path, "backup", path+BackupSuffix, "err", err)
+	return nil
+}
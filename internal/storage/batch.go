@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Batch replaces several files together. Commit first writes and fsyncs a
+// temp file for every entry; only if all of them succeed are they renamed
+// into place, so a failed write (disk full, bad permissions) leaves every
+// file as it was rather than some old and some new.
+//
+// The renames are not one atomic step, so Commit keeps the file each one
+// replaces as <path>.prev and records the batch in a journal next to the
+// first file (<first>.batch) before renaming anything. A failed rename
+// restores every file from its .prev; a crash between renames leaves the
+// journal behind, and RecoverBatch does the same restore at the next start.
+// The journal is removed, and the directories fsynced, once every file is
+// in place.
+type Batch struct {
+	writes []batchWrite
+	hooks  []func()
+}
+
+type batchWrite struct {
+	path       string
+	data       []byte
+	keepBackup bool
+}
+
+// Add queues data to replace path on Commit. With keepBackup, the file being
+// replaced is kept as path+BackupSuffix, as with WriteFileAtomic.
+func (b *Batch) Add(path string, data []byte, keepBackup bool) {
+	b.writes = append(b.writes, batchWrite{path: filepath.Clean(path), data: data, keepBackup: keepBackup})
+}
+
+// AddVersioned encodes v with f and queues it like Add.
+func (b *Batch) AddVersioned(path string, f Format, v any, keepBackup bool) error {
+	data, err := f.Encode(v)
+	if err != nil {
+		return err
+	}
+	b.Add(path, data, keepBackup)
+	return nil
+}
+
+// OnCommit registers fn to run once a Commit has replaced every queued file,
+// e.g. to discard a log the new files make redundant. It is not run if the
+// Commit fails.
+func (b *Batch) OnCommit(fn func()) {
+	b.hooks = append(b.hooks, fn)
+}
+
+// Len is the number of queued files.
+func (b *Batch) Len() int { return len(b.writes) }
+
+// Commit writes the queued files in the order they were added, then empties
+// the batch and runs its OnCommit hooks. If any file cannot be written or
+// replaced, every file is left (or put back) as it was and the error names
+// the one that failed.
+func (b *Batch) Commit() error {
+	writes, hooks := b.writes, b.hooks
+	b.writes, b.hooks = nil, nil
+	if len(writes) == 0 {
+		return nil
+	}
+
+	written := 0
+	removeTemps := func() {
+		for _, w := range writes[:written] {
+			_ = os.Remove(w.path + ".tmp")
+		}
+	}
+	for _, w := range writes {
+		if err := writeSynced(w.path+".tmp", w.data); err != nil {
+			_ = os.Remove(w.path + ".tmp")
+			removeTemps()
+			return fmt.Errorf("write %s: %w", w.path, err)
+		}
+		written++
+	}
+
+	j := batchJournal{Files: make([]journalFile, len(writes))}
+	for i, w := range writes {
+		existed, err := keepPrevious(w.path)
+		if err != nil {
+			removeTemps()
+			return fmt.Errorf("keep %s: %w", w.path, err)
+		}
+		j.Files[i] = journalFile{Path: w.path, Existed: existed}
+	}
+	journal := writes[0].path + batchJournalSuffix
+	raw, err := json.Marshal(j)
+	if err == nil {
+		err = writeSynced(journal, raw)
+	}
+	if err == nil {
+		err = syncDir(filepath.Dir(journal))
+	}
+	if err != nil {
+		_ = os.Remove(journal)
+		removeTemps()
+		return fmt.Errorf("write batch journal: %w", err)
+	}
+
+	for _, w := range writes {
+		err := func() error {
+			if w.keepBackup {
+				if err := backup(w.path); err != nil {
+					return fmt.Errorf("back up %s: %w", w.path, err)
+				}
+			}
+			if err := renameFile(w.path+".tmp", w.path); err != nil {
+				return fmt.Errorf("replace %s: %w", w.path, err)
+			}
+			return nil
+		}()
+		if err != nil {
+			if rerr := rollBack(journal, j); rerr != nil {
+				return fmt.Errorf("%w (roll back: %v)", err, rerr)
+			}
+			return err
+		}
+		_ = os.Chmod(w.path, 0o600)
+	}
+
+	for _, dir := range j.dirs() {
+		if err := syncDir(dir); err != nil {
+			// The renames are done; only their durability is in doubt.
+			// Keeping the journal lets the next start roll them back.
+			return fmt.Errorf("sync %s: %w", dir, err)
+		}
+	}
+	if err := os.Remove(journal); err != nil {
+		return fmt.Errorf("remove batch journal: %w", err)
+	}
+	_ = syncDir(filepath.Dir(journal))
+	for _, f := range j.Files {
+		_ = os.Remove(f.Path + prevSuffix)
+	}
+	for _, fn := range hooks {
+		fn()
+	}
+	return nil
+}
+
+const (
+	// batchJournalSuffix names the journal Commit keeps next to a batch's
+	// first file while replacing its files.
+	batchJournalSuffix = ".batch"
+	// prevSuffix names the file a Commit in progress replaced.
+	prevSuffix = ".prev"
+)
+
+// renameFile is os.Rename; tests replace it to fail or stop a Commit midway.
+var renameFile = os.Rename
+
+type batchJournal struct {
+	Files []journalFile `json:"files"`
+}
+
+type journalFile struct {
+	Path    string `json:"path"`
+	Existed bool   `json:"existed"` // false: roll back by removing Path
+}
+
+func (j batchJournal) dirs() []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, f := range j.Files {
+		if d := filepath.Dir(f.Path); !seen[d] {
+			seen[d] = true
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// RecoverBatch finishes off a Commit that stopped part-way (a crash between
+// its renames) whose first file was first: every file of the batch is put
+// back as it was before it, and the journal is removed. It reports whether
+// there was anything to recover. Call it at startup, before loading any of
+// the batch's files.
+func RecoverBatch(first string) (bool, error) {
+	journal := filepath.Clean(first) + batchJournalSuffix
+	raw, err := os.ReadFile(journal)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	var j batchJournal
+	if err := json.Unmarshal(raw, &j); err != nil {
+		// A torn journal: the crash came before any rename, which waits
+		// for the journal to be synced.
+		return true, os.Remove(journal)
+	}
+	return true, rollBack(journal, j)
+}
+
+// rollBack puts every file of j back as it was before the batch, removes its
+// temp files, and then the journal.
+func rollBack(journal string, j batchJournal) error {
+	var errs []error
+	for _, f := range j.Files {
+		_ = os.Remove(f.Path + ".tmp")
+		if !f.Existed {
+			if err := os.Remove(f.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		// A file whose .prev is gone was already put back. For one never
+		// replaced, .prev links the same file, and rename(2) leaves both
+		// names in place; the Remove drops the extra one.
+		if err := os.Rename(f.Path+prevSuffix, f.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		_ = os.Remove(f.Path + prevSuffix)
+	}
+	for _, dir := range j.dirs() {
+		if err := syncDir(dir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if err := os.Remove(journal); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// keepPrevious links path to path+prevSuffix (copying if links are not
+// supported) and reports whether path existed.
+func keepPrevious(path string) (bool, error) {
+	prev := path + prevSuffix
+	if err := os.Remove(prev); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := os.Link(path, prev); err == nil {
+		return true, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return true, writeSynced(prev, raw)
+}
+
+// syncDir fsyncs dir so the renames in it survive a power loss.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func writeSynced(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// batchFiles creates a, b and c in a temp dir holding "old <name>", except
+// c, which does not exist yet.
+func batchFiles(t *testing.T) (dir string, paths []string) {
+	t.Helper()
+	dir = t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("old "+name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, []string{filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "c")}
+}
+
+func newBatch(paths []string) *Batch {
+	var b Batch
+	for _, p := range paths {
+		b.Add(p, []byte("new "+filepath.Base(p)), true)
+	}
+	return &b
+}
+
+// wantContents checks each path holds "<state> <name>", or is missing for
+// state "".
+func wantContents(t *testing.T, state string, paths ...string) {
+	t.Helper()
+	for _, p := range paths {
+		raw, err := os.ReadFile(p)
+		if state == "" {
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("%s exists (%q), want it missing", filepath.Base(p), raw)
+			}
+			continue
+		}
+		if want := state + " " + filepath.Base(p); string(raw) != want {
+			t.Errorf("%s = %q (%v), want %q", filepath.Base(p), raw, err, want)
+		}
+	}
+}
+
+// wantClean checks no temp, .prev or journal file is left in dir.
+func wantClean(t *testing.T, dir string) {
+	t.Helper()
+	for _, pat := range []string{"*.tmp", "*" + prevSuffix, "*" + batchJournalSuffix} {
+		if left, _ := filepath.Glob(filepath.Join(dir, pat)); len(left) > 0 {
+			t.Errorf("left behind: %v", left)
+		}
+	}
+}
+
+func TestBatchCommit(t *testing.T) {
+	dir, paths := batchFiles(t)
+	b := newBatch(paths)
+	ran := false
+	b.OnCommit(func() { ran = true })
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	wantContents(t, "new", paths...)
+	for _, p := range paths[:2] {
+		if raw, _ := os.ReadFile(p + BackupSuffix); string(raw) != "old "+filepath.Base(p) {
+			t.Errorf("%s%s = %q, want the replaced file", filepath.Base(p), BackupSuffix, raw)
+		}
+	}
+	wantClean(t, dir)
+	if !ran {
+		t.Error("OnCommit hook not run")
+	}
+}
+
+func TestBatchFailedWriteChangesNothing(t *testing.T) {
+	_, paths := batchFiles(t)
+	// b's temp file cannot be created: its name is taken by a directory.
+	if err := os.Mkdir(paths[1]+".tmp", 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(paths[1]+".tmp", "x"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	b := newBatch(paths)
+	b.OnCommit(func() { t.Error("OnCommit hook run after a failed commit") })
+	if err := b.Commit(); err == nil {
+		t.Fatal("commit succeeded")
+	}
+	wantContents(t, "old", paths[0], paths[1])
+	wantContents(t, "", paths[2])
+	for _, p := range []string{paths[0] + ".tmp", paths[2] + ".tmp", paths[0] + batchJournalSuffix} {
+		if _, err := os.Stat(p); err == nil {
+			t.Errorf("%s left behind", filepath.Base(p))
+		}
+	}
+}
+
+func TestBatchFailedRenameRollsBack(t *testing.T) {
+	dir, paths := batchFiles(t)
+	renames := 0
+	renameFile = func(from, to string) error {
+		if renames++; renames == 2 {
+			return errors.New("injected rename failure")
+		}
+		return os.Rename(from, to)
+	}
+	t.Cleanup(func() { renameFile = os.Rename })
+
+	b := newBatch(paths)
+	b.OnCommit(func() { t.Error("OnCommit hook run after a failed commit") })
+	if err := b.Commit(); err == nil {
+		t.Fatal("commit succeeded")
+	}
+	// a was already replaced when b failed; it is put back.
+	wantContents(t, "old", paths[0], paths[1])
+	wantContents(t, "", paths[2])
+	wantClean(t, dir)
+}
+
+func TestRecoverBatchAfterCrash(t *testing.T) {
+	dir, paths := batchFiles(t)
+	// Crash after the first two renames: c never got its new file.
+	crash := errors.New("crash")
+	renames := 0
+	renameFile = func(from, to string) error {
+		if renames++; renames == 3 {
+			panic(crash)
+		}
+		return os.Rename(from, to)
+	}
+	t.Cleanup(func() { renameFile = os.Rename })
+	func() {
+		defer func() {
+			if r := recover(); r != crash {
+				panic(r)
+			}
+		}()
+		_ = newBatch(paths).Commit()
+	}()
+	renameFile = os.Rename
+	wantContents(t, "new", paths[0], paths[1])
+
+	undone, err := RecoverBatch(paths[0])
+	if err != nil || !undone {
+		t.Fatalf("RecoverBatch = %v, %v; want true, nil", undone, err)
+	}
+	wantContents(t, "old", paths[0], paths[1])
+	wantContents(t, "", paths[2])
+	wantClean(t, dir)
+
+	// Nothing left to recover; the next commit goes through.
+	if undone, err := RecoverBatch(paths[0]); err != nil || undone {
+		t.Fatalf("second RecoverBatch = %v, %v; want false, nil", undone, err)
+	}
+	if err := newBatch(paths).Commit(); err != nil {
+		t.Fatal(err)
+	}
+	wantContents(t, "new", paths...)
+}
+
+func TestRecoverBatchTornJournal(t *testing.T) {
+	dir, paths := batchFiles(t)
+	if err := os.WriteFile(paths[0]+batchJournalSuffix, []byte(`{"files":[{"pa`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if undone, err := RecoverBatch(paths[0]); err != nil || !undone {
+		t.Fatalf("RecoverBatch = %v, %v; want true, nil", undone, err)
+	}
+	wantContents(t, "old", paths[0], paths[1])
+	wantClean(t, dir)
+}
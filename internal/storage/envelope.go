@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Stores are persisted as an envelope recording their format version:
+//
+//	{"version": N, "data": <store payload>}
+//
+// Files written before the envelope existed hold the bare payload; they
+// decode as version 1.
+
+// ErrUnsupportedVersion is returned for a store written by a newer format
+// than this build knows.
+var ErrUnsupportedVersion = errors.New("unsupported store version")
+
+type envelope struct {
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Migration rewrites a store payload from one version to the next.
+type Migration func(data json.RawMessage) (json.RawMessage, error)
+
+// Format describes one store's on-disk format: its current Version and the
+// Migrations that bring older payloads up to it, keyed by the version they
+// upgrade from.
+type Format struct {
+	Name       string
+	Version    int
+	Migrations map[int]Migration
+}
+
+// Encode wraps v in an envelope at f.Version.
+func (f Format) Encode(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(envelope{Version: f.Version, Data: data}, "", "  ")
+}
+
+// Decode unwraps raw, migrates its payload up to f.Version and unmarshals it
+// into v. A bare (pre-envelope) payload is treated as version 1.
+func (f Format) Decode(raw []byte, v any) error {
+	version, data, err := unwrap(raw)
+	if err != nil {
+		return fmt.Errorf("%s store: %w", f.Name, err)
+	}
+	if version < 1 || version > f.Version {
+		return fmt.Errorf("%s store: %w %d (this build reads up to %d)", f.Name, ErrUnsupportedVersion, version, f.Version)
+	}
+	for ; version < f.Version; version++ {
+		migrate, ok := f.Migrations[version]
+		if !ok {
+			return fmt.Errorf("%s store: no migration from version %d", f.Name, version)
+		}
+		if data, err = migrate(data); err != nil {
+			return fmt.Errorf("%s store: migrate from version %d: %w", f.Name, version, err)
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+func unwrap(raw []byte) (int, json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return 1, trimmed, nil
+	}
+	var env envelope
+	if err := json.Unmarshal(trimmed, &env); err != nil {
+		return 0, nil, err
+	}
+	if env.Data == nil {
+		return 0, nil, errors.New("envelope has no data")
+	}
+	return env.Version, env.Data, nil
+}
+
+// WriteVersioned encodes v with f and writes it with WriteFileAtomic.
+func WriteVersioned(path string, f Format, v any, keepBackup bool) error {
+	data, err := f.Encode(v)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, data, keepBackup)
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RuntimeMeta is the persisted node run history. Counters accumulate across
+// restarts and are cleared only by RuntimeTracker.Reset.
+type RuntimeMeta struct {
+	// Starts counts node starts since the counters were last reset; Restarts
+	// is Starts minus the first one.
+	Starts   uint64 `json:"starts"`
+	Restarts uint64 `json:"restarts"`
+
+	// UncleanStops counts starts whose previous run did not reach Close
+	// (crash, kill -9, power loss). A climbing value indicates a crash loop.
+	UncleanStops uint64 `json:"uncleanStops"`
+
+	FirstStart time.Time `json:"firstStart"`
+	LastStart  time.Time `json:"lastStart"`
+	LastSeen   time.Time `json:"lastSeen"`
+	ResetAt    time.Time `json:"resetAt,omitzero"`
+
+	// CumulativeUptimeSec is the total uptime over all runs, including the
+	// current one as of LastSeen.
+	CumulativeUptimeSec int64 `json:"cumulativeUptimeSec"`
+
+	// Running is true while a node owns the file; Close clears it.
+	Running bool `json:"running"`
+}
+
+// RuntimeTracker maintains RuntimeMeta for the running node. The file is
+// rewritten atomically on Start, on each Flush and on Close; uptime since the
+// last Flush is lost if the process dies.
+type RuntimeTracker struct {
+	mu         sync.Mutex
+	path       string
+	keepBackup bool
+
+	meta       RuntimeMeta
+	baseUptime int64     // CumulativeUptimeSec from earlier runs
+	countFrom  time.Time // when this run began accruing into the counters
+}
+
+func NewRuntimeTracker(path string, keepBackup bool) *RuntimeTracker {
+	return &RuntimeTracker{path: filepath.Clean(path), keepBackup: keepBackup}
+}
+
+// Start loads the previous metadata (a missing file starts fresh), records
+// this start and persists it.
+func (t *RuntimeTracker) Start(now time.Time) error {
+	var meta RuntimeMeta
+	err := ReadFileWithFallback(t.path, func(raw []byte) error {
+		meta = RuntimeMeta{}
+		return json.Unmarshal(raw, &meta)
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if meta.Running {
+		meta.UncleanStops++
+	}
+	if meta.Starts > 0 {
+		meta.Restarts++
+	}
+	meta.Starts++
+	if meta.FirstStart.IsZero() {
+		meta.FirstStart = now
+	}
+	meta.LastStart = now
+	meta.LastSeen = now
+	meta.Running = true
+
+	t.meta = meta
+	t.baseUptime = meta.CumulativeUptimeSec
+	t.countFrom = now
+	return t.saveLocked()
+}
+
+// Flush folds the current run's uptime into the counters and persists them.
+func (t *RuntimeTracker) Flush(now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.advanceLocked(now)
+	return t.saveLocked()
+}
+
+// Close records a clean stop.
+func (t *RuntimeTracker) Close(now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.advanceLocked(now)
+	t.meta.Running = false
+	return t.saveLocked()
+}
+
+// Reset clears the cumulative counters (starts, restarts, unclean stops,
+// uptime). The current run keeps going and counts as the first start; its
+// uptime before the reset is not counted.
+func (t *RuntimeTracker) Reset(now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.meta.Starts = 1
+	t.meta.Restarts = 0
+	t.meta.UncleanStops = 0
+	t.meta.CumulativeUptimeSec = 0
+	t.meta.FirstStart = t.meta.LastStart
+	t.meta.LastSeen = now
+	t.meta.ResetAt = now
+	t.baseUptime = 0
+	t.countFrom = now
+	return t.saveLocked()
+}
+
+// Snapshot returns the metadata with uptime current as of now.
+func (t *RuntimeTracker) Snapshot(now time.Time) RuntimeMeta {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m := t.meta
+	m.CumulativeUptimeSec = t.baseUptime + int64(now.Sub(t.countFrom).Seconds())
+	return m
+}
+
+func (t *RuntimeTracker) advanceLocked(now time.Time) {
+	t.meta.CumulativeUptimeSec = t.baseUptime + int64(now.Sub(t.countFrom).Seconds())
+	t.meta.LastSeen = now
+}
+
+func (t *RuntimeTracker) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(t.meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(t.path, b, t.keepBackup)
+}
@@ -0,0 +1,184 @@
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Encrypted key files are a JSON envelope around the 64-byte ed25519 private
+// key:
+//
+//	key        = PBKDF2-HMAC-SHA256(passphrase, salt, iterations, 32 bytes)
+//	ciphertext = AES-256-GCM(key, nonce, priv)
+//
+// The KDF and cipher are the ones the web wallet vault uses; both are in the
+// standard library, so the node and CLI keep no third-party dependencies. The
+// salt, nonce and iteration count travel in the envelope, so the cost can be
+// raised later without breaking existing files.
+
+const (
+	KeystoreVersion = 1
+
+	// DefaultKDFIterations follows current OWASP guidance for PBKDF2-SHA256.
+	DefaultKDFIterations = 600_000
+
+	// maxKDFIterations bounds the work a crafted key file can demand.
+	maxKDFIterations = 10_000_000
+
+	keystoreKDF    = "pbkdf2-sha256"
+	keystoreCipher = "aes-256-gcm"
+	keystoreSalt   = 16
+)
+
+var (
+	// ErrWrongPassphrase is returned by LoadEncrypted when the ciphertext does
+	// not authenticate: the passphrase is wrong or the file was altered.
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted key file")
+
+	ErrEmptyPassphrase = errors.New("passphrase must not be empty")
+)
+
+type keystoreFile struct {
+	Version    int          `json:"version"`
+	Address    string       `json:"address"`
+	KDF        string       `json:"kdf"`
+	KDFParams  keystoreKDFP `json:"kdfParams"`
+	Cipher     string       `json:"cipher"`
+	Nonce      string       `json:"nonce"`
+	Ciphertext string       `json:"ciphertext"`
+}
+
+type keystoreKDFP struct {
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+}
+
+// SaveEncrypted writes priv to path encrypted under passphrase (0600, atomic).
+func SaveEncrypted(path string, priv ed25519.PrivateKey, passphrase []byte) error {
+	if len(priv) != ed25519.PrivateKeySize {
+		return errors.New("invalid ed25519 private key size")
+	}
+	if len(passphrase) == 0 {
+		return ErrEmptyPassphrase
+	}
+	addr, err := AddressFromPublicKey(priv.Public().(ed25519.PublicKey))
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, keystoreSalt)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	aead, err := keystoreAEAD(passphrase, salt, DefaultKDFIterations)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	f := keystoreFile{
+		Version: KeystoreVersion,
+		Address: addr,
+		KDF:     keystoreKDF,
+		KDFParams: keystoreKDFP{
+			Iterations: DefaultKDFIterations,
+			Salt:       hex.EncodeToString(salt),
+		},
+		Cipher:     keystoreCipher,
+		Nonce:      hex.EncodeToString(nonce),
+		Ciphertext: hex.EncodeToString(aead.Seal(nil, nonce, priv, []byte(addr))),
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(path, data)
+}
+
+// LoadEncrypted decrypts a key file written by SaveEncrypted. A wrong
+// passphrase returns ErrWrongPassphrase.
+func LoadEncrypted(path string, passphrase []byte) (ed25519.PrivateKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f keystoreFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("invalid encrypted key file: %w", err)
+	}
+	if f.Version != KeystoreVersion {
+		return nil, fmt.Errorf("unsupported key file version: %d", f.Version)
+	}
+	if f.KDF != keystoreKDF || f.Cipher != keystoreCipher {
+		return nil, fmt.Errorf("unsupported key file kdf/cipher: %s/%s", f.KDF, f.Cipher)
+	}
+	if f.KDFParams.Iterations < 1 || f.KDFParams.Iterations > maxKDFIterations {
+		return nil, fmt.Errorf("invalid kdf iterations: %d", f.KDFParams.Iterations)
+	}
+	salt, err := hex.DecodeString(f.KDFParams.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, errors.New("invalid key file salt")
+	}
+	nonce, err := hex.DecodeString(f.Nonce)
+	if err != nil {
+		return nil, errors.New("invalid key file nonce")
+	}
+	ct, err := hex.DecodeString(f.Ciphertext)
+	if err != nil {
+		return nil, errors.New("invalid key file ciphertext")
+	}
+	if len(passphrase) == 0 {
+		return nil, ErrEmptyPassphrase
+	}
+
+	aead, err := keystoreAEAD(passphrase, salt, f.KDFParams.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid key file nonce")
+	}
+	pt, err := aead.Open(nil, nonce, ct, []byte(f.Address))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	if len(pt) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key size: got %d want %d", len(pt), ed25519.PrivateKeySize)
+	}
+	return ed25519.PrivateKey(pt), nil
+}
+
+// IsEncryptedKeyFile reports whether path holds a SaveEncrypted envelope
+// rather than a plaintext hex key.
+func IsEncryptedKeyFile(path string) (bool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(strings.TrimSpace(string(raw)), "{"), nil
+}
+
+func keystoreAEAD(passphrase, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
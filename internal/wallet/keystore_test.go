@@ -0,0 +1,95 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// saveTestKey writes a fresh key encrypted under passphrase and returns it
+// with the file's path.
+func saveTestKey(t *testing.T, passphrase string) (ed25519.PrivateKey, string) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := SaveEncrypted(path, priv, []byte(passphrase)); err != nil {
+		t.Fatal(err)
+	}
+	return priv, path
+}
+
+// editKeyFile rewrites the envelope at path through edit.
+func editKeyFile(t *testing.T, path string, edit func(*keystoreFile)) {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f keystoreFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		t.Fatal(err)
+	}
+	edit(&f)
+	if raw, err = json.Marshal(f); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	priv, path := saveTestKey(t, "correct horse")
+
+	if enc, err := IsEncryptedKeyFile(path); err != nil || !enc {
+		t.Fatalf("IsEncryptedKeyFile = %v, %v", enc, err)
+	}
+	got, err := LoadEncrypted(path, []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.Equal(got) {
+		t.Error("loaded key differs from the saved one")
+	}
+
+	if _, err := LoadEncrypted(path, []byte("correct horsE")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("wrong passphrase: err = %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestKeystoreTamperedAddress(t *testing.T) {
+	_, path := saveTestKey(t, "pw")
+	other, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := AddressFromPublicKey(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The address is the AEAD's associated data, so swapping it must fail
+	// authentication even with the right passphrase.
+	editKeyFile(t, path, func(f *keystoreFile) { f.Address = addr })
+
+	if _, err := LoadEncrypted(path, []byte("pw")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("err = %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestKeystoreRejectsIterationsOutOfRange(t *testing.T) {
+	_, path := saveTestKey(t, "pw")
+	for _, n := range []int{0, -1, maxKDFIterations + 1} {
+		editKeyFile(t, path, func(f *keystoreFile) { f.KDFParams.Iterations = n })
+		_, err := LoadEncrypted(path, []byte("pw"))
+		if err == nil || !strings.Contains(err.Error(), "invalid kdf iterations") {
+			t.Errorf("iterations %d: err = %v", n, err)
+		}
+	}
+}
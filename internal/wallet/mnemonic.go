@@ -0,0 +1,147 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Mnemonics follow BIP39 (English wordlist, 11 bits per word, SHA-256
+// checksum). The ed25519 key is derived as:
+//
+//	seed   = PBKDF2-HMAC-SHA512(phrase, "mnemonic", 2048 rounds, 64 bytes)  (BIP39, empty passphrase)
+//	master = HMAC-SHA512(key="ed25519 seed", seed)                           (SLIP-0010 master key)
+//	priv   = ed25519.NewKeyFromSeed(master[:32])
+//
+// so the same phrase always yields the same key and address.
+
+// DefaultMnemonicWords is used by GenerateMnemonic (256 bits of entropy,
+// matching the ed25519 seed size).
+const DefaultMnemonicWords = 24
+
+//go:embed english.txt
+var englishWords string
+
+var (
+	wordlist  = strings.Fields(englishWords)
+	wordIndex = func() map[string]int {
+		m := make(map[string]int, len(wordlist))
+		for i, w := range wordlist {
+			m[w] = i
+		}
+		return m
+	}()
+)
+
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// GenerateMnemonic returns a new DefaultMnemonicWords-word phrase and the key
+// it derives.
+func GenerateMnemonic() (string, Keypair, error) {
+	phrase, err := NewMnemonic(DefaultMnemonicWords)
+	if err != nil {
+		return "", Keypair{}, err
+	}
+	kp, err := FromMnemonic(phrase)
+	if err != nil {
+		return "", Keypair{}, err
+	}
+	return phrase, kp, nil
+}
+
+// NewMnemonic returns a random phrase of words words (12, 15, 18, 21 or 24).
+func NewMnemonic(words int) (string, error) {
+	if words < 12 || words > 24 || words%3 != 0 {
+		return "", fmt.Errorf("mnemonic must have 12, 15, 18, 21 or 24 words, got %d", words)
+	}
+	entropy := make([]byte, words*4/3)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+	return entropyToMnemonic(entropy), nil
+}
+
+// FromMnemonic derives the keypair for phrase. Case and extra whitespace are
+// ignored; unknown words or a bad checksum return ErrInvalidMnemonic.
+func FromMnemonic(phrase string) (Keypair, error) {
+	words := strings.Fields(strings.ToLower(phrase))
+	if _, err := mnemonicToEntropy(words); err != nil {
+		return Keypair{}, err
+	}
+
+	seed, err := mnemonicSeed(strings.Join(words, " "), "")
+	if err != nil {
+		return Keypair{}, err
+	}
+	priv := masterKey(seed)
+	return Keypair{PublicKey: priv.Public().(ed25519.PublicKey), PrivateKey: priv}, nil
+}
+
+// masterKey is the SLIP-0010 ed25519 master key for a BIP39 seed.
+func masterKey(seed []byte) ed25519.PrivateKey {
+	mac := hmac.New(sha512.New, []byte("ed25519 seed"))
+	mac.Write(seed)
+	master := mac.Sum(nil)
+	return ed25519.NewKeyFromSeed(master[:ed25519.SeedSize])
+}
+
+func mnemonicSeed(phrase, passphrase string) ([]byte, error) {
+	return pbkdf2.Key(sha512.New, phrase, []byte("mnemonic"+passphrase), 2048, 64)
+}
+
+func entropyToMnemonic(entropy []byte) string {
+	sum := sha256.Sum256(entropy)
+	csBits := len(entropy) * 8 / 32
+
+	// entropy || checksum bits, read 11 bits at a time
+	bits := append(append([]byte{}, entropy...), sum[0])
+	n := (len(entropy)*8 + csBits) / 11
+	words := make([]string, n)
+	for i := range n {
+		idx := 0
+		for j := range 11 {
+			pos := i*11 + j
+			bit := (bits[pos/8] >> (7 - pos%8)) & 1
+			idx = idx<<1 | int(bit)
+		}
+		words[i] = wordlist[idx]
+	}
+	return strings.Join(words, " ")
+}
+
+func mnemonicToEntropy(words []string) ([]byte, error) {
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("%w: expected 12, 15, 18, 21 or 24 words, got %d", ErrInvalidMnemonic, len(words))
+	}
+
+	total := len(words) * 11
+	csBits := total / 33
+	bits := make([]byte, (total+7)/8)
+	for i, w := range words {
+		idx, ok := wordIndex[w]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown word %q", ErrInvalidMnemonic, w)
+		}
+		for j := range 11 {
+			if idx>>(10-j)&1 == 1 {
+				pos := i*11 + j
+				bits[pos/8] |= 1 << (7 - pos%8)
+			}
+		}
+	}
+
+	entropy := bits[:(total-csBits)/8]
+	sum := sha256.Sum256(entropy)
+	mask := byte(0xff) << (8 - csBits)
+	if bits[len(entropy)]&mask != sum[0]&mask {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidMnemonic)
+	}
+	return entropy, nil
+}
@@ -0,0 +1,119 @@
+package wallet
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// BIP39 vectors from the reference implementation (trezor/python-mnemonic),
+// whose seeds use the passphrase "TREZOR".
+var bip39Vectors = []struct {
+	entropy, phrase, seed string
+}{
+	{
+		"00000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		"legal winner thank year wave sausage worth useful legal winner thank yellow",
+		"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		"ffffffffffffffffffffffffffffffff",
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+	},
+	{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+		"bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+	},
+}
+
+func TestBIP39Vectors(t *testing.T) {
+	for _, v := range bip39Vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+		if got := entropyToMnemonic(entropy); got != v.phrase {
+			t.Errorf("mnemonic(%s) = %q, want %q", v.entropy, got, v.phrase)
+		}
+		back, err := mnemonicToEntropy(strings.Fields(v.phrase))
+		if err != nil || hex.EncodeToString(back) != v.entropy {
+			t.Errorf("entropy(%q) = %x, %v; want %s", v.phrase, back, err, v.entropy)
+		}
+		seed, err := mnemonicSeed(v.phrase, "TREZOR")
+		if err != nil || hex.EncodeToString(seed) != v.seed {
+			t.Errorf("seed(%q) = %x, %v; want %s", v.phrase, seed, err, v.seed)
+		}
+	}
+}
+
+func TestSLIP10MasterKey(t *testing.T) {
+	// SLIP-0010 ed25519 test vector 1, chain m.
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	priv := masterKey(seed)
+	if got := hex.EncodeToString(priv.Seed()); got != "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7" {
+		t.Errorf("private key = %s", got)
+	}
+	// SLIP-0010 prefixes ed25519 public keys with 0x00.
+	if got := "00" + hex.EncodeToString(priv.Public().(ed25519.PublicKey)); got != "00a4b2856bfec510abab89753fac1ac0e1112364e7d250545963f135f2a33188ed" {
+		t.Errorf("public key = %s", got)
+	}
+}
+
+func TestFromMnemonicStable(t *testing.T) {
+	phrase := bip39Vectors[0].phrase
+	kp, err := FromMnemonic(phrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := AddressFromPublicKey(kp.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != stableAddress {
+		t.Errorf("address of %q = %s, want %s", phrase, addr, stableAddress)
+	}
+
+	// Case and spacing do not matter.
+	again, err := FromMnemonic("  ABANDON abandon abandon abandon abandon abandon\tabandon abandon abandon abandon abandon About ")
+	if err != nil || !again.PrivateKey.Equal(kp.PrivateKey) {
+		t.Errorf("normalized phrase gave a different key (%v)", err)
+	}
+}
+
+// stableAddress is the address FromMnemonic derives for the all-zero
+// 12-word phrase; it must never change.
+const stableAddress = "ada8e3423e041d247dca60598e6d3d8834161fe51ee063d6"
+
+func TestGenerateMnemonicRoundTrip(t *testing.T) {
+	phrase, kp, err := GenerateMnemonic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(strings.Fields(phrase)); n != DefaultMnemonicWords {
+		t.Errorf("%d words, want %d", n, DefaultMnemonicWords)
+	}
+	back, err := FromMnemonic(phrase)
+	if err != nil || !back.PrivateKey.Equal(kp.PrivateKey) {
+		t.Errorf("FromMnemonic(GenerateMnemonic()) differs (%v)", err)
+	}
+}
+
+func TestFromMnemonicRejects(t *testing.T) {
+	for _, phrase := range []string{
+		"",
+		"abandon abandon abandon",
+		// checksum: "about" -> "abandon"
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon veltaros",
+	} {
+		if _, err := FromMnemonic(phrase); !errors.Is(err, ErrInvalidMnemonic) {
+			t.Errorf("FromMnemonic(%q) err = %v, want ErrInvalidMnemonic", phrase, err)
+		}
+	}
+}
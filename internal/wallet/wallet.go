@@ -52,6 +52,32 @@ func ValidateAddress(addr string) bool {
 	return vcrypto.ConstantTimeEqual(got, want[:4])
 }
 
+// EncodeAddressBech32 re-encodes a hex address as bech32m(hrp, pubKeyHash20).
+// The bech32 checksum replaces checksum4, which is recomputed on decode.
+func EncodeAddressBech32(addr, hrp string) (string, error) {
+	addr = strings.TrimSpace(addr)
+	if !ValidateAddress(addr) {
+		return "", errors.New("invalid address")
+	}
+	b, _ := hex.DecodeString(addr)
+	return vcrypto.Bech32Encode(hrp, b[:20])
+}
+
+// DecodeAddressBech32 returns the HRP and the hex address of a bech32
+// address. It does not check the HRP against a network; see
+// blockchain.ValidateAddressBech32 for that.
+func DecodeAddressBech32(s string) (hrp, addr string, err error) {
+	hrp, pubHash20, err := vcrypto.Bech32Decode(strings.TrimSpace(s))
+	if err != nil {
+		return "", "", err
+	}
+	if len(pubHash20) != 20 {
+		return "", "", errors.New("invalid bech32 address length")
+	}
+	check := vcrypto.DoubleSha256(pubHash20)
+	return hrp, hex.EncodeToString(pubHash20) + hex.EncodeToString(check[:4]), nil
+}
+
 func Generate() (Keypair, error) {
 	pub, priv, err := ed25519.GenerateKey(rand.Reader)
 	if err != nil {
@@ -67,15 +93,17 @@ func SavePrivateKeyHex(path string, priv ed25519.PrivateKey) error {
 	if len(priv) != ed25519.PrivateKeySize {
 		return errors.New("invalid ed25519 private key size")
 	}
+	return writeKeyFile(path, []byte(hex.EncodeToString(priv)))
+}
 
+// writeKeyFile writes data to path via temp file + rename with 0600 perms.
+func writeKeyFile(path string, data []byte) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0o700); err != nil {
 		return err
 	}
 
 	tmp := path + ".tmp"
-	data := []byte(hex.EncodeToString(priv))
-
 	if err := os.WriteFile(tmp, data, 0o600); err != nil {
 		return err
 	}
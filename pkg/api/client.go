@@ -1,20 +1,47 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// DefaultPathPrefix is the API version the client targets by default.
+const DefaultPathPrefix = "/v1"
+
 type Client struct {
 	baseURL string
+	prefix  string
+	apiKey  string
 	http    *http.Client
 }
 
+// StatusError is returned for non-2xx responses. Message and Code carry the
+// node's JSON "error" and "code" fields when present; Code is stable (e.g.
+// "BAD_SIGNATURE", "NONCE_TOO_LOW") and is what callers should match on.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+	Code       string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("http %s %s: status %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("http %s %s: status %d", e.Method, e.Path, e.StatusCode)
+}
+
 type Option func(*Client)
 
 func WithHTTPClient(c *http.Client) Option {
@@ -25,6 +52,21 @@ func WithHTTPClient(c *http.Client) Option {
 	}
 }
 
+// WithAPIKey sends the key as X-API-Key on every request.
+func WithAPIKey(key string) Option {
+	return func(cl *Client) {
+		cl.apiKey = strings.TrimSpace(key)
+	}
+}
+
+// WithPathPrefix overrides the API version prefix (default "/v1"). Pass ""
+// to target a node that predates versioned routes.
+func WithPathPrefix(prefix string) Option {
+	return func(cl *Client) {
+		cl.prefix = strings.TrimRight(strings.TrimSpace(prefix), "/")
+	}
+}
+
 func New(baseURL string, opts ...Option) (*Client, error) {
 	baseURL = strings.TrimSpace(baseURL)
 	if baseURL == "" {
@@ -34,6 +76,7 @@ func New(baseURL string, opts ...Option) (*Client, error) {
 
 	cl := &Client{
 		baseURL: baseURL,
+		prefix:  DefaultPathPrefix,
 		http: &http.Client{
 			Timeout: 10 * time.Second,
 		},
@@ -60,6 +103,15 @@ func (c *Client) Version(ctx context.Context) (VersionInfo, error) {
 	return out, nil
 }
 
+// Features reports which optional capabilities the node runs with.
+func (c *Client) Features(ctx context.Context) (Features, error) {
+	var out Features
+	if err := c.getJSON(ctx, "/features", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *Client) Status(ctx context.Context) (NodeStatus, error) {
 	var out NodeStatus
 	if err := c.getJSON(ctx, "/status", &out); err != nil {
@@ -68,6 +120,15 @@ func (c *Client) Status(ctx context.Context) (NodeStatus, error) {
 	return out, nil
 }
 
+// Params returns the chain rules the node enforces when validating txs.
+func (c *Client) Params(ctx context.Context) (ChainParams, error) {
+	var out ChainParams
+	if err := c.getJSON(ctx, "/params", &out); err != nil {
+		return ChainParams{}, err
+	}
+	return out, nil
+}
+
 func (c *Client) Peers(ctx context.Context) (PeerList, error) {
 	var out PeerList
 	if err := c.getJSON(ctx, "/peers", &out); err != nil {
@@ -76,12 +137,175 @@ func (c *Client) Peers(ctx context.Context) (PeerList, error) {
 	return out, nil
 }
 
+// Bans lists the node's active peer bans. Nodes with api.keyOnPeerState
+// need WithAPIKey.
+func (c *Client) Bans(ctx context.Context) (BanList, error) {
+	var out BanList
+	if err := c.getJSON(ctx, "/bans", &out); err != nil {
+		return BanList{}, err
+	}
+	return out, nil
+}
+
+// Scores lists peers with a positive misbehaviour score, highest first.
+// Nodes with api.keyOnPeerState need WithAPIKey.
+func (c *Client) Scores(ctx context.Context) (ScoreList, error) {
+	var out ScoreList
+	if err := c.getJSON(ctx, "/scores", &out); err != nil {
+		return ScoreList{}, err
+	}
+	return out, nil
+}
+
+// Mempool lists pending txs; if from is non-empty only that sender's txs are returned.
+func (c *Client) Mempool(ctx context.Context, from string) (MempoolList, error) {
+	path := "/mempool"
+	if from != "" {
+		path += "?from=" + url.QueryEscape(from)
+	}
+	var out MempoolList
+	if err := c.getJSON(ctx, path, &out); err != nil {
+		return MempoolList{}, err
+	}
+	return out, nil
+}
+
+// MempoolStats returns mempool occupancy (count, bytes) and its limits.
+func (c *Client) MempoolStats(ctx context.Context) (MempoolStats, error) {
+	var out MempoolStats
+	if err := c.getJSON(ctx, "/mempool/stats", &out); err != nil {
+		return MempoolStats{}, err
+	}
+	return out, nil
+}
+
+// Supply returns the total, circulating and staked token supply.
+func (c *Client) Supply(ctx context.Context) (Supply, error) {
+	var out Supply
+	if err := c.getJSON(ctx, "/supply", &out); err != nil {
+		return Supply{}, err
+	}
+	return out, nil
+}
+
+// Account returns the balances and nonce state of address.
+func (c *Client) Account(ctx context.Context, address string) (AccountInfo, error) {
+	var out AccountInfo
+	if err := c.getJSON(ctx, "/account/"+url.PathEscape(address), &out); err != nil {
+		return AccountInfo{}, err
+	}
+	return out, nil
+}
+
+// AccountNonce returns address's confirmed and reserved nonces. If check is
+// non-zero the node also classifies that nonce (see NonceCheck).
+func (c *Client) AccountNonce(ctx context.Context, address string, check uint64) (AccountNonce, error) {
+	path := "/account/" + url.PathEscape(address) + "/nonce"
+	if check > 0 {
+		path += "?check=" + strconv.FormatUint(check, 10)
+	}
+	var out AccountNonce
+	if err := c.getJSON(ctx, path, &out); err != nil {
+		return AccountNonce{}, err
+	}
+	return out, nil
+}
+
+// AccountTxs returns a page of address's confirmed txs, newest first. A zero
+// limit uses the node's default page size.
+func (c *Client) AccountTxs(ctx context.Context, address string, offset, limit int) (AccountTxs, error) {
+	q := url.Values{}
+	if offset > 0 {
+		q.Set("offset", strconv.Itoa(offset))
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/account/" + url.PathEscape(address) + "/txs"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var out AccountTxs
+	if err := c.getJSON(ctx, path, &out); err != nil {
+		return AccountTxs{}, err
+	}
+	return out, nil
+}
+
+// BlockProof returns the merkle inclusion proof of txID in the block
+// blockHash.
+func (c *Client) BlockProof(ctx context.Context, blockHash, txID string) (MerkleProof, error) {
+	var out MerkleProof
+	if err := c.getJSON(ctx, "/block/"+url.PathEscape(blockHash)+"/proof/"+url.PathEscape(txID), &out); err != nil {
+		return MerkleProof{}, err
+	}
+	return out, nil
+}
+
+// ValidateTx runs the node's admission checks on tx without broadcasting it.
+// Nodes with api.keyOnValidate need WithAPIKey.
+func (c *Client) ValidateTx(ctx context.Context, tx SignedTx) (ValidateResult, error) {
+	var out ValidateResult
+	if err := c.postJSON(ctx, "/tx/validate", tx, &out); err != nil {
+		return ValidateResult{}, err
+	}
+	return out, nil
+}
+
+// Broadcast submits tx to the mempool and relays it to peers. Nodes with
+// api.keyOnBroadcast need WithAPIKey.
+func (c *Client) Broadcast(ctx context.Context, tx SignedTx) (BroadcastResult, error) {
+	var out BroadcastResult
+	if err := c.postJSON(ctx, "/tx/broadcast", tx, &out); err != nil {
+		return BroadcastResult{}, err
+	}
+	return out, nil
+}
+
+// BroadcastRaw submits a tx in the binary block encoding
+// (length-prefixed canonical draft, public key, signature) as
+// application/octet-stream. Nodes started with api.binaryTx=false answer 415.
+func (c *Client) BroadcastRaw(ctx context.Context, raw []byte) (BroadcastResult, error) {
+	var out BroadcastResult
+	if err := c.do(ctx, http.MethodPost, "/tx/broadcast", "application/octet-stream", bytes.NewReader(raw), &out); err != nil {
+		return BroadcastResult{}, err
+	}
+	return out, nil
+}
+
+func (c *Client) Faucet(ctx context.Context, address string, amount uint64) (FaucetResult, error) {
+	var out FaucetResult
+	in := FaucetRequest{Address: address, Amount: amount}
+	if err := c.postJSON(ctx, "/faucet", in, &out); err != nil {
+		return FaucetResult{}, err
+	}
+	return out, nil
+}
+
 func (c *Client) getJSON(ctx context.Context, path string, out any) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	return c.do(ctx, http.MethodGet, path, "", nil, out)
+}
+
+func (c *Client) postJSON(ctx context.Context, path string, in any, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, path, "application/json", bytes.NewReader(body), out)
+}
+
+func (c *Client) do(ctx context.Context, method, path, contentType string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+c.prefix+path, body)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
 
 	resp, err := c.http.Do(req)
 	if err != nil {
@@ -90,7 +314,16 @@ func (c *Client) getJSON(ctx context.Context, path string, out any) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return fmt.Errorf("http %s %s: status %d", http.MethodGet, path, resp.StatusCode)
+		se := &StatusError{Method: method, Path: path, StatusCode: resp.StatusCode}
+		var e struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}
+		if json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&e) == nil {
+			se.Message = e.Error
+			se.Code = e.Code
+		}
+		return se
 	}
 
 	dec := json.NewDecoder(resp.Body)
@@ -7,20 +7,134 @@ type VersionInfo struct {
 	Platform  string `json:"platform"`
 }
 
+// Features maps optional node capabilities to whether they are enabled
+// (GET /features). Names unknown to an older node are simply absent.
+type Features map[string]bool
+
 type Health struct {
 	OK   bool   `json:"ok"`
 	Time string `json:"time"`
 }
 
 type NodeStatus struct {
-	NetworkID   string `json:"networkID"`
-	StartedAt   string `json:"startedAt"`
-	UptimeSec   int64  `json:"uptimeSec"`
-	Peers       int    `json:"peers"`
-	KnownPeers  int    `json:"knownPeers"`
-	BannedPeers int    `json:"bannedPeers"`
-	Height      uint64 `json:"height"`
-	DataDir     string `json:"dataDir"`
+	NetworkID    string `json:"networkID"`
+	StartedAt    string `json:"startedAt"`
+	UptimeSec    int64  `json:"uptimeSec"`
+	Peers        int    `json:"peers"`
+	Handshaking  int    `json:"handshaking"`
+	Unverified   int    `json:"unverified"`
+	KnownPeers   int    `json:"knownPeers"`
+	BannedPeers  int    `json:"bannedPeers"`
+	Height       uint64 `json:"height"`
+	Mempool      int    `json:"mempool"`
+	MempoolBytes int    `json:"mempoolBytes"`
+	TipHash      string `json:"tipHash"`
+	DataDir      string `json:"dataDir"`
+	DevMode      bool   `json:"devMode"`
+
+	// MinFee and MinFeePerByte are the node's fee floor: a tx must pay at
+	// least max(MinFee, MinFeePerByte × canonical draft size).
+	MinFee        uint64 `json:"minFee"`
+	MinFeePerByte uint64 `json:"minFeePerByte"`
+
+	Runtime RuntimeStatus `json:"runtime"`
+}
+
+// RuntimeStatus is the node's persisted run history; counters survive
+// restarts until an operator resets them (POST /admin/runtime/reset).
+type RuntimeStatus struct {
+	Starts              uint64 `json:"starts"`
+	Restarts            uint64 `json:"restarts"`
+	UncleanStops        uint64 `json:"uncleanStops"`
+	FirstStart          string `json:"firstStart"`
+	LastStart           string `json:"lastStart"`
+	LastSeen            string `json:"lastSeen"`
+	ResetAt             string `json:"resetAt,omitempty"`
+	CumulativeUptimeSec int64  `json:"cumulativeUptimeSec"`
+	Running             bool   `json:"running"`
+}
+
+// AccountInfo is the /account/<address> response. ExpectedNonce is the nonce
+// the node will accept next from this address; QueuedTxs are its pending
+// mempool txs and FutureTxs those held waiting for a nonce gap to fill.
+type AccountInfo struct {
+	Address          string `json:"address"`
+	LastNonce        uint64 `json:"lastNonce"`
+	ExpectedNonce    uint64 `json:"expectedNonce"`
+	QueuedTxs        int    `json:"queuedTxs"`
+	FutureTxs        int    `json:"futureTxs"`
+	ConfirmedBalance uint64 `json:"confirmedBalance"`
+	PendingOut       uint64 `json:"pendingOut"`
+	StakedBalance    uint64 `json:"stakedBalance"`
+	SpendableBalance uint64 `json:"spendableBalance"`
+}
+
+// AccountNonce is the /account/<address>/nonce response: nonces confirmed in
+// blocks (up to LastConfirmed), reserved by pending mempool txs, or held for
+// a nonce gap, and the nonce the node accepts next. Check is set when the
+// request asked about one nonce.
+type AccountNonce struct {
+	Address           string      `json:"address"`
+	LastConfirmed     uint64      `json:"lastConfirmed"`
+	ExpectedNext      uint64      `json:"expectedNext"`
+	ReservedInMempool []uint64    `json:"reservedInMempool"`
+	Held              []uint64    `json:"held"`
+	Check             *NonceCheck `json:"check,omitempty"`
+}
+
+// NonceCheck classifies one nonce: "confirmed", "reserved", "held",
+// "skipped" (passed over, no longer usable), "next" or "future".
+type NonceCheck struct {
+	Nonce  uint64 `json:"nonce"`
+	Status string `json:"status"`
+}
+
+// AccountTxs is the /account/<address>/txs response: one page of the
+// address's confirmed txs, sent or received, newest first. Total counts all
+// of them.
+type AccountTxs struct {
+	Address string      `json:"address"`
+	Total   int         `json:"total"`
+	Offset  int         `json:"offset"`
+	Count   int         `json:"count"`
+	Txs     []AccountTx `json:"txs"`
+}
+
+// AccountTx is a confirmed tx with the block that holds it.
+type AccountTx struct {
+	Height    uint64   `json:"height"`
+	BlockHash string   `json:"blockHash"`
+	Tx        SignedTx `json:"tx"`
+}
+
+type ChainParams struct {
+	TxVersion       uint32 `json:"txVersion"`
+	MinFee          uint64 `json:"minFee"`
+	MaxTxBytes      int    `json:"maxTxBytes"`
+	MaxMemoLen      int    `json:"maxMemoLen"`
+	MaxClientRefLen int    `json:"maxClientRefLen"`
+	MaxTxClass      uint8  `json:"maxTxClass"`
+
+	MaxFutureSkewSec int64 `json:"maxFutureSkewSec"`
+	MaxPastSkewSec   int64 `json:"maxPastSkewSec"`
+
+	MaxBlockBytes         int   `json:"maxBlockBytes"`
+	TargetBlockSpacingSec int64 `json:"targetBlockSpacingSec"`
+
+	AddressFormat   string `json:"addressFormat"`
+	AddressLenBytes int    `json:"addressLenBytes"`
+
+	RequireKnownRecipient bool `json:"requireKnownRecipient"`
+	HonorTxClass          bool `json:"honorTxClass"`
+
+	MaxMempoolTxs   int `json:"maxMempoolTxs"`
+	MaxMempoolBytes int `json:"maxMempoolBytes"`
+
+	MinRelayFee        uint64 `json:"minRelayFee"`
+	MinRelayFeePerByte uint64 `json:"minRelayFeePerByte"`
+
+	BlockReward   uint64 `json:"blockReward"`
+	PowDifficulty uint   `json:"powDifficulty"`
 }
 
 type PeerInfo struct {
@@ -31,9 +145,148 @@ type PeerInfo struct {
 	NodeVersion  string `json:"nodeVersion"`
 	Verified     bool   `json:"verified"`
 	Score        int    `json:"score"`
+	Height       uint64 `json:"height"`
+
+	ProtocolVersion uint16 `json:"protocolVersion"`
+	Encrypted       bool   `json:"encrypted"`
 }
 
 type PeerList struct {
 	Count int        `json:"count"`
 	Peers []PeerInfo `json:"peers"`
 }
+
+// BanInfo is one active ban from /bans. Until is zero and RemainingSec is
+// omitted for a Permanent ban.
+type BanInfo struct {
+	Addr         string `json:"addr"`
+	Until        string `json:"until"`
+	Permanent    bool   `json:"permanent,omitempty"`
+	Reason       string `json:"reason"`
+	UpdatedAt    string `json:"updatedAt"`
+	RemainingSec int64  `json:"remainingSec,omitempty"`
+}
+
+type BanList struct {
+	Count int       `json:"count"`
+	Bans  []BanInfo `json:"bans"`
+}
+
+// PeerScore is a peer's misbehaviour score (after decay) from /scores;
+// peers are banned when it reaches the node's threshold.
+type PeerScore struct {
+	Addr       string `json:"addr"`
+	Score      int    `json:"score"`
+	LastUpdate string `json:"lastUpdate"`
+}
+
+type ScoreList struct {
+	Count  int         `json:"count"`
+	Scores []PeerScore `json:"scores"`
+}
+
+type FaucetRequest struct {
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+}
+
+type FaucetResult struct {
+	OK      bool   `json:"ok"`
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+	Balance uint64 `json:"balance"`
+}
+
+// TxDraft and SignedTx mirror the node's transaction wire format.
+type TxDraft struct {
+	Version   uint32 `json:"version"`
+	NetworkID string `json:"networkId"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Amount    uint64 `json:"amount"`
+	Fee       uint64 `json:"fee"`
+	// Outputs is set on version 2 (multi-output) drafts instead of To/Amount.
+	Outputs   []TxOutput `json:"outputs,omitempty"`
+	Nonce     uint64     `json:"nonce"`
+	Timestamp int64      `json:"timestamp"`
+	Memo      string     `json:"memo,omitempty"`
+	ClientRef string     `json:"clientRef,omitempty"`
+	Class     uint8      `json:"class,omitempty"`
+}
+
+type TxOutput struct {
+	To     string `json:"to"`
+	Amount uint64 `json:"amount"`
+}
+
+type SignedTx struct {
+	Draft        TxDraft `json:"draft"`
+	PublicKeyHex string  `json:"publicKeyHex"`
+	SignatureHex string  `json:"signatureHex"`
+	TxID         string  `json:"txId"`
+}
+
+// MempoolStats is the /mempool/stats response; a zero limit is unlimited.
+type MempoolStats struct {
+	Count    int `json:"count"`
+	Bytes    int `json:"bytes"`
+	MaxTxs   int `json:"maxTxs"`
+	MaxBytes int `json:"maxBytes"`
+}
+
+// Supply is the /supply response: ledger-wide totals at the ledger's applied
+// height. Staked funds count toward Total but not Circulating.
+type Supply struct {
+	Total       uint64 `json:"total"`
+	Circulating uint64 `json:"circulating"`
+	Staked      uint64 `json:"staked"`
+	Accounts    int    `json:"accounts"`
+	Height      uint64 `json:"height"`
+}
+
+type MempoolList struct {
+	Count int        `json:"count"`
+	Txs   []SignedTx `json:"txs"`
+}
+
+// ValidateResult is the /tx/validate response: the tx passed every check the
+// broadcast path applies, without being admitted to the mempool.
+type ValidateResult struct {
+	OK            bool   `json:"ok"`
+	TxID          string `json:"txId"`
+	ClientRef     string `json:"clientRef"`
+	From          string `json:"from"`
+	LastNonce     uint64 `json:"lastNonce"`
+	ExpectedNonce uint64 `json:"expectedNonce"`
+	MempoolHas    bool   `json:"mempoolHas"`
+	Spendable     uint64 `json:"spendable"`
+}
+
+type BroadcastResult struct {
+	OK        bool   `json:"ok"`
+	TxID      string `json:"txId"`
+	ClientRef string `json:"clientRef"`
+	Peers     int    `json:"peers"`
+	MinPeers  int    `json:"minPeers,omitempty"`
+	Warning   string `json:"warning,omitempty"`
+	Note      string `json:"note,omitempty"`
+	Replaced  string `json:"replaced,omitempty"`
+
+	// Held is set (HTTP 202) when the nonce is ahead of ExpectedNonce and the
+	// node parked the tx until the gap fills.
+	Held          bool   `json:"held,omitempty"`
+	ExpectedNonce uint64 `json:"expectedNonce,omitempty"`
+}
+
+// MerkleProof proves a tx is in a block: hashing TxID with each Path entry
+// in turn (on the right of the running hash when the matching Dirs entry is
+// true, else on the left) gives MerkleRoot, which the block header commits
+// to. Hashes are hex; the hash function is doubleSha256.
+type MerkleProof struct {
+	BlockHash  string   `json:"blockHash"`
+	Height     uint64   `json:"height"`
+	MerkleRoot string   `json:"merkleRoot"`
+	TxID       string   `json:"txId"`
+	Path       []string `json:"path"`
+	Dirs       []bool   `json:"dirs"`
+}
@@ -31,6 +31,10 @@ type PeerInfo struct {
 	NodeVersion  string `json:"nodeVersion"`
 	Verified     bool   `json:"verified"`
 	Score        int    `json:"score"`
+	Height       uint64 `json:"height"`
+
+	ProtocolVersion uint16 `json:"protocolVersion"`
+	Encrypted       bool   `json:"encrypted"`
 }
 
 type PeerList struct {
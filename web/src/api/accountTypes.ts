@@ -2,8 +2,21 @@ export type AccountInfo = {
     address: string;
     lastNonce: number;
     expectedNonce: number;
+    queuedTxs: number;
+    futureTxs: number;
 
     confirmedBalance: number;
     pendingOut: number;
     spendableBalance: number;
 };
+
+export type NonceStatus = "confirmed" | "reserved" | "held" | "skipped" | "next" | "future";
+
+export type AccountNonce = {
+    address: string;
+    lastConfirmed: number;
+    expectedNext: number;
+    reservedInMempool: number[];
+    held: number[];
+    check?: { nonce: number; status: NonceStatus };
+};
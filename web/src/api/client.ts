@@ -1,6 +1,6 @@
 import type { Health, NodeStatus, PeerList, VersionInfo } from "./types";
 import type { MempoolResponse } from "./mempoolTypes";
-import type { AccountInfo } from "./accountTypes";
+import type { AccountInfo, AccountNonce } from "./accountTypes";
 import type { SignedTx } from "../tx/types";
 
 type Json = Record<string, unknown> | unknown[] | string | number | boolean | null;
@@ -17,6 +17,8 @@ export type TxValidateOk = {
 export type TxValidateErr = {
     ok: false;
     error: string;
+    /** Stable reason code, e.g. "BAD_SIGNATURE", "NONCE_TOO_LOW"; match on this, not `error`. */
+    code?: string;
     lastNonce?: number;
     expectedNonce?: number;
 };
@@ -30,6 +32,8 @@ export type TxBroadcastOk = {
 export type TxBroadcastErr = {
     ok: false;
     error: string;
+    /** Stable reason code, e.g. "BAD_SIGNATURE", "NONCE_TOO_LOW"; match on this, not `error`. */
+    code?: string;
     lastNonce?: number;
     expectedNonce?: number;
 };
@@ -87,6 +91,12 @@ export class VeltarosApiClient {
         return this.getJson<AccountInfo>(`/account/${safe}`, signal);
     }
 
+    async accountNonce(address: string, check?: number, signal?: AbortSignal): Promise<AccountNonce> {
+        const safe = encodeURIComponent(address.trim());
+        const q = check ? `?check=${encodeURIComponent(String(check))}` : "";
+        return this.getJson<AccountNonce>(`/account/${safe}/nonce${q}`, signal);
+    }
+
     async txValidate(tx: SignedTx, signal?: AbortSignal): Promise<TxValidateResponse> {
         return this.postJson<TxValidateResponse>("/tx/validate", tx, signal, true);
     }
@@ -10,6 +10,8 @@ export type VersionInfo = {
     buildTime?: string;
 };
 
+export type NodeFeatures = Record<string, boolean>;
+
 export type NodeStatus = {
     networkID: string;
     startedAt: string;
@@ -17,6 +19,7 @@ export type NodeStatus = {
 
     height: number;
     mempool: number;
+    mempoolBytes?: number;
 
     peers: number;
     knownPeers?: number;
@@ -24,6 +27,20 @@ export type NodeStatus = {
 
     dataDir?: string;
     devMode?: boolean;
+
+    runtime?: RuntimeStatus;
+};
+
+export type RuntimeStatus = {
+    starts: number;
+    restarts: number;
+    uncleanStops: number;
+    firstStart: string;
+    lastStart: string;
+    lastSeen: string;
+    resetAt?: string;
+    cumulativeUptimeSec: number;
+    running: boolean;
 };
 
 export type PeerInfo = {
@@ -34,6 +51,7 @@ export type PeerInfo = {
     publicKeyHex?: string;
     nodeVersion?: string;
     connectedAt: number;
+    height?: number;
 };
 
 export type PeerList = {
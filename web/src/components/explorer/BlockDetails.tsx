@@ -56,11 +56,21 @@ export default function BlockDetails({ block }: { block: StoredBlock }): React.R
                                 <div className="explorerTxRow">
                                     <span className="muted tiny mono">{tx.draft.from.slice(0, 16)}…</span>
                                     <span className="muted tiny">→</span>
-                                    <span className="muted tiny mono">{tx.draft.to.slice(0, 16)}…</span>
+                                    {tx.draft.outputs?.length ? (
+                                        <span className="muted tiny">{tx.draft.outputs.length} recipients</span>
+                                    ) : (
+                                        <span className="muted tiny mono">{tx.draft.to.slice(0, 16)}…</span>
+                                    )}
                                 </div>
+                                {tx.draft.outputs?.map((o) => (
+                                    <div key={o.to} className="explorerTxRow">
+                                        <span className="muted tiny mono">{o.to.slice(0, 16)}…</span>
+                                        <span className="mono tiny">{o.amount}</span>
+                                    </div>
+                                ))}
                                 <div className="explorerTxRow">
                                     <span className="muted tiny">Amount:</span>
-                                    <span className="mono tiny">{tx.draft.amount}</span>
+                                    <span className="mono tiny">{tx.draft.outputs?.length ? tx.draft.outputs.reduce((n, o) => n + o.amount, 0) : tx.draft.amount}</span>
                                     <span className="muted tiny">Fee:</span>
                                     <span className="mono tiny">{tx.draft.fee}</span>
                                     <span className="muted tiny">Nonce:</span>
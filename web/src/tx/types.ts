@@ -1,5 +1,10 @@
+export type TxOutput = {
+    to: string;
+    amount: number;
+};
+
 export type TxDraft = {
-    version: number; // must be 1
+    version: number; // 1, or 2 for multi-output txs (this wallet signs only 1)
     networkId: string;
 
     from: string;
@@ -12,6 +17,9 @@ export type TxDraft = {
     timestamp: number;
 
     memo?: string;
+
+    // Version 2 only: each output receives its amount in full; to/amount are empty.
+    outputs?: TxOutput[];
 };
 
 export type SignedTx = {